@@ -3,6 +3,7 @@ package api
 import (
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
 
 	db "github.com/aulas/demo-bank/db/sqlc"
@@ -13,8 +14,11 @@ import (
 
 type createAccountRequest struct {
 	Currency string `json:"currency" binding:"required,currency"`
+	Product  string `json:"product"`
 }
 
+const defaultProduct = "checking"
+
 func (s *Server) createAccount(ctx *gin.Context) {
 	var req createAccountRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -22,14 +26,30 @@ func (s *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
+	if req.Product == "" {
+		req.Product = defaultProduct
+	}
+
+	if _, err := s.store.GetProduct(ctx, req.Product); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = fmt.Errorf("unknown product %q", req.Product)
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	arg := db.CreateAccountParams{
 		Owner:    authPayload.Username,
 		Currency: req.Currency,
 		Balance:  0,
+		Product:  req.Product,
 	}
 
-	account, err := s.store.CreateAccount(ctx, arg)
+	account, err := s.store.CreateAccountTx(ctx, arg)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
@@ -75,6 +95,10 @@ func (s *Server) getAccount(ctx *gin.Context) {
 		return
 	}
 
+	if !allowAccount(ctx, account.ID) {
+		return
+	}
+
 	ctx.JSON(http.StatusOK, account)
 }
 
@@ -103,38 +127,132 @@ func (s *Server) listAccount(ctx *gin.Context) {
 		return
 	}
 
+	// Tokens restricted to some accounts only see those, so their pages may
+	// come back short.
+	allowed := accounts[:0]
+	for _, account := range accounts {
+		if authPayload.CanUseAccount(account.ID) {
+			allowed = append(allowed, account)
+		}
+	}
+	accounts = allowed
+
 	ctx.JSON(http.StatusOK, accounts)
 }
 
-type updateAccountRequest struct {
-	ID      int64 `json:"id" binding:"required,min=1"`
-	Balance int64 `json:"balance" binding:"required"`
+type createAdjustmentURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type createAdjustmentRequest struct {
+	Amount     int64  `json:"amount" binding:"required"`
+	ReasonCode string `json:"reason_code" binding:"required,oneof=correction goodwill chargeback fee_refund"`
+	Note       string `json:"note" binding:"max=500"`
 }
 
-func (s *Server) updateAccount(ctx *gin.Context) {
-	var req updateAccountRequest
+func (s *Server) createAdjustment(ctx *gin.Context) {
+	var uri createAdjustmentURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req createAdjustmentRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
 
-	arg := db.UpdateAccountParams{
-		ID:      req.ID,
-		Balance: req.Balance,
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.AdjustmentTxParams{
+		AccountID:  uri.ID,
+		Amount:     req.Amount,
+		ReasonCode: db.AdjustmentReason(req.ReasonCode),
+		Note:       req.Note,
+		Operator:   authPayload.Username,
 	}
 
-	updatedAccount, err := s.store.UpdateAccount(ctx, arg)
+	result, err := s.store.AdjustmentTx(ctx, arg)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, err)
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
 
-		ctx.JSON(http.StatusInternalServerError, err)
+		if errors.Is(err, db.ErrInternalAccount) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, updatedAccount)
+	ctx.JSON(http.StatusCreated, result)
+}
+
+var (
+	errAccountNotEmpty      = errors.New("account balance must be zero to close it")
+	errAccountAlreadyClosed = errors.New("account is already closed")
+)
+
+type closeAccountRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// closeAccount closes an empty account. Closed accounts keep their history
+// but can no longer send or receive transfers.
+func (s *Server) closeAccount(ctx *gin.Context) {
+	var req closeAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := s.store.GetAccount(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doest belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !allowAccount(ctx, account.ID) {
+		return
+	}
+
+	switch {
+	case account.Status == db.AccountStatusClosed:
+		ctx.JSON(http.StatusConflict, errorResponse(errAccountAlreadyClosed))
+		return
+	case account.Balance != 0:
+		ctx.JSON(http.StatusConflict, errorResponse(errAccountNotEmpty))
+		return
+	}
+
+	account, err = s.store.CloseAccount(ctx, account.ID)
+	if err != nil {
+		// the balance changed since it was read
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errAccountNotEmpty))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
 }
 
 type deleteAccountRequest struct {
@@ -148,7 +266,29 @@ func (s *Server) deleteAccount(ctx *gin.Context) {
 		return
 	}
 
-	err := s.store.DeleteAccount(ctx, req.ID)
+	account, err := s.store.GetAccount(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doest belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !allowAccount(ctx, account.ID) {
+		return
+	}
+
+	err = s.store.DeleteAccount(ctx, account.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, err)
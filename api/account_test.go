@@ -149,9 +149,10 @@ func TestCreateAccount(t *testing.T) {
 						Owner:    acc.Owner,
 						Currency: acc.Currency,
 						Balance:  0,
+						Product:  defaultProduct,
 					}
 					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Eq(expectedArg)).
+						CreateAccountTx(gomock.Any(), gomock.Eq(expectedArg)).
 						Times(1).
 						Return(acc, nil)
 				},
@@ -161,6 +162,30 @@ func TestCreateAccount(t *testing.T) {
 				},
 			},
 		},
+		{
+			request: createAccountRequest{
+				Currency: acc.Currency,
+				Product:  "gold",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "UnknownProduct",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetProduct(gomock.Any(), gomock.Eq("gold")).
+						Times(1).
+						Return(db.Product{}, sql.ErrNoRows)
+					store.EXPECT().
+						CreateAccountTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
 		{
 			request: createAccountRequest{
 				Currency: "invalid",
@@ -172,7 +197,7 @@ func TestCreateAccount(t *testing.T) {
 				name: "BadRequest",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Any()).
+						CreateAccountTx(gomock.Any(), gomock.Any()).
 						Times(0)
 				},
 				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -191,7 +216,7 @@ func TestCreateAccount(t *testing.T) {
 				name: "foreign_key_violation",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Any()).
+						CreateAccountTx(gomock.Any(), gomock.Any()).
 						Times(1).
 						Return(db.Account{}, &pq.Error{Code: "23503"})
 				},
@@ -211,7 +236,7 @@ func TestCreateAccount(t *testing.T) {
 				name: "unique_violation",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Any()).
+						CreateAccountTx(gomock.Any(), gomock.Any()).
 						Times(1).
 						Return(db.Account{}, &pq.Error{Code: "23505"})
 				},
@@ -231,7 +256,7 @@ func TestCreateAccount(t *testing.T) {
 				name: "StatusInternalServerError",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Any()).
+						CreateAccountTx(gomock.Any(), gomock.Any()).
 						Times(1).
 						Return(db.Account{}, errors.New("some error"))
 				},
@@ -251,7 +276,7 @@ func TestCreateAccount(t *testing.T) {
 				name: "Unauthorized",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						CreateAccount(gomock.Any(), gomock.Any()).
+						CreateAccountTx(gomock.Any(), gomock.Any()).
 						Times(0)
 				},
 				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -266,6 +291,10 @@ func TestCreateAccount(t *testing.T) {
 			// given
 			test := newTest(t, "/accounts")
 			tc.buildStubs(test.store)
+			test.store.EXPECT().
+				GetProduct(gomock.Any(), gomock.Eq(defaultProduct)).
+				AnyTimes().
+				Return(db.Product{Code: defaultProduct}, nil)
 
 			body, err := toReader(tc.request)
 			require.NoError(t, err)
@@ -299,6 +328,10 @@ func TestDeleteAccount(t *testing.T) {
 			baseTestCase: baseTestCase{
 				name: "OK",
 				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
+						Times(1).
+						Return(acc, nil)
 					store.EXPECT().
 						DeleteAccount(gomock.Any(), gomock.Eq(acc.ID)).
 						Times(1).
@@ -309,6 +342,27 @@ func TestDeleteAccount(t *testing.T) {
 				},
 			},
 		},
+		{
+			accountID: acc.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, util.RandomUsername(), time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "UnauthorizedUser",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
+						Times(1).
+						Return(acc, nil)
+					store.EXPECT().
+						DeleteAccount(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
 		{
 			accountID: 0,
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
@@ -335,9 +389,12 @@ func TestDeleteAccount(t *testing.T) {
 				name: "NotFound",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						DeleteAccount(gomock.Any(), gomock.Any()).
+						GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
 						Times(1).
-						Return(sql.ErrNoRows)
+						Return(db.Account{}, sql.ErrNoRows)
+					store.EXPECT().
+						DeleteAccount(gomock.Any(), gomock.Any()).
+						Times(0)
 				},
 				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 					require.Equal(t, http.StatusNotFound, recorder.Code)
@@ -352,6 +409,10 @@ func TestDeleteAccount(t *testing.T) {
 			baseTestCase: baseTestCase{
 				name: "InternalServerError",
 				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
+						Times(1).
+						Return(acc, nil)
 					store.EXPECT().
 						DeleteAccount(gomock.Any(), gomock.Any()).
 						Times(1).
@@ -383,54 +444,199 @@ func TestDeleteAccount(t *testing.T) {
 	}
 }
 
-func TestUpdateAccount(t *testing.T) {
+func TestCloseAccount(t *testing.T) {
 	user, _ := randomUser(t)
 	acc := randomAccount(user.Username)
+	acc.Balance = 0
+	closed := acc
+	closed.Status = db.AccountStatusClosed
 
 	testCases := []struct {
 		baseTestCase //
-		request      updateAccountRequest
+		username     string
+	}{
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().CloseAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(closed, nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+					requireBodyMatchAccount(t, recorder.Body, closed)
+				},
+			},
+		},
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "NotEmpty",
+				buildStubs: func(store *mockdb.MockStore) {
+					funded := acc
+					funded.Balance = 10
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(funded, nil)
+					store.EXPECT().CloseAccount(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "AlreadyClosed",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(closed, nil)
+					store.EXPECT().CloseAccount(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "BalanceChanged",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().CloseAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(db.Account{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			username: util.RandomUsername(),
+			baseTestCase: baseTestCase{
+				name: "UnauthorizedUser",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().CloseAccount(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, fmt.Sprintf("/accounts/%d/close", acc.ID))
+			tc.buildStubs(test.store)
+
+			request, err := http.NewRequest(http.MethodPost, test.url, nil)
+			require.NoError(t, err)
+
+			addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestCreateAdjustment(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+	customer, _ := randomUser(t)
+	customer.Role = db.UserRoleCustomer
+
+	acc := randomAccount(customer.Username)
+	amount := int64(-25)
+
+	testCases := []struct {
+		baseTestCase //
+		accountID    int64
+		request      createAdjustmentRequest
 		setupAuth    func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 	}{
 		{
-			request: updateAccountRequest{
-				ID:      acc.ID,
-				Balance: acc.Balance,
+			accountID: acc.ID,
+			request: createAdjustmentRequest{
+				Amount:     amount,
+				ReasonCode: string(db.AdjustmentReasonCorrection),
+				Note:       "duplicate card settlement",
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuth(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
 			},
 			baseTestCase: baseTestCase{
 				name: "OK",
 				buildStubs: func(store *mockdb.MockStore) {
-					expectedArg := db.UpdateAccountParams{
-						ID:      acc.ID,
-						Balance: acc.Balance,
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					expectedArg := db.AdjustmentTxParams{
+						AccountID:  acc.ID,
+						Amount:     amount,
+						ReasonCode: db.AdjustmentReasonCorrection,
+						Note:       "duplicate card settlement",
+						Operator:   admin.Username,
 					}
 
 					store.EXPECT().
-						UpdateAccount(gomock.Any(), gomock.Eq(expectedArg)).
+						AdjustmentTx(gomock.Any(), gomock.Eq(expectedArg)).
 						Times(1).
-						Return(acc, nil)
+						Return(db.AdjustmentTxResult{Account: acc}, nil)
 				},
 				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusOK, recorder.Code)
-					requireBodyMatchAccount(t, recorder.Body, acc)
+					require.Equal(t, http.StatusCreated, recorder.Code)
 				},
 			},
 		},
 		{
-			request: updateAccountRequest{
-				Balance: acc.Balance,
+			accountID: acc.ID,
+			request: createAdjustmentRequest{
+				Amount:     amount,
+				ReasonCode: string(db.AdjustmentReasonCorrection),
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuth(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
 			},
 			baseTestCase: baseTestCase{
-				name: "BadRequest",
+				name: "NotAdmin",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						UpdateAccount(gomock.Any(), gomock.Any()).
+						GetUser(gomock.Any(), gomock.Eq(customer.Username)).
+						Times(1).
+						Return(customer, nil)
+
+					store.EXPECT().
+						AdjustmentTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+		{
+			accountID: acc.ID,
+			request: createAdjustmentRequest{
+				Amount:     amount,
+				ReasonCode: "because",
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "InvalidReasonCode",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					store.EXPECT().
+						AdjustmentTx(gomock.Any(), gomock.Any()).
 						Times(0)
 				},
 				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
@@ -439,44 +645,54 @@ func TestUpdateAccount(t *testing.T) {
 			},
 		},
 		{
-			request: updateAccountRequest{
-				ID:      acc.ID,
-				Balance: acc.Balance,
+			accountID: acc.ID,
+			request: createAdjustmentRequest{
+				ReasonCode: string(db.AdjustmentReasonGoodwill),
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuth(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
 			},
 			baseTestCase: baseTestCase{
-				name: "NotFound",
+				name: "ZeroAmount",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						UpdateAccount(gomock.Any(), gomock.Any()).
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
 						Times(1).
-						Return(db.Account{}, sql.ErrNoRows)
+						Return(admin, nil)
+
+					store.EXPECT().
+						AdjustmentTx(gomock.Any(), gomock.Any()).
+						Times(0)
 				},
 				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusNotFound, recorder.Code)
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
 				},
 			},
 		},
 		{
-			request: updateAccountRequest{
-				ID:      acc.ID,
-				Balance: acc.Balance,
+			accountID: acc.ID,
+			request: createAdjustmentRequest{
+				Amount:     amount,
+				ReasonCode: string(db.AdjustmentReasonCorrection),
 			},
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
-				addAuth(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
 			},
 			baseTestCase: baseTestCase{
-				name: "InternalServerError",
+				name: "NotFound",
 				buildStubs: func(store *mockdb.MockStore) {
 					store.EXPECT().
-						UpdateAccount(gomock.Any(), gomock.Any()).
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
 						Times(1).
-						Return(db.Account{}, sql.ErrConnDone)
+						Return(admin, nil)
+
+					store.EXPECT().
+						AdjustmentTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.AdjustmentTxResult{}, sql.ErrNoRows)
 				},
 				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
-					require.Equal(t, http.StatusInternalServerError, recorder.Code)
+					require.Equal(t, http.StatusNotFound, recorder.Code)
 				},
 			},
 		},
@@ -485,13 +701,13 @@ func TestUpdateAccount(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			// given
-			test := newTest(t, "/accounts")
+			test := newTest(t, fmt.Sprintf("/accounts/%d/adjustments", tc.accountID))
 			tc.buildStubs(test.store)
 
 			body, err := toReader(tc.request)
 			require.NoError(t, err)
 
-			request, err := http.NewRequest(http.MethodPut, test.url, body)
+			request, err := http.NewRequest(http.MethodPost, test.url, body)
 			require.NoError(t, err)
 
 			// when
@@ -619,6 +835,8 @@ func randomAccount(owner string) db.Account {
 		Owner:    owner,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Status:   db.AccountStatusActive,
+		Product:  defaultProduct,
 	}
 }
 
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	apiKeyHeaderKey = "X-API-Key"
+	// apiKeyPrefixTag starts every key so it is easy to spot in logs and
+	// secret scanners.
+	apiKeyPrefixTag = "dbk_"
+)
+
+var (
+	errInvalidAPIKey = errors.New("API key is invalid")
+	errAPIKeyRevoked = errors.New("API key was revoked")
+	errAPIKeyExpired = errors.New("API key has expired")
+)
+
+// newAPIKey returns a random key to hand to the user once, its visible
+// prefix, and the hash of the secret part to store.
+func newAPIKey() (key string, prefix string, hash string, err error) {
+	id := make([]byte, 6)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+
+	prefix = apiKeyPrefixTag + hex.EncodeToString(id)
+	encoded := base64.RawURLEncoding.EncodeToString(secret)
+	return prefix + "_" + encoded, prefix, hashToken(encoded), nil
+}
+
+// splitAPIKey returns the prefix and secret of a key made by newAPIKey.
+func splitAPIKey(key string) (prefix string, secret string, ok bool) {
+	prefixLen := len(apiKeyPrefixTag) + 12
+	if !strings.HasPrefix(key, apiKeyPrefixTag) || len(key) <= prefixLen+1 || key[prefixLen] != '_' {
+		return "", "", false
+	}
+
+	return key[:prefixLen], key[prefixLen+1:], true
+}
+
+// authenticateAPIKey checks the key in the X-API-Key header and returns a
+// payload standing in for an access token: the key's owner, scopes and
+// restrictions. Otherwise it aborts with an error response.
+func authenticateAPIKey(ctx *gin.Context, store db.Store, key string) (*token.Payload, bool) {
+	prefix, secret, ok := splitAPIKey(key)
+	if !ok {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+		return nil, false
+	}
+
+	apiKey, err := store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+			return nil, false
+		}
+
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(apiKey.SecretHash)) != 1 {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errInvalidAPIKey))
+		return nil, false
+	}
+
+	if apiKey.RevokedAt.Valid {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errAPIKeyRevoked))
+		return nil, false
+	}
+
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errAPIKeyExpired))
+		return nil, false
+	}
+
+	if err := store.TouchAPIKey(ctx, apiKey.ID); err != nil {
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+
+	return &token.Payload{
+		Username:          apiKey.Owner,
+		IssuedAt:          apiKey.CreatedAt,
+		NotBefore:         apiKey.CreatedAt,
+		ExpiresAt:         apiKey.ExpiresAt.Time,
+		Scopes:            apiKey.Scopes,
+		AccountIDs:        apiKey.AccountIds,
+		MaxTransferAmount: apiKey.MaxTransferAmount,
+	}, true
+}
+
+// createAPIKeyRequest leaves the profile scope out of what keys may be
+// granted, so keys cannot mint more keys or change the password.
+type createAPIKeyRequest struct {
+	Name              string     `json:"name" binding:"required,max=64"`
+	Scopes            []string   `json:"scopes" binding:"required,min=1,dive,oneof=accounts:read accounts:write transfers:write webhooks"`
+	AccountIDs        []int64    `json:"account_ids" binding:"dive,min=1"`
+	MaxTransferAmount int64      `json:"max_transfer_amount" binding:"min=0"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+}
+
+type apiKeyResponse struct {
+	ID                int64      `json:"id"`
+	Name              string     `json:"name"`
+	Prefix            string     `json:"prefix"`
+	Scopes            []string   `json:"scopes"`
+	AccountIDs        []int64    `json:"account_ids"`
+	MaxTransferAmount int64      `json:"max_transfer_amount"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	LastUsedAt        *time.Time `json:"last_used_at"`
+	RevokedAt         *time.Time `json:"revoked_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
+
+// createAPIKeyResponse is the only response that includes the key; only its
+// hash is stored.
+type createAPIKeyResponse struct {
+	apiKeyResponse
+	Key string `json:"key"`
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func newAPIKeyResponse(key db.ApiKey) apiKeyResponse {
+	return apiKeyResponse{
+		ID:                key.ID,
+		Name:              key.Name,
+		Prefix:            key.Prefix,
+		Scopes:            key.Scopes,
+		AccountIDs:        key.AccountIds,
+		MaxTransferAmount: key.MaxTransferAmount,
+		ExpiresAt:         nullTimePtr(key.ExpiresAt),
+		LastUsedAt:        nullTimePtr(key.LastUsedAt),
+		RevokedAt:         nullTimePtr(key.RevokedAt),
+		CreatedAt:         key.CreatedAt,
+	}
+}
+
+func (s *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		err := errors.New("expires_at must be in the future")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	for _, id := range req.AccountIDs {
+		account, err := s.store.GetAccount(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if account.Owner != authPayload.Username {
+			err := errors.New("account doesnt belong to the authenticated user")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+	}
+
+	key, prefix, hash, err := newAPIKey()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.CreateAPIKeyParams{
+		Owner:             authPayload.Username,
+		Name:              req.Name,
+		Prefix:            prefix,
+		SecretHash:        hash,
+		Scopes:            req.Scopes,
+		AccountIds:        req.AccountIDs,
+		MaxTransferAmount: req.MaxTransferAmount,
+	}
+	if arg.AccountIds == nil {
+		arg.AccountIds = []int64{}
+	}
+	if req.ExpiresAt != nil {
+		arg.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	apiKey, err := s.store.CreateAPIKey(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createAPIKeyResponse{
+		apiKeyResponse: newAPIKeyResponse(apiKey),
+		Key:            key,
+	})
+}
+
+func (s *Server) listAPIKeys(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	keys, err := s.store.ListAPIKeys(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result := make([]apiKeyResponse, len(keys))
+	for i, key := range keys {
+		result[i] = newAPIKeyResponse(key)
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type apiKeyURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// revokeAPIKey stops the key from authenticating. Revoking a key twice is not
+// an error.
+func (s *Server) revokeAPIKey(ctx *gin.Context) {
+	var uri apiKeyURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	apiKey, err := s.store.GetAPIKey(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if apiKey.Owner != authPayload.Username {
+		err := errors.New("API key doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	_, err = s.store.RevokeAPIKey(ctx, apiKey.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
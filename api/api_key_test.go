@@ -0,0 +1,428 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// randomAPIKey returns a key the way createAPIKey stores it and the key the
+// user would send.
+func randomAPIKey(t *testing.T, owner string, scopes ...string) (db.ApiKey, string) {
+	key, prefix, hash, err := newAPIKey()
+	require.NoError(t, err)
+
+	return db.ApiKey{
+		ID:         util.RandomInt(1, 1000),
+		Owner:      owner,
+		Name:       "integration",
+		Prefix:     prefix,
+		SecretHash: hash,
+		Scopes:     scopes,
+		AccountIds: []int64{},
+		CreatedAt:  time.Now().Add(-time.Hour).UTC().Truncate(time.Second),
+	}, key
+}
+
+func TestSplitAPIKey(t *testing.T) {
+	key, prefix, hash, err := newAPIKey()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(key, prefix+"_"))
+
+	gotPrefix, secret, ok := splitAPIKey(key)
+	require.True(t, ok)
+	require.Equal(t, prefix, gotPrefix)
+	require.Equal(t, hash, hashToken(secret))
+
+	for _, bad := range []string{"", "dbk_", prefix, prefix + "_", "xyz_" + key[4:], prefix + "x" + key[len(prefix)+1:]} {
+		_, _, ok := splitAPIKey(bad)
+		require.False(t, ok, bad)
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	foreign := randomAccount(util.RandomUsername())
+
+	testCases := []struct {
+		baseTestCase //
+		body         map[string]any
+	}{
+		{
+			body: map[string]any{
+				"name":                "payroll",
+				"scopes":              []string{scopeAccountsRead, scopeTransfersWrite},
+				"account_ids":         []int64{account.ID},
+				"max_transfer_amount": 500,
+				"expires_at":          time.Now().Add(24 * time.Hour),
+			},
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+					store.EXPECT().
+						CreateAPIKey(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ any, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+							require.Equal(t, user.Username, arg.Owner)
+							require.Equal(t, "payroll", arg.Name)
+							require.True(t, strings.HasPrefix(arg.Prefix, apiKeyPrefixTag))
+							require.Equal(t, []string{scopeAccountsRead, scopeTransfersWrite}, arg.Scopes)
+							require.Equal(t, []int64{account.ID}, arg.AccountIds)
+							require.Equal(t, int64(500), arg.MaxTransferAmount)
+							require.True(t, arg.ExpiresAt.Valid)
+							return db.ApiKey{
+								ID:                1,
+								Owner:             arg.Owner,
+								Name:              arg.Name,
+								Prefix:            arg.Prefix,
+								SecretHash:        arg.SecretHash,
+								Scopes:            arg.Scopes,
+								AccountIds:        arg.AccountIds,
+								MaxTransferAmount: arg.MaxTransferAmount,
+								ExpiresAt:         arg.ExpiresAt,
+							}, nil
+						})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+
+					var got createAPIKeyResponse
+					require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+					require.True(t, strings.HasPrefix(got.Key, got.Prefix+"_"))
+					require.NotNil(t, got.ExpiresAt)
+					require.Nil(t, got.LastUsedAt)
+					require.NotContains(t, recorder.Body.String(), "secret_hash")
+				},
+			},
+		},
+		{
+			body: map[string]any{
+				"name":   "read only",
+				"scopes": []string{scopeAccountsRead},
+			},
+			baseTestCase: baseTestCase{
+				name: "NoRestrictions",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						CreateAPIKey(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ any, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+							require.Equal(t, []int64{}, arg.AccountIds)
+							require.False(t, arg.ExpiresAt.Valid)
+							return db.ApiKey{ID: 2, Owner: arg.Owner, Prefix: arg.Prefix, Scopes: arg.Scopes}, nil
+						})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+				},
+			},
+		},
+		{
+			body: map[string]any{
+				"name":   "escalate",
+				"scopes": []string{scopeProfile},
+			},
+			baseTestCase: baseTestCase{
+				name: "ProfileScope",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			body: map[string]any{
+				"name":       "stale",
+				"scopes":     []string{scopeAccountsRead},
+				"expires_at": time.Now().Add(-time.Minute),
+			},
+			baseTestCase: baseTestCase{
+				name: "PastExpiry",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			body: map[string]any{
+				"name":        "snoop",
+				"scopes":      []string{scopeAccountsRead},
+				"account_ids": []int64{foreign.ID},
+			},
+			baseTestCase: baseTestCase{
+				name: "ForeignAccount",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(foreign.ID)).Times(1).Return(foreign, nil)
+					store.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, "/api-keys")
+			tc.buildStubs(test.store)
+
+			postJSON(t, test, tc.body, testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute))
+
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name      string
+		method    string
+		url       string
+		setupKey  func(key *db.ApiKey, raw string) string
+		stubs     func(store *mockdb.MockStore, key db.ApiKey)
+		code      int
+		errorCode string
+	}{
+		{
+			name:   "OK",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			stubs: func(store *mockdb.MockStore, key db.ApiKey) {
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			code: http.StatusOK,
+		},
+		{
+			name:   "WrongSecret",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupKey: func(_ *db.ApiKey, raw string) string {
+				return raw[:len(raw)-4] + "AAAA"
+			},
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:   "Malformed",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupKey: func(_ *db.ApiKey, _ string) string {
+				return "not-a-key"
+			},
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:   "Revoked",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupKey: func(key *db.ApiKey, raw string) string {
+				key.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				return raw
+			},
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:   "Expired",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupKey: func(key *db.ApiKey, raw string) string {
+				key.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Second), Valid: true}
+				return raw
+			},
+			code: http.StatusUnauthorized,
+		},
+		{
+			name:   "AccountNotAllowed",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			setupKey: func(key *db.ApiKey, raw string) string {
+				key.AccountIds = []int64{account.ID + 1}
+				return raw
+			},
+			stubs: func(store *mockdb.MockStore, key db.ApiKey) {
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			code:      http.StatusForbidden,
+			errorCode: insufficientScopeCode,
+		},
+		{
+			name:   "ReadOnly",
+			method: http.MethodPost,
+			url:    "/accounts",
+			stubs: func(store *mockdb.MockStore, key db.ApiKey) {
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code:      http.StatusForbidden,
+			errorCode: insufficientScopeCode,
+		},
+		{
+			name:   "CannotMintKeys",
+			method: http.MethodGet,
+			url:    "/api-keys",
+			stubs: func(store *mockdb.MockStore, key db.ApiKey) {
+				store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(nil)
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code:      http.StatusForbidden,
+			errorCode: insufficientScopeCode,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, tc.url)
+
+			key, raw := randomAPIKey(t, user.Username, scopeAccountsRead)
+			if tc.setupKey != nil {
+				raw = tc.setupKey(&key, raw)
+			}
+
+			test.store.EXPECT().
+				GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).
+				AnyTimes().
+				Return(key, nil)
+			if tc.stubs != nil {
+				tc.stubs(test.store, key)
+			} else {
+				test.store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			}
+
+			request, err := http.NewRequest(tc.method, test.url, strings.NewReader(`{"currency":"USD"}`))
+			require.NoError(t, err)
+			request.Header.Set(apiKeyHeaderKey, raw)
+
+			test.server.router.ServeHTTP(test.recorder, request)
+			require.Equal(t, tc.code, test.recorder.Code)
+			if tc.errorCode != "" {
+				require.Contains(t, test.recorder.Body.String(), tc.errorCode)
+			}
+		})
+	}
+}
+
+func TestAPIKeyTransferCap(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+
+	test := newTest(t, "/transfers")
+	key, raw := randomAPIKey(t, user.Username, scopeTransfersWrite)
+	key.MaxTransferAmount = 100
+
+	test.store.EXPECT().GetAPIKeyByPrefix(gomock.Any(), gomock.Eq(key.Prefix)).Times(1).Return(key, nil)
+	test.store.EXPECT().TouchAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(nil)
+	test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+	test.store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+
+	body, err := toReader(transferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 101, Currency: "USD"})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, test.url, body)
+	require.NoError(t, err)
+	request.Header.Set(apiKeyHeaderKey, raw)
+
+	test.server.router.ServeHTTP(test.recorder, request)
+	require.Equal(t, http.StatusForbidden, test.recorder.Code)
+	require.Contains(t, test.recorder.Body.String(), insufficientScopeCode)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	user, _ := randomUser(t)
+	key, _ := randomAPIKey(t, user.Username, scopeAccountsRead)
+	foreign, _ := randomAPIKey(t, util.RandomUsername(), scopeAccountsRead)
+
+	testCases := []struct {
+		baseTestCase //
+		id           int64
+	}{
+		{
+			id: key.ID,
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(key, nil)
+					store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(key, nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNoContent, recorder.Code)
+				},
+			},
+		},
+		{
+			id: key.ID,
+			baseTestCase: baseTestCase{
+				name: "AlreadyRevoked",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(key, nil)
+					store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(db.ApiKey{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNoContent, recorder.Code)
+				},
+			},
+		},
+		{
+			id: foreign.ID,
+			baseTestCase: baseTestCase{
+				name: "OtherOwner",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(foreign.ID)).Times(1).Return(foreign, nil)
+					store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			id: key.ID,
+			baseTestCase: baseTestCase{
+				name: "NotFound",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAPIKey(gomock.Any(), gomock.Eq(key.ID)).Times(1).Return(db.ApiKey{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNotFound, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, fmt.Sprintf("/api-keys/%d", tc.id))
+			tc.buildStubs(test.store)
+
+			request, err := http.NewRequest(http.MethodDelete, test.url, nil)
+			require.NoError(t, err)
+
+			addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errDataExportInProgress = errors.New("a data export is already in progress")
+	errDataExportNotReady   = errors.New("data export is not completed")
+	errDataExportExpired    = errors.New("data export has expired")
+)
+
+type dataExportResponse struct {
+	ID          int64               `json:"id"`
+	Status      db.DataExportStatus `json:"status"`
+	Error       string              `json:"error,omitempty"`
+	CompletedAt *time.Time          `json:"completed_at"`
+	ExpiresAt   *time.Time          `json:"expires_at"`
+	CreatedAt   time.Time           `json:"created_at"`
+}
+
+func newDataExportResponse(export db.DataExport) dataExportResponse {
+	return dataExportResponse{
+		ID:          export.ID,
+		Status:      export.Status,
+		Error:       export.Error,
+		CompletedAt: nullTimePtr(export.CompletedAt),
+		ExpiresAt:   nullTimePtr(export.ExpiresAt),
+		CreatedAt:   export.CreatedAt,
+	}
+}
+
+// requestDataExport queues an export of the user's data. The archive is
+// built in the background; poll the export until it is completed.
+func (s *Server) requestDataExport(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	latest, err := s.store.ListDataExports(ctx, db.ListDataExportsParams{
+		Username: authPayload.Username,
+		Limit:    1,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if len(latest) > 0 {
+		switch latest[0].Status {
+		case db.DataExportStatusPending, db.DataExportStatusRunning:
+			ctx.JSON(http.StatusConflict, errorResponse(errDataExportInProgress))
+			return
+		}
+	}
+
+	export, err := s.store.RequestDataExportTx(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, newDataExportResponse(export))
+}
+
+func (s *Server) listDataExports(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	exports, err := s.store.ListDataExports(ctx, db.ListDataExportsParams{
+		Username: authPayload.Username,
+		Limit:    20,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result := make([]dataExportResponse, len(exports))
+	for i, export := range exports {
+		result[i] = newDataExportResponse(export)
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type dataExportURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// ownDataExport loads the export in the URI and checks that it belongs to
+// the authenticated user. Otherwise it writes the error response itself.
+func (s *Server) ownDataExport(ctx *gin.Context) (db.DataExport, bool) {
+	var uri dataExportURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.DataExport{}, false
+	}
+
+	export, err := s.store.GetDataExport(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return export, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return export, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if export.Username != authPayload.Username {
+		err := errors.New("data export doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return export, false
+	}
+
+	return export, true
+}
+
+func (s *Server) getDataExport(ctx *gin.Context) {
+	export, ok := s.ownDataExport(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newDataExportResponse(export))
+}
+
+// downloadDataExport sends the archive and records the download in the audit
+// log, since it is when the data leaves the bank.
+func (s *Server) downloadDataExport(ctx *gin.Context) {
+	export, ok := s.ownDataExport(ctx)
+	if !ok {
+		return
+	}
+
+	if export.Status != db.DataExportStatusCompleted {
+		ctx.JSON(http.StatusConflict, errorResponse(errDataExportNotReady))
+		return
+	}
+
+	if export.Archive == nil || (export.ExpiresAt.Valid && time.Now().After(export.ExpiresAt.Time)) {
+		ctx.JSON(http.StatusGone, errorResponse(errDataExportExpired))
+		return
+	}
+
+	details, err := json.Marshal(map[string]any{"export_id": export.ID, "ip": ctx.ClientIP()})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = s.store.CreateAuditLog(ctx, db.CreateAuditLogParams{
+		Actor:   export.Username,
+		Action:  "user.export_download",
+		Target:  "user:" + export.Username,
+		Reason:  "downloaded by the user",
+		Details: details,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	filename := fmt.Sprintf("demo-bank-export-%d.zip", export.ID)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, "application/zip", export.Archive)
+}
+
+type eraseUserURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type eraseUserRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// eraseUser pseudonymizes the personal data of a user whose accounts are all
+// closed. Their ledger history is kept.
+func (s *Server) eraseUser(ctx *gin.Context) {
+	var uri eraseUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req eraseUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := s.store.EraseUserTx(ctx, db.EraseUserTxParams{
+		Username: uri.Username,
+		Operator: authPayload.Username,
+		Reason:   req.Reason,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrUserErased), errors.Is(err, db.ErrUserHasOpenAccounts):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRequestDataExport(t *testing.T) {
+	user, _ := randomUser(t)
+	latestParams := db.ListDataExportsParams{Username: user.Username, Limit: 1}
+
+	testCases := []struct {
+		baseTestCase //
+	}{
+		{baseTestCase{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDataExports(gomock.Any(), gomock.Eq(latestParams)).
+					Times(1).
+					Return([]db.DataExport{{ID: 1, Username: user.Username, Status: db.DataExportStatusCompleted}}, nil)
+				store.EXPECT().
+					RequestDataExportTx(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.DataExport{ID: 2, Username: user.Username, Status: db.DataExportStatusPending}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Contains(t, recorder.Body.String(), `"status":"pending"`)
+			},
+		}},
+		{baseTestCase{
+			name: "InProgress",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListDataExports(gomock.Any(), gomock.Eq(latestParams)).
+					Times(1).
+					Return([]db.DataExport{{ID: 1, Username: user.Username, Status: db.DataExportStatusRunning}}, nil)
+				store.EXPECT().RequestDataExportTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusConflict, recorder.Code)
+			},
+		}},
+		{baseTestCase{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ListDataExports(gomock.Any(), gomock.Any()).Times(1).Return(nil, nil)
+				store.EXPECT().
+					RequestDataExportTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.DataExport{}, sql.ErrConnDone)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, "/users/me/export")
+			tc.buildStubs(test.store)
+
+			postJSON(t, test, gin.H{}, testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute))
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestDownloadDataExport(t *testing.T) {
+	user, _ := randomUser(t)
+	archive := []byte("PK\x05\x06 not really a zip")
+	completed := db.DataExport{
+		ID:        5,
+		Username:  user.Username,
+		Status:    db.DataExportStatusCompleted,
+		Archive:   archive,
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	testCases := []struct {
+		baseTestCase //
+		username     string
+	}{
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetDataExport(gomock.Any(), gomock.Eq(completed.ID)).Times(1).Return(completed, nil)
+					store.EXPECT().
+						CreateAuditLog(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ context.Context, arg db.CreateAuditLogParams) (db.AuditLog, error) {
+							require.Equal(t, user.Username, arg.Actor)
+							require.Equal(t, "user.export_download", arg.Action)
+							require.Equal(t, "user:"+user.Username, arg.Target)
+							return db.AuditLog{}, nil
+						})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+					require.Equal(t, "application/zip", recorder.Header().Get("Content-Type"))
+					require.Contains(t, recorder.Header().Get("Content-Disposition"), "demo-bank-export-5.zip")
+					require.Equal(t, archive, recorder.Body.Bytes())
+				},
+			},
+		},
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "NotReady",
+				buildStubs: func(store *mockdb.MockStore) {
+					pending := db.DataExport{ID: completed.ID, Username: user.Username, Status: db.DataExportStatusPending}
+					store.EXPECT().GetDataExport(gomock.Any(), gomock.Eq(completed.ID)).Times(1).Return(pending, nil)
+					store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "Expired",
+				buildStubs: func(store *mockdb.MockStore) {
+					expired := completed
+					expired.Archive = nil
+					store.EXPECT().GetDataExport(gomock.Any(), gomock.Eq(completed.ID)).Times(1).Return(expired, nil)
+					store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusGone, recorder.Code)
+				},
+			},
+		},
+		{
+			username: "someoneelse",
+			baseTestCase: baseTestCase{
+				name: "UnauthorizedUser",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetDataExport(gomock.Any(), gomock.Eq(completed.ID)).Times(1).Return(completed, nil)
+					store.EXPECT().CreateAuditLog(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, fmt.Sprintf("/users/me/exports/%d/download", completed.ID))
+			tc.buildStubs(test.store)
+
+			request, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+
+			addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestEraseUser(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+	customer, _ := randomUser(t)
+	customer.Role = db.UserRoleCustomer
+	target, _ := randomUser(t)
+
+	testCases := []struct {
+		baseTestCase //
+		actor        db.User
+		body         gin.H
+	}{
+		{
+			actor: admin,
+			body:  gin.H{"reason": "erasure request by email"},
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+					store.EXPECT().
+						EraseUserTx(gomock.Any(), gomock.Eq(db.EraseUserTxParams{
+							Username: target.Username,
+							Operator: admin.Username,
+							Reason:   "erasure request by email",
+						})).
+						Times(1).
+						Return(target, nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+				},
+			},
+		},
+		{
+			actor: admin,
+			body:  gin.H{},
+			baseTestCase: baseTestCase{
+				name: "MissingReason",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+					store.EXPECT().EraseUserTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			actor: admin,
+			body:  gin.H{"reason": "erasure request by email"},
+			baseTestCase: baseTestCase{
+				name: "OpenAccounts",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+					store.EXPECT().
+						EraseUserTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.User{}, db.ErrUserHasOpenAccounts)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			actor: admin,
+			body:  gin.H{"reason": "erasure request by email"},
+			baseTestCase: baseTestCase{
+				name: "NotFound",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+					store.EXPECT().EraseUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNotFound, recorder.Code)
+				},
+			},
+		},
+		{
+			actor: customer,
+			body:  gin.H{"reason": "forget me"},
+			baseTestCase: baseTestCase{
+				name: "NotAdmin",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(customer, nil)
+					store.EXPECT().EraseUserTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, fmt.Sprintf("/admin/users/%s/erase", target.Username))
+			tc.buildStubs(test.store)
+
+			accessToken := testAccessToken(t, test.server.tokenMaker, tc.actor.Username, time.Minute)
+
+			postJSON(t, test, tc.body, accessToken)
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/mailer"
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+const emailNotVerifiedCode = "email_not_verified"
+
+var (
+	errEmailNotVerified     = errors.New("email address is not verified")
+	errEmailAlreadyVerified = errors.New("email address is already verified")
+)
+
+// newOneTimeToken returns a random token to email to the user and the hash to
+// store in its place.
+func newOneTimeToken() (token string, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Server) verificationEmail(user db.User, token string) mailer.Message {
+	link := fmt.Sprintf("%s/users/verify_email?token=%s", s.config.PublicBaseURL, url.QueryEscape(token))
+
+	return mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email address by opening the link below within %s:\n\n%s\n\nIf you did not sign up, you can ignore this email.\n",
+			user.FullName, s.config.EmailVerificationDuration, link,
+		),
+	}
+}
+
+type verifyEmailRequest struct {
+	Token string `form:"token" binding:"required"`
+}
+
+func (s *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := s.store.VerifyEmailTx(ctx, hashToken(req.Token), time.Now())
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("verification token not found")))
+		case errors.Is(err, db.ErrVerificationTokenUsed):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		case errors.Is(err, db.ErrVerificationTokenExpired), errors.Is(err, db.ErrVerificationEmailChanged):
+			ctx.JSON(http.StatusGone, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// resendVerificationEmail sends a new verification link, for users whose
+// first one expired or never arrived. Earlier links stay valid until they
+// expire.
+func (s *Server) resendVerificationEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.IsEmailVerified {
+		ctx.JSON(http.StatusConflict, errorResponse(errEmailAlreadyVerified))
+		return
+	}
+
+	verificationToken, verificationTokenHash, err := newOneTimeToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = s.store.CreateEmailVerification(ctx, db.CreateEmailVerificationParams{
+		Username:  user.Username,
+		Email:     user.Email,
+		TokenHash: verificationTokenHash,
+		ExpiresAt: time.Now().Add(s.config.EmailVerificationDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = s.mailer.Send(ctx, s.verificationEmail(user, verificationToken))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/mailer"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateUserSendsVerificationEmail(t *testing.T) {
+	user, password := randomUser(t)
+	user.IsEmailVerified = false
+
+	test := newTest(t, "/users")
+	var tokenHash string
+	test.store.EXPECT().
+		CreateUserTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateUserTxParams) (db.User, error) {
+			tokenHash = arg.VerificationTokenHash
+			return user, nil
+		})
+
+	body, err := toReader(createUserRequest{
+		Username: user.Username,
+		Password: password,
+		FullName: user.FullName,
+		Email:    user.Email,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, test.url, body)
+	require.NoError(t, err)
+
+	test.server.router.ServeHTTP(test.recorder, request)
+	require.Equal(t, http.StatusCreated, test.recorder.Code)
+	require.Contains(t, test.recorder.Body.String(), `"is_email_verified":false`)
+
+	messages := test.server.mailer.(*mailer.MemorySender).Messages()
+	require.Len(t, messages, 1)
+	require.Equal(t, user.Email, messages[0].To)
+
+	link := regexp.MustCompile(`http://localhost:8080/users/verify_email\?token=\S+`).FindString(messages[0].Body)
+	require.NotEmpty(t, link)
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	require.Equal(t, tokenHash, hashToken(u.Query().Get("token")))
+}
+
+func TestVerifyEmail(t *testing.T) {
+	user, _ := randomUser(t)
+	token, tokenHash, err := newOneTimeToken()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		baseTestCase //
+		query        string
+	}{
+		{
+			query: "?token=" + token,
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						VerifyEmailTx(gomock.Any(), gomock.Eq(tokenHash), gomock.Any()).
+						Times(1).
+						Return(user, nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+					requireBodyMatchUser(t, recorder.Body, user)
+				},
+			},
+		},
+		{
+			query: "",
+			baseTestCase: baseTestCase{
+				name: "MissingToken",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						VerifyEmailTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			query: "?token=" + token,
+			baseTestCase: baseTestCase{
+				name: "NotFound",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						VerifyEmailTx(gomock.Any(), gomock.Eq(tokenHash), gomock.Any()).
+						Times(1).
+						Return(db.User{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNotFound, recorder.Code)
+				},
+			},
+		},
+		{
+			query: "?token=" + token,
+			baseTestCase: baseTestCase{
+				name: "AlreadyUsed",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						VerifyEmailTx(gomock.Any(), gomock.Eq(tokenHash), gomock.Any()).
+						Times(1).
+						Return(db.User{}, db.ErrVerificationTokenUsed)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			query: "?token=" + token,
+			baseTestCase: baseTestCase{
+				name: "Expired",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						VerifyEmailTx(gomock.Any(), gomock.Eq(tokenHash), gomock.Any()).
+						Times(1).
+						Return(db.User{}, db.ErrVerificationTokenExpired)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusGone, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, "/users/verify_email"+tc.query)
+			tc.buildStubs(test.store)
+
+			request, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+
+			// when
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestResendVerificationEmail(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = false
+
+	t.Run("OK", func(t *testing.T) {
+		test := newTest(t, "/users/verify_email/resend")
+		var tokenHash string
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+		test.store.EXPECT().
+			CreateEmailVerification(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateEmailVerificationParams) (db.EmailVerification, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, user.Email, arg.Email)
+				tokenHash = arg.TokenHash
+				return db.EmailVerification{}, nil
+			})
+
+		request, err := http.NewRequest(http.MethodPost, test.url, nil)
+		require.NoError(t, err)
+		addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+		test.server.router.ServeHTTP(test.recorder, request)
+		require.Equal(t, http.StatusAccepted, test.recorder.Code)
+
+		messages := test.server.mailer.(*mailer.MemorySender).Messages()
+		require.Len(t, messages, 1)
+		require.Equal(t, user.Email, messages[0].To)
+
+		link := regexp.MustCompile(`http://localhost:8080/users/verify_email\?token=\S+`).FindString(messages[0].Body)
+		u, err := url.Parse(link)
+		require.NoError(t, err)
+		require.Equal(t, tokenHash, hashToken(u.Query().Get("token")))
+	})
+
+	t.Run("AlreadyVerified", func(t *testing.T) {
+		test := newTest(t, "/users/verify_email/resend")
+		verified := user
+		verified.IsEmailVerified = true
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(verified, nil)
+		test.store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(0)
+
+		request, err := http.NewRequest(http.MethodPost, test.url, nil)
+		require.NoError(t, err)
+		addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+
+		test.server.router.ServeHTTP(test.recorder, request)
+		require.Equal(t, http.StatusConflict, test.recorder.Code)
+		require.Empty(t, test.server.mailer.(*mailer.MemorySender).Messages())
+	})
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type listHeldTransfersRequest struct {
+	Status   string `form:"status" binding:"omitempty,oneof=pending approved rejected"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (s *Server) listHeldTransfers(ctx *gin.Context) {
+	var req listHeldTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	status := db.HeldTransferStatusPending
+	if req.Status != "" {
+		status = db.HeldTransferStatus(req.Status)
+	}
+
+	held, err := s.store.ListHeldTransfers(ctx, db.ListHeldTransfersParams{
+		Status: status,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, held)
+}
+
+type decideHeldTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) approveHeldTransfer(ctx *gin.Context) {
+	s.decideHeldTransfer(ctx, true)
+}
+
+func (s *Server) rejectHeldTransfer(ctx *gin.Context) {
+	s.decideHeldTransfer(ctx, false)
+}
+
+func (s *Server) decideHeldTransfer(ctx *gin.Context, approve bool) {
+	var req decideHeldTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := s.store.DecideHeldTransferTx(ctx, db.DecideHeldTransferTxParams{
+		ID:       req.ID,
+		Approve:  approve,
+		Reviewer: authPayload.Username,
+	})
+	if err != nil {
+		var limitErr *db.LimitExceededError
+		var pqErr *pq.Error
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, db.ErrHeldTransferDecided):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		case errors.Is(err, db.ErrAccountNotActive):
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, db.ErrInsufficientFunds):
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+		case errors.As(err, &limitErr):
+			ctx.JSON(http.StatusUnprocessableEntity, limitExceededResponse(limitErr))
+		case errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation":
+			// the withdrawal's reference was paid out while it was held
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDecideHeldTransfer(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+	customer, _ := randomUser(t)
+	customer.Role = db.UserRoleCustomer
+
+	heldID := util.RandomInt(1, 1000)
+
+	testCases := []struct {
+		baseTestCase //
+		action       string
+		setupAuth    func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	}{
+		{
+			action: "approve",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "Approve",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					expectedArg := db.DecideHeldTransferTxParams{
+						ID:       heldID,
+						Approve:  true,
+						Reviewer: admin.Username,
+					}
+
+					store.EXPECT().
+						DecideHeldTransferTx(gomock.Any(), gomock.Eq(expectedArg)).
+						Times(1)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+				},
+			},
+		},
+		{
+			action: "reject",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "Reject",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					expectedArg := db.DecideHeldTransferTxParams{
+						ID:       heldID,
+						Approve:  false,
+						Reviewer: admin.Username,
+					}
+
+					store.EXPECT().
+						DecideHeldTransferTx(gomock.Any(), gomock.Eq(expectedArg)).
+						Times(1)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+				},
+			},
+		},
+		{
+			action: "approve",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "AlreadyDecided",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					store.EXPECT().
+						DecideHeldTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.DecideHeldTransferTxResult{}, db.ErrHeldTransferDecided)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			action: "approve",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "AccountNotActive",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					store.EXPECT().
+						DecideHeldTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.DecideHeldTransferTxResult{}, fmt.Errorf("%w: account [1] is frozen", db.ErrAccountNotActive))
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+		{
+			action: "approve",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "InsufficientFunds",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					store.EXPECT().
+						DecideHeldTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.DecideHeldTransferTxResult{}, db.ErrInsufficientFunds)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				},
+			},
+		},
+		{
+			action: "approve",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "DuplicateReference",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					store.EXPECT().
+						DecideHeldTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.DecideHeldTransferTxResult{}, &pq.Error{Code: "23505"})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			action: "approve",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "NotFound",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					store.EXPECT().
+						DecideHeldTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.DecideHeldTransferTxResult{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNotFound, recorder.Code)
+				},
+			},
+		},
+		{
+			action: "approve",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "NotAdmin",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(customer.Username)).
+						Times(1).
+						Return(customer, nil)
+
+					store.EXPECT().
+						DecideHeldTransferTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, fmt.Sprintf("/admin/held_transfers/%d/%s", heldID, tc.action))
+			tc.buildStubs(test.store)
+
+			request, err := http.NewRequest(http.MethodPost, test.url, nil)
+			require.NoError(t, err)
+
+			// when
+			tc.setupAuth(t, request, test.server.tokenMaker)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+// keySetCacheControl lets verifiers cache the key sets for a while; they
+// refetch early when they see a key ID they don't know.
+const keySetCacheControl = "public, max-age=300"
+
+var errNoPublicKeys = errors.New("tokens are not signed with public keys")
+
+func (s *Server) publicKeys(ctx *gin.Context) ([]token.PublicKey, bool) {
+	source, ok := s.tokenMaker.(token.KeySource)
+	if !ok {
+		ctx.JSON(http.StatusNotFound, errorResponse(errNoPublicKeys))
+		return nil, false
+	}
+
+	ctx.Header("Cache-Control", keySetCacheControl)
+	return source.PublicKeys(), true
+}
+
+func (s *Server) getJWKS(ctx *gin.Context) {
+	keys, ok := s.publicKeys(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, token.NewJWKS(keys))
+}
+
+func (s *Server) getPASERKSet(ctx *gin.Context) {
+	keys, ok := s.publicKeys(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, token.NewPASERKSet(keys))
+}
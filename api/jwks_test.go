@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGetKeySets(t *testing.T) {
+	secretKey, _ := token.GenerateSigningKey()
+	_, oldPublicKey := token.GenerateSigningKey()
+
+	store := mockdb.NewMockStore(gomock.NewController(t))
+	server := newTestServerWithConfig(t, store, func(config *util.Config) {
+		config.TokenMaker = token.KindPasetoPublic
+		config.TokenSigningKeyID = "new"
+		config.TokenSigningKey = secretKey
+		config.TokenVerificationKeys = []string{"old:" + oldPublicKey}
+	})
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, keySetCacheControl, recorder.Header().Get("Cache-Control"))
+
+	var jwks token.JWKS
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &jwks))
+	require.Len(t, jwks.Keys, 2)
+	require.Equal(t, "new", jwks.Keys[0].KeyID)
+	require.Equal(t, "old", jwks.Keys[1].KeyID)
+
+	recorder = httptest.NewRecorder()
+	request, err = http.NewRequest(http.MethodGet, "/.well-known/paserk.json", nil)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var paserks token.PASERKSet
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &paserks))
+	require.Len(t, paserks.Keys, 2)
+
+	keys, err := paserks.PublicKeys()
+	require.NoError(t, err)
+	require.Equal(t, server.tokenMaker.(token.KeySource).PublicKeys(), keys)
+}
+
+func TestGetKeySetsWithSymmetricMaker(t *testing.T) {
+	test := newTest(t, "/.well-known/jwks.json")
+
+	request, err := http.NewRequest(http.MethodGet, test.url, nil)
+	require.NoError(t, err)
+	test.server.router.ServeHTTP(test.recorder, request)
+
+	require.Equal(t, http.StatusNotFound, test.recorder.Code)
+}
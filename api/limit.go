@@ -0,0 +1,62 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+const limitExceededCode = "limit_exceeded"
+
+func limitExceededResponse(err *db.LimitExceededError) gin.H {
+	return gin.H{
+		"error":     err.Error(),
+		"code":      limitExceededCode,
+		"limit":     err.Limit,
+		"max":       err.Max,
+		"remaining": err.Remaining,
+	}
+}
+
+func (s *Server) getAccountLimits(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := s.store.GetAccount(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doest belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !allowAccount(ctx, account.ID) {
+		return
+	}
+
+	status, err := s.store.GetLimitStatus(ctx, account, time.Now())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, status)
+}
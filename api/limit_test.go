@@ -0,0 +1,124 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGetAccountLimits(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	acc := randomAccount(user.Username)
+
+	daily := int64(1000)
+	left := int64(400)
+	status := db.LimitStatus{
+		Limits:    db.Limits{DailyTotal: &daily},
+		Usage:     db.LimitUsage{DailyTotal: 600, MonthlyTotal: 600, HourlyCount: 2},
+		Remaining: db.Limits{DailyTotal: &left},
+	}
+
+	testCases := []struct {
+		baseTestCase //
+		setupAuth    func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	}{
+		{
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
+						Times(1).
+						Return(acc, nil)
+
+					store.EXPECT().
+						GetLimitStatus(gomock.Any(), gomock.Eq(acc), gomock.Any()).
+						Times(1).
+						Return(status, nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+
+					var got db.LimitStatus
+					err := json.Unmarshal(recorder.Body.Bytes(), &got)
+					require.NoError(t, err)
+					require.Equal(t, status, got)
+				},
+			},
+		},
+		{
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, other.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "Unauthorized",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
+						Times(1).
+						Return(acc, nil)
+
+					store.EXPECT().
+						GetLimitStatus(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "NotFound",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc.ID)).
+						Times(1).
+						Return(db.Account{}, sql.ErrNoRows)
+
+					store.EXPECT().
+						GetLimitStatus(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNotFound, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, fmt.Sprintf("/accounts/%d/limits", acc.ID))
+			tc.buildStubs(test.store)
+
+			request, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+
+			// when
+			tc.setupAuth(t, request, test.server.tokenMaker)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
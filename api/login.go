@@ -0,0 +1,258 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/util"
+	"github.com/gin-gonic/gin"
+)
+
+// maxLoginLockout caps how long repeated lockouts can keep a username or an
+// IP out.
+const maxLoginLockout = 24 * time.Hour
+
+var (
+	errInvalidCredentials = errors.New("invalid credentials")
+	errLoginLocked        = errors.New("too many failed login attempts, try again later")
+)
+
+// burnPasswordCompare spends as long as a real password check does, so
+// unknown usernames can't be told apart by how fast the login fails.
+func (s *Server) burnPasswordCompare(password string) {
+	s.dummyPasswordHashOnce.Do(func() {
+		s.dummyPasswordHash, _ = s.passwords.Hash(util.RandomString(12))
+	})
+
+	s.passwords.Verify(password, s.dummyPasswordHash)
+}
+
+// rehashPassword stores a hash of password made with the current algorithm
+// and cost in place of user's outdated one. Failing to is logged, not fatal:
+// the next login tries again.
+func (s *Server) rehashPassword(ctx *gin.Context, user db.User, password string) {
+	hashedPassword, err := s.passwords.Hash(password)
+	if err == nil {
+		_, err = s.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+			Username:          user.Username,
+			HashedPassword:    hashedPassword,
+			OldHashedPassword: user.HashedPassword,
+		})
+	}
+
+	if err != nil {
+		log.Printf("cannot rehash password of %s: %v", user.Username, err)
+	}
+}
+
+var loginThrottleScopes = []db.LoginThrottleScope{db.LoginThrottleScopeUsername, db.LoginThrottleScopeIp}
+
+func loginThrottleKey(scope db.LoginThrottleScope, username string, ip string) string {
+	if scope == db.LoginThrottleScopeIp {
+		return ip
+	}
+
+	return username
+}
+
+func (s *Server) loginThrottled() bool {
+	return s.config.LoginMaxFailures > 0
+}
+
+func (s *Server) loginPolicy(scope db.LoginThrottleScope) db.LoginPolicy {
+	maxFailures := s.config.LoginMaxFailures
+	if scope == db.LoginThrottleScopeIp {
+		maxFailures = s.config.LoginMaxIPFailures
+	}
+
+	return db.LoginPolicy{
+		MaxFailures: maxFailures,
+		Window:      s.config.LoginFailureWindow,
+		BaseLockout: s.config.LoginLockoutDuration,
+		MaxLockout:  maxLoginLockout,
+	}
+}
+
+// loginLockedUntil returns when the latest lockout of username or ip ends, or
+// the zero time if neither is locked out.
+func (s *Server) loginLockedUntil(ctx *gin.Context, username string, ip string) (time.Time, error) {
+	var until time.Time
+	if !s.loginThrottled() {
+		return until, nil
+	}
+
+	now := time.Now()
+	for _, scope := range loginThrottleScopes {
+		key := loginThrottleKey(scope, username, ip)
+		throttle, err := s.store.GetLoginThrottle(ctx, db.GetLoginThrottleParams{Scope: scope, Key: key})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+
+			return until, err
+		}
+
+		if throttle.Locked(now) && throttle.LockedUntil.Time.After(until) {
+			until = throttle.LockedUntil.Time
+		}
+	}
+
+	return until, nil
+}
+
+// loginFailed counts a failed login against both username and ip.
+func (s *Server) loginFailed(ctx *gin.Context, username string, ip string) error {
+	if !s.loginThrottled() {
+		return nil
+	}
+
+	now := time.Now()
+	for _, scope := range loginThrottleScopes {
+		_, err := s.store.RecordLoginFailureTx(ctx, db.RecordLoginFailureTxParams{
+			Scope:  scope,
+			Key:    loginThrottleKey(scope, username, ip),
+			Policy: s.loginPolicy(scope),
+			Now:    now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loginSucceeded clears the failures counted against username. Failures
+// counted against the IP are left to expire, so one good password doesn't
+// reset guessing at every other username.
+func (s *Server) loginSucceeded(ctx *gin.Context, username string) error {
+	if !s.loginThrottled() {
+		return nil
+	}
+
+	_, err := s.store.ResetLoginThrottle(ctx, db.ResetLoginThrottleParams{
+		Scope: db.LoginThrottleScopeUsername,
+		Key:   username,
+	})
+	return err
+}
+
+func loginLockedResponse(ctx *gin.Context, until time.Time) {
+	retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	ctx.JSON(http.StatusTooManyRequests, gin.H{
+		"error":        errLoginLocked.Error(),
+		"code":         "login_locked",
+		"locked_until": until,
+	})
+}
+
+type userLockoutRequest struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type unlockUserRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (s *Server) unlockUser(ctx *gin.Context) {
+	var uri userLockoutRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req unlockUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	details, err := json.Marshal(map[string]string{"ip": ctx.ClientIP()})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err = s.store.AuditTx(ctx, db.CreateAuditLogParams{
+		Actor:   authPayload.Username,
+		Action:  "user.unlock",
+		Target:  "user:" + uri.Username,
+		Reason:  req.Reason,
+		Details: details,
+	}, func(q *db.Queries) error {
+		rows, err := q.ResetLoginThrottle(ctx, db.ResetLoginThrottleParams{
+			Scope: db.LoginThrottleScopeUsername,
+			Key:   uri.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		if rows == 0 {
+			return fmt.Errorf("no failed logins recorded for %s: %w", uri.Username, sql.ErrNoRows)
+		}
+
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listUserLockoutsRequest struct {
+	Limit int32 `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+func (s *Server) listUserLockouts(ctx *gin.Context) {
+	var uri userLockoutRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listUserLockoutsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	events, err := s.store.ListLockoutEvents(ctx, db.ListLockoutEventsParams{
+		Scope: db.LoginThrottleScopeUsername,
+		Key:   uri.Username,
+		Limit: req.Limit,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, events)
+}
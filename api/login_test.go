@@ -0,0 +1,455 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func enableLoginThrottling(s *Server) {
+	s.config.LoginMaxFailures = 5
+	s.config.LoginMaxIPFailures = 50
+	s.config.LoginFailureWindow = 15 * time.Minute
+	s.config.LoginLockoutDuration = time.Minute
+}
+
+func lockedThrottle(scope db.LoginThrottleScope, key string, until time.Time) db.LoginThrottle {
+	return db.LoginThrottle{
+		Scope:       scope,
+		Key:         key,
+		Lockouts:    1,
+		LockedUntil: sql.NullTime{Time: until, Valid: true},
+	}
+}
+
+func TestLoginUserThrottling(t *testing.T) {
+	user, password := randomUser(t)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	bcryptHasher, err := util.NewPasswordHasher(util.PasswordParams{Algorithm: util.PasswordAlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	require.NoError(t, err)
+	legacyUser := user
+	legacyUser.HashedPassword, err = bcryptHasher.Hash(password)
+	require.NoError(t, err)
+
+	const ip = "192.0.2.1"
+	usernameKey := db.GetLoginThrottleParams{Scope: db.LoginThrottleScopeUsername, Key: user.Username}
+	ipKey := db.GetLoginThrottleParams{Scope: db.LoginThrottleScopeIp, Key: ip}
+
+	expectFailures := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			RecordLoginFailureTx(gomock.Any(), gomock.Any()).
+			Times(2).
+			DoAndReturn(func(_ any, arg db.RecordLoginFailureTxParams) (db.LoginThrottle, error) {
+				switch arg.Scope {
+				case db.LoginThrottleScopeUsername:
+					require.Equal(t, user.Username, arg.Key)
+					require.Equal(t, int32(5), arg.Policy.MaxFailures)
+				case db.LoginThrottleScopeIp:
+					require.Equal(t, ip, arg.Key)
+					require.Equal(t, int32(50), arg.Policy.MaxFailures)
+				}
+				require.Equal(t, time.Minute, arg.Policy.BaseLockout)
+				return db.LoginThrottle{Scope: arg.Scope, Key: arg.Key, Failures: 1}, nil
+			})
+	}
+
+	requireInvalidCredentials := func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+		require.JSONEq(t, `{"error":"invalid credentials"}`, recorder.Body.String())
+	}
+
+	testCases := []struct {
+		baseTestCase //
+		password     string
+	}{
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Eq(usernameKey)).Times(1).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Eq(ipKey)).Times(1).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().
+						ResetLoginThrottle(gomock.Any(), gomock.Eq(db.ResetLoginThrottleParams{Scope: db.LoginThrottleScopeUsername, Key: user.Username})).
+						Times(1).
+						Return(int64(1), nil)
+					store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserMfa{}, sql.ErrNoRows)
+					store.EXPECT().RecordLoginFailureTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+				},
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "RehashLegacyHash",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(legacyUser, nil)
+					store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+					store.EXPECT().
+						RehashUserPassword(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ any, arg db.RehashUserPasswordParams) (int64, error) {
+							require.Equal(t, user.Username, arg.Username)
+							require.Equal(t, legacyUser.HashedPassword, arg.OldHashedPassword)
+							require.True(t, strings.HasPrefix(arg.HashedPassword, "$argon2id$"))
+							require.NoError(t, util.ComparePasswords(password, arg.HashedPassword))
+							return 1, nil
+						})
+					store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserMfa{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+				},
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "MFAChallenge",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(randomMFA(t, user.Username), nil)
+					store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusAccepted, recorder.Code)
+				},
+			},
+		},
+		{
+			password: "wrong-password",
+			baseTestCase: baseTestCase{
+				name: "WrongPassword",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					expectFailures(store)
+					store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: requireInvalidCredentials,
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "UnknownUser",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+					expectFailures(store)
+				},
+				checkResponse: requireInvalidCredentials,
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "UsernameLocked",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetLoginThrottle(gomock.Any(), gomock.Eq(usernameKey)).
+						Times(1).
+						Return(lockedThrottle(db.LoginThrottleScopeUsername, user.Username, time.Now().Add(90*time.Second)), nil)
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Eq(ipKey)).Times(1).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+					store.EXPECT().RecordLoginFailureTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+					require.Equal(t, "90", recorder.Header().Get("Retry-After"))
+
+					var got gin.H
+					require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+					require.Equal(t, "login_locked", got["code"])
+				},
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "IPLocked",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Eq(usernameKey)).Times(1).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().
+						GetLoginThrottle(gomock.Any(), gomock.Eq(ipKey)).
+						Times(1).
+						Return(lockedThrottle(db.LoginThrottleScopeIp, ip, time.Now().Add(time.Hour)), nil)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+					require.Equal(t, "3600", recorder.Header().Get("Retry-After"))
+				},
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "LockoutExpired",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetLoginThrottle(gomock.Any(), gomock.Eq(usernameKey)).
+						Times(1).
+						Return(lockedThrottle(db.LoginThrottleScopeUsername, user.Username, time.Now().Add(-time.Second)), nil)
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Eq(ipKey)).Times(1).Return(db.LoginThrottle{}, sql.ErrNoRows)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+					store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserMfa{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+				},
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "InternalError",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(1).Return(db.LoginThrottle{}, sql.ErrConnDone)
+					store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, "/users/login")
+			enableLoginThrottling(test.server)
+			tc.buildStubs(test.store)
+
+			reader, err := toReader(loginUserRequest{Username: user.Username, Password: tc.password})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, test.url, reader)
+			require.NoError(t, err)
+			request.RemoteAddr = ip + ":41234"
+
+			test.server.router.ServeHTTP(test.recorder, request)
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestVerifyLoginMFAThrottling(t *testing.T) {
+	user, _ := randomUser(t)
+	mfa := randomMFA(t, user.Username)
+
+	const ip = "192.0.2.1"
+	usernameKey := db.GetLoginThrottleParams{Scope: db.LoginThrottleScopeUsername, Key: user.Username}
+	ipKey := db.GetLoginThrottleParams{Scope: db.LoginThrottleScopeIp, Key: ip}
+
+	testCases := []baseTestCase{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, sql.ErrNoRows)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+				store.EXPECT().
+					ResetLoginThrottle(gomock.Any(), gomock.Eq(db.ResetLoginThrottleParams{Scope: db.LoginThrottleScopeUsername, Key: user.Username})).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().RecordLoginFailureTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusCreated, recorder.Code)
+			},
+		},
+		{
+			name: "WrongCode",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Any()).Times(2).Return(db.LoginThrottle{}, sql.ErrNoRows)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+				store.EXPECT().
+					RecordLoginFailureTx(gomock.Any(), gomock.Any()).
+					Times(2).
+					DoAndReturn(func(_ any, arg db.RecordLoginFailureTxParams) (db.LoginThrottle, error) {
+						if arg.Scope == db.LoginThrottleScopeIp {
+							require.Equal(t, ip, arg.Key)
+						} else {
+							require.Equal(t, user.Username, arg.Key)
+						}
+						return db.LoginThrottle{Scope: arg.Scope, Key: arg.Key, Failures: 1}, nil
+					})
+				store.EXPECT().ResetLoginThrottle(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Locked",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetLoginThrottle(gomock.Any(), gomock.Eq(usernameKey)).
+					Times(1).
+					Return(lockedThrottle(db.LoginThrottleScopeUsername, user.Username, time.Now().Add(time.Minute)), nil)
+				store.EXPECT().GetLoginThrottle(gomock.Any(), gomock.Eq(ipKey)).Times(1).Return(db.LoginThrottle{}, sql.ErrNoRows)
+				store.EXPECT().GetUserMFA(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Contains(t, recorder.Body.String(), "login_locked")
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, "/users/login/mfa")
+			enableLoginThrottling(test.server)
+			test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+			tc.buildStubs(test.store)
+
+			payload, err := token.NewPayload(user.Username, time.Minute)
+			require.NoError(t, err)
+			payload.Purpose = token.PurposeMFAChallenge
+			challengeToken, err := test.server.tokenMaker.CreateTokenFromPayload(payload)
+			require.NoError(t, err)
+
+			reader, err := toReader(verifyLoginMFARequest{
+				ChallengeToken:      challengeToken,
+				secondFactorRequest: secondFactorRequest{Code: currentCode(t, mfa.TotpSecret)},
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, test.url, reader)
+			require.NoError(t, err)
+			request.RemoteAddr = ip + ":41234"
+
+			test.server.router.ServeHTTP(test.recorder, request)
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+	customer, _ := randomUser(t)
+	customer.Role = db.UserRoleCustomer
+	locked, _ := randomUser(t)
+
+	testCases := []struct {
+		baseTestCase //
+		actor        db.User
+		body         gin.H
+	}{
+		{
+			actor: admin,
+			body:  gin.H{"reason": "verified identity over the phone"},
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+					store.EXPECT().
+						AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ any, arg db.CreateAuditLogParams, _ func(*db.Queries) error) (db.AuditLog, error) {
+							require.Equal(t, admin.Username, arg.Actor)
+							require.Equal(t, "user.unlock", arg.Action)
+							require.Equal(t, "user:"+locked.Username, arg.Target)
+							require.Equal(t, "verified identity over the phone", arg.Reason)
+							return db.AuditLog{}, nil
+						})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNoContent, recorder.Code)
+				},
+			},
+		},
+		{
+			actor: admin,
+			body:  gin.H{},
+			baseTestCase: baseTestCase{
+				name: "MissingReason",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+					store.EXPECT().AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			actor: admin,
+			body:  gin.H{"reason": "support ticket"},
+			baseTestCase: baseTestCase{
+				name: "NoThrottle",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+					store.EXPECT().
+						AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.AuditLog{}, fmt.Errorf("no failed logins recorded: %w", sql.ErrNoRows))
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNotFound, recorder.Code)
+				},
+			},
+		},
+		{
+			actor: customer,
+			body:  gin.H{"reason": "let me in"},
+			baseTestCase: baseTestCase{
+				name: "NotAdmin",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(customer, nil)
+					store.EXPECT().AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, fmt.Sprintf("/admin/users/%s/unlock", locked.Username))
+			tc.buildStubs(test.store)
+
+			accessToken := testAccessToken(t, test.server.tokenMaker, tc.actor.Username, time.Minute)
+
+			postJSON(t, test, tc.body, accessToken)
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
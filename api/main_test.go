@@ -8,6 +8,9 @@ import (
 
 	mockdb "github.com/aulas/demo-bank/db/mock"
 	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/fraud"
+	"github.com/aulas/demo-bank/mailer"
+	"github.com/aulas/demo-bank/stream"
 	"github.com/aulas/demo-bank/util"
 	"github.com/stretchr/testify/require"
 
@@ -24,14 +27,32 @@ type test struct {
 }
 
 func newTestServer(t *testing.T, store db.Store) *Server {
+	return newTestServerWithConfig(t, store, func(*util.Config) {})
+}
+
+// newTestServerWithConfig lets tests change the config before the routes are
+// set up.
+func newTestServerWithConfig(t *testing.T, store db.Store, configure func(*util.Config)) *Server {
 	config := util.Config{
 		TokenSymmetricKey: util.RandomString(32),
 		TokenDuration:     time.Minute,
+
+		PublicBaseURL:             "http://localhost:8080",
+		EmailVerificationDuration: time.Hour,
+		PasswordResetDuration:     time.Hour,
+
+		OAuthCodeDuration:         time.Minute,
+		OAuthRefreshTokenDuration: time.Hour,
 	}
+	configure(&config)
 
-	server, err := NewServer(&config, store)
+	server, err := NewServer(&config, store, stream.NewBroker())
 	require.NoError(t, err)
 
+	// fraud rules query the store; tests that exercise screening set their own
+	server.screener = fraud.NewScreener(store)
+	server.mailer = mailer.NewMemorySender()
+
 	return server
 }
 
@@ -40,6 +61,13 @@ func newTest(t *testing.T, url string) *test {
 	store := mockdb.NewMockStore(ctrl)
 	server := newTestServer(t, store)
 
+	// authMiddleware checks every token against the last password change;
+	// the middleware tests cover that with their own store
+	store.EXPECT().
+		GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(time.Time{}, nil)
+
 	return &test{
 		ctrl:     ctrl,
 		store:    store,
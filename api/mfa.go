@@ -0,0 +1,488 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/totp"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	mfaIssuer            = "demo-bank"
+	mfaChallengeDuration = 5 * time.Minute
+	recoveryCodeCount    = 10
+	mfaStepUpCode        = "mfa_step_up_required"
+	mfaEnrollmentCode    = "mfa_enrollment_required"
+)
+
+var (
+	errMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	errInvalidMFACode    = errors.New("invalid two-factor code")
+	errMFAStepUpRequired = errors.New("this operation requires a recent two-factor verification")
+	errMFAEnrollment     = errors.New("this operation requires two-factor authentication to be enabled")
+	errNotChallengeToken = errors.New("not an MFA challenge token")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// secondFactorRequest takes either a TOTP code or one of the recovery codes.
+type secondFactorRequest struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// checkSecondFactor reports whether the request carries a code for mfa that
+// has not been used before.
+func (s *Server) checkSecondFactor(ctx *gin.Context, mfa db.UserMfa, req secondFactorRequest) (bool, error) {
+	if req.Code != "" {
+		step, ok := totp.Validate(mfa.TotpSecret, req.Code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		n, err := s.store.UseTOTPStep(ctx, db.UseTOTPStepParams{
+			Username: mfa.Username,
+			Step:     step,
+		})
+		return n == 1, err
+	}
+
+	n, err := s.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		Username: mfa.Username,
+		CodeHash: hashToken(normalizeRecoveryCode(req.RecoveryCode)),
+	})
+	return n == 1, err
+}
+
+// lockedSecondFactor checks req like checkSecondFactor, under the same
+// lockout as logins: a locked out user is refused and a wrong code counts as
+// a failed login. It writes an error response unless the code was right.
+func (s *Server) lockedSecondFactor(ctx *gin.Context, mfa db.UserMfa, req secondFactorRequest) bool {
+	ip := ctx.ClientIP()
+	lockedUntil, err := s.loginLockedUntil(ctx, mfa.Username, ip)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !lockedUntil.IsZero() {
+		loginLockedResponse(ctx, lockedUntil)
+		return false
+	}
+
+	ok, err := s.checkSecondFactor(ctx, mfa, req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !ok {
+		if err := s.loginFailed(ctx, mfa.Username, ip); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+		return false
+	}
+
+	return true
+}
+
+// enabledMFA loads the user's confirmed MFA enrollment and writes an error
+// response unless there is one.
+func (s *Server) enabledMFA(ctx *gin.Context, username string) (db.UserMfa, bool) {
+	mfa, err := s.store.GetUserMFA(ctx, username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return mfa, false
+	}
+
+	if err != nil || !mfa.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusConflict, errorResponse(errMFANotEnabled))
+		return mfa, false
+	}
+
+	return mfa, true
+}
+
+// newRecoveryCodes returns codes formatted for the user and their hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b)[:10])
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashToken(code)
+	}
+
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// accessToken issues an access token, recording a second factor check made
+// now if mfa is set.
+func (s *Server) accessToken(user db.User, mfa bool) (string, *token.Payload, error) {
+	payload, err := token.NewPayload(user.Username, s.config.TokenDuration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload.Role = string(user.Role)
+	payload.Scopes = scopesForRole(user.Role)
+	if mfa {
+		payload.MFAVerifiedAt = payload.IssuedAt
+	}
+
+	accessToken, err := s.tokenMaker.CreateTokenFromPayload(payload)
+	return accessToken, payload, err
+}
+
+// reissueToken issues a fresh access token in the session of current, keeping
+// every restriction current carries.
+func (s *Server) reissueToken(current *token.Payload, mfa bool) (string, *token.Payload, error) {
+	payload, err := token.NewPayload(current.Username, s.config.TokenDuration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload.SessionID = current.SessionID
+	payload.Role = current.Role
+	payload.Scopes = current.Scopes
+	payload.AccountIDs = current.AccountIDs
+	payload.MaxTransferAmount = current.MaxTransferAmount
+	payload.ClientID = current.ClientID
+	payload.MFAVerifiedAt = current.MFAVerifiedAt
+	if mfa {
+		payload.MFAVerifiedAt = payload.IssuedAt
+	}
+
+	accessToken, err := s.tokenMaker.CreateTokenFromPayload(payload)
+	return accessToken, payload, err
+}
+
+type enrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// enrollTOTP starts enrollment with a new secret. Two-factor authentication
+// is only enforced once the user confirms it with a code.
+func (s *Server) enrollTOTP(ctx *gin.Context) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err = s.store.StartMFAEnrollment(ctx, db.StartMFAEnrollmentParams{
+		Username:   authPayload.Username,
+		TotpSecret: secret,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusConflict, errorResponse(db.ErrMFAAlreadyEnabled))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, enrollTOTPResponse{
+		Secret: secret,
+		URI:    totp.URI(mfaIssuer, authPayload.Username, secret),
+	})
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables two-factor authentication and responds with recovery
+// codes, which are only ever shown here.
+func (s *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	mfa, err := s.store.GetUserMFA(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(errors.New("no two-factor enrollment in progress")))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if mfa.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusConflict, errorResponse(db.ErrMFAAlreadyEnabled))
+		return
+	}
+
+	ok, err := s.checkSecondFactor(ctx, mfa, secondFactorRequest{Code: req.Code})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = s.store.ConfirmMFATx(ctx, mfa.Username, hashes)
+	if err != nil {
+		if errors.Is(err, db.ErrMFAAlreadyEnabled) {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, confirmTOTPResponse{RecoveryCodes: codes})
+}
+
+func (s *Server) disableTOTP(ctx *gin.Context) {
+	var req secondFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	mfa, ok := s.enabledMFA(ctx, authPayload.Username)
+	if !ok {
+		return
+	}
+
+	if !s.lockedSecondFactor(ctx, mfa, req) {
+		return
+	}
+
+	err := s.store.DisableMFATx(ctx, mfa.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// stepUpMFA exchanges a second factor for an access token that records it,
+// for operations that require a recent check.
+func (s *Server) stepUpMFA(ctx *gin.Context) {
+	var req secondFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	mfa, ok := s.enabledMFA(ctx, authPayload.Username)
+	if !ok {
+		return
+	}
+
+	if !s.lockedSecondFactor(ctx, mfa, req) {
+		return
+	}
+
+	accessToken, _, err := s.reissueToken(authPayload, true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, gin.H{"access_token": accessToken})
+}
+
+type mfaChallengeResponse struct {
+	MFARequired    bool      `json:"mfa_required"`
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// mfaChallenge is the second step of logging in for users with two-factor
+// authentication: the password step only hands out a challenge token.
+func (s *Server) mfaChallenge(ctx *gin.Context, username string) {
+	payload, err := token.NewPayload(username, mfaChallengeDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	payload.Purpose = token.PurposeMFAChallenge
+	challengeToken, err := s.tokenMaker.CreateTokenFromPayload(payload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, mfaChallengeResponse{
+		MFARequired:    true,
+		ChallengeToken: challengeToken,
+		ExpiresAt:      payload.ExpiresAt,
+	})
+}
+
+type verifyLoginMFARequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	secondFactorRequest
+}
+
+func (s *Server) verifyLoginMFA(ctx *gin.Context) {
+	var req verifyLoginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := s.tokenMaker.VerifyToken(req.ChallengeToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if payload.Purpose != token.PurposeMFAChallenge {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errNotChallengeToken))
+		return
+	}
+
+	user, err := s.store.GetUser(ctx, payload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if payload.IssuedAt.Before(user.PasswordChangedAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errTokenRevoked))
+		return
+	}
+
+	// wrong codes count against the same lockout as wrong passwords
+	ip := ctx.ClientIP()
+	lockedUntil, err := s.loginLockedUntil(ctx, user.Username, ip)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !lockedUntil.IsZero() {
+		loginLockedResponse(ctx, lockedUntil)
+		return
+	}
+
+	mfa, ok := s.enabledMFA(ctx, user.Username)
+	if !ok {
+		return
+	}
+
+	ok, err = s.checkSecondFactor(ctx, mfa, req.secondFactorRequest)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !ok {
+		if err := s.loginFailed(ctx, user.Username, ip); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidMFACode))
+		return
+	}
+
+	if err := s.loginSucceeded(ctx, user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessToken, _, err := s.accessToken(user, true)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, loginUserResponse{
+		AccessToken: accessToken,
+		User:        newUserResponse(user),
+	})
+}
+
+// requireStepUp reports whether the request may move amount. Amounts at or
+// above the configured threshold need a second factor entered within the
+// step-up window; otherwise a 403 has been written. Users without two-factor
+// authentication cannot step up, so they are told to enable it instead.
+func (s *Server) requireStepUp(ctx *gin.Context, amount int64) bool {
+	threshold := s.config.MFAStepUpAmount
+	if threshold <= 0 || amount < threshold {
+		return true
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !authPayload.MFAVerifiedAt.IsZero() && time.Since(authPayload.MFAVerifiedAt) <= s.config.MFAStepUpWindow {
+		return true
+	}
+
+	mfa, err := s.store.GetUserMFA(ctx, authPayload.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if err != nil || !mfa.ConfirmedAt.Valid {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error": errMFAEnrollment.Error(),
+			"code":  mfaEnrollmentCode,
+		})
+		return false
+	}
+
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"error": errMFAStepUpRequired.Error(),
+		"code":  mfaStepUpCode,
+	})
+	return false
+}
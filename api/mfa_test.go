@@ -0,0 +1,524 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/totp"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomMFA(t *testing.T, username string) db.UserMfa {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	return db.UserMfa{
+		Username:    username,
+		TotpSecret:  secret,
+		ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true},
+	}
+}
+
+func currentCode(t *testing.T, secret string) string {
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func postJSON(t *testing.T, test *test, body any, authToken string) {
+	reader, err := toReader(body)
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, test.url, reader)
+	require.NoError(t, err)
+
+	if authToken != "" {
+		request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, authToken))
+	}
+
+	test.server.router.ServeHTTP(test.recorder, request)
+}
+
+func TestLoginUserWithMFA(t *testing.T) {
+	user, password := randomUser(t)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	t.Run("WithoutMFA", func(t *testing.T) {
+		test := newTest(t, "/users/login")
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+		test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.UserMfa{}, sql.ErrNoRows)
+
+		postJSON(t, test, loginUserRequest{Username: user.Username, Password: password}, "")
+		require.Equal(t, http.StatusCreated, test.recorder.Code)
+
+		var got loginUserResponse
+		require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+		require.NotEmpty(t, got.AccessToken)
+
+		payload, err := test.server.tokenMaker.VerifyToken(got.AccessToken)
+		require.NoError(t, err)
+		require.Equal(t, string(user.Role), payload.Role)
+		require.Equal(t, scopesForRole(user.Role), payload.Scopes)
+		require.NotZero(t, payload.SessionID)
+	})
+
+	t.Run("ChallengeRequired", func(t *testing.T) {
+		test := newTest(t, "/users/login")
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+		test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(randomMFA(t, user.Username), nil)
+
+		postJSON(t, test, loginUserRequest{Username: user.Username, Password: password}, "")
+		require.Equal(t, http.StatusAccepted, test.recorder.Code)
+
+		var got mfaChallengeResponse
+		require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+		require.True(t, got.MFARequired)
+
+		payload, err := test.server.tokenMaker.VerifyToken(got.ChallengeToken)
+		require.NoError(t, err)
+		require.Equal(t, token.PurposeMFAChallenge, payload.Purpose)
+
+		// the challenge token is not an access token
+		request, err := http.NewRequest(http.MethodGet, "/webhooks", nil)
+		require.NoError(t, err)
+		request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, got.ChallengeToken))
+
+		recorder := httptest.NewRecorder()
+		test.server.router.ServeHTTP(recorder, request)
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+}
+
+func TestVerifyLoginMFA(t *testing.T) {
+	user, _ := randomUser(t)
+	mfa := randomMFA(t, user.Username)
+
+	challenge := func(t *testing.T, maker token.Maker, purpose string) string {
+		payload, err := token.NewPayload(user.Username, time.Minute)
+		require.NoError(t, err)
+		payload.Purpose = purpose
+
+		challengeToken, err := maker.CreateTokenFromPayload(payload)
+		require.NoError(t, err)
+		return challengeToken
+	}
+
+	testCases := []struct {
+		baseTestCase //
+		purpose      string
+		request      func(t *testing.T) secondFactorRequest
+	}{
+		{
+			purpose: token.PurposeMFAChallenge,
+			request: func(t *testing.T) secondFactorRequest {
+				return secondFactorRequest{Code: currentCode(t, mfa.TotpSecret)}
+			},
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+					store.EXPECT().
+						UseTOTPStep(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(int64(1), nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+				},
+			},
+		},
+		{
+			purpose: token.PurposeMFAChallenge,
+			request: func(t *testing.T) secondFactorRequest {
+				return secondFactorRequest{Code: currentCode(t, mfa.TotpSecret)}
+			},
+			baseTestCase: baseTestCase{
+				name: "CodeReplayed",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+					store.EXPECT().
+						UseTOTPStep(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(int64(0), nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			purpose: token.PurposeMFAChallenge,
+			request: func(t *testing.T) secondFactorRequest {
+				return secondFactorRequest{RecoveryCode: "ABCDE-FGHIJ"}
+			},
+			baseTestCase: baseTestCase{
+				name: "RecoveryCode",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+					store.EXPECT().
+						UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+							Username: user.Username,
+							CodeHash: hashToken("abcdefghij"),
+						})).
+						Times(1).
+						Return(int64(1), nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+				},
+			},
+		},
+		{
+			purpose: "",
+			request: func(t *testing.T) secondFactorRequest {
+				return secondFactorRequest{Code: currentCode(t, mfa.TotpSecret)}
+			},
+			baseTestCase: baseTestCase{
+				name: "AccessTokenInsteadOfChallenge",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			purpose: token.PurposeMFAChallenge,
+			request: func(t *testing.T) secondFactorRequest {
+				return secondFactorRequest{}
+			},
+			baseTestCase: baseTestCase{
+				name: "NoCode",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, "/users/login/mfa")
+			tc.buildStubs(test.store)
+
+			// when
+			postJSON(t, test, verifyLoginMFARequest{
+				ChallengeToken:      challenge(t, test.server.tokenMaker, tc.purpose),
+				secondFactorRequest: tc.request(t),
+			}, "")
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestEnrollAndConfirmTOTP(t *testing.T) {
+	user, _ := randomUser(t)
+
+	t.Run("Enroll", func(t *testing.T) {
+		test := newTest(t, "/users/mfa/totp")
+		test.store.EXPECT().
+			StartMFAEnrollment(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.StartMFAEnrollmentParams) (db.UserMfa, error) {
+				require.Equal(t, user.Username, arg.Username)
+				return db.UserMfa{Username: arg.Username, TotpSecret: arg.TotpSecret}, nil
+			})
+
+		accessToken := testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute)
+
+		postJSON(t, test, nil, accessToken)
+		require.Equal(t, http.StatusCreated, test.recorder.Code)
+
+		var got enrollTOTPResponse
+		require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+		require.NotEmpty(t, got.Secret)
+		require.Contains(t, got.URI, "otpauth://totp/demo-bank:"+user.Username)
+	})
+
+	t.Run("AlreadyEnabled", func(t *testing.T) {
+		test := newTest(t, "/users/mfa/totp")
+		test.store.EXPECT().
+			StartMFAEnrollment(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.UserMfa{}, sql.ErrNoRows)
+
+		accessToken := testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute)
+
+		postJSON(t, test, nil, accessToken)
+		require.Equal(t, http.StatusConflict, test.recorder.Code)
+	})
+
+	t.Run("Confirm", func(t *testing.T) {
+		mfa := randomMFA(t, user.Username)
+		mfa.ConfirmedAt = sql.NullTime{}
+
+		test := newTest(t, "/users/mfa/totp/confirm")
+		test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+		test.store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+
+		var hashes []string
+		test.store.EXPECT().
+			ConfirmMFATx(gomock.Any(), gomock.Eq(user.Username), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, _ string, h []string) (db.UserMfa, error) {
+				hashes = h
+				return mfa, nil
+			})
+
+		accessToken := testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute)
+
+		postJSON(t, test, confirmTOTPRequest{Code: currentCode(t, mfa.TotpSecret)}, accessToken)
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+
+		var got confirmTOTPResponse
+		require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+		require.Len(t, got.RecoveryCodes, recoveryCodeCount)
+		for i, code := range got.RecoveryCodes {
+			require.Equal(t, hashes[i], hashToken(normalizeRecoveryCode(code)))
+		}
+	})
+}
+
+func TestCreateTransferRequiresStepUp(t *testing.T) {
+	user, _ := randomUser(t)
+	request := transferRequest{FromAccountID: 1, ToAccountID: 2, Amount: 500, Currency: "USD"}
+
+	mfa := randomMFA(t, user.Username)
+
+	newStepUpTest := func(t *testing.T) *test {
+		test := newTest(t, "/transfers")
+		test.server.config.MFAStepUpAmount = 500
+		test.server.config.MFAStepUpWindow = 5 * time.Minute
+		test.store.EXPECT().
+			GetUser(gomock.Any(), gomock.Eq(user.Username)).
+			AnyTimes().
+			Return(user, nil)
+		return test
+	}
+
+	t.Run("WithoutMFA", func(t *testing.T) {
+		test := newStepUpTest(t)
+		test.store.EXPECT().
+			GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).
+			Times(1).
+			Return(db.UserMfa{}, sql.ErrNoRows)
+		test.store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		accessToken := testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute)
+
+		postJSON(t, test, request, accessToken)
+		require.Equal(t, http.StatusForbidden, test.recorder.Code)
+		require.Contains(t, test.recorder.Body.String(), mfaEnrollmentCode)
+	})
+
+	t.Run("WithoutStepUp", func(t *testing.T) {
+		test := newStepUpTest(t)
+		test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+		test.store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		accessToken := testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute)
+
+		postJSON(t, test, request, accessToken)
+		require.Equal(t, http.StatusForbidden, test.recorder.Code)
+		require.Contains(t, test.recorder.Body.String(), mfaStepUpCode)
+	})
+
+	t.Run("StaleStepUp", func(t *testing.T) {
+		test := newStepUpTest(t)
+		test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+		test.store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+
+		payload, err := token.NewPayload(user.Username, time.Hour)
+		require.NoError(t, err)
+		payload.Scopes = scopesForRole(user.Role)
+		payload.MFAVerifiedAt = time.Now().Add(-10 * time.Minute)
+		accessToken, err := test.server.tokenMaker.CreateTokenFromPayload(payload)
+		require.NoError(t, err)
+
+		postJSON(t, test, request, accessToken)
+		require.Equal(t, http.StatusForbidden, test.recorder.Code)
+		require.Contains(t, test.recorder.Body.String(), mfaStepUpCode)
+	})
+
+	t.Run("RecentStepUp", func(t *testing.T) {
+		test := newStepUpTest(t)
+		test.store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(request.FromAccountID)).
+			Times(1).
+			Return(db.Account{}, sql.ErrNoRows)
+
+		accessToken, _, err := test.server.accessToken(user, true)
+		require.NoError(t, err)
+
+		postJSON(t, test, request, accessToken)
+		require.Equal(t, http.StatusNotFound, test.recorder.Code)
+	})
+}
+
+func TestStepUpMFAKeepsSession(t *testing.T) {
+	user, _ := randomUser(t)
+	mfa := randomMFA(t, user.Username)
+
+	test := newTest(t, "/users/mfa/step_up")
+	test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+	test.store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+
+	accessToken, session, err := test.server.accessToken(user, false)
+	require.NoError(t, err)
+
+	postJSON(t, test, secondFactorRequest{Code: currentCode(t, mfa.TotpSecret)}, accessToken)
+	require.Equal(t, http.StatusCreated, test.recorder.Code)
+
+	var got loginUserResponse
+	require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+
+	payload, err := test.server.tokenMaker.VerifyToken(got.AccessToken)
+	require.NoError(t, err)
+	require.NotEqual(t, session.ID, payload.ID)
+	require.Equal(t, session.SessionID, payload.SessionID)
+	require.Equal(t, session.Scopes, payload.Scopes)
+	require.False(t, payload.MFAVerifiedAt.IsZero())
+}
+
+func TestStepUpMFAKeepsRestrictions(t *testing.T) {
+	user, _ := randomUser(t)
+	mfa := randomMFA(t, user.Username)
+
+	test := newTest(t, "/users/mfa/step_up")
+	test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(mfa, nil)
+	test.store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(1).Return(int64(1), nil)
+
+	// a token issued to an OAuth client, limited to one account and amount
+	session, err := token.NewPayload(user.Username, time.Minute)
+	require.NoError(t, err)
+	session.Scopes = []string{scopeProfile}
+	session.AccountIDs = []int64{util.RandomInt(1, 1000)}
+	session.MaxTransferAmount = 100
+	session.ClientID = "client"
+	test.store.EXPECT().
+		GetOAuthGrant(gomock.Any(), gomock.Eq(session.SessionID)).
+		Times(1).
+		Return(db.OauthGrant{ID: session.SessionID, ClientID: session.ClientID}, nil)
+
+	accessToken, err := test.server.tokenMaker.CreateTokenFromPayload(session)
+	require.NoError(t, err)
+
+	postJSON(t, test, secondFactorRequest{Code: currentCode(t, mfa.TotpSecret)}, accessToken)
+	require.Equal(t, http.StatusCreated, test.recorder.Code)
+
+	var got loginUserResponse
+	require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+
+	payload, err := test.server.tokenMaker.VerifyToken(got.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, session.Scopes, payload.Scopes)
+	require.Equal(t, session.AccountIDs, payload.AccountIDs)
+	require.Equal(t, session.MaxTransferAmount, payload.MaxTransferAmount)
+	require.Equal(t, session.ClientID, payload.ClientID)
+	require.False(t, payload.MFAVerifiedAt.IsZero())
+}
+
+// fakeLoginThrottles keeps login throttles in memory, locking a scope out
+// once it reaches the policy's failures.
+func fakeLoginThrottles(store *mockdb.MockStore) {
+	throttles := make(map[db.GetLoginThrottleParams]db.LoginThrottle)
+
+	store.EXPECT().
+		GetLoginThrottle(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ any, arg db.GetLoginThrottleParams) (db.LoginThrottle, error) {
+			throttle, ok := throttles[arg]
+			if !ok {
+				return db.LoginThrottle{}, sql.ErrNoRows
+			}
+			return throttle, nil
+		})
+	store.EXPECT().
+		RecordLoginFailureTx(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		DoAndReturn(func(_ any, arg db.RecordLoginFailureTxParams) (db.LoginThrottle, error) {
+			key := db.GetLoginThrottleParams{Scope: arg.Scope, Key: arg.Key}
+			throttle := throttles[key]
+			throttle.Scope, throttle.Key = arg.Scope, arg.Key
+			throttle.Failures++
+			if throttle.Failures >= arg.Policy.MaxFailures {
+				throttle = lockedThrottle(arg.Scope, arg.Key, arg.Now.Add(arg.Policy.BaseLockout))
+			}
+
+			throttles[key] = throttle
+			return throttle, nil
+		})
+}
+
+func TestSecondFactorLockout(t *testing.T) {
+	user, _ := randomUser(t)
+	mfa := randomMFA(t, user.Username)
+
+	testCases := []struct {
+		name   string
+		method string
+		url    string
+	}{
+		{name: "StepUp", method: http.MethodPost, url: "/users/mfa/step_up"},
+		{name: "DisableTOTP", method: http.MethodDelete, url: "/users/mfa/totp"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, tc.url)
+			enableLoginThrottling(test.server)
+			fakeLoginThrottles(test.store)
+
+			// the right code is never checked once the user is locked out
+			test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(mfa, nil)
+			test.store.EXPECT().UseRecoveryCode(gomock.Any(), gomock.Any()).Times(5).Return(int64(0), nil)
+			test.store.EXPECT().UseTOTPStep(gomock.Any(), gomock.Any()).Times(0)
+			test.store.EXPECT().DisableMFATx(gomock.Any(), gomock.Any()).Times(0)
+
+			accessToken, _, err := test.server.accessToken(user, false)
+			require.NoError(t, err)
+
+			send := func(req secondFactorRequest) *httptest.ResponseRecorder {
+				body, err := toReader(req)
+				require.NoError(t, err)
+
+				request, err := http.NewRequest(tc.method, tc.url, body)
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+				return serveFrom(t, test.server, request, "192.0.2.1")
+			}
+
+			for i := 0; i < 5; i++ {
+				recorder := send(secondFactorRequest{RecoveryCode: "aaaaa-bbbbb"})
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			}
+
+			recorder := send(secondFactorRequest{Code: currentCode(t, mfa.TotpSecret)})
+			require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			require.Contains(t, recorder.Body.String(), "login_locked")
+		})
+	}
+}
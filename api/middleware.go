@@ -1,11 +1,13 @@
 package api
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	db "github.com/aulas/demo-bank/db/sqlc"
 	"github.com/aulas/demo-bank/token"
 	"github.com/gin-gonic/gin"
 )
@@ -16,37 +18,192 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+var (
+	errTokenRevoked   = errors.New("token was issued before the last password change")
+	errNotAccessToken = errors.New("token cannot be used for this request")
+)
+
+// authMiddleware accepts an API key in the X-API-Key header, or a valid
+// bearer token issued after the user last changed their password and, for
+// OAuth clients, whose grant was not revoked. Either way handlers find a
+// token.Payload for the caller.
+func authMiddleware(tokenMaker token.Maker, store db.Store) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		authHeader := ctx.GetHeader(authorizationHeaderKey)
-		if len(authHeader) == 0 {
-			err := errors.New("authorization header is not provided")
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		var payload *token.Payload
+		var ok bool
+		if key := ctx.GetHeader(apiKeyHeaderKey); key != "" {
+			payload, ok = authenticateAPIKey(ctx, store, key)
+		} else {
+			payload, ok = authenticateBearer(ctx, tokenMaker, store)
+		}
+
+		if !ok {
 			return
 		}
 
-		fields := strings.Fields(authHeader)
-		if len(fields) != 2 {
-			err := errors.New("invalid authorization header format")
+		ctx.Set(authorizationPayloadKey, payload)
+		ctx.Next()
+	}
+}
+
+// authenticateBearer verifies the access token in the Authorization header.
+// Otherwise it aborts with an error response.
+func authenticateBearer(ctx *gin.Context, tokenMaker token.Maker, store db.Store) (*token.Payload, bool) {
+	authHeader := ctx.GetHeader(authorizationHeaderKey)
+	if len(authHeader) == 0 {
+		err := errors.New("authorization header is not provided")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil, false
+	}
+
+	fields := strings.Fields(authHeader)
+	if len(fields) != 2 {
+		err := errors.New("invalid authorization header format")
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil, false
+	}
+
+	authorizationType := strings.ToLower(fields[0])
+	if authorizationType != authorizationTypeBearer {
+		err := fmt.Errorf("authorization type %s not supported", authorizationType)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil, false
+	}
+
+	accessToken := fields[1]
+	payload, err := tokenMaker.VerifyToken(accessToken)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		return nil, false
+	}
+
+	if payload.Purpose != "" {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errNotAccessToken))
+		return nil, false
+	}
+
+	passwordChangedAt, err := store.GetUserPasswordChangedAt(ctx, payload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return nil, false
+		}
+
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+
+	if payload.IssuedAt.Before(passwordChangedAt) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errTokenRevoked))
+		return nil, false
+	}
+
+	if payload.ClientID != "" {
+		active, err := oauthGrantActive(ctx, store, payload.SessionID)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return nil, false
+		}
+
+		if !active {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errGrantRevoked))
+			return nil, false
+		}
+	}
+
+	return payload, true
+}
+
+const insufficientScopeCode = "insufficient_scope"
+
+var (
+	errAccountNotAllowed  = errors.New("token may not act on this account")
+	errTransferNotAllowed = errors.New("transfer amount is above what the token allows")
+)
+
+func insufficientScopeResponse(err error) gin.H {
+	return gin.H{
+		"error": err.Error(),
+		"code":  insufficientScopeCode,
+	}
+}
+
+// allowAccount writes a 403 response unless the token may act on the account.
+func allowAccount(ctx *gin.Context, accountID int64) bool {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !authPayload.CanUseAccount(accountID) {
+		ctx.JSON(http.StatusForbidden, insufficientScopeResponse(errAccountNotAllowed))
+		return false
+	}
+
+	return true
+}
+
+// requireScopes must run after authMiddleware. It only lets tokens granted
+// every one of scopes through.
+func requireScopes(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !authPayload.HasScopes(scopes...) {
+			err := fmt.Errorf("token lacks the scopes %s", strings.Join(scopes, ", "))
+			ctx.AbortWithStatusJSON(http.StatusForbidden, insufficientScopeResponse(err))
 			return
 		}
 
-		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
-			err := fmt.Errorf("authorization type %s not supported", authorizationType)
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.Next()
+	}
+}
+
+// adminMiddleware must run after authMiddleware. It loads the authenticated
+// user and only lets administrators through.
+func adminMiddleware(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := store.GetUser(ctx, authPayload.Username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if user.Role != db.UserRoleAdmin {
+			err := errors.New("admin role required")
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
 			return
 		}
 
-		accessToken := fields[1]
-		payload, err := tokenMaker.VerifyToken(accessToken)
+		ctx.Next()
+	}
+}
+
+// verifiedEmailMiddleware only lets users whose email address is verified
+// through.
+func verifiedEmailMiddleware(store db.Store) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := store.GetUser(ctx, authPayload.Username)
 		if err != nil {
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			if err == sql.ErrNoRows {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if !user.IsEmailVerified {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": errEmailNotVerified.Error(),
+				"code":  emailNotVerifiedCode,
+			})
 			return
 		}
 
-		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
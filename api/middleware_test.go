@@ -1,15 +1,19 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
 	"github.com/aulas/demo-bank/token"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func addAuth(
@@ -20,17 +24,29 @@ func addAuth(
 	username string,
 	duration time.Duration,
 ) {
-	token, err := tokenMaker.CreateToken(username, duration)
-	require.NoError(t, err)
+	token := testAccessToken(t, tokenMaker, username, duration)
 
 	authorizationHeader := fmt.Sprintf("%s %s", authorizationType, token)
 	request.Header.Set(authorizationHeaderKey, authorizationHeader)
 }
 
+// testAccessToken creates an access token with every scope, like the one an
+// administrator gets by logging in. Handlers still check the role itself.
+func testAccessToken(t *testing.T, tokenMaker token.Maker, username string, duration time.Duration) string {
+	payload, err := token.NewPayload(username, duration)
+	require.NoError(t, err)
+	payload.Scopes = scopesForRole(db.UserRoleAdmin)
+
+	accessToken, err := tokenMaker.CreateTokenFromPayload(payload)
+	require.NoError(t, err)
+	return accessToken
+}
+
 func TestMiddleware(t *testing.T) {
 	testCases := []struct {
 		name          string
 		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
@@ -38,10 +54,47 @@ func TestMiddleware(t *testing.T) {
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
 				addAuth(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
 			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserPasswordChangedAt(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(time.Now().Add(-time.Hour), nil)
+			},
 			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 				require.Equal(t, http.StatusOK, recorder.Code)
 			},
 		},
+		{
+			name: "IssuedBeforePasswordChange",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserPasswordChangedAt(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(time.Now().Add(time.Second), nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errTokenRevoked.Error())
+			},
+		},
+		{
+			name: "UserNotFound",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, "user", time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUserPasswordChangedAt(gomock.Any(), gomock.Eq("user")).
+					Times(1).
+					Return(time.Time{}, sql.ErrNoRows)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
 		{
 			name: "NoAuthorization",
 			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
@@ -82,12 +135,16 @@ func TestMiddleware(t *testing.T) {
 
 	for _, tC := range testCases {
 		t.Run(tC.name, func(t *testing.T) {
-			server := newTestServer(t, nil)
+			store := mockdb.NewMockStore(gomock.NewController(t))
+			if tC.buildStubs != nil {
+				tC.buildStubs(store)
+			}
+			server := newTestServer(t, store)
 
 			authPath := "/auth"
 			server.router.GET(
 				authPath,
-				authMiddleware(server.tokenMaker),
+				authMiddleware(server.tokenMaker, server.store),
 				func(ctx *gin.Context) {
 					ctx.JSON(http.StatusOK, gin.H{})
 				},
@@ -103,3 +160,91 @@ func TestMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestRequireScopes(t *testing.T) {
+	testCases := []struct {
+		name   string
+		scopes []string
+		code   int
+	}{
+		{name: "Granted", scopes: []string{scopeWebhooks, scopeAccountsRead}, code: http.StatusOK},
+		{name: "Missing", scopes: []string{scopeAccountsRead}, code: http.StatusForbidden},
+		{name: "None", code: http.StatusForbidden},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, "/scoped")
+			test.server.router.GET(
+				test.url,
+				authMiddleware(test.server.tokenMaker, test.store),
+				requireScopes(scopeWebhooks),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			payload, err := token.NewPayload("user", time.Minute)
+			require.NoError(t, err)
+			payload.Scopes = tc.scopes
+
+			accessToken, err := test.server.tokenMaker.CreateTokenFromPayload(payload)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+
+			test.server.router.ServeHTTP(test.recorder, request)
+			require.Equal(t, tc.code, test.recorder.Code)
+			if tc.code == http.StatusForbidden {
+				require.Contains(t, test.recorder.Body.String(), "insufficient_scope")
+			}
+		})
+	}
+}
+
+func TestMetricsRequiresAdmin(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+	customer, _ := randomUser(t)
+	customer.Role = db.UserRoleCustomer
+
+	testCases := []struct {
+		name         string
+		user         *db.User
+		expectedCode int
+	}{
+		{"Admin", &admin, http.StatusOK},
+		{"NotAdmin", &customer, http.StatusForbidden},
+		{"NoAuthorization", nil, http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, "/admin/metrics")
+			if tc.user != nil {
+				test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(tc.user.Username)).Times(1).Return(*tc.user, nil)
+			}
+
+			request, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+
+			if tc.user != nil {
+				addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, tc.user.Username, time.Minute)
+			}
+
+			test.server.router.ServeHTTP(test.recorder, request)
+			require.Equal(t, tc.expectedCode, test.recorder.Code)
+		})
+	}
+
+	t.Run("NotPublic", func(t *testing.T) {
+		test := newTest(t, "/metrics")
+		request, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+
+		test.server.router.ServeHTTP(test.recorder, request)
+		require.Equal(t, http.StatusNotFound, test.recorder.Code)
+	})
+}
@@ -0,0 +1,657 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// OAuth error codes from RFC 6749, used by the endpoints clients call
+// directly. The consent endpoints are called by our own frontend and answer
+// like the rest of the API.
+const (
+	oauthInvalidRequest       = "invalid_request"
+	oauthInvalidClient        = "invalid_client"
+	oauthInvalidGrant         = "invalid_grant"
+	oauthUnsupportedGrantType = "unsupported_grant_type"
+	oauthAccessDenied         = "access_denied"
+)
+
+var (
+	errUnknownRedirectURI = errors.New("redirect_uri is not registered for the client")
+	errPKCEMismatch       = errors.New("code_verifier does not match the code_challenge")
+	errRedirectMismatch   = errors.New("redirect_uri differs from the authorization request")
+	errGrantRevoked       = errors.New("access was revoked")
+)
+
+func oauthErrorResponse(code string, err error) gin.H {
+	return gin.H{
+		"error":             code,
+		"error_description": err.Error(),
+	}
+}
+
+// pkceChallenge is the S256 code challenge of verifier.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type createOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,oneof=accounts:read accounts:write transfers:write"`
+	// Confidential clients get a secret. Public ones, like mobile apps, can't
+	// keep one and rely on PKCE alone.
+	Confidential bool   `json:"confidential"`
+	Reason       string `json:"reason" binding:"required,max=500"`
+}
+
+type oauthClientResponse struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// createOAuthClientResponse is the only response that includes the client
+// secret; only its hash is stored.
+type createOAuthClientResponse struct {
+	oauthClientResponse
+	Secret string `json:"secret,omitempty"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ID:           client.ID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.SecretHash != "",
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func newOAuthClientID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return "cli_" + hex.EncodeToString(b), nil
+}
+
+// createOAuthClient registers a third-party app. Apps get access to customer
+// data, so only admins register them.
+func (s *Server) createOAuthClient(ctx *gin.Context) {
+	var req createOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	id, err := newOAuthClientID()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var secret, secretHash string
+	if req.Confidential {
+		secret, secretHash, err = newOneTimeToken()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	details, err := json.Marshal(gin.H{"name": req.Name, "redirect_uris": req.RedirectURIs, "scopes": req.Scopes})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	var client db.OauthClient
+	_, err = s.store.AuditTx(ctx, db.CreateAuditLogParams{
+		Actor:   authPayload.Username,
+		Action:  "oauth_client.create",
+		Target:  "oauth_client:" + id,
+		Reason:  req.Reason,
+		Details: details,
+	}, func(q *db.Queries) error {
+		client, err = q.CreateOAuthClient(ctx, db.CreateOAuthClientParams{
+			ID:           id,
+			Name:         req.Name,
+			SecretHash:   secretHash,
+			RedirectUris: req.RedirectURIs,
+			Scopes:       req.Scopes,
+			CreatedBy:    authPayload.Username,
+		})
+		return err
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createOAuthClientResponse{
+		oauthClientResponse: newOAuthClientResponse(client),
+		Secret:              secret,
+	})
+}
+
+func (s *Server) listOAuthClients(ctx *gin.Context) {
+	clients, err := s.store.ListOAuthClients(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result := make([]oauthClientResponse, len(clients))
+	for i, client := range clients {
+		result[i] = newOAuthClientResponse(client)
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// authorizeRequest is the authorization request of RFC 6749 section 4.1.1.
+// Only the code flow with an S256 PKCE challenge is supported.
+type authorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required,eq=code"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope" binding:"required"`
+	State               string `form:"state" json:"state" binding:"max=500"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" binding:"required,len=43"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" binding:"required,eq=S256"`
+}
+
+type consentResponse struct {
+	Client      oauthClientResponse `json:"client"`
+	Scopes      []string            `json:"scopes"`
+	RedirectURI string              `json:"redirect_uri"`
+	State       string              `json:"state"`
+}
+
+// validAuthorization checks the request against the client's registration and
+// returns the client and requested scopes. Otherwise it writes an error
+// response.
+func (s *Server) validAuthorization(ctx *gin.Context, req authorizeRequest) (db.OauthClient, []string, bool) {
+	client, err := s.store.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return client, nil, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return client, nil, false
+	}
+
+	registered := false
+	for _, uri := range client.RedirectUris {
+		if uri == req.RedirectURI {
+			registered = true
+			break
+		}
+	}
+
+	if !registered {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errUnknownRedirectURI))
+		return client, nil, false
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 || !scopesInclude(client.Scopes, scopes) {
+		err := errors.New("scope asks for more than the client may be granted")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return client, nil, false
+	}
+
+	return client, scopes, true
+}
+
+// getAuthorization returns what the consent screen shows the signed-in user
+// before they approve or deny the client.
+func (s *Server) getAuthorization(ctx *gin.Context) {
+	var req authorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	client, scopes, ok := s.validAuthorization(ctx, req)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, consentResponse{
+		Client:      newOAuthClientResponse(client),
+		Scopes:      scopes,
+		RedirectURI: req.RedirectURI,
+		State:       req.State,
+	})
+}
+
+type authorizeDecisionRequest struct {
+	authorizeRequest
+	Approve bool `json:"approve"`
+}
+
+type authorizeDecisionResponse struct {
+	// RedirectTo is where the frontend sends the user back to the client.
+	RedirectTo string `json:"redirect_to"`
+}
+
+// decideAuthorization records the signed-in user's consent decision. Either
+// way the user is sent back to the client: with an authorization code if they
+// approved, with access_denied otherwise.
+func (s *Server) decideAuthorization(ctx *gin.Context) {
+	var req authorizeDecisionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	client, scopes, ok := s.validAuthorization(ctx, req.authorizeRequest)
+	if !ok {
+		return
+	}
+
+	redirect, err := url.Parse(req.RedirectURI)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	query := redirect.Query()
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		query.Set("error", oauthAccessDenied)
+		redirect.RawQuery = query.Encode()
+		ctx.JSON(http.StatusOK, authorizeDecisionResponse{RedirectTo: redirect.String()})
+		return
+	}
+
+	code, codeHash, err := newOneTimeToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err = s.store.CreateOAuthAuthorizationCode(ctx, db.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      codeHash,
+		ClientID:      client.ID,
+		Username:      authPayload.Username,
+		RedirectUri:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(s.config.OAuthCodeDuration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	query.Set("code", code)
+	redirect.RawQuery = query.Encode()
+	ctx.JSON(http.StatusOK, authorizeDecisionResponse{RedirectTo: redirect.String()})
+}
+
+// oauthClient authenticates the client calling the token, introspection or
+// revocation endpoint, with HTTP Basic or client_id and client_secret form
+// fields. Public clients only send their ID. Otherwise it writes an
+// invalid_client response.
+func (s *Server) oauthClient(ctx *gin.Context) (db.OauthClient, bool) {
+	clientID, secret, ok := ctx.Request.BasicAuth()
+	if !ok {
+		clientID = ctx.PostForm("client_id")
+		secret = ctx.PostForm("client_secret")
+	}
+
+	client, err := s.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthInvalidClient, err))
+			return client, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return client, false
+	}
+
+	if client.SecretHash == "" {
+		if secret == "" {
+			return client, true
+		}
+	} else if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) == 1 {
+		return client, true
+	}
+
+	err = errors.New("client authentication failed")
+	ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthInvalidClient, err))
+	return client, false
+}
+
+type oauthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier" binding:"omitempty,min=43,max=128"`
+	RefreshToken string `form:"refresh_token"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// createOAuthToken is the token endpoint. It exchanges authorization codes and
+// rotates refresh tokens; access tokens come from the token maker like the
+// user's own, carrying only the granted scopes.
+func (s *Server) createOAuthToken(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req oauthTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err))
+		return
+	}
+
+	client, ok := s.oauthClient(ctx)
+	if !ok {
+		return
+	}
+
+	refreshToken, refreshHash, err := newOneTimeToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	now := time.Now()
+	var result db.OAuthTokenTxResult
+	switch req.GrantType {
+	case "authorization_code":
+		if req.Code == "" || req.CodeVerifier == "" {
+			err := errors.New("code and code_verifier are required")
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err))
+			return
+		}
+
+		result, err = s.store.ExchangeOAuthCodeTx(ctx, db.ExchangeOAuthCodeTxParams{
+			CodeHash: hashToken(req.Code),
+			ClientID: client.ID,
+			Verify: func(code db.OauthAuthorizationCode) error {
+				if code.RedirectUri != req.RedirectURI {
+					return errRedirectMismatch
+				}
+
+				if subtle.ConstantTimeCompare([]byte(pkceChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+					return errPKCEMismatch
+				}
+
+				return nil
+			},
+			GrantID:          uuid.New(),
+			RefreshTokenHash: refreshHash,
+			RefreshExpiresAt: now.Add(s.config.OAuthRefreshTokenDuration),
+			Now:              now,
+		})
+	case "refresh_token":
+		if req.RefreshToken == "" {
+			err := errors.New("refresh_token is required")
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err))
+			return
+		}
+
+		result, err = s.store.RefreshOAuthTokenTx(ctx, db.RefreshOAuthTokenTxParams{
+			TokenHash:        hashToken(req.RefreshToken),
+			ClientID:         client.ID,
+			NewTokenHash:     refreshHash,
+			RefreshExpiresAt: now.Add(s.config.OAuthRefreshTokenDuration),
+			Now:              now,
+		})
+	default:
+		err := errors.New("only authorization_code and refresh_token grants are supported")
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthUnsupportedGrantType, err))
+		return
+	}
+	if err != nil {
+		if isInvalidGrant(err) {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessToken, payload, err := s.oauthAccessToken(result.Grant)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, oauthTokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(payload.ExpiresAt).Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        strings.Join(result.Grant.Scopes, " "),
+	})
+}
+
+func isInvalidGrant(err error) bool {
+	for _, target := range []error{
+		sql.ErrNoRows,
+		db.ErrOAuthCodeUsed,
+		db.ErrOAuthCodeExpired,
+		db.ErrOAuthRefreshTokenUsed,
+		db.ErrOAuthRefreshTokenExpired,
+		db.ErrOAuthGrantRevoked,
+		db.ErrOAuthWrongClient,
+		errRedirectMismatch,
+		errPKCEMismatch,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// oauthAccessToken issues an access token under grant. The grant's ID is the
+// token's session ID, which is how revoking the grant revokes the token.
+func (s *Server) oauthAccessToken(grant db.OauthGrant) (string, *token.Payload, error) {
+	payload, err := token.NewPayload(grant.Username, s.config.TokenDuration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	payload.SessionID = grant.ID
+	payload.ClientID = grant.ClientID
+	payload.Scopes = grant.Scopes
+
+	accessToken, err := s.tokenMaker.CreateTokenFromPayload(payload)
+	return accessToken, payload, err
+}
+
+// oauthGrantActive reports whether the grant an OAuth access token was issued
+// under still stands.
+func oauthGrantActive(ctx *gin.Context, store db.Store, grantID uuid.UUID) (bool, error) {
+	grant, err := store.GetOAuthGrant(ctx, grantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return !grant.RevokedAt.Valid, nil
+}
+
+type oauthTokenLookupRequest struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// lookupOAuthToken finds the grant of an access or refresh token issued to
+// client. active is false when the token is unknown, no longer usable or
+// belongs to another client; payload is nil for refresh tokens.
+func (s *Server) lookupOAuthToken(ctx *gin.Context, client db.OauthClient, raw string) (db.OauthGrant, *token.Payload, bool, error) {
+	grantID, payload, err := s.oauthTokenGrantID(ctx, raw)
+	if err != nil || grantID == uuid.Nil {
+		return db.OauthGrant{}, nil, false, err
+	}
+
+	grant, err := s.store.GetOAuthGrant(ctx, grantID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return grant, nil, false, nil
+		}
+
+		return grant, nil, false, err
+	}
+
+	active := grant.ClientID == client.ID && !grant.RevokedAt.Valid
+	return grant, payload, active, nil
+}
+
+// oauthTokenGrantID returns the grant of raw if it is a valid OAuth access
+// token or an unused refresh token, and uuid.Nil otherwise.
+func (s *Server) oauthTokenGrantID(ctx *gin.Context, raw string) (uuid.UUID, *token.Payload, error) {
+	payload, err := s.tokenMaker.VerifyToken(raw)
+	if err == nil {
+		if payload.ClientID == "" || payload.Purpose != "" {
+			return uuid.Nil, nil, nil
+		}
+
+		return payload.SessionID, payload, nil
+	}
+
+	refresh, err := s.store.GetOAuthRefreshToken(ctx, hashToken(raw))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, nil, nil
+		}
+
+		return uuid.Nil, nil, err
+	}
+
+	if refresh.UsedAt.Valid || time.Now().After(refresh.ExpiresAt) {
+		return uuid.Nil, nil, nil
+	}
+
+	return refresh.GrantID, nil, nil
+}
+
+type introspectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// introspectOAuthToken implements RFC 7662 for the client's own tokens.
+// Tokens of other clients are reported inactive.
+func (s *Server) introspectOAuthToken(ctx *gin.Context) {
+	client, ok := s.oauthClient(ctx)
+	if !ok {
+		return
+	}
+
+	var req oauthTokenLookupRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err))
+		return
+	}
+
+	grant, payload, active, err := s.lookupOAuthToken(ctx, client, req.Token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !active {
+		ctx.JSON(http.StatusOK, introspectionResponse{})
+		return
+	}
+
+	rsp := introspectionResponse{
+		Active:    true,
+		Scope:     strings.Join(grant.Scopes, " "),
+		ClientID:  grant.ClientID,
+		Username:  grant.Username,
+		TokenType: "refresh_token",
+	}
+	if payload != nil {
+		rsp.TokenType = "access_token"
+		rsp.ExpiresAt = payload.ExpiresAt.Unix()
+		rsp.IssuedAt = payload.IssuedAt.Unix()
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// revokeOAuthToken implements RFC 7009. Revoking either kind of token revokes
+// the whole grant, so the app loses access until the user consents again.
+// Unknown tokens are not an error.
+func (s *Server) revokeOAuthToken(ctx *gin.Context) {
+	client, ok := s.oauthClient(ctx)
+	if !ok {
+		return
+	}
+
+	var req oauthTokenLookupRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err))
+		return
+	}
+
+	grant, _, active, err := s.lookupOAuthToken(ctx, client, req.Token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if active {
+		err = s.store.RevokeOAuthGrant(ctx, grant.ID)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	ctx.Status(http.StatusOK)
+}
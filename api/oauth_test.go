@@ -0,0 +1,491 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testRedirectURI = "https://budget.example.com/callback"
+
+func randomOAuthClient(t *testing.T, scopes ...string) (db.OauthClient, string) {
+	secret, hash, err := newOneTimeToken()
+	require.NoError(t, err)
+
+	id, err := newOAuthClientID()
+	require.NoError(t, err)
+
+	return db.OauthClient{
+		ID:           id,
+		Name:         "Budget App",
+		SecretHash:   hash,
+		RedirectUris: []string{testRedirectURI},
+		Scopes:       scopes,
+		CreatedBy:    util.RandomUsername(),
+		CreatedAt:    time.Now().UTC().Truncate(time.Second),
+	}, secret
+}
+
+func authorizeQuery(client db.OauthClient, scope string, verifier string) url.Values {
+	return url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID},
+		"redirect_uri":          {testRedirectURI},
+		"scope":                 {scope},
+		"state":                 {"xyz"},
+		"code_challenge":        {pkceChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+}
+
+func postForm(t *testing.T, test *test, form url.Values, client db.OauthClient, secret string) {
+	request, err := http.NewRequest(http.MethodPost, test.url, strings.NewReader(form.Encode()))
+	require.NoError(t, err)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.SetBasicAuth(client.ID, secret)
+
+	test.server.router.ServeHTTP(test.recorder, request)
+}
+
+func TestOAuthConsent(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(t, scopeAccountsRead, scopeTransfersWrite)
+	verifier := util.RandomString(64)
+
+	t.Run("Show", func(t *testing.T) {
+		query := authorizeQuery(client, scopeAccountsRead, verifier)
+		test := newTest(t, "/oauth/authorize?"+query.Encode())
+		test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+
+		request, err := http.NewRequest(http.MethodGet, test.url, nil)
+		require.NoError(t, err)
+		addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		test.server.router.ServeHTTP(test.recorder, request)
+
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+
+		var got consentResponse
+		require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+		require.Equal(t, client.Name, got.Client.Name)
+		require.Equal(t, []string{scopeAccountsRead}, got.Scopes)
+		require.NotContains(t, test.recorder.Body.String(), client.SecretHash)
+	})
+
+	t.Run("Approve", func(t *testing.T) {
+		test := newTest(t, "/oauth/authorize")
+		test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+
+		var codeHash string
+		test.store.EXPECT().
+			CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.CreateOAuthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, []string{scopeAccountsRead}, arg.Scopes)
+				require.Equal(t, pkceChallenge(verifier), arg.CodeChallenge)
+				require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+				codeHash = arg.CodeHash
+				return db.OauthAuthorizationCode{CodeHash: arg.CodeHash}, nil
+			})
+
+		body := map[string]any{"approve": true}
+		for k, v := range authorizeQuery(client, scopeAccountsRead, verifier) {
+			body[k] = v[0]
+		}
+		postJSON(t, test, body, testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute))
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+
+		var got authorizeDecisionResponse
+		require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+		redirect, err := url.Parse(got.RedirectTo)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(got.RedirectTo, testRedirectURI+"?"))
+		require.Equal(t, "xyz", redirect.Query().Get("state"))
+		require.Equal(t, codeHash, hashToken(redirect.Query().Get("code")))
+	})
+
+	t.Run("Deny", func(t *testing.T) {
+		test := newTest(t, "/oauth/authorize")
+		test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+		test.store.EXPECT().CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+
+		body := map[string]any{"approve": false}
+		for k, v := range authorizeQuery(client, scopeAccountsRead, verifier) {
+			body[k] = v[0]
+		}
+		postJSON(t, test, body, testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute))
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+		require.Contains(t, test.recorder.Body.String(), "error=access_denied")
+	})
+
+	invalid := []struct {
+		name  string
+		query func(url.Values)
+	}{
+		{name: "UnregisteredRedirect", query: func(q url.Values) { q.Set("redirect_uri", "https://evil.example.com/cb") }},
+		{name: "ScopeBeyondClient", query: func(q url.Values) { q.Set("scope", scopeAccountsWrite) }},
+		{name: "PlainChallenge", query: func(q url.Values) { q.Set("code_challenge_method", "plain") }},
+		{name: "NoChallenge", query: func(q url.Values) { q.Del("code_challenge") }},
+	}
+
+	for _, tc := range invalid {
+		t.Run(tc.name, func(t *testing.T) {
+			query := authorizeQuery(client, scopeAccountsRead, verifier)
+			tc.query(query)
+
+			test := newTest(t, "/oauth/authorize?"+query.Encode())
+			test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).AnyTimes().Return(client, nil)
+
+			request, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+			addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			require.Equal(t, http.StatusBadRequest, test.recorder.Code)
+		})
+	}
+}
+
+func TestOAuthTokenExchange(t *testing.T) {
+	user, _ := randomUser(t)
+	client, secret := randomOAuthClient(t, scopeAccountsRead)
+	verifier := util.RandomString(64)
+	code := db.OauthAuthorizationCode{
+		ClientID:      client.ID,
+		Username:      user.Username,
+		RedirectUri:   testRedirectURI,
+		Scopes:        []string{scopeAccountsRead},
+		CodeChallenge: pkceChallenge(verifier),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+
+	exchange := func(store *mockdb.MockStore) {
+		store.EXPECT().
+			ExchangeOAuthCodeTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ any, arg db.ExchangeOAuthCodeTxParams) (db.OAuthTokenTxResult, error) {
+				require.Equal(t, client.ID, arg.ClientID)
+				if err := arg.Verify(code); err != nil {
+					return db.OAuthTokenTxResult{}, err
+				}
+
+				return db.OAuthTokenTxResult{
+					Grant: db.OauthGrant{ID: arg.GrantID, ClientID: client.ID, Username: user.Username, Scopes: code.Scopes},
+				}, nil
+			})
+	}
+
+	testCases := []struct {
+		name     string
+		form     url.Values
+		secret   string
+		stubs    func(store *mockdb.MockStore)
+		code     int
+		oauthErr string
+	}{
+		{
+			name:   "OK",
+			form:   url.Values{"grant_type": {"authorization_code"}, "code": {"abc"}, "redirect_uri": {testRedirectURI}, "code_verifier": {verifier}},
+			secret: secret,
+			stubs:  exchange,
+			code:   http.StatusOK,
+		},
+		{
+			name:     "WrongVerifier",
+			form:     url.Values{"grant_type": {"authorization_code"}, "code": {"abc"}, "redirect_uri": {testRedirectURI}, "code_verifier": {util.RandomString(64)}},
+			secret:   secret,
+			stubs:    exchange,
+			code:     http.StatusBadRequest,
+			oauthErr: oauthInvalidGrant,
+		},
+		{
+			name:     "WrongRedirect",
+			form:     url.Values{"grant_type": {"authorization_code"}, "code": {"abc"}, "redirect_uri": {"https://budget.example.com/other"}, "code_verifier": {verifier}},
+			secret:   secret,
+			stubs:    exchange,
+			code:     http.StatusBadRequest,
+			oauthErr: oauthInvalidGrant,
+		},
+		{
+			name:   "CodeReplayed",
+			form:   url.Values{"grant_type": {"authorization_code"}, "code": {"abc"}, "redirect_uri": {testRedirectURI}, "code_verifier": {verifier}},
+			secret: secret,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExchangeOAuthCodeTx(gomock.Any(), gomock.Any()).Times(1).Return(db.OAuthTokenTxResult{}, db.ErrOAuthCodeUsed)
+			},
+			code:     http.StatusBadRequest,
+			oauthErr: oauthInvalidGrant,
+		},
+		{
+			name:   "WrongSecret",
+			form:   url.Values{"grant_type": {"authorization_code"}, "code": {"abc"}, "redirect_uri": {testRedirectURI}, "code_verifier": {verifier}},
+			secret: "wrong",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExchangeOAuthCodeTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code:     http.StatusUnauthorized,
+			oauthErr: oauthInvalidClient,
+		},
+		{
+			name:   "PasswordGrant",
+			form:   url.Values{"grant_type": {"password"}},
+			secret: secret,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().ExchangeOAuthCodeTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code:     http.StatusBadRequest,
+			oauthErr: oauthUnsupportedGrantType,
+		},
+		{
+			name:   "Refresh",
+			form:   url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"old"}},
+			secret: secret,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					RefreshOAuthTokenTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.RefreshOAuthTokenTxParams) (db.OAuthTokenTxResult, error) {
+						require.Equal(t, hashToken("old"), arg.TokenHash)
+						require.NotEqual(t, arg.TokenHash, arg.NewTokenHash)
+						return db.OAuthTokenTxResult{
+							Grant: db.OauthGrant{ID: uuid.New(), ClientID: client.ID, Username: user.Username, Scopes: code.Scopes},
+						}, nil
+					})
+			},
+			code: http.StatusOK,
+		},
+		{
+			name:   "RefreshReplayed",
+			form:   url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"old"}},
+			secret: secret,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().RefreshOAuthTokenTx(gomock.Any(), gomock.Any()).Times(1).Return(db.OAuthTokenTxResult{}, db.ErrOAuthRefreshTokenUsed)
+			},
+			code:     http.StatusBadRequest,
+			oauthErr: oauthInvalidGrant,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, "/oauth/token")
+			test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).AnyTimes().Return(client, nil)
+			tc.stubs(test.store)
+
+			postForm(t, test, tc.form, client, tc.secret)
+			require.Equal(t, tc.code, test.recorder.Code, test.recorder.Body.String())
+			require.Equal(t, "no-store", test.recorder.Header().Get("Cache-Control"))
+
+			if tc.oauthErr != "" {
+				var got map[string]string
+				require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+				require.Equal(t, tc.oauthErr, got["error"])
+				return
+			}
+
+			var got oauthTokenResponse
+			require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+			require.Equal(t, "Bearer", got.TokenType)
+			require.Equal(t, scopeAccountsRead, got.Scope)
+			require.NotEmpty(t, got.RefreshToken)
+
+			payload, err := test.server.tokenMaker.VerifyToken(got.AccessToken)
+			require.NoError(t, err)
+			require.Equal(t, user.Username, payload.Username)
+			require.Equal(t, client.ID, payload.ClientID)
+			require.Equal(t, []string{scopeAccountsRead}, payload.Scopes)
+		})
+	}
+}
+
+func TestOAuthAccessToken(t *testing.T) {
+	user, _ := randomUser(t)
+	user.IsEmailVerified = true
+	account := randomAccount(user.Username)
+	client, _ := randomOAuthClient(t, scopeAccountsRead)
+	grant := db.OauthGrant{ID: uuid.New(), ClientID: client.ID, Username: user.Username, Scopes: []string{scopeAccountsRead}}
+
+	testCases := []struct {
+		name    string
+		method  string
+		url     string
+		revoked bool
+		stubs   func(store *mockdb.MockStore)
+		code    int
+	}{
+		{
+			name:   "ReadAccount",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+			},
+			code: http.StatusOK,
+		},
+		{
+			name:   "ReadOnlyCannotTransfer",
+			method: http.MethodPost,
+			url:    "/transfers",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			name:   "CannotConsentForOthers",
+			method: http.MethodPost,
+			url:    "/oauth/authorize",
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateOAuthAuthorizationCode(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: http.StatusForbidden,
+		},
+		{
+			name:    "Revoked",
+			method:  http.MethodGet,
+			url:     fmt.Sprintf("/accounts/%d", account.ID),
+			revoked: true,
+			stubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			code: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, tc.url)
+			tc.stubs(test.store)
+
+			g := grant
+			if tc.revoked {
+				g.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+			}
+			test.store.EXPECT().GetOAuthGrant(gomock.Any(), gomock.Eq(grant.ID)).Times(1).Return(g, nil)
+			test.store.EXPECT().GetUser(gomock.Any(), gomock.Any()).AnyTimes().Return(user, nil)
+
+			accessToken, _, err := test.server.oauthAccessToken(grant)
+			require.NoError(t, err)
+
+			body, err := toReader(transferRequest{FromAccountID: account.ID, ToAccountID: account.ID + 1, Amount: 10, Currency: account.Currency})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(tc.method, test.url, body)
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" "+accessToken)
+
+			test.server.router.ServeHTTP(test.recorder, request)
+			require.Equal(t, tc.code, test.recorder.Code)
+		})
+	}
+}
+
+func TestOAuthIntrospectAndRevoke(t *testing.T) {
+	user, _ := randomUser(t)
+	client, secret := randomOAuthClient(t, scopeAccountsRead)
+	other, _ := randomOAuthClient(t, scopeAccountsRead)
+	grant := db.OauthGrant{ID: uuid.New(), ClientID: client.ID, Username: user.Username, Scopes: []string{scopeAccountsRead}}
+	refresh := db.OauthRefreshToken{TokenHash: hashToken("refresh"), GrantID: grant.ID, ExpiresAt: time.Now().Add(time.Hour)}
+
+	t.Run("IntrospectAccessToken", func(t *testing.T) {
+		test := newTest(t, "/oauth/introspect")
+		test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+		test.store.EXPECT().GetOAuthGrant(gomock.Any(), gomock.Eq(grant.ID)).Times(1).Return(grant, nil)
+
+		accessToken, _, err := test.server.oauthAccessToken(grant)
+		require.NoError(t, err)
+
+		postForm(t, test, url.Values{"token": {accessToken}}, client, secret)
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+
+		var got introspectionResponse
+		require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+		require.True(t, got.Active)
+		require.Equal(t, "access_token", got.TokenType)
+		require.Equal(t, user.Username, got.Username)
+		require.Equal(t, scopeAccountsRead, got.Scope)
+	})
+
+	t.Run("IntrospectOtherClientsToken", func(t *testing.T) {
+		test := newTest(t, "/oauth/introspect")
+		test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+		test.store.EXPECT().GetOAuthGrant(gomock.Any(), gomock.Any()).Times(1).
+			Return(db.OauthGrant{ID: grant.ID, ClientID: other.ID, Username: user.Username}, nil)
+		test.store.EXPECT().GetOAuthRefreshToken(gomock.Any(), gomock.Eq(refresh.TokenHash)).Times(1).Return(refresh, nil)
+
+		postForm(t, test, url.Values{"token": {"refresh"}}, client, secret)
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+		require.JSONEq(t, `{"active":false}`, test.recorder.Body.String())
+	})
+
+	t.Run("IntrospectUnknown", func(t *testing.T) {
+		test := newTest(t, "/oauth/introspect")
+		test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+		test.store.EXPECT().GetOAuthRefreshToken(gomock.Any(), gomock.Any()).Times(1).Return(db.OauthRefreshToken{}, sql.ErrNoRows)
+
+		postForm(t, test, url.Values{"token": {"nope"}}, client, secret)
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+		require.JSONEq(t, `{"active":false}`, test.recorder.Body.String())
+	})
+
+	t.Run("RevokeRefreshToken", func(t *testing.T) {
+		test := newTest(t, "/oauth/revoke")
+		test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+		test.store.EXPECT().GetOAuthRefreshToken(gomock.Any(), gomock.Eq(refresh.TokenHash)).Times(1).Return(refresh, nil)
+		test.store.EXPECT().GetOAuthGrant(gomock.Any(), gomock.Eq(grant.ID)).Times(1).Return(grant, nil)
+		test.store.EXPECT().RevokeOAuthGrant(gomock.Any(), gomock.Eq(grant.ID)).Times(1).Return(nil)
+
+		postForm(t, test, url.Values{"token": {"refresh"}}, client, secret)
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+	})
+
+	t.Run("RevokeWrongSecret", func(t *testing.T) {
+		test := newTest(t, "/oauth/revoke")
+		test.store.EXPECT().GetOAuthClient(gomock.Any(), gomock.Eq(client.ID)).Times(1).Return(client, nil)
+		test.store.EXPECT().RevokeOAuthGrant(gomock.Any(), gomock.Any()).Times(0)
+
+		postForm(t, test, url.Values{"token": {"refresh"}}, client, "wrong")
+		require.Equal(t, http.StatusUnauthorized, test.recorder.Code)
+	})
+}
+
+func TestCreateOAuthClient(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+
+	test := newTest(t, "/admin/oauth/clients")
+	test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+	test.store.EXPECT().
+		AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ any, arg db.CreateAuditLogParams, _ func(*db.Queries) error) (db.AuditLog, error) {
+			require.Equal(t, "oauth_client.create", arg.Action)
+			require.True(t, strings.HasPrefix(arg.Target, "oauth_client:cli_"))
+			return db.AuditLog{}, nil
+		})
+
+	postJSON(t, test, map[string]any{
+		"name":          "Budget App",
+		"redirect_uris": []string{testRedirectURI},
+		"scopes":        []string{scopeAccountsRead},
+		"confidential":  true,
+		"reason":        "partner onboarding",
+	}, testAccessToken(t, test.server.tokenMaker, admin.Username, time.Minute))
+	require.Equal(t, http.StatusCreated, test.recorder.Code)
+
+	var got createOAuthClientResponse
+	require.NoError(t, json.Unmarshal(test.recorder.Body.Bytes(), &got))
+	require.NotEmpty(t, got.Secret)
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/mailer"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestChangePassword(t *testing.T) {
+	user, password := randomUser(t)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	newPassword := util.RandomString(12)
+
+	testCases := []struct {
+		baseTestCase //
+		request      changePasswordRequest
+	}{
+		{
+			request: changePasswordRequest{CurrentPassword: password, NewPassword: newPassword},
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(user.Username)).
+						Times(1).
+						Return(user, nil)
+
+					store.EXPECT().
+						ChangePasswordTx(gomock.Any(), gomock.Eq(user.Username), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ context.Context, username string, hashed string) (db.User, error) {
+							require.NoError(t, util.ComparePasswords(newPassword, hashed))
+							return user, nil
+						})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+
+					var got loginUserResponse
+					err := json.Unmarshal(recorder.Body.Bytes(), &got)
+					require.NoError(t, err)
+					require.NotEmpty(t, got.AccessToken)
+					require.Equal(t, user.Username, got.User.Username)
+				},
+			},
+		},
+		{
+			request: changePasswordRequest{CurrentPassword: "wrong-password", NewPassword: newPassword},
+			baseTestCase: baseTestCase{
+				name: "WrongCurrentPassword",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(user.Username)).
+						Times(1).
+						Return(user, nil)
+
+					store.EXPECT().
+						ChangePasswordTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			request: changePasswordRequest{CurrentPassword: password, NewPassword: "short"},
+			baseTestCase: baseTestCase{
+				name: "NewPasswordTooShort",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						ChangePasswordTx(gomock.Any(), gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, "/users/password")
+			tc.buildStubs(test.store)
+
+			body, err := toReader(tc.request)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPut, test.url, body)
+			require.NoError(t, err)
+
+			// when
+			addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestForgotPassword(t *testing.T) {
+	user, _ := randomUser(t)
+
+	t.Run("OK", func(t *testing.T) {
+		test := newTest(t, "/users/password/forgot")
+
+		var tokenHash string
+		test.store.EXPECT().
+			GetUserByEmail(gomock.Any(), gomock.Eq(user.Email)).
+			Times(1).
+			Return(user, nil)
+
+		test.store.EXPECT().
+			CreatePasswordReset(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.True(t, arg.ExpiresAt.After(time.Now()))
+				tokenHash = arg.TokenHash
+				return db.PasswordReset{}, nil
+			})
+
+		body, err := toReader(forgotPasswordRequest{Email: user.Email})
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, test.url, body)
+		require.NoError(t, err)
+
+		test.server.router.ServeHTTP(test.recorder, request)
+		require.Equal(t, http.StatusAccepted, test.recorder.Code)
+
+		messages := test.server.mailer.(*mailer.MemorySender).Messages()
+		require.Len(t, messages, 1)
+		require.Equal(t, user.Email, messages[0].To)
+
+		var found bool
+		for _, word := range strings.Fields(messages[0].Body) {
+			if hashToken(word) == tokenHash {
+				found = true
+			}
+		}
+		require.True(t, found, "reset email does not contain the token")
+	})
+
+	t.Run("UnknownEmail", func(t *testing.T) {
+		test := newTest(t, "/users/password/forgot")
+
+		test.store.EXPECT().
+			GetUserByEmail(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.User{}, sql.ErrNoRows)
+
+		test.store.EXPECT().
+			CreatePasswordReset(gomock.Any(), gomock.Any()).
+			Times(0)
+
+		body, err := toReader(forgotPasswordRequest{Email: util.RandomEmail()})
+		require.NoError(t, err)
+
+		request, err := http.NewRequest(http.MethodPost, test.url, body)
+		require.NoError(t, err)
+
+		test.server.router.ServeHTTP(test.recorder, request)
+		require.Equal(t, http.StatusAccepted, test.recorder.Code)
+		require.Empty(t, test.server.mailer.(*mailer.MemorySender).Messages())
+	})
+}
+
+func TestResetPassword(t *testing.T) {
+	user, _ := randomUser(t)
+	token, tokenHash, err := newOneTimeToken()
+	require.NoError(t, err)
+
+	newPassword := util.RandomString(12)
+
+	testCases := []struct {
+		baseTestCase //
+		err          error
+		status       int
+	}{
+		{baseTestCase: baseTestCase{name: "OK"}, status: http.StatusOK},
+		{baseTestCase: baseTestCase{name: "NotFound"}, err: sql.ErrNoRows, status: http.StatusNotFound},
+		{baseTestCase: baseTestCase{name: "AlreadyUsed"}, err: db.ErrResetTokenUsed, status: http.StatusConflict},
+		{baseTestCase: baseTestCase{name: "Expired"}, err: db.ErrResetTokenExpired, status: http.StatusGone},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, "/users/password/reset")
+			test.store.EXPECT().
+				ResetPasswordTx(gomock.Any(), gomock.Eq(tokenHash), gomock.Any(), gomock.Any()).
+				Times(1).
+				DoAndReturn(func(_ context.Context, _ string, hashed string, _ time.Time) (db.User, error) {
+					require.NoError(t, util.ComparePasswords(newPassword, hashed))
+					if tc.err != nil {
+						return db.User{}, tc.err
+					}
+					return user, nil
+				})
+
+			body, err := toReader(resetPasswordRequest{Token: token, NewPassword: newPassword})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, test.url, body)
+			require.NoError(t, err)
+
+			// when
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			require.Equal(t, tc.status, test.recorder.Code)
+		})
+	}
+}
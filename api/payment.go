@@ -0,0 +1,162 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/fee"
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type paymentURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type paymentRequest struct {
+	Amount            int64  `json:"amount" binding:"required,gt=0"`
+	Currency          string `json:"currency" binding:"required,currency"`
+	ExternalReference string `json:"external_reference" binding:"required,max=128"`
+}
+
+func bindPayment(ctx *gin.Context) (paymentURI, paymentRequest, bool) {
+	var uri paymentURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return uri, paymentRequest{}, false
+	}
+
+	var req paymentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return uri, req, false
+	}
+
+	return uri, req, true
+}
+
+// createDeposit credits an account with money received on the payment rail.
+// It is called by the rail integration, so it is restricted to admins.
+func (s *Server) createDeposit(ctx *gin.Context) {
+	uri, req, ok := bindPayment(ctx)
+	if !ok {
+		return
+	}
+
+	account, valid := s.validAccount(ctx, uri.ID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if !allowAccount(ctx, account.ID) {
+		return
+	}
+
+	s.postPayment(ctx, db.PaymentTxParams{
+		AccountID:         account.ID,
+		Direction:         db.PaymentDirectionDeposit,
+		Amount:            req.Amount,
+		ExternalReference: req.ExternalReference,
+	})
+}
+
+// createWithdrawal sends money from the user's account out to the payment
+// rail. It is money leaving the bank, so it gets the same checks as a
+// transfer: token restrictions, step-up, fraud screening and, in PaymentTx,
+// the transfer limits.
+func (s *Server) createWithdrawal(ctx *gin.Context) {
+	uri, req, ok := bindPayment(ctx)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !authPayload.CanTransfer(req.Amount) {
+		ctx.JSON(http.StatusForbidden, insufficientScopeResponse(errTransferNotAllowed))
+		return
+	}
+
+	if !s.requireStepUp(ctx, req.Amount) {
+		return
+	}
+
+	account, valid := s.validAccount(ctx, uri.ID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if account.Owner != authPayload.Username {
+		err := errors.New("account doesnt belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if !allowAccount(ctx, account.ID) {
+		return
+	}
+
+	settlement, err := s.store.GetAccountByOwner(ctx, db.GetAccountByOwnerParams{
+		Owner:    db.SettlementOwner,
+		Currency: account.Currency,
+	})
+	if err != nil {
+		err = fmt.Errorf("cannot get %s settlement account: %w", account.Currency, err)
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	plan := transferPlan{
+		fromAccount: account,
+		toAccount:   settlement,
+		quote:       fee.Quote{GrossAmount: req.Amount, NetAmount: req.Amount, Rules: []string{}},
+	}
+
+	hold := db.HoldTransferTxParams{
+		Transfer: db.TransferTxParams{
+			FromAccountID: account.ID,
+			ToAccountID:   settlement.ID,
+			Amount:        req.Amount,
+		},
+		ExternalReference: req.ExternalReference,
+	}
+
+	if !s.screen(ctx, plan, hold) {
+		return
+	}
+
+	s.postPayment(ctx, db.PaymentTxParams{
+		AccountID:         account.ID,
+		Direction:         db.PaymentDirectionWithdrawal,
+		Amount:            req.Amount,
+		ExternalReference: req.ExternalReference,
+	})
+}
+
+func (s *Server) postPayment(ctx *gin.Context, arg db.PaymentTxParams) {
+	result, err := s.store.PaymentTx(ctx, arg)
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+
+		var limitErr *db.LimitExceededError
+		if errors.As(err, &limitErr) {
+			ctx.JSON(http.StatusUnprocessableEntity, limitExceededResponse(limitErr))
+			return
+		}
+
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, result)
+}
@@ -0,0 +1,391 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/fraud"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreatePayment(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+	customer, _ := randomUser(t)
+	customer.Role = db.UserRoleCustomer
+	other, _ := randomUser(t)
+
+	acc := randomAccount(customer.Username)
+	amount := int64(100)
+	reference := util.RandomString(16)
+
+	settlementParams := db.GetAccountByOwnerParams{Owner: db.SettlementOwner, Currency: acc.Currency}
+	settlement := db.Account{ID: acc.ID + 1, Owner: db.SettlementOwner, Currency: acc.Currency, Status: db.AccountStatusActive}
+
+	testCases := []struct {
+		baseTestCase //
+		kind         string
+		request      paymentRequest
+		rules        []fraud.Rule
+		setupAuth    func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	}{
+		{
+			kind:    "deposits",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "DepositOK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(admin.Username)).Times(1).Return(admin, nil)
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+
+					expectedArg := db.PaymentTxParams{
+						AccountID:         acc.ID,
+						Direction:         db.PaymentDirectionDeposit,
+						Amount:            amount,
+						ExternalReference: reference,
+					}
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Eq(expectedArg)).Times(1)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "deposits",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "DepositNotAdmin",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(customer, nil)
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "WithdrawalOK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().
+						GetAccountByOwner(gomock.Any(), gomock.Eq(settlementParams)).
+						Times(1).
+						Return(settlement, nil)
+
+					expectedArg := db.PaymentTxParams{
+						AccountID:         acc.ID,
+						Direction:         db.PaymentDirectionWithdrawal,
+						Amount:            amount,
+						ExternalReference: reference,
+					}
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Eq(expectedArg)).Times(1)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, db.InterestOwner, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "WithdrawalFromSystemAccount",
+				buildStubs: func(store *mockdb.MockStore) {
+					system := acc
+					system.Owner = db.InterestOwner
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(system, nil)
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, other.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "WithdrawalUnauthorized",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "InsufficientFunds",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().
+						GetAccountByOwner(gomock.Any(), gomock.Eq(settlementParams)).
+						Times(1).
+						Return(settlement, nil)
+					store.EXPECT().
+						PaymentTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.PaymentTxResult{}, db.ErrInsufficientFunds)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "DuplicateReference",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().
+						GetAccountByOwner(gomock.Any(), gomock.Eq(settlementParams)).
+						Times(1).
+						Return(settlement, nil)
+					store.EXPECT().
+						PaymentTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.PaymentTxResult{}, &pq.Error{Code: "23505"})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "MissingReference",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "EmailNotVerified",
+				buildStubs: func(store *mockdb.MockStore) {
+					unverified := customer
+					unverified.IsEmailVerified = false
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(customer.Username)).Times(1).Return(unverified, nil)
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+					require.Contains(t, recorder.Body.String(), emailNotVerifiedCode)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			rules:   []fraud.Rule{fraud.NewPayeeLargeAmount{ReviewAmount: 1, DenyAmount: amount + 1}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "WithdrawalHeldForReview",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().
+						GetAccountByOwner(gomock.Any(), gomock.Eq(settlementParams)).
+						Times(1).
+						Return(settlement, nil)
+					store.EXPECT().CountTransfersBetween(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+
+					expectedArg := db.HoldTransferTxParams{
+						Transfer: db.TransferTxParams{
+							FromAccountID: acc.ID,
+							ToAccountID:   settlement.ID,
+							Amount:        amount,
+						},
+						Rules:             []string{"new_payee_large_amount"},
+						ExternalReference: reference,
+					}
+					store.EXPECT().
+						HoldTransferTx(gomock.Any(), gomock.Eq(expectedArg)).
+						Times(1).
+						Return(db.HeldTransfer{ID: 1, Status: db.HeldTransferStatusPending}, nil)
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusAccepted, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			rules:   []fraud.Rule{fraud.NewPayeeLargeAmount{ReviewAmount: 1, DenyAmount: amount + 1}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "HeldWithdrawalDuplicateReference",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().
+						GetAccountByOwner(gomock.Any(), gomock.Eq(settlementParams)).
+						Times(1).
+						Return(settlement, nil)
+					store.EXPECT().CountTransfersBetween(gomock.Any(), gomock.Any()).Times(1).Return(int64(0), nil)
+					store.EXPECT().
+						HoldTransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.HeldTransfer{}, db.ErrDuplicateReference)
+					store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			kind:    "withdrawals",
+			request: paymentRequest{Amount: amount, Currency: acc.Currency, ExternalReference: reference},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "WithdrawalLimitExceeded",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(acc.ID)).Times(1).Return(acc, nil)
+					store.EXPECT().
+						GetAccountByOwner(gomock.Any(), gomock.Eq(settlementParams)).
+						Times(1).
+						Return(settlement, nil)
+					store.EXPECT().
+						PaymentTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.PaymentTxResult{}, &db.LimitExceededError{Limit: db.LimitDailyTotal, Max: 50, Remaining: 10})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+					require.Contains(t, recorder.Body.String(), limitExceededCode)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, fmt.Sprintf("/accounts/%d/%s", acc.ID, tc.kind))
+			tc.buildStubs(test.store)
+			test.store.EXPECT().
+				CreateFraudDecision(gomock.Any(), gomock.Any()).
+				AnyTimes()
+			test.store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+					return db.User{Username: username, IsEmailVerified: true}, nil
+				})
+			if tc.rules != nil {
+				test.server.screener = fraud.NewScreener(test.store, tc.rules...)
+			}
+
+			body, err := toReader(tc.request)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, test.url, body)
+			require.NoError(t, err)
+
+			// when
+			tc.setupAuth(t, request, test.server.tokenMaker)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestCreateWithdrawalRequiresStepUp(t *testing.T) {
+	user, _ := randomUser(t)
+	test := newTest(t, "/accounts/1/withdrawals")
+	test.server.config.MFAStepUpAmount = 500
+	test.server.config.MFAStepUpWindow = 5 * time.Minute
+
+	test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).AnyTimes().Return(user, nil)
+	test.store.EXPECT().GetUserMFA(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(randomMFA(t, user.Username), nil)
+	test.store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+	test.store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+
+	accessToken := testAccessToken(t, test.server.tokenMaker, user.Username, time.Minute)
+	postJSON(t, test, paymentRequest{Amount: 500, Currency: "USD", ExternalReference: "ref-1"}, accessToken)
+	require.Equal(t, http.StatusForbidden, test.recorder.Code)
+	require.Contains(t, test.recorder.Body.String(), mfaStepUpCode)
+}
+
+func TestCreateWithdrawalRequiresTransferScope(t *testing.T) {
+	user, _ := randomUser(t)
+	test := newTest(t, "/accounts/1/withdrawals")
+	test.store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+	test.store.EXPECT().PaymentTx(gomock.Any(), gomock.Any()).Times(0)
+
+	payload, err := token.NewPayload(user.Username, time.Minute)
+	require.NoError(t, err)
+	payload.Scopes = []string{scopeAccountsRead, scopeAccountsWrite}
+	accessToken, err := test.server.tokenMaker.CreateTokenFromPayload(payload)
+	require.NoError(t, err)
+
+	postJSON(t, test, paymentRequest{Amount: 100, Currency: "USD", ExternalReference: "ref-1"}, accessToken)
+	require.Equal(t, http.StatusForbidden, test.recorder.Code)
+	require.Contains(t, test.recorder.Body.String(), "insufficient_scope")
+}
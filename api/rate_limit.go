@@ -0,0 +1,126 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/ratelimit"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/util"
+	"github.com/gin-gonic/gin"
+)
+
+var errRateLimited = errors.New("too many requests, slow down")
+
+// newRateLimiter picks where buckets are kept: "memory" (the default) or
+// "postgres" to share them between instances.
+func newRateLimiter(kind string, store db.Store) (ratelimit.Store, error) {
+	switch kind {
+	case "", "memory":
+		return ratelimit.NewMemoryStore(), nil
+	case "postgres":
+		return ratelimit.NewPostgresStore(store), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", kind)
+	}
+}
+
+// rateLimitPolicies are the policies of each route group. Public routes are
+// limited by client IP and authenticated ones by user.
+type rateLimitPolicies struct {
+	public        ratelimit.Policy
+	login         ratelimit.Policy
+	authenticated ratelimit.Policy
+	transfers     ratelimit.Policy
+}
+
+func parseRateLimitPolicies(config *util.Config) (rateLimitPolicies, error) {
+	var policies rateLimitPolicies
+
+	for _, p := range []struct {
+		policy *ratelimit.Policy
+		value  string
+	}{
+		{&policies.public, config.RateLimitPublic},
+		{&policies.login, config.RateLimitLogin},
+		{&policies.authenticated, config.RateLimitAuthenticated},
+		{&policies.transfers, config.RateLimitTransfers},
+	} {
+		policy, err := ratelimit.ParsePolicy(p.value)
+		if err != nil {
+			return policies, err
+		}
+		*p.policy = policy
+	}
+
+	return policies, nil
+}
+
+func rateLimitByIP(ctx *gin.Context) string {
+	return "ip:" + ctx.ClientIP()
+}
+
+// rateLimitByUser must run after authMiddleware.
+func rateLimitByUser(ctx *gin.Context) string {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	return "user:" + authPayload.Username
+}
+
+// rateLimitMiddleware lets each key make policy's worth of requests to the
+// routes it guards. Buckets are named after the policy, so routes sharing a
+// name share buckets. If the limiter fails the request is let through: an
+// outage of the limiter shouldn't take the API down with it.
+func rateLimitMiddleware(limiter ratelimit.Store, name string, policy ratelimit.Policy, key func(*gin.Context) string) gin.HandlerFunc {
+	if !policy.Enabled() {
+		return func(ctx *gin.Context) {
+			ctx.Next()
+		}
+	}
+
+	return func(ctx *gin.Context) {
+		result, err := limiter.Take(ctx, name+":"+key(ctx), policy)
+		if err != nil {
+			log.Printf("rate limiter failed, allowing request: %v", err)
+			ctx.Next()
+			return
+		}
+
+		setRateLimitHeaders(ctx, policy, result)
+
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			ctx.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": errRateLimited.Error(),
+				"code":  "rate_limited",
+			})
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// setRateLimitHeaders writes the RateLimit-* headers. When several policies
+// guard a route the one with the fewest requests left is reported.
+func setRateLimitHeaders(ctx *gin.Context, policy ratelimit.Policy, result ratelimit.Result) {
+	if current := ctx.Writer.Header().Get("RateLimit-Remaining"); current != "" {
+		if remaining, err := strconv.Atoi(current); err == nil && remaining <= result.Remaining {
+			return
+		}
+	}
+
+	ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	ctx.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Period)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/stream"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newRateLimitedServer(t *testing.T) (*Server, *mockdb.MockStore) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(time.Time{}, nil)
+
+	server := newTestServerWithConfig(t, store, func(config *util.Config) {
+		config.RateLimitPublic = "3/1m"
+		config.RateLimitLogin = "2/1m"
+		config.RateLimitAuthenticated = "2/1m"
+	})
+
+	return server, store
+}
+
+func serveFrom(t *testing.T, server *Server, request *http.Request, ip string) *httptest.ResponseRecorder {
+	request.RemoteAddr = ip + ":41234"
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestRateLimitByIP(t *testing.T) {
+	server, _ := newRateLimitedServer(t)
+
+	// an empty body is rejected before the store is reached, but still counts
+	login := func(ip string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader("{}"))
+		require.NoError(t, err)
+		return serveFrom(t, server, request, ip)
+	}
+
+	recorder := login("192.0.2.1")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+	require.Equal(t, "2;w=60", recorder.Header().Get("RateLimit-Policy"))
+
+	recorder = login("192.0.2.1")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = login("192.0.2.1")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+	require.Contains(t, recorder.Body.String(), `"code":"rate_limited"`)
+
+	// other clients are not affected
+	recorder = login("192.0.2.2")
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	// the login policy is stricter than the public one, but both apply
+	request, err := http.NewRequest(http.MethodGet, "/users/verify_email", nil)
+	require.NoError(t, err)
+	recorder = serveFrom(t, server, request, "192.0.2.1")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "3", recorder.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	server, store := newRateLimitedServer(t)
+	alice, _ := randomUser(t)
+	bob, _ := randomUser(t)
+
+	store.EXPECT().
+		ListWebhookSubscriptions(gomock.Any(), gomock.Any()).
+		Times(3).
+		Return([]db.WebhookSubscription{}, nil)
+
+	listWebhooks := func(username string, ip string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodGet, "/webhooks", nil)
+		require.NoError(t, err)
+		addAuth(t, request, server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+		return serveFrom(t, server, request, ip)
+	}
+
+	// the same user is limited across IPs
+	for i, ip := range []string{"192.0.2.1", "192.0.2.2"} {
+		recorder := listWebhooks(alice.Username, ip)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Equal(t, fmt.Sprint(1-i), recorder.Header().Get("RateLimit-Remaining"))
+	}
+
+	recorder := listWebhooks(alice.Username, "192.0.2.3")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+
+	recorder = listWebhooks(bob.Username, "192.0.2.1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRateLimitResendVerificationEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetUserPasswordChangedAt(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(time.Time{}, nil)
+
+	server := newTestServerWithConfig(t, store, func(config *util.Config) {
+		config.RateLimitLogin = "1/1m"
+	})
+
+	user, _ := randomUser(t)
+	user.IsEmailVerified = false
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+	store.EXPECT().CreateEmailVerification(gomock.Any(), gomock.Any()).Times(1)
+
+	resend := func() *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodPost, "/users/verify_email/resend", nil)
+		require.NoError(t, err)
+		addAuth(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+		return serveFrom(t, server, request, "192.0.2.1")
+	}
+
+	recorder := resend()
+	require.Equal(t, http.StatusAccepted, recorder.Code)
+
+	recorder = resend()
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	test := newTest(t, "/users/login")
+
+	for i := 0; i < 5; i++ {
+		request, err := http.NewRequest(http.MethodPost, test.url, strings.NewReader("{}"))
+		require.NoError(t, err)
+
+		recorder := serveFrom(t, test.server, request, "192.0.2.1")
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		require.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestParseRateLimitPolicies(t *testing.T) {
+	_, err := parseRateLimitPolicies(&util.Config{RateLimitTransfers: "often"})
+	require.Error(t, err)
+
+	_, err = newRateLimiter("redis", nil)
+	require.Error(t, err)
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	server, _ := newRateLimitedServer(t)
+
+	login := func(forwardedFor string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader("{}"))
+		require.NoError(t, err)
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		return serveFrom(t, server, request, "192.0.2.1")
+	}
+
+	require.Equal(t, http.StatusBadRequest, login("198.51.100.1").Code)
+	require.Equal(t, http.StatusBadRequest, login("198.51.100.2").Code)
+
+	// without trusted proxies the header is ignored and the client is limited
+	recorder := login("198.51.100.3")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
+
+func TestRateLimitTrustedProxy(t *testing.T) {
+	server := newTestServerWithConfig(t, mockdb.NewMockStore(gomock.NewController(t)), func(config *util.Config) {
+		config.RateLimitLogin = "1/1m"
+		config.TrustedProxies = []string{"192.0.2.1"}
+	})
+
+	login := func(forwardedFor string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest(http.MethodPost, "/users/login", strings.NewReader("{}"))
+		require.NoError(t, err)
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		return serveFrom(t, server, request, "192.0.2.1")
+	}
+
+	// behind the proxy, each client gets its own bucket
+	require.Equal(t, http.StatusBadRequest, login("198.51.100.1").Code)
+	require.Equal(t, http.StatusBadRequest, login("198.51.100.2").Code)
+	require.Equal(t, http.StatusTooManyRequests, login("198.51.100.2").Code)
+}
+
+func TestNewServerInvalidTrustedProxy(t *testing.T) {
+	config := util.Config{
+		TokenSymmetricKey: util.RandomString(32),
+		TrustedProxies:    []string{"not-an-ip"},
+	}
+
+	_, err := NewServer(&config, mockdb.NewMockStore(gomock.NewController(t)), stream.NewBroker())
+	require.ErrorContains(t, err, "trusted proxies")
+}
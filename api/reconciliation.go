@@ -0,0 +1,84 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+type listReconciliationRunsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (s *Server) listReconciliationRuns(ctx *gin.Context) {
+	var req listReconciliationRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	runs, err := s.store.ListReconciliationRuns(ctx, db.ListReconciliationRunsParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, runs)
+}
+
+type getReconciliationRunRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (s *Server) getReconciliationRun(ctx *gin.Context) {
+	var req getReconciliationRunRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	run, err := s.store.GetReconciliationRun(ctx, req.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, run)
+}
+
+func (s *Server) listReconciliationDiscrepancies(ctx *gin.Context) {
+	var uri getReconciliationRunRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listReconciliationRunsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	discrepancies, err := s.store.ListReconciliationDiscrepancies(ctx, db.ListReconciliationDiscrepanciesParams{
+		RunID:  uri.ID,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, discrepancies)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestGetReconciliationRun(t *testing.T) {
+	admin, _ := randomUser(t)
+	admin.Role = db.UserRoleAdmin
+	customer, _ := randomUser(t)
+	customer.Role = db.UserRoleCustomer
+
+	run := db.ReconciliationRun{
+		ID:              util.RandomInt(1, 1000),
+		Status:          db.ReconciliationStatusCompleted,
+		AccountsChecked: util.RandomInt(1, 1000),
+		Discrepancies:   1,
+	}
+
+	testCases := []struct {
+		baseTestCase //
+		runID        int64
+		setupAuth    func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+	}{
+		{
+			runID: run.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					store.EXPECT().
+						GetReconciliationRun(gomock.Any(), gomock.Eq(run.ID)).
+						Times(1).
+						Return(run, nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+					requireBodyMatchReconciliationRun(t, recorder.Body, run)
+				},
+			},
+		},
+		{
+			runID: run.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, customer.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "NotAdmin",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(customer.Username)).
+						Times(1).
+						Return(customer, nil)
+
+					store.EXPECT().
+						GetReconciliationRun(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+		{
+			runID: run.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			baseTestCase: baseTestCase{
+				name: "NoAuthorization",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			runID: run.ID,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, admin.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "NotFound",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(admin.Username)).
+						Times(1).
+						Return(admin, nil)
+
+					store.EXPECT().
+						GetReconciliationRun(gomock.Any(), gomock.Eq(run.ID)).
+						Times(1).
+						Return(db.ReconciliationRun{}, sql.ErrNoRows)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNotFound, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, fmt.Sprintf("/admin/reconciliation/runs/%d", tc.runID))
+			tc.buildStubs(test.store)
+
+			request, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+
+			// when
+			tc.setupAuth(t, request, test.server.tokenMaker)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func requireBodyMatchReconciliationRun(t *testing.T, body *bytes.Buffer, run db.ReconciliationRun) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotRun db.ReconciliationRun
+	err = json.Unmarshal(data, &gotRun)
+	require.NoError(t, err)
+	require.Equal(t, run, gotRun)
+}
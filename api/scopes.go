@@ -0,0 +1,40 @@
+package api
+
+import (
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+)
+
+// Scopes limit what an access token can be used for. Users logging in get
+// every scope of their role.
+const (
+	scopeAccountsRead   = "accounts:read"
+	scopeAccountsWrite  = "accounts:write"
+	scopeTransfersWrite = "transfers:write"
+	scopeWebhooks       = "webhooks"
+	scopeProfile        = "profile"
+	scopeAdmin          = "admin"
+)
+
+var customerScopes = []string{
+	scopeAccountsRead,
+	scopeAccountsWrite,
+	scopeTransfersWrite,
+	scopeWebhooks,
+	scopeProfile,
+}
+
+func scopesForRole(role db.UserRole) []string {
+	scopes := append([]string{}, customerScopes...)
+	if role == db.UserRoleAdmin {
+		scopes = append(scopes, scopeAdmin)
+	}
+
+	return scopes
+}
+
+// scopesInclude reports whether granted includes every one of wanted.
+func scopesInclude(granted []string, wanted []string) bool {
+	payload := token.Payload{Scopes: granted}
+	return payload.HasScopes(wanted...)
+}
@@ -1,9 +1,16 @@
 package api
 
 import (
+	"expvar"
 	"fmt"
+	"sync"
 
 	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/fee"
+	"github.com/aulas/demo-bank/fraud"
+	"github.com/aulas/demo-bank/mailer"
+	"github.com/aulas/demo-bank/ratelimit"
+	"github.com/aulas/demo-bank/stream"
 	"github.com/aulas/demo-bank/token"
 	"github.com/aulas/demo-bank/util"
 	"github.com/gin-gonic/gin"
@@ -16,40 +23,212 @@ type Server struct {
 	router     *gin.Engine
 	tokenMaker token.Maker
 	config     *util.Config
+	fees       *fee.Schedule
+	screener   *fraud.Screener
+	changes    *stream.Broker
+	mailer     mailer.Sender
+	limiter    ratelimit.Store
+	passwords  *util.PasswordHasher
+	policy     *util.PasswordPolicy
+
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
 }
 
-func NewServer(config *util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+func NewServer(config *util.Config, store db.Store, changes *stream.Broker) (*Server, error) {
+	tokenMaker, err := token.NewMaker(token.Config{
+		Kind:             config.TokenMaker,
+		SymmetricKey:     config.TokenSymmetricKey,
+		Issuer:           config.TokenIssuer,
+		Audience:         config.TokenAudience,
+		SigningKeyID:     config.TokenSigningKeyID,
+		SigningKey:       config.TokenSigningKey,
+		VerificationKeys: config.TokenVerificationKeys,
+		RetiredKeyIDs:    config.TokenRetiredKeyIDs,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
 
+	fees, err := fee.LoadSchedule(config.FeeScheduleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := mailer.NewSender(mailer.Config{
+		Kind:     config.Mailer,
+		Target:   config.MailerTarget,
+		From:     config.MailFrom,
+		Host:     config.SMTPHost,
+		Port:     config.SMTPPort,
+		Username: config.SMTPUsername,
+		Password: config.SMTPPassword,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mailer: %w", err)
+	}
+
+	passwords, err := util.NewPasswordHasher(util.PasswordParams{
+		Algorithm:   config.PasswordHashAlgorithm,
+		Memory:      config.PasswordArgon2Memory,
+		Iterations:  config.PasswordArgon2Iterations,
+		Parallelism: config.PasswordArgon2Parallelism,
+		BcryptCost:  config.PasswordBcryptCost,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+
+	policy, err := util.NewPasswordPolicy(config.PasswordMinLength, config.PasswordMaxLength, config.PasswordHashAlgorithm, config.PasswordBreachedListFile)
+	if err != nil {
+		return nil, err
+	}
+
+	limits, err := parseRateLimitPolicies(config)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter, err := newRateLimiter(config.RateLimitStore, store)
+	if err != nil {
+		return nil, err
+	}
+
 	server := &Server{
 		store:      store,
 		tokenMaker: tokenMaker,
 		config:     config,
+		fees:       fees,
+		screener:   fraud.NewScreener(store, fraud.DefaultRules()...),
+		changes:    changes,
+		mailer:     sender,
+		limiter:    limiter,
+		passwords:  passwords,
+		policy:     policy,
 	}
 
 	router := gin.Default()
-	authRouter := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	err = router.SetTrustedProxies(config.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("cannot set trusted proxies: %w", err)
+	}
+
+	userLimit := rateLimitMiddleware(limiter, "authenticated", limits.authenticated, rateLimitByUser)
+	loginLimit := rateLimitMiddleware(limiter, "login", limits.login, rateLimitByIP)
+	transferLimit := rateLimitMiddleware(limiter, "transfers", limits.transfers, rateLimitByUser)
+	// sending email is limited as strictly as logging in, per user
+	mailLimit := rateLimitMiddleware(limiter, "mail", limits.login, rateLimitByUser)
+	publicRouter := router.Group("/").Use(rateLimitMiddleware(limiter, "public", limits.public, rateLimitByIP))
+	authRouter := router.Group("/").Use(authMiddleware(server.tokenMaker, server.store), userLimit)
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("https_url", validHTTPSURL)
 	}
 
 	const accountsPath = "/accounts"
-	authRouter.GET(path(accountsPath, "/:id"), server.getAccount)
-	authRouter.GET(accountsPath, server.listAccount)
-	authRouter.POST(accountsPath, server.createAccount)
-	authRouter.PUT(accountsPath, server.updateAccount)
-	authRouter.DELETE(path(accountsPath, "/:id"), server.deleteAccount)
+	readAccounts := requireScopes(scopeAccountsRead)
+	writeAccounts := requireScopes(scopeAccountsWrite)
+	adminScope := requireScopes(scopeAdmin)
+	authRouter.GET(path(accountsPath, "/:id"), readAccounts, server.getAccount)
+	authRouter.GET(accountsPath, readAccounts, server.listAccount)
+	authRouter.GET(path(accountsPath, "/stream"), readAccounts, server.streamAccounts)
+	authRouter.POST(accountsPath, writeAccounts, server.createAccount)
+	authRouter.POST(path(accountsPath, "/:id/adjustments"), adminScope, adminMiddleware(server.store), server.createAdjustment)
+	authRouter.POST(path(accountsPath, "/:id/deposits"), adminScope, adminMiddleware(server.store), server.createDeposit)
+	authRouter.POST(
+		path(accountsPath, "/:id/withdrawals"),
+		requireScopes(scopeTransfersWrite),
+		transferLimit,
+		verifiedEmailMiddleware(server.store),
+		server.createWithdrawal,
+	)
+	authRouter.GET(path(accountsPath, "/:id/limits"), readAccounts, server.getAccountLimits)
+	authRouter.DELETE(path(accountsPath, "/:id"), writeAccounts, server.deleteAccount)
+	authRouter.POST(path(accountsPath, "/:id/close"), writeAccounts, server.closeAccount)
 
 	const transfersPath = "/transfers"
-	authRouter.POST(transfersPath, server.createTransfer)
+	authRouter.POST(
+		transfersPath,
+		requireScopes(scopeTransfersWrite),
+		transferLimit,
+		verifiedEmailMiddleware(server.store),
+		server.createTransfer,
+	)
+	authRouter.POST(path(transfersPath, "/quote"), requireScopes(scopeTransfersWrite), server.quoteTransfer)
+
+	const webhooksPath = "/webhooks"
+	webhooks := requireScopes(scopeWebhooks)
+	authRouter.POST(webhooksPath, webhooks, server.createWebhook)
+	authRouter.GET(webhooksPath, webhooks, server.listWebhooks)
+	authRouter.DELETE(path(webhooksPath, "/:id"), webhooks, server.deleteWebhook)
+	authRouter.GET(path(webhooksPath, "/:id/deliveries"), webhooks, server.listWebhookDeliveries)
+
+	const apiKeysPath = "/api-keys"
+	profile := requireScopes(scopeProfile)
+	authRouter.POST(apiKeysPath, profile, server.createAPIKey)
+	authRouter.GET(apiKeysPath, profile, server.listAPIKeys)
+	authRouter.DELETE(path(apiKeysPath, "/:id"), profile, server.revokeAPIKey)
 
 	const usersPath = "/users"
-	router.POST(usersPath, server.createUser)
-	router.POST(path(usersPath, "/login"), server.loginUser)
+	publicRouter.POST(usersPath, server.createUser)
+	publicRouter.POST(path(usersPath, "/login"), loginLimit, server.loginUser)
+	publicRouter.GET(path(usersPath, "/verify_email"), server.verifyEmail)
+	authRouter.POST(path(usersPath, "/verify_email/resend"), profile, mailLimit, server.resendVerificationEmail)
+	publicRouter.POST(path(usersPath, "/password/forgot"), loginLimit, server.forgotPassword)
+	publicRouter.POST(path(usersPath, "/password/reset"), loginLimit, server.resetPassword)
+	authRouter.GET(path(usersPath, "/me"), profile, server.getCurrentUser)
+	authRouter.PATCH(path(usersPath, "/me"), profile, server.updateCurrentUser)
+	authRouter.DELETE(path(usersPath, "/me"), profile, server.deleteCurrentUser)
+	authRouter.POST(path(usersPath, "/me/export"), profile, server.requestDataExport)
+	authRouter.GET(path(usersPath, "/me/exports"), profile, server.listDataExports)
+	authRouter.GET(path(usersPath, "/me/exports/:id"), profile, server.getDataExport)
+	authRouter.GET(path(usersPath, "/me/exports/:id/download"), profile, server.downloadDataExport)
+	authRouter.PUT(path(usersPath, "/password"), profile, server.changePassword)
+	publicRouter.POST(path(usersPath, "/login/mfa"), loginLimit, server.verifyLoginMFA)
+	authRouter.POST(path(usersPath, "/mfa/totp"), profile, server.enrollTOTP)
+	authRouter.POST(path(usersPath, "/mfa/totp/confirm"), profile, server.confirmTOTP)
+	authRouter.DELETE(path(usersPath, "/mfa/totp"), profile, server.disableTOTP)
+	authRouter.POST(path(usersPath, "/mfa/step_up"), profile, server.stepUpMFA)
+
+	adminRouter := router.Group("/admin").Use(
+		authMiddleware(server.tokenMaker, server.store),
+		userLimit,
+		adminScope,
+		adminMiddleware(server.store),
+	)
+
+	const reconciliationPath = "/reconciliation/runs"
+	adminRouter.GET(reconciliationPath, server.listReconciliationRuns)
+	adminRouter.GET(path(reconciliationPath, "/:id"), server.getReconciliationRun)
+	adminRouter.GET(path(reconciliationPath, "/:id/discrepancies"), server.listReconciliationDiscrepancies)
+
+	const heldTransfersPath = "/held_transfers"
+	adminRouter.GET(heldTransfersPath, server.listHeldTransfers)
+	adminRouter.POST(path(heldTransfersPath, "/:id/approve"), server.approveHeldTransfer)
+	adminRouter.POST(path(heldTransfersPath, "/:id/reject"), server.rejectHeldTransfer)
+
+	const adminUsersPath = "/users"
+	adminRouter.GET(path(adminUsersPath, "/:username/lockouts"), server.listUserLockouts)
+	adminRouter.POST(path(adminUsersPath, "/:username/unlock"), server.unlockUser)
+	adminRouter.POST(path(adminUsersPath, "/:username/erase"), server.eraseUser)
+
+	const oauthPath = "/oauth"
+	authRouter.GET(path(oauthPath, "/authorize"), profile, server.getAuthorization)
+	authRouter.POST(path(oauthPath, "/authorize"), profile, server.decideAuthorization)
+	publicRouter.POST(path(oauthPath, "/token"), loginLimit, server.createOAuthToken)
+	publicRouter.POST(path(oauthPath, "/introspect"), server.introspectOAuthToken)
+	publicRouter.POST(path(oauthPath, "/revoke"), server.revokeOAuthToken)
+
+	const oauthClientsPath = "/oauth/clients"
+	adminRouter.GET(oauthClientsPath, server.listOAuthClients)
+	adminRouter.POST(oauthClientsPath, server.createOAuthClient)
+
+	const wellKnownPath = "/.well-known"
+	publicRouter.GET(path(wellKnownPath, "/jwks.json"), server.getJWKS)
+	publicRouter.GET(path(wellKnownPath, "/paserk.json"), server.getPASERKSet)
+
+	adminRouter.GET("/metrics", gin.WrapH(expvar.Handler()))
 
 	server.router = router
 	return server, nil
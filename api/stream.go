@@ -0,0 +1,66 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aulas/demo-bank/token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	reauthenticateEvent = "reauthenticate"
+	heartbeatInterval   = 15 * time.Second
+)
+
+// streamAccounts sends the caller's balance changes and new entries as
+// Server-Sent Events. The stream ends with a reauthenticate event when the
+// access token expires, and without one when the client has fallen behind;
+// either way the client should reload its accounts and reconnect. API keys
+// without an expiry never get the reauthenticate event.
+func (s *Server) streamAccounts(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	changes, cancel := s.changes.Subscribe(authPayload.Username)
+	defer cancel()
+
+	var expired <-chan time.Time
+	if !authPayload.ExpiresAt.IsZero() {
+		timer := time.NewTimer(time.Until(authPayload.ExpiresAt))
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Request.Context().Done():
+			return false
+		case <-expired:
+			ctx.SSEvent(reauthenticateEvent, errorResponse(token.ErrExpiredToken))
+			return false
+		case c, ok := <-changes:
+			if !ok {
+				return false
+			}
+
+			if !authPayload.CanUseAccount(c.AccountID) {
+				return true
+			}
+
+			ctx.SSEvent(c.Kind, c)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		}
+	})
+}
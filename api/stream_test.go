@@ -0,0 +1,70 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/stream"
+	"github.com/stretchr/testify/require"
+)
+
+// readEvent reads one Server-Sent Event, skipping heartbeats.
+func readEvent(t *testing.T, r *bufio.Reader) (event string, data string) {
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = line[len("event:"):]
+		case strings.HasPrefix(line, "data:"):
+			data = line[len("data:"):]
+		case line == "" && event != "":
+			return event, data
+		}
+	}
+}
+
+func TestStreamAccounts(t *testing.T) {
+	user, _ := randomUser(t)
+	test := newTest(t, "/accounts/stream")
+	srv := httptest.NewServer(test.server.router)
+	defer srv.Close()
+
+	request, err := http.NewRequest(http.MethodGet, srv.URL+test.url, nil)
+	require.NoError(t, err)
+	addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, 2*time.Second)
+
+	resp, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	balance := int64(70)
+	test.server.changes.Publish(stream.Change{Kind: stream.KindBalance, Owner: "someone-else", AccountID: 1, Balance: &balance})
+	test.server.changes.Publish(stream.Change{Kind: stream.KindBalance, Owner: user.Username, AccountID: 2, Balance: &balance})
+
+	r := bufio.NewReader(resp.Body)
+	event, data := readEvent(t, r)
+	require.Equal(t, stream.KindBalance, event)
+	require.JSONEq(t, `{"kind":"balance","account_id":2,"currency":"","balance":70}`, data)
+
+	event, _ = readEvent(t, r)
+	require.Equal(t, reauthenticateEvent, event)
+}
+
+func TestStreamAccountsUnauthenticated(t *testing.T) {
+	test := newTest(t, "/accounts/stream")
+
+	request, err := http.NewRequest(http.MethodGet, test.url, nil)
+	require.NoError(t, err)
+
+	test.server.router.ServeHTTP(test.recorder, request)
+	require.Equal(t, http.StatusUnauthorized, test.recorder.Code)
+}
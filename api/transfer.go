@@ -5,17 +5,31 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/fee"
+	"github.com/aulas/demo-bank/fraud"
 	"github.com/aulas/demo-bank/token"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
+const transferDeclinedCode = "transfer_declined"
+
+var errTransferDeclined = errors.New("transfer was declined")
+
 type transferRequest struct {
 	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	Instant       bool   `json:"instant"`
+}
+
+type transferResponse struct {
+	db.TransferTxResult
+	fee.Quote
 }
 
 func (s *Server) createTransfer(ctx *gin.Context) {
@@ -25,19 +39,17 @@ func (s *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
-	fromAccount, valid := s.validAccount(ctx, req.FromAccountID, req.Currency)
-	if !valid {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !authPayload.CanTransfer(req.Amount) {
+		ctx.JSON(http.StatusForbidden, insufficientScopeResponse(errTransferNotAllowed))
 		return
 	}
 
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if fromAccount.Owner != authPayload.Username {
-		err := errors.New("from account doesnt belong to authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	if !s.requireStepUp(ctx, req.Amount) {
 		return
 	}
 
-	_, valid = s.validAccount(ctx, req.ToAccountID, req.Currency)
+	plan, valid := s.planTransfer(ctx, req)
 	if !valid {
 		return
 	}
@@ -45,18 +57,166 @@ func (s *Server) createTransfer(ctx *gin.Context) {
 	arg := db.TransferTxParams{
 		FromAccountID: req.FromAccountID,
 		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+		Amount:        plan.quote.GrossAmount,
+		Fee:           plan.quote.Fee,
+	}
+
+	if !s.screen(ctx, plan, db.HoldTransferTxParams{Transfer: arg}) {
+		return
 	}
 
 	result, err := s.store.TransferTx(ctx, arg)
 	if err != nil {
+		var limitErr *db.LimitExceededError
+		if errors.As(err, &limitErr) {
+			ctx.JSON(http.StatusUnprocessableEntity, limitExceededResponse(limitErr))
+			return
+		}
+
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, transferResponse{
+		TransferTxResult: result,
+		Quote:            plan.quote,
+	})
 }
 
+type heldTransferResponse struct {
+	HeldTransfer db.HeldTransfer `json:"held_transfer"`
+	fee.Quote
+}
+
+// screen runs the fraud screening and reports whether the transfer may be
+// posted. Otherwise the response has been written: denied transfers get 403,
+// transfers sent to review are held and get 202. Every verdict is recorded.
+func (s *Server) screen(ctx *gin.Context, plan transferPlan, hold db.HoldTransferTxParams) bool {
+	arg := hold.Transfer
+	result, err := s.screener.Screen(ctx, fraud.Input{
+		FromAccount: plan.fromAccount,
+		ToAccount:   plan.toAccount,
+		Amount:      arg.Amount,
+		Now:         time.Now(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if result.Verdict == db.FraudVerdictReview {
+		hold.Rules = result.Rules
+		held, err := s.store.HoldTransferTx(ctx, hold)
+		if err != nil {
+			if errors.Is(err, db.ErrDuplicateReference) {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+				return false
+			}
+
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+				ctx.JSON(http.StatusConflict, errorResponse(err))
+				return false
+			}
+
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+
+		ctx.JSON(http.StatusAccepted, heldTransferResponse{
+			HeldTransfer: held,
+			Quote:        plan.quote,
+		})
+		return false
+	}
+
+	_, err = s.store.CreateFraudDecision(ctx, db.CreateFraudDecisionParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Verdict:       result.Verdict,
+		Rules:         result.Rules,
+		Actor:         db.ScreeningActor,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if result.Verdict == db.FraudVerdictDeny {
+		ctx.JSON(http.StatusForbidden, gin.H{
+			"error": errTransferDeclined.Error(),
+			"code":  transferDeclinedCode,
+		})
+		return false
+	}
+
+	return true
+}
+
+func (s *Server) quoteTransfer(ctx *gin.Context) {
+	var req transferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	plan, valid := s.planTransfer(ctx, req)
+	if !valid {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, plan.quote)
+}
+
+type transferPlan struct {
+	fromAccount db.Account
+	toAccount   db.Account
+	quote       fee.Quote
+}
+
+// planTransfer validates both accounts and evaluates the fee schedule. The fee
+// is deducted from the requested amount, which must cover it.
+func (s *Server) planTransfer(ctx *gin.Context, req transferRequest) (transferPlan, bool) {
+	var plan transferPlan
+	var valid bool
+
+	plan.fromAccount, valid = s.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return plan, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if plan.fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesnt belong to authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return plan, false
+	}
+
+	if !allowAccount(ctx, plan.fromAccount.ID) {
+		return plan, false
+	}
+
+	plan.toAccount, valid = s.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return plan, false
+	}
+
+	plan.quote = s.fees.Quote(fee.Transfer{
+		Amount:   req.Amount,
+		Currency: req.Currency,
+		Instant:  req.Instant,
+	})
+
+	if plan.quote.NetAmount <= 0 {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(db.ErrFeeExceedsAmount))
+		return plan, false
+	}
+
+	return plan, true
+}
+
+// validAccount loads a customer account that is active and in currency.
+// Accounts of the system users backing the ledger are refused with 403.
 func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency string) (db.Account, bool) {
 	acc, err := server.store.GetAccount(ctx, accountID)
 	if err != nil {
@@ -75,5 +235,17 @@ func (server *Server) validAccount(ctx *gin.Context, accountID int64, currency s
 		return acc, false
 	}
 
+	if acc.Status != db.AccountStatusActive {
+		err := fmt.Errorf("account [%d] is %s", acc.ID, acc.Status)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return acc, false
+	}
+
+	if db.IsInternalOwner(acc.Owner) {
+		err := fmt.Errorf("account [%d] is a system account", acc.ID)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return acc, false
+	}
+
 	return acc, true
 }
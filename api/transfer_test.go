@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,6 +10,8 @@ import (
 
 	mockdb "github.com/aulas/demo-bank/db/mock"
 	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/fee"
+	"github.com/aulas/demo-bank/fraud"
 	"github.com/aulas/demo-bank/token"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
@@ -23,11 +27,59 @@ func TestCreateTransfer(t *testing.T) {
 	acc2 := randomAccount(user2.Username)
 	acc2.Currency = acc1.Currency
 
+	frozenAcc := randomAccount(user2.Username)
+	frozenAcc.Currency = acc1.Currency
+	frozenAcc.Status = db.AccountStatusFrozen
+
+	suspenseAcc := randomAccount(db.SuspenseOwner)
+	suspenseAcc.Currency = acc1.Currency
+
+	instantFee := &fee.Schedule{Rules: []fee.Rule{
+		{Name: "instant", Instant: true, Type: fee.TypeFlat, Flat: 3},
+	}}
+
 	testCases := []struct {
 		baseTestCase //
 		request      transferRequest
+		fees         *fee.Schedule
+		rules        []fraud.Rule
 		setupAuth    func(t *testing.T, request *http.Request, tokenMaker token.Maker)
 	}{
+		{
+			request: transferRequest{
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      acc1.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "EmailNotVerified",
+				buildStubs: func(store *mockdb.MockStore) {
+					unverified := user1
+					unverified.IsEmailVerified = false
+
+					store.EXPECT().
+						GetUser(gomock.Any(), gomock.Eq(user1.Username)).
+						Times(1).
+						Return(unverified, nil)
+
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Any()).
+						Times(0)
+
+					store.EXPECT().
+						TransferTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+					require.Contains(t, recorder.Body.String(), emailNotVerifiedCode)
+				},
+			},
+		},
 		{
 			request: transferRequest{
 				FromAccountID: acc1.ID,
@@ -47,6 +99,266 @@ func TestCreateTransfer(t *testing.T) {
 						Amount:        amount,
 					}
 
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
+						Times(1).
+						Return(acc1, nil)
+
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).
+						Times(1).
+						Return(acc2, nil)
+
+					store.EXPECT().
+						CreateFraudDecision(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ any, arg db.CreateFraudDecisionParams) (db.FraudDecision, error) {
+							require.Equal(t, db.FraudVerdictAllow, arg.Verdict)
+							require.Equal(t, db.ScreeningActor, arg.Actor)
+							require.Empty(t, arg.Rules)
+							return db.FraudDecision{}, nil
+						})
+
+					store.EXPECT().
+						TransferTx(gomock.Any(), gomock.Eq(expectedArg)).
+						Times(1)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+				},
+			},
+		},
+		{
+			request: transferRequest{
+				FromAccountID: acc1.ID,
+				ToAccountID:   frozenAcc.ID,
+				Amount:        amount,
+				Currency:      acc1.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "FrozenAccount",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
+						Times(1).
+						Return(acc1, nil)
+
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(frozenAcc.ID)).
+						Times(1).
+						Return(frozenAcc, nil)
+
+					store.EXPECT().
+						TransferTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+				},
+			},
+		},
+		{
+			request: transferRequest{
+				FromAccountID: acc1.ID,
+				ToAccountID:   suspenseAcc.ID,
+				Amount:        amount,
+				Currency:      acc1.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "SystemAccount",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
+						Times(1).
+						Return(acc1, nil)
+
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(suspenseAcc.ID)).
+						Times(1).
+						Return(suspenseAcc, nil)
+
+					store.EXPECT().
+						TransferTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+					require.Contains(t, recorder.Body.String(), "system account")
+				},
+			},
+		},
+		{
+			request: transferRequest{
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      acc1.Currency,
+			},
+			rules: []fraud.Rule{fraud.RoundTrip{Window: time.Hour}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "HeldForReview",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
+						Times(1).
+						Return(acc1, nil)
+
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).
+						Times(1).
+						Return(acc2, nil)
+
+					store.EXPECT().
+						CountTransfersBetween(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(int64(1), nil)
+
+					expectedArg := db.HoldTransferTxParams{
+						Transfer: db.TransferTxParams{
+							FromAccountID: acc1.ID,
+							ToAccountID:   acc2.ID,
+							Amount:        amount,
+						},
+						Rules: []string{"round_trip"},
+					}
+
+					store.EXPECT().
+						HoldTransferTx(gomock.Any(), gomock.Eq(expectedArg)).
+						Times(1).
+						Return(db.HeldTransfer{ID: 1, Status: db.HeldTransferStatusPending}, nil)
+
+					store.EXPECT().
+						TransferTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusAccepted, recorder.Code)
+				},
+			},
+		},
+		{
+			request: transferRequest{
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      acc1.Currency,
+			},
+			rules: []fraud.Rule{fraud.NewPayeeLargeAmount{ReviewAmount: 1, DenyAmount: amount}},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "Declined",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
+						Times(1).
+						Return(acc1, nil)
+
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).
+						Times(1).
+						Return(acc2, nil)
+
+					store.EXPECT().
+						CountTransfersBetween(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(int64(0), nil)
+
+					store.EXPECT().
+						CreateFraudDecision(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ any, arg db.CreateFraudDecisionParams) (db.FraudDecision, error) {
+							require.Equal(t, db.FraudVerdictDeny, arg.Verdict)
+							require.Equal(t, []string{"new_payee_large_amount"}, arg.Rules)
+							return db.FraudDecision{}, nil
+						})
+
+					store.EXPECT().
+						TransferTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusForbidden, recorder.Code)
+					require.Contains(t, recorder.Body.String(), transferDeclinedCode)
+				},
+			},
+		},
+		{
+			request: transferRequest{
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      acc1.Currency,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "LimitExceeded",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
+						Times(1).
+						Return(acc1, nil)
+
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).
+						Times(1).
+						Return(acc2, nil)
+
+					store.EXPECT().
+						TransferTx(gomock.Any(), gomock.Any()).
+						Times(1).
+						Return(db.TransferTxResult{}, &db.LimitExceededError{Limit: db.LimitDailyTotal, Max: 100, Remaining: 4})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+
+					var body struct {
+						Code      string `json:"code"`
+						Limit     string `json:"limit"`
+						Remaining int64  `json:"remaining"`
+					}
+					err := json.Unmarshal(recorder.Body.Bytes(), &body)
+					require.NoError(t, err)
+					require.Equal(t, limitExceededCode, body.Code)
+					require.Equal(t, db.LimitDailyTotal, body.Limit)
+					require.Equal(t, int64(4), body.Remaining)
+				},
+			},
+		},
+		{
+			request: transferRequest{
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        amount,
+				Currency:      acc1.Currency,
+				Instant:       true,
+			},
+			fees: instantFee,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "InstantFee",
+				buildStubs: func(store *mockdb.MockStore) {
+					expectedArg := db.TransferTxParams{
+						FromAccountID: acc1.ID,
+						ToAccountID:   acc2.ID,
+						Amount:        amount,
+						Fee:           3,
+					}
+
 					store.EXPECT().
 						GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
 						Times(1).
@@ -63,6 +375,47 @@ func TestCreateTransfer(t *testing.T) {
 				},
 				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
 					require.Equal(t, http.StatusOK, recorder.Code)
+
+					var response transferResponse
+					err := json.Unmarshal(recorder.Body.Bytes(), &response)
+					require.NoError(t, err)
+					require.Equal(t, amount, response.GrossAmount)
+					require.Equal(t, int64(3), response.Fee)
+					require.Equal(t, amount-3, response.NetAmount)
+				},
+			},
+		},
+		{
+			request: transferRequest{
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        3,
+				Currency:      acc1.Currency,
+				Instant:       true,
+			},
+			fees: instantFee,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuth(t, request, tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+			},
+			baseTestCase: baseTestCase{
+				name: "FeeExceedsAmount",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
+						Times(1).
+						Return(acc1, nil)
+
+					store.EXPECT().
+						GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).
+						Times(1).
+						Return(acc2, nil)
+
+					store.EXPECT().
+						TransferTx(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
 				},
 			},
 		},
@@ -73,6 +426,22 @@ func TestCreateTransfer(t *testing.T) {
 			// given
 			test := newTest(t, "/transfers")
 			tc.buildStubs(test.store)
+			test.store.EXPECT().
+				CreateFraudDecision(gomock.Any(), gomock.Any()).
+				AnyTimes()
+			test.store.EXPECT().
+				GetUser(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ context.Context, username string) (db.User, error) {
+					return db.User{Username: username, IsEmailVerified: true}, nil
+				})
+			if tc.fees != nil {
+				test.server.fees = tc.fees
+			}
+
+			if tc.rules != nil {
+				test.server.screener = fraud.NewScreener(test.store, tc.rules...)
+			}
 
 			body, err := toReader(tc.request)
 			require.NoError(t, err)
@@ -89,3 +458,59 @@ func TestCreateTransfer(t *testing.T) {
 		})
 	}
 }
+
+func TestQuoteTransfer(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	acc1 := randomAccount(user1.Username)
+	acc2 := randomAccount(user2.Username)
+	acc2.Currency = acc1.Currency
+
+	request := transferRequest{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        1000,
+		Currency:      acc1.Currency,
+	}
+
+	test := newTest(t, "/transfers/quote")
+	test.server.fees = &fee.Schedule{Rules: []fee.Rule{
+		{Name: "percentage", Type: fee.TypePercentage, BasisPoints: 100},
+	}}
+
+	test.store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(acc1.ID)).
+		Times(1).
+		Return(acc1, nil)
+
+	test.store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(acc2.ID)).
+		Times(1).
+		Return(acc2, nil)
+
+	test.store.EXPECT().
+		TransferTx(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	body, err := toReader(request)
+	require.NoError(t, err)
+
+	httpRequest, err := http.NewRequest(http.MethodPost, test.url, body)
+	require.NoError(t, err)
+
+	addAuth(t, httpRequest, test.server.tokenMaker, authorizationTypeBearer, user1.Username, time.Minute)
+	test.server.router.ServeHTTP(test.recorder, httpRequest)
+
+	require.Equal(t, http.StatusOK, test.recorder.Code)
+
+	var quote fee.Quote
+	err = json.Unmarshal(test.recorder.Body.Bytes(), &quote)
+	require.NoError(t, err)
+	require.Equal(t, fee.Quote{
+		GrossAmount: 1000,
+		Fee:         10,
+		NetAmount:   990,
+		Rules:       []string{"percentage"},
+	}, quote)
+}
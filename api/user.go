@@ -2,10 +2,13 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
 	"github.com/aulas/demo-bank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
@@ -13,7 +16,7 @@ import (
 
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=8"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -22,7 +25,9 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
 
@@ -31,7 +36,9 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
+		UpdatedAt:         user.UpdatedAt,
 		CreatedAt:         user.CreatedAt,
 	}
 }
@@ -43,20 +50,40 @@ func (s *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	p, err := util.HashPassword(req.Password)
+	if err := s.policy.Check(req.Password, req.Username, req.Email); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	p, err := s.passwords.Hash(req.Password)
+	if err != nil {
+		if errors.Is(err, util.ErrPasswordTooLong) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	verificationToken, verificationTokenHash, err := newOneTimeToken()
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: p,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: p,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		VerificationTokenHash: verificationTokenHash,
+		VerificationExpiresAt: time.Now().Add(s.config.EmailVerificationDuration),
 	}
 
-	user, err := s.store.CreateUser(ctx, arg)
+	user, err := s.store.CreateUserTx(ctx, arg)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			errName := pqErr.Code.Name()
@@ -70,6 +97,13 @@ func (s *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
+	// The email is sent once the user is committed, so a slow mailer holds no
+	// transaction open. If it fails, the user can ask for another one.
+	err = s.mailer.Send(ctx, s.verificationEmail(user, verificationToken))
+	if err != nil {
+		log.Printf("cannot send verification email to %s: %v", user.Username, err)
+	}
+
 	u := newUserResponse(user)
 
 	ctx.JSON(http.StatusCreated, u)
@@ -92,27 +126,64 @@ func (s *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	ip := ctx.ClientIP()
+	lockedUntil, err := s.loginLockedUntil(ctx, req.Username, ip)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if !lockedUntil.IsZero() {
+		loginLockedResponse(ctx, lockedUntil)
+		return
+	}
+
+	// unknown users and wrong passwords get the same answer in about the same
+	// time, so logins can't be used to find out which usernames exist
+	var rehash bool
 	user, err := s.store.GetUser(ctx, req.Username)
+	if err == nil {
+		rehash, err = s.passwords.Verify(req.Password, user.HashedPassword)
+	} else if errors.Is(err, sql.ErrNoRows) {
+		s.burnPasswordCompare(req.Password)
+	} else {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
 	if err != nil {
-		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		if err := s.loginFailed(ctx, req.Username, ip); err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 			return
 		}
 
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	if rehash {
+		s.rehashPassword(ctx, user, req.Password)
+	}
+
+	mfa, err := s.store.GetUserMFA(ctx, user.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	err = util.ComparePasswords(req.Password, user.HashedPassword)
-	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	// failures are only cleared once the second factor is in too, or every
+	// correct password would buy another round of guessing codes
+	if err == nil && mfa.ConfirmedAt.Valid {
+		s.mfaChallenge(ctx, user.Username)
+		return
+	}
+
+	if err := s.loginSucceeded(ctx, user.Username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	accessToken, err := s.tokenMaker.CreateToken(
-		user.Username,
-		s.config.TokenDuration,
-	)
+	accessToken, _, err := s.accessToken(user, false)
 
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
@@ -126,3 +197,146 @@ func (s *Server) loginUser(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusCreated, response)
 }
+
+// currentUser writes the error response itself when the authenticated user
+// cannot be loaded.
+func (s *Server) currentUser(ctx *gin.Context) (db.User, bool) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := s.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return user, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return user, false
+	}
+
+	return user, true
+}
+
+func (s *Server) getCurrentUser(ctx *gin.Context) {
+	user, ok := s.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// updateUserRequest leaves out fields that should not change. Changing the
+// email address needs the current password, since the address is where
+// password reset links go.
+type updateUserRequest struct {
+	FullName        *string `json:"full_name" binding:"omitempty,min=1"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+func (s *Server) updateCurrentUser(ctx *gin.Context) {
+	var req updateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.FullName == nil && req.Email == nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("nothing to update")))
+		return
+	}
+
+	user, ok := s.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	arg := db.UpdateUserProfileTxParams{
+		UpdateUserProfileParams: db.UpdateUserProfileParams{
+			Username: user.Username,
+		},
+	}
+	if req.FullName != nil {
+		arg.FullName = sql.NullString{String: *req.FullName, Valid: true}
+	}
+
+	var verificationToken string
+	if req.Email != nil && *req.Email != user.Email {
+		_, err := s.passwords.Verify(req.CurrentPassword, user.HashedPassword)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("current password is incorrect")))
+			return
+		}
+
+		verificationToken, arg.VerificationTokenHash, err = newOneTimeToken()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		arg.Email = sql.NullString{String: *req.Email, Valid: true}
+		arg.VerificationExpiresAt = time.Now().Add(s.config.EmailVerificationDuration)
+	}
+
+	user, err := s.store.UpdateUserProfileTx(ctx, arg)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// sent after commit, like the signup email
+	if verificationToken != "" {
+		err = s.mailer.Send(ctx, s.verificationEmail(user, verificationToken))
+		if err != nil {
+			log.Printf("cannot send verification email to %s: %v", user.Username, err)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type deleteUserRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// deleteCurrentUser soft deletes the user, who must have closed all of their
+// accounts first. It also revokes every token, API key and OAuth grant of the
+// user.
+func (s *Server) deleteCurrentUser(ctx *gin.Context) {
+	var req deleteUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, ok := s.currentUser(ctx)
+	if !ok {
+		return
+	}
+
+	_, err := s.passwords.Verify(req.Password, user.HashedPassword)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("password is incorrect")))
+		return
+	}
+
+	_, err = s.store.DeleteUserTx(ctx, user.Username)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrUserHasOpenAccounts):
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+		case errors.Is(err, sql.ErrNoRows):
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
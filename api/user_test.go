@@ -2,29 +2,35 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	mockdb "github.com/aulas/demo-bank/db/mock"
 	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/mailer"
 	"github.com/aulas/demo-bank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
 
-type eqCreateUserParamsMatcher struct {
+type eqCreateUserTxParamsMatcher struct {
 	arg      db.CreateUserParams
 	password string
 }
 
-func (e eqCreateUserParamsMatcher) Matches(x any) bool {
-	arg, ok := x.(db.CreateUserParams)
+func (e eqCreateUserTxParamsMatcher) Matches(x any) bool {
+	arg, ok := x.(db.CreateUserTxParams)
 	if !ok {
 		return false
 	}
@@ -34,16 +40,20 @@ func (e eqCreateUserParamsMatcher) Matches(x any) bool {
 		return false
 	}
 
+	if arg.VerificationTokenHash == "" || !arg.VerificationExpiresAt.After(time.Now()) {
+		return false
+	}
+
 	e.arg.HashedPassword = arg.HashedPassword
-	return reflect.DeepEqual(e.arg, arg)
+	return reflect.DeepEqual(e.arg, arg.CreateUserParams)
 }
 
-func (e eqCreateUserParamsMatcher) String() string {
+func (e eqCreateUserTxParamsMatcher) String() string {
 	return fmt.Sprintf("matchs arg %v and password %v", e.arg, e.password)
 }
 
-func EqCreateUserParams(arg db.CreateUserParams, password string) gomock.Matcher {
-	return eqCreateUserParamsMatcher{arg, password}
+func EqCreateUserTxParams(arg db.CreateUserParams, password string) gomock.Matcher {
+	return eqCreateUserTxParamsMatcher{arg, password}
 }
 
 func TestCreateUser(t *testing.T) {
@@ -69,7 +79,7 @@ func TestCreateUser(t *testing.T) {
 					}
 
 					store.EXPECT().
-						CreateUser(gomock.Any(), EqCreateUserParams(arg, password)).
+						CreateUserTx(gomock.Any(), EqCreateUserTxParams(arg, password)).
 						Times(1).
 						Return(user, nil)
 				},
@@ -78,6 +88,38 @@ func TestCreateUser(t *testing.T) {
 					requireBodyMatchUser(t, recorder.Body, user)
 				},
 			}},
+		{
+			request: createUserRequest{
+				Username: user.Username,
+				Password: "password123",
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			baseTestCase: baseTestCase{
+				name: "BreachedPassword",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			}},
+		{
+			request: createUserRequest{
+				Username: user.Username,
+				Password: user.Username,
+				FullName: user.FullName,
+				Email:    user.Email,
+			},
+			baseTestCase: baseTestCase{
+				name: "PasswordIsUsername",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			}},
 	}
 
 	for _, tc := range testCases {
@@ -101,14 +143,250 @@ func TestCreateUser(t *testing.T) {
 	}
 }
 
+func TestCreateUserBcryptPasswordTooLong(t *testing.T) {
+	user, _ := randomUser(t)
+	store := mockdb.NewMockStore(gomock.NewController(t))
+	server := newTestServerWithConfig(t, store, func(config *util.Config) {
+		config.PasswordHashAlgorithm = util.PasswordAlgorithmBcrypt
+		config.PasswordBcryptCost = 4
+	})
+
+	store.EXPECT().CreateUserTx(gomock.Any(), gomock.Any()).Times(0)
+
+	// 40 characters, but 80 bytes
+	body, err := toReader(createUserRequest{
+		Username: user.Username,
+		Password: strings.Repeat("é", 40),
+		FullName: user.FullName,
+		Email:    user.Email,
+	})
+	require.NoError(t, err)
+
+	recorder := httptest.NewRecorder()
+	request, err := http.NewRequest(http.MethodPost, "/users", body)
+	require.NoError(t, err)
+	server.router.ServeHTTP(recorder, request)
+
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func sendUserMe(t *testing.T, test *test, method string, body any, username string) {
+	var reader io.Reader
+	if body != nil {
+		var err error
+		reader, err = toReader(body)
+		require.NoError(t, err)
+	}
+
+	request, err := http.NewRequest(method, test.url, reader)
+	require.NoError(t, err)
+
+	addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, username, time.Minute)
+	test.server.router.ServeHTTP(test.recorder, request)
+}
+
+func TestGetCurrentUser(t *testing.T) {
+	user, _ := randomUser(t)
+
+	t.Run("OK", func(t *testing.T) {
+		test := newTest(t, "/users/me")
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+		sendUserMe(t, test, http.MethodGet, nil, user.Username)
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+		requireBodyMatchUser(t, test.recorder.Body, user)
+	})
+
+	t.Run("Deleted", func(t *testing.T) {
+		test := newTest(t, "/users/me")
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, sql.ErrNoRows)
+
+		sendUserMe(t, test, http.MethodGet, nil, user.Username)
+		require.Equal(t, http.StatusUnauthorized, test.recorder.Code)
+	})
+}
+
+func TestUpdateCurrentUser(t *testing.T) {
+	user, password := randomUser(t)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	fullName := util.RandomFullName()
+	email := util.RandomEmail()
+
+	t.Run("FullName", func(t *testing.T) {
+		test := newTest(t, "/users/me")
+		updated := user
+		updated.FullName = fullName
+
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+		test.store.EXPECT().
+			UpdateUserProfileTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.UpdateUserProfileTxParams) (db.User, error) {
+				require.Equal(t, user.Username, arg.Username)
+				require.Equal(t, sql.NullString{String: fullName, Valid: true}, arg.FullName)
+				require.False(t, arg.Email.Valid)
+				return updated, nil
+			})
+
+		sendUserMe(t, test, http.MethodPatch, gin.H{"full_name": fullName}, user.Username)
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+		requireBodyMatchUser(t, test.recorder.Body, updated)
+		require.Empty(t, test.server.mailer.(*mailer.MemorySender).Messages())
+	})
+
+	t.Run("Email", func(t *testing.T) {
+		test := newTest(t, "/users/me")
+		updated := user
+		updated.Email = email
+		updated.IsEmailVerified = false
+
+		var tokenHash string
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+		test.store.EXPECT().
+			UpdateUserProfileTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.UpdateUserProfileTxParams) (db.User, error) {
+				require.Equal(t, sql.NullString{String: email, Valid: true}, arg.Email)
+				require.True(t, arg.VerificationExpiresAt.After(time.Now()))
+				tokenHash = arg.VerificationTokenHash
+				return updated, nil
+			})
+
+		sendUserMe(t, test, http.MethodPatch, gin.H{"email": email, "current_password": password}, user.Username)
+		require.Equal(t, http.StatusOK, test.recorder.Code)
+		requireBodyMatchUser(t, test.recorder.Body, updated)
+
+		messages := test.server.mailer.(*mailer.MemorySender).Messages()
+		require.Len(t, messages, 1)
+		require.Equal(t, email, messages[0].To)
+		require.NotEmpty(t, tokenHash)
+	})
+
+	t.Run("EmailWrongPassword", func(t *testing.T) {
+		test := newTest(t, "/users/me")
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+		test.store.EXPECT().UpdateUserProfileTx(gomock.Any(), gomock.Any()).Times(0)
+
+		sendUserMe(t, test, http.MethodPatch, gin.H{"email": email, "current_password": "wrong-password"}, user.Username)
+		require.Equal(t, http.StatusUnauthorized, test.recorder.Code)
+	})
+
+	t.Run("EmailTaken", func(t *testing.T) {
+		test := newTest(t, "/users/me")
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+		test.store.EXPECT().
+			UpdateUserProfileTx(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(db.User{}, &pq.Error{Code: "23505"})
+
+		sendUserMe(t, test, http.MethodPatch, gin.H{"email": email, "current_password": password}, user.Username)
+		require.Equal(t, http.StatusForbidden, test.recorder.Code)
+	})
+
+	t.Run("InvalidEmail", func(t *testing.T) {
+		test := newTest(t, "/users/me")
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+		sendUserMe(t, test, http.MethodPatch, gin.H{"email": "not-an-email"}, user.Username)
+		require.Equal(t, http.StatusBadRequest, test.recorder.Code)
+	})
+
+	t.Run("NothingToUpdate", func(t *testing.T) {
+		test := newTest(t, "/users/me")
+		test.store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+		sendUserMe(t, test, http.MethodPatch, gin.H{}, user.Username)
+		require.Equal(t, http.StatusBadRequest, test.recorder.Code)
+	})
+}
+
+func TestDeleteCurrentUser(t *testing.T) {
+	user, password := randomUser(t)
+	hashedPassword, err := util.HashPassword(password)
+	require.NoError(t, err)
+	user.HashedPassword = hashedPassword
+
+	testCases := []struct {
+		baseTestCase //
+		password     string
+	}{
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNoContent, recorder.Code)
+				},
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "OpenAccounts",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(db.User{}, db.ErrUserHasOpenAccounts)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusConflict, recorder.Code)
+				},
+			},
+		},
+		{
+			password: "wrong-password",
+			baseTestCase: baseTestCase{
+				name: "WrongPassword",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Any()).Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			password: password,
+			baseTestCase: baseTestCase{
+				name: "InternalError",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+					store.EXPECT().DeleteUserTx(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusInternalServerError, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			test := newTest(t, "/users/me")
+			tc.buildStubs(test.store)
+
+			sendUserMe(t, test, http.MethodDelete, deleteUserRequest{Password: tc.password}, user.Username)
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
 func randomUser(t *testing.T) (db.User, string) {
 	password := util.RandomString(12)
 
 	return db.User{
-		Username:       util.RandomOnwer(),
-		HashedPassword: password,
-		FullName:       util.RandomFullName(),
-		Email:          util.RandomEmail(),
+		Username:        util.RandomOnwer(),
+		HashedPassword:  password,
+		FullName:        util.RandomFullName(),
+		Email:           util.RandomEmail(),
+		IsEmailVerified: true,
 	}, password
 }
 
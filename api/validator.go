@@ -1,6 +1,8 @@
 package api
 
 import (
+	"net/url"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -26,3 +28,14 @@ func isValidCurrency(currency string) bool {
 
 	return false
 }
+
+// validHTTPSURL accepts absolute https URLs. Webhook receivers must use TLS.
+var validHTTPSURL validator.Func = func(fieldLvl validator.FieldLevel) bool {
+	raw, ok := fieldLvl.Field().Interface().(string)
+	if !ok {
+		return false
+	}
+
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "https" && u.Host != ""
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+type createWebhookRequest struct {
+	URL        string   `json:"url" binding:"required,https_url"`
+	EventTypes []string `json:"event_types" binding:"required,min=1,dive,oneof=transfer.received transfer.sent account.created"`
+}
+
+type webhookResponse struct {
+	ID         int64     `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// createWebhookResponse is the only response that includes the signing
+// secret; it cannot be read back afterwards.
+type createWebhookResponse struct {
+	webhookResponse
+	Secret string `json:"secret"`
+}
+
+func newWebhookResponse(sub db.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		ID:         sub.ID,
+		URL:        sub.Url,
+		EventTypes: sub.EventTypes,
+		CreatedAt:  sub.CreatedAt,
+	}
+}
+
+func (s *Server) createWebhook(ctx *gin.Context) {
+	var req createWebhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	sub, err := s.store.CreateWebhookSubscription(ctx, db.CreateWebhookSubscriptionParams{
+		Owner:      authPayload.Username,
+		Url:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createWebhookResponse{
+		webhookResponse: newWebhookResponse(sub),
+		Secret:          sub.Secret,
+	})
+}
+
+func (s *Server) listWebhooks(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	subs, err := s.store.ListWebhookSubscriptions(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result := make([]webhookResponse, len(subs))
+	for i, sub := range subs {
+		result[i] = newWebhookResponse(sub)
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+type webhookURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// ownWebhook loads the subscription in the URI and writes an error response
+// unless it belongs to the authenticated user.
+func (s *Server) ownWebhook(ctx *gin.Context) (db.WebhookSubscription, bool) {
+	var uri webhookURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.WebhookSubscription{}, false
+	}
+
+	sub, err := s.store.GetWebhookSubscription(ctx, uri.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.WebhookSubscription{}, false
+		}
+
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.WebhookSubscription{}, false
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if sub.Owner != authPayload.Username {
+		err := errors.New("webhook doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return db.WebhookSubscription{}, false
+	}
+
+	return sub, true
+}
+
+func (s *Server) deleteWebhook(ctx *gin.Context) {
+	sub, ok := s.ownWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	err := s.store.DeleteWebhookSubscription(ctx, sub.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+func (s *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	sub, ok := s.ownWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	deliveries, err := s.store.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		SubscriptionID: sub.ID,
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
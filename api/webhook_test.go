@@ -0,0 +1,291 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomWebhook(owner string) db.WebhookSubscription {
+	return db.WebhookSubscription{
+		ID:         util.RandomInt(1, 1000),
+		Owner:      owner,
+		Url:        "https://example.com/hooks",
+		EventTypes: []string{"transfer.received"},
+		Secret:     "whsec_" + util.RandomString(32),
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	user, _ := randomUser(t)
+	sub := randomWebhook(user.Username)
+
+	testCases := []struct {
+		baseTestCase //
+		body         map[string]any
+	}{
+		{
+			body: map[string]any{
+				"url":         sub.Url,
+				"event_types": sub.EventTypes,
+			},
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+						Times(1).
+						DoAndReturn(func(_ any, arg db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+							require.Equal(t, user.Username, arg.Owner)
+							require.Equal(t, sub.Url, arg.Url)
+							require.Equal(t, sub.EventTypes, arg.EventTypes)
+							require.True(t, strings.HasPrefix(arg.Secret, "whsec_"))
+							return sub, nil
+						})
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusCreated, recorder.Code)
+
+					var got createWebhookResponse
+					err := json.Unmarshal(recorder.Body.Bytes(), &got)
+					require.NoError(t, err)
+					require.Equal(t, sub.ID, got.ID)
+					require.Equal(t, sub.Secret, got.Secret)
+				},
+			},
+		},
+		{
+			body: map[string]any{
+				"url":         "ftp://example.com",
+				"event_types": sub.EventTypes,
+			},
+			baseTestCase: baseTestCase{
+				name: "InvalidURL",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			body: map[string]any{
+				"url":         "http://example.com/hooks",
+				"event_types": sub.EventTypes,
+			},
+			baseTestCase: baseTestCase{
+				name: "PlainHTTP",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			body: map[string]any{
+				"url":         sub.Url,
+				"event_types": []string{"transfer.posted"},
+			},
+			baseTestCase: baseTestCase{
+				name: "UnknownEventType",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+		{
+			body: map[string]any{
+				"url":         sub.Url,
+				"event_types": []string{},
+			},
+			baseTestCase: baseTestCase{
+				name: "NoEventTypes",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						CreateWebhookSubscription(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusBadRequest, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, "/webhooks")
+			tc.buildStubs(test.store)
+
+			body, err := toReader(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, test.url, body)
+			require.NoError(t, err)
+
+			// when
+			addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
+
+func TestListWebhooksHidesSecret(t *testing.T) {
+	user, _ := randomUser(t)
+	sub := randomWebhook(user.Username)
+
+	test := newTest(t, "/webhooks")
+	test.store.EXPECT().
+		ListWebhookSubscriptions(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return([]db.WebhookSubscription{sub}, nil)
+
+	request, err := http.NewRequest(http.MethodGet, test.url, nil)
+	require.NoError(t, err)
+
+	addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, user.Username, time.Minute)
+	test.server.router.ServeHTTP(test.recorder, request)
+
+	require.Equal(t, http.StatusOK, test.recorder.Code)
+	require.NotContains(t, test.recorder.Body.String(), sub.Secret)
+	require.NotContains(t, test.recorder.Body.String(), `"secret"`)
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	user, _ := randomUser(t)
+	other, _ := randomUser(t)
+	sub := randomWebhook(user.Username)
+
+	deliveries := []db.WebhookDelivery{
+		{
+			ID:             2,
+			SubscriptionID: sub.ID,
+			EventID:        7,
+			EventType:      "transfer.received",
+			Payload:        json.RawMessage(`{"event_id":7}`),
+			Status:         db.WebhookDeliveryStatusDead,
+			Attempts:       8,
+			LastStatusCode: 500,
+			LastError:      "receiver responded 500 Internal Server Error",
+		},
+	}
+
+	testCases := []struct {
+		baseTestCase //
+		username     string
+	}{
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "OK",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+						Times(1).
+						Return(sub, nil)
+
+					store.EXPECT().
+						ListWebhookDeliveries(gomock.Any(), gomock.Eq(db.ListWebhookDeliveriesParams{
+							SubscriptionID: sub.ID,
+							Limit:          5,
+							Offset:         0,
+						})).
+						Times(1).
+						Return(deliveries, nil)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusOK, recorder.Code)
+
+					var got []db.WebhookDelivery
+					err := json.Unmarshal(recorder.Body.Bytes(), &got)
+					require.NoError(t, err)
+					require.Equal(t, deliveries, got)
+				},
+			},
+		},
+		{
+			username: other.Username,
+			baseTestCase: baseTestCase{
+				name: "Unauthorized",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+						Times(1).
+						Return(sub, nil)
+
+					store.EXPECT().
+						ListWebhookDeliveries(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				},
+			},
+		},
+		{
+			username: user.Username,
+			baseTestCase: baseTestCase{
+				name: "NotFound",
+				buildStubs: func(store *mockdb.MockStore) {
+					store.EXPECT().
+						GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+						Times(1).
+						Return(db.WebhookSubscription{}, sql.ErrNoRows)
+
+					store.EXPECT().
+						ListWebhookDeliveries(gomock.Any(), gomock.Any()).
+						Times(0)
+				},
+				checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+					require.Equal(t, http.StatusNotFound, recorder.Code)
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			test := newTest(t, fmt.Sprintf("/webhooks/%d/deliveries?page_id=1&page_size=5", sub.ID))
+			tc.buildStubs(test.store)
+
+			request, err := http.NewRequest(http.MethodGet, test.url, nil)
+			require.NoError(t, err)
+
+			// when
+			addAuth(t, request, test.server.tokenMaker, authorizationTypeBearer, tc.username, time.Minute)
+			test.server.router.ServeHTTP(test.recorder, request)
+
+			// then
+			tc.checkResponse(t, test.recorder)
+		})
+	}
+}
@@ -0,0 +1,794 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/interest"
+	"github.com/aulas/demo-bank/reconcile"
+	"github.com/aulas/demo-bank/util"
+)
+
+const adminUsage = `usage: admin <command> [flags]
+
+commands:
+  user create      -username -full-name -email -password -reason
+  user erase       -username -reason
+  user promote     -username -reason
+  user demote      -username -reason
+  account list     [-owner] [-after-id] [-limit]
+  account freeze   -id -reason
+  account unfreeze -id -reason
+  account adjust   -id -amount -reason-code -reason
+  account overdraft -id -limit -reason
+  account balances [-after-id] [-limit] [-mismatched]
+  transfer replay  -id -reason
+  audit list       -target [-limit]
+  reconcile run    [-batch-size] -reason
+  product list
+  product rate     -code -rate-bps -reason
+  interest accrue  -day YYYY-MM-DD [-batch-size] -reason
+  interest post    -period YYYY-MM [-batch-size] -reason
+  limit list
+  limit set        -scope product|user|account -ref [-max-per-transfer] [-daily] [-monthly] [-hourly-count] -reason
+
+common flags: -output table|json, -operator (defaults to $USER)`
+
+type adminCommand struct {
+	store     db.Store
+	out       io.Writer
+	passwords *util.PasswordHasher
+	policy    *util.PasswordPolicy
+}
+
+type adminFlags struct {
+	set      *flag.FlagSet
+	output   *string
+	operator *string
+	reason   *string
+}
+
+// newAdminFlags registers the flags every admin command accepts. Mutating
+// commands also get a -reason flag, which parse then requires.
+func newAdminFlags(name string, mutating bool) *adminFlags {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	f := &adminFlags{
+		set:      set,
+		output:   set.String("output", formatTable, "output format: table or json"),
+		operator: set.String("operator", os.Getenv("USER"), "operator recorded in the audit log"),
+	}
+
+	if mutating {
+		f.reason = set.String("reason", "", "why the change is made; written to the audit log")
+	}
+
+	return f
+}
+
+func (f *adminFlags) parse(args []string) error {
+	if err := f.set.Parse(args); err != nil {
+		return err
+	}
+
+	if f.reason == nil {
+		return nil
+	}
+
+	if *f.reason == "" {
+		return errors.New("-reason is required for this command")
+	}
+
+	if *f.operator == "" {
+		return errors.New("-operator is required for this command")
+	}
+
+	return nil
+}
+
+func (f *adminFlags) audit(action string, target string, details any) (db.CreateAuditLogParams, error) {
+	data, err := json.Marshal(details)
+	if err != nil {
+		return db.CreateAuditLogParams{}, err
+	}
+
+	return db.CreateAuditLogParams{
+		Actor:   *f.operator,
+		Action:  action,
+		Target:  target,
+		Reason:  *f.reason,
+		Details: data,
+	}, nil
+}
+
+func runAdmin(config *util.Config, args []string, out io.Writer) error {
+	if len(args) < 2 {
+		return errors.New(adminUsage)
+	}
+
+	// passwords set here follow the same rules as the API's
+	passwords, err := util.NewPasswordHasher(util.PasswordParams{
+		Algorithm:   config.PasswordHashAlgorithm,
+		Memory:      config.PasswordArgon2Memory,
+		Iterations:  config.PasswordArgon2Iterations,
+		Parallelism: config.PasswordArgon2Parallelism,
+		BcryptCost:  config.PasswordBcryptCost,
+	})
+	if err != nil {
+		return fmt.Errorf("cannot create password hasher: %w", err)
+	}
+
+	policy, err := util.NewPasswordPolicy(config.PasswordMinLength, config.PasswordMaxLength, config.PasswordHashAlgorithm, config.PasswordBreachedListFile)
+	if err != nil {
+		return err
+	}
+
+	conn, err := sql.Open(config.DBDriver, config.DBSource)
+	if err != nil {
+		return fmt.Errorf("cannot open db connection: %w", err)
+	}
+	defer conn.Close()
+
+	cmd := &adminCommand{
+		store:     db.NewStore(conn),
+		out:       out,
+		passwords: passwords,
+		policy:    policy,
+	}
+
+	return cmd.run(context.Background(), args[0]+" "+args[1], args[2:])
+}
+
+func (c *adminCommand) run(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "user create":
+		return c.createUser(ctx, args)
+	case "user erase":
+		return c.eraseUser(ctx, args)
+	case "user promote":
+		return c.setUserRole(ctx, args, "user promote", db.UserRoleAdmin)
+	case "user demote":
+		return c.setUserRole(ctx, args, "user demote", db.UserRoleCustomer)
+	case "account list":
+		return c.listAccounts(ctx, args)
+	case "account freeze":
+		return c.setAccountStatus(ctx, args, db.AccountStatusFrozen)
+	case "account unfreeze":
+		return c.setAccountStatus(ctx, args, db.AccountStatusActive)
+	case "account adjust":
+		return c.adjustBalance(ctx, args)
+	case "account overdraft":
+		return c.setOverdraftLimit(ctx, args)
+	case "account balances":
+		return c.listBalances(ctx, args)
+	case "transfer replay":
+		return c.replayTransfer(ctx, args)
+	case "audit list":
+		return c.listAuditLogs(ctx, args)
+	case "reconcile run":
+		return c.reconcile(ctx, args)
+	case "product list":
+		return c.listProducts(ctx, args)
+	case "product rate":
+		return c.setProductRate(ctx, args)
+	case "interest accrue":
+		return c.accrueInterest(ctx, args)
+	case "interest post":
+		return c.postInterest(ctx, args)
+	case "limit list":
+		return c.listLimits(ctx, args)
+	case "limit set":
+		return c.setLimit(ctx, args)
+	}
+
+	return errors.New(adminUsage)
+}
+
+func (c *adminCommand) createUser(ctx context.Context, args []string) error {
+	f := newAdminFlags("user create", true)
+	username := f.set.String("username", "", "username")
+	fullName := f.set.String("full-name", "", "full name")
+	email := f.set.String("email", "", "email")
+	password := f.set.String("password", "", "initial password")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" || *fullName == "" || *email == "" || *password == "" {
+		return errors.New("-username, -full-name, -email and -password are required")
+	}
+
+	if err := c.policy.Check(*password, *username, *email); err != nil {
+		return err
+	}
+
+	hashedPassword, err := c.passwords.Hash(*password)
+	if err != nil {
+		return err
+	}
+
+	audit, err := f.audit("user.create", "user:"+*username, map[string]string{"email": *email})
+	if err != nil {
+		return err
+	}
+
+	var user db.User
+	_, err = c.store.AuditTx(ctx, audit, func(q *db.Queries) error {
+		user, err = q.CreateUserWithEvent(ctx, db.CreateUserParams{
+			Username:       *username,
+			HashedPassword: hashedPassword,
+			FullName:       *fullName,
+			Email:          *email,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	user.HashedPassword = ""
+	t := &table{header: []string{"USERNAME", "FULL NAME", "EMAIL", "CREATED AT"}}
+	t.append(user.Username, user.FullName, user.Email, user.CreatedAt)
+	return render(c.out, *f.output, user, t)
+}
+
+// eraseUser pseudonymizes a user's personal data. Their accounts must all be
+// closed; the ledger is kept.
+func (c *adminCommand) eraseUser(ctx context.Context, args []string) error {
+	f := newAdminFlags("user erase", true)
+	username := f.set.String("username", "", "username")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+
+	user, err := c.store.EraseUserTx(ctx, db.EraseUserTxParams{
+		Username: *username,
+		Operator: *f.operator,
+		Reason:   *f.reason,
+	})
+	if err != nil {
+		return err
+	}
+
+	user.HashedPassword = ""
+	t := &table{header: []string{"USERNAME", "FULL NAME", "EMAIL", "ERASED AT"}}
+	t.append(user.Username, user.FullName, user.Email, user.ErasedAt.Time)
+	return render(c.out, *f.output, user, t)
+}
+
+// setUserRole grants or takes away the admin role. Nothing in the API can
+// change roles, so this is how the first admin is made.
+func (c *adminCommand) setUserRole(ctx context.Context, args []string, name string, role db.UserRole) error {
+	f := newAdminFlags(name, true)
+	username := f.set.String("username", "", "username")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+
+	audit, err := f.audit("user.role", "user:"+*username, map[string]db.UserRole{"role": role})
+	if err != nil {
+		return err
+	}
+
+	var user db.User
+	_, err = c.store.AuditTx(ctx, audit, func(q *db.Queries) error {
+		user, err = q.SetUserRole(ctx, db.SetUserRoleParams{
+			Username: *username,
+			Role:     role,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	user.HashedPassword = ""
+	t := &table{header: []string{"USERNAME", "FULL NAME", "ROLE", "UPDATED AT"}}
+	t.append(user.Username, user.FullName, user.Role, user.UpdatedAt)
+	return render(c.out, *f.output, user, t)
+}
+
+func (c *adminCommand) listAccounts(ctx context.Context, args []string) error {
+	f := newAdminFlags("account list", false)
+	owner := f.set.String("owner", "", "only list accounts of this owner")
+	afterID := f.set.Int64("after-id", 0, "list accounts with an id greater than this")
+	limit := f.set.Int("limit", 50, "maximum number of accounts")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	accounts, err := c.store.ListAllAccounts(ctx, db.ListAllAccountsParams{
+		AfterID: *afterID,
+		Owner:   sql.NullString{String: *owner, Valid: *owner != ""},
+		Size:    int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "OWNER", "CURRENCY", "BALANCE", "STATUS", "CREATED AT"}}
+	for _, acc := range accounts {
+		t.append(acc.ID, acc.Owner, acc.Currency, acc.Balance, acc.Status, acc.CreatedAt)
+	}
+
+	return render(c.out, *f.output, accounts, t)
+}
+
+func (c *adminCommand) setAccountStatus(ctx context.Context, args []string, status db.AccountStatus) error {
+	f := newAdminFlags("account "+string(status), true)
+	id := f.set.Int64("id", 0, "account id")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *id <= 0 {
+		return errors.New("-id is required")
+	}
+
+	audit, err := f.audit("account.status", fmt.Sprintf("account:%d", *id), map[string]db.AccountStatus{"status": status})
+	if err != nil {
+		return err
+	}
+
+	var account db.Account
+	_, err = c.store.AuditTx(ctx, audit, func(q *db.Queries) error {
+		account, err = q.SetAccountStatus(ctx, db.SetAccountStatusParams{
+			ID:     *id,
+			Status: status,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// the account may not exist at all
+			if _, err := q.GetAccount(ctx, *id); err != nil {
+				return err
+			}
+
+			return fmt.Errorf("account [%d] is closed", *id)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.renderAccount(*f.output, account)
+}
+
+func (c *adminCommand) adjustBalance(ctx context.Context, args []string) error {
+	f := newAdminFlags("account adjust", true)
+	id := f.set.Int64("id", 0, "account id")
+	amount := f.set.Int64("amount", 0, "signed amount to add to the balance")
+	reasonCode := f.set.String("reason-code", "", "correction, goodwill, chargeback or fee_refund")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *id <= 0 || *amount == 0 || *reasonCode == "" {
+		return errors.New("-id, a non-zero -amount and -reason-code are required")
+	}
+
+	result, err := c.store.AdjustmentTx(ctx, db.AdjustmentTxParams{
+		AccountID:  *id,
+		Amount:     *amount,
+		ReasonCode: db.AdjustmentReason(*reasonCode),
+		Note:       *f.reason,
+		Operator:   *f.operator,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.renderAccount(*f.output, result.Account)
+}
+
+func (c *adminCommand) setOverdraftLimit(ctx context.Context, args []string) error {
+	f := newAdminFlags("account overdraft", true)
+	id := f.set.Int64("id", 0, "account id")
+	limit := f.set.Int64("limit", -1, "how far below zero withdrawals may take the balance")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *id <= 0 || *limit < 0 {
+		return errors.New("-id and a non-negative -limit are required")
+	}
+
+	audit, err := f.audit("account.overdraft", fmt.Sprintf("account:%d", *id), map[string]int64{"overdraft_limit": *limit})
+	if err != nil {
+		return err
+	}
+
+	var account db.Account
+	_, err = c.store.AuditTx(ctx, audit, func(q *db.Queries) error {
+		account, err = q.SetAccountOverdraftLimit(ctx, db.SetAccountOverdraftLimitParams{
+			ID:             *id,
+			OverdraftLimit: *limit,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.renderAccount(*f.output, account)
+}
+
+func (c *adminCommand) listBalances(ctx context.Context, args []string) error {
+	f := newAdminFlags("account balances", false)
+	afterID := f.set.Int64("after-id", 0, "list accounts with an id greater than this")
+	limit := f.set.Int("limit", 50, "maximum number of accounts")
+	mismatched := f.set.Bool("mismatched", false, "only show accounts whose balance differs from the entry sum")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	rows, err := c.store.ListAccountEntrySums(ctx, db.ListAccountEntrySumsParams{
+		AfterID: *afterID,
+		Size:    int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	result := make([]db.ListAccountEntrySumsRow, 0, len(rows))
+	t := &table{header: []string{"ID", "OWNER", "CURRENCY", "BALANCE", "ENTRIES SUM", "DIFF"}}
+	for _, row := range rows {
+		diff := row.Balance - row.EntriesSum
+		if *mismatched && diff == 0 {
+			continue
+		}
+
+		result = append(result, row)
+		t.append(row.ID, row.Owner, row.Currency, row.Balance, row.EntriesSum, diff)
+	}
+
+	return render(c.out, *f.output, result, t)
+}
+
+func (c *adminCommand) replayTransfer(ctx context.Context, args []string) error {
+	f := newAdminFlags("transfer replay", true)
+	id := f.set.Int64("id", 0, "id of the transfer to replay")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *id <= 0 {
+		return errors.New("-id is required")
+	}
+
+	transfer, err := c.store.GetTransfer(ctx, *id)
+	if err != nil {
+		return fmt.Errorf("cannot get transfer %d: %w", *id, err)
+	}
+
+	// the accounts may have been frozen or closed since the transfer
+	for _, accountID := range []int64{transfer.FromAccountID, transfer.ToAccountID} {
+		if err := c.checkAccountActive(ctx, accountID); err != nil {
+			return err
+		}
+	}
+
+	arg := db.TransferTxParams{
+		FromAccountID: transfer.FromAccountID,
+		ToAccountID:   transfer.ToAccountID,
+		Amount:        transfer.Amount,
+	}
+
+	audit, err := f.audit("transfer.replay", fmt.Sprintf("transfer:%d", *id), arg)
+	if err != nil {
+		return err
+	}
+
+	var result db.TransferTxResult
+	_, err = c.store.AuditTx(ctx, audit, func(q *db.Queries) error {
+		result, err = q.PostTransfer(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		// checked again on the locked rows, in case the status changed since
+		for _, account := range []db.Account{result.FromAccount, result.ToAccount} {
+			if account.Status != db.AccountStatusActive {
+				return fmt.Errorf("account [%d] is %s", account.ID, account.Status)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"TRANSFER", "FROM", "TO", "AMOUNT", "CREATED AT"}}
+	t.append(result.Transfer.ID, result.Transfer.FromAccountID, result.Transfer.ToAccountID, result.Transfer.Amount, result.Transfer.CreatedAt)
+	return render(c.out, *f.output, result, t)
+}
+
+func (c *adminCommand) checkAccountActive(ctx context.Context, id int64) error {
+	account, err := c.store.GetAccount(ctx, id)
+	if err != nil {
+		return fmt.Errorf("cannot get account %d: %w", id, err)
+	}
+
+	if account.Status != db.AccountStatusActive {
+		return fmt.Errorf("account [%d] is %s", account.ID, account.Status)
+	}
+
+	return nil
+}
+
+func (c *adminCommand) listAuditLogs(ctx context.Context, args []string) error {
+	f := newAdminFlags("audit list", false)
+	target := f.set.String("target", "", "audited target, e.g. account:42")
+	limit := f.set.Int("limit", 50, "maximum number of entries")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *target == "" {
+		return errors.New("-target is required")
+	}
+
+	logs, err := c.store.ListAuditLogs(ctx, db.ListAuditLogsParams{
+		Target: *target,
+		Limit:  int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "ACTOR", "ACTION", "REASON", "DETAILS", "CREATED AT"}}
+	for _, log := range logs {
+		t.append(log.ID, log.Actor, log.Action, log.Reason, string(log.Details), log.CreatedAt)
+	}
+
+	return render(c.out, *f.output, logs, t)
+}
+
+func (c *adminCommand) reconcile(ctx context.Context, args []string) error {
+	f := newAdminFlags("reconcile run", true)
+	batchSize := f.set.Int("batch-size", 500, "rows checked per query")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	audit, err := f.audit("reconcile.run", "reconciliation", map[string]int{"batch_size": *batchSize})
+	if err != nil {
+		return err
+	}
+
+	var run db.ReconciliationRun
+	_, err = c.store.AuditTx(ctx, audit, func(*db.Queries) error {
+		run, err = reconcile.NewJob(c.store, int32(*batchSize)).Run(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"RUN", "STATUS", "ACCOUNTS", "TRANSFERS", "DISCREPANCIES"}}
+	t.append(run.ID, run.Status, run.AccountsChecked, run.TransfersChecked, run.Discrepancies)
+	return render(c.out, *f.output, run, t)
+}
+
+func (c *adminCommand) listProducts(ctx context.Context, args []string) error {
+	f := newAdminFlags("product list", false)
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	products, err := c.store.ListProducts(ctx)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"CODE", "NAME", "ANNUAL RATE BPS"}}
+	for _, product := range products {
+		t.append(product.Code, product.Name, product.AnnualRateBps)
+	}
+
+	return render(c.out, *f.output, products, t)
+}
+
+func (c *adminCommand) setProductRate(ctx context.Context, args []string) error {
+	f := newAdminFlags("product rate", true)
+	code := f.set.String("code", "", "product code")
+	rate := f.set.Int64("rate-bps", -1, "annual interest rate in basis points")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *code == "" || *rate < 0 {
+		return errors.New("-code and a non-negative -rate-bps are required")
+	}
+
+	audit, err := f.audit("product.rate", "product:"+*code, map[string]int64{"annual_rate_bps": *rate})
+	if err != nil {
+		return err
+	}
+
+	var product db.Product
+	_, err = c.store.AuditTx(ctx, audit, func(q *db.Queries) error {
+		product, err = q.SetProductRate(ctx, db.SetProductRateParams{
+			Code:          *code,
+			AnnualRateBps: *rate,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"CODE", "NAME", "ANNUAL RATE BPS"}}
+	t.append(product.Code, product.Name, product.AnnualRateBps)
+	return render(c.out, *f.output, product, t)
+}
+
+func (c *adminCommand) accrueInterest(ctx context.Context, args []string) error {
+	f := newAdminFlags("interest accrue", true)
+	day := f.set.String("day", "", "day to accrue, YYYY-MM-DD")
+	batchSize := f.set.Int("batch-size", 500, "accounts accrued per query")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	d, err := time.Parse(time.DateOnly, *day)
+	if err != nil {
+		return fmt.Errorf("invalid -day: %w", err)
+	}
+
+	audit, err := f.audit("interest.accrue", "interest:"+*day, map[string]string{"day": *day})
+	if err != nil {
+		return err
+	}
+
+	// the job commits in batches, so the entry is only written once all of
+	// them are; a failed run can be repeated, as accrued accounts are skipped
+	var written int
+	_, err = c.store.AuditTx(ctx, audit, func(*db.Queries) error {
+		written, err = interest.NewJob(c.store, int32(*batchSize)).Accrue(ctx, d)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	result := map[string]any{"day": *day, "accruals": written}
+	t := &table{header: []string{"DAY", "ACCRUALS"}}
+	t.append(*day, written)
+	return render(c.out, *f.output, result, t)
+}
+
+func (c *adminCommand) postInterest(ctx context.Context, args []string) error {
+	f := newAdminFlags("interest post", true)
+	period := f.set.String("period", "", "month to post, YYYY-MM")
+	batchSize := f.set.Int("batch-size", 500, "accounts posted per query")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	p, err := time.Parse("2006-01", *period)
+	if err != nil {
+		return fmt.Errorf("invalid -period: %w", err)
+	}
+
+	audit, err := f.audit("interest.post", "interest:"+*period, map[string]string{"period": *period})
+	if err != nil {
+		return err
+	}
+
+	// like accruals, postings are committed per account and skipped when
+	// repeated
+	var posted int
+	_, err = c.store.AuditTx(ctx, audit, func(*db.Queries) error {
+		posted, err = interest.NewJob(c.store, int32(*batchSize)).Post(ctx, p)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	result := map[string]any{"period": *period, "postings": posted}
+	t := &table{header: []string{"PERIOD", "POSTINGS"}}
+	t.append(*period, posted)
+	return render(c.out, *f.output, result, t)
+}
+
+func (c *adminCommand) listLimits(ctx context.Context, args []string) error {
+	f := newAdminFlags("limit list", false)
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	limits, err := c.store.ListTransferLimits(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.renderLimits(*f.output, limits)
+}
+
+// setLimit replaces every limit of a scope; a negative value leaves the limit
+// to the less specific scopes.
+func (c *adminCommand) setLimit(ctx context.Context, args []string) error {
+	f := newAdminFlags("limit set", true)
+	scope := f.set.String("scope", "", "product, user or account")
+	ref := f.set.String("ref", "", "product code, username or account id")
+	maxPerTransfer := f.set.Int64("max-per-transfer", -1, "largest single transfer")
+	daily := f.set.Int64("daily", -1, "outgoing total per UTC day")
+	monthly := f.set.Int64("monthly", -1, "outgoing total per UTC month")
+	hourlyCount := f.set.Int64("hourly-count", -1, "transfers in any rolling hour")
+	if err := f.parse(args); err != nil {
+		return err
+	}
+
+	if *scope == "" || *ref == "" {
+		return errors.New("-scope and -ref are required")
+	}
+
+	arg := db.UpsertTransferLimitParams{
+		Scope:          db.LimitScope(*scope),
+		ScopeRef:       *ref,
+		MaxPerTransfer: optionalInt64(*maxPerTransfer),
+		DailyTotal:     optionalInt64(*daily),
+		MonthlyTotal:   optionalInt64(*monthly),
+		HourlyCount:    optionalInt64(*hourlyCount),
+	}
+
+	audit, err := f.audit("limit.set", fmt.Sprintf("limit:%s:%s", *scope, *ref), arg)
+	if err != nil {
+		return err
+	}
+
+	var limit db.TransferLimit
+	_, err = c.store.AuditTx(ctx, audit, func(q *db.Queries) error {
+		limit, err = q.UpsertTransferLimit(ctx, arg)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.renderLimits(*f.output, []db.TransferLimit{limit})
+}
+
+func (c *adminCommand) renderLimits(format string, limits []db.TransferLimit) error {
+	t := &table{header: []string{"SCOPE", "REF", "MAX PER TRANSFER", "DAILY", "MONTHLY", "HOURLY COUNT"}}
+	for _, l := range limits {
+		t.append(l.Scope, l.ScopeRef, nullInt64(l.MaxPerTransfer), nullInt64(l.DailyTotal), nullInt64(l.MonthlyTotal), nullInt64(l.HourlyCount))
+	}
+
+	return render(c.out, format, limits, t)
+}
+
+func optionalInt64(v int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: v, Valid: v >= 0}
+}
+
+func nullInt64(v sql.NullInt64) any {
+	if !v.Valid {
+		return "-"
+	}
+
+	return v.Int64
+}
+
+func (c *adminCommand) renderAccount(format string, account db.Account) error {
+	t := &table{header: []string{"ID", "OWNER", "CURRENCY", "BALANCE", "STATUS"}}
+	t.append(account.ID, account.Owner, account.Currency, account.Balance, account.Status)
+	return render(c.out, format, account, t)
+}
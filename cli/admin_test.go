@@ -0,0 +1,209 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func newTestAdminCommand(t *testing.T) (*adminCommand, *mockdb.MockStore, *bytes.Buffer) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	out := &bytes.Buffer{}
+
+	passwords, err := util.NewPasswordHasher(util.PasswordParams{})
+	require.NoError(t, err)
+
+	policy, err := util.NewPasswordPolicy(0, 0, "", "")
+	require.NoError(t, err)
+
+	return &adminCommand{store: store, out: out, passwords: passwords, policy: policy}, store, out
+}
+
+func TestMutatingCommandRequiresReason(t *testing.T) {
+	commands := map[string][]string{
+		"user create":       {"-username", "alice", "-full-name", "Alice", "-email", "a@mail.com", "-password", "secret123"},
+		"user erase":        {"-username", "alice"},
+		"user promote":      {"-username", "alice"},
+		"account freeze":    {"-id", "1"},
+		"account unfreeze":  {"-id", "1"},
+		"account adjust":    {"-id", "1", "-amount", "10"},
+		"account overdraft": {"-id", "1", "-limit", "100"},
+		"transfer replay":   {"-id", "1"},
+		"product rate":      {"-code", "savings", "-rate-bps", "250"},
+		"limit set":         {"-scope", "user", "-ref", "alice", "-daily", "1000"},
+		"reconcile run":     {},
+		"interest accrue":   {"-day", "2024-03-05"},
+		"interest post":     {"-period", "2024-03"},
+	}
+
+	for name, args := range commands {
+		t.Run(name, func(t *testing.T) {
+			cmd, store, _ := newTestAdminCommand(t)
+			store.EXPECT().AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().GetTransfer(gomock.Any(), gomock.Any()).Times(0)
+			store.EXPECT().EraseUserTx(gomock.Any(), gomock.Any()).Times(0)
+
+			err := cmd.run(context.Background(), name, args)
+			require.ErrorContains(t, err, "-reason is required")
+		})
+	}
+}
+
+func TestCreateUserPasswordPolicy(t *testing.T) {
+	cmd, store, _ := newTestAdminCommand(t)
+	store.EXPECT().AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	err := cmd.run(context.Background(), "user create", []string{
+		"-username", "alice", "-full-name", "Alice", "-email", "a@mail.com", "-password", "password123",
+		"-operator", "bob", "-reason", "new customer",
+	})
+	require.ErrorIs(t, err, util.ErrPasswordBreached)
+}
+
+func TestEraseUser(t *testing.T) {
+	cmd, store, out := newTestAdminCommand(t)
+	erased := db.User{
+		Username: "alice",
+		FullName: "Erased user 2bd806c97f0e",
+		Email:    "2bd806c97f0e@erased.invalid",
+	}
+
+	store.EXPECT().
+		EraseUserTx(gomock.Any(), gomock.Eq(db.EraseUserTxParams{
+			Username: "alice",
+			Operator: "bob",
+			Reason:   "erasure request #42",
+		})).
+		Times(1).
+		Return(erased, nil)
+
+	err := cmd.run(context.Background(), "user erase", []string{
+		"-username", "alice", "-operator", "bob", "-reason", "erasure request #42", "-output", "json",
+	})
+	require.NoError(t, err)
+
+	var got db.User
+	err = json.Unmarshal(out.Bytes(), &got)
+	require.NoError(t, err)
+	require.Equal(t, erased.Email, got.Email)
+}
+
+func TestListBalancesMismatched(t *testing.T) {
+	rows := []db.ListAccountEntrySumsRow{
+		{ID: 1, Owner: util.RandomOnwer(), Currency: "USD", Balance: 100, EntriesSum: 100},
+		{ID: 2, Owner: util.RandomOnwer(), Currency: "EUR", Balance: 50, EntriesSum: 20},
+	}
+
+	cmd, store, out := newTestAdminCommand(t)
+	store.EXPECT().
+		ListAccountEntrySums(gomock.Any(), gomock.Eq(db.ListAccountEntrySumsParams{AfterID: 0, Size: 50})).
+		Times(1).
+		Return(rows, nil)
+
+	err := cmd.run(context.Background(), "account balances", []string{"-mismatched", "-output", "json"})
+	require.NoError(t, err)
+
+	var got []db.ListAccountEntrySumsRow
+	err = json.Unmarshal(out.Bytes(), &got)
+	require.NoError(t, err)
+	require.Equal(t, rows[1:], got)
+}
+
+func TestListAccountsTable(t *testing.T) {
+	account := db.Account{
+		ID:       util.RandomInt(1, 1000),
+		Owner:    util.RandomOnwer(),
+		Balance:  util.RandomMoney(),
+		Currency: util.RandomCurrency(),
+		Status:   db.AccountStatusFrozen,
+	}
+
+	cmd, store, out := newTestAdminCommand(t)
+	store.EXPECT().
+		ListAllAccounts(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.Account{account}, nil)
+
+	err := cmd.run(context.Background(), "account list", []string{"-owner", account.Owner})
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "OWNER")
+	require.Contains(t, out.String(), account.Owner)
+	require.Contains(t, out.String(), string(db.AccountStatusFrozen))
+}
+
+func TestPostInterestInvalidPeriod(t *testing.T) {
+	cmd, store, _ := newTestAdminCommand(t)
+	store.EXPECT().ListInterestAccrualSums(gomock.Any(), gomock.Any()).Times(0)
+
+	err := cmd.run(context.Background(), "interest post", []string{"-period", "2023-13", "-operator", "bob", "-reason", "month end"})
+	require.ErrorContains(t, err, "invalid -period")
+}
+
+func TestPostInterestAudited(t *testing.T) {
+	cmd, store, out := newTestAdminCommand(t)
+	gomock.InOrder(
+		store.EXPECT().
+			AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).
+			Times(1).
+			DoAndReturn(func(_ context.Context, arg db.CreateAuditLogParams, fn func(*db.Queries) error) (db.AuditLog, error) {
+				require.Equal(t, "bob", arg.Actor)
+				require.Equal(t, "interest.post", arg.Action)
+				require.Equal(t, "interest:2024-03", arg.Target)
+				require.Equal(t, "month end", arg.Reason)
+				return db.AuditLog{}, fn(nil)
+			}),
+		store.EXPECT().
+			ListInterestAccrualSums(gomock.Any(), gomock.Any()).
+			Times(1).
+			Return(nil, nil),
+	)
+
+	err := cmd.run(context.Background(), "interest post", []string{"-period", "2024-03", "-operator", "bob", "-reason", "month end"})
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "POSTINGS")
+}
+
+func TestReplayTransferInactiveAccount(t *testing.T) {
+	transfer := db.Transfer{ID: 7, FromAccountID: 1, ToAccountID: 2, Amount: 10}
+
+	cmd, store, _ := newTestAdminCommand(t)
+	store.EXPECT().GetTransfer(gomock.Any(), gomock.Eq(transfer.ID)).Times(1).Return(transfer, nil)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(transfer.FromAccountID)).
+		Times(1).
+		Return(db.Account{ID: transfer.FromAccountID, Status: db.AccountStatusActive}, nil)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(transfer.ToAccountID)).
+		Times(1).
+		Return(db.Account{ID: transfer.ToAccountID, Status: db.AccountStatusFrozen}, nil)
+	store.EXPECT().AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	err := cmd.run(context.Background(), "transfer replay", []string{"-id", "7", "-operator", "bob", "-reason", "lost at the rail"})
+	require.ErrorContains(t, err, "account [2] is frozen")
+}
+
+func TestPromoteUser(t *testing.T) {
+	cmd, store, out := newTestAdminCommand(t)
+	store.EXPECT().
+		AuditTx(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CreateAuditLogParams, _ func(*db.Queries) error) (db.AuditLog, error) {
+			require.Equal(t, "bob", arg.Actor)
+			require.Equal(t, "user.role", arg.Action)
+			require.Equal(t, "user:alice", arg.Target)
+			require.JSONEq(t, `{"role":"admin"}`, string(arg.Details))
+			return db.AuditLog{}, nil
+		})
+
+	err := cmd.run(context.Background(), "user promote", []string{"-username", "alice", "-operator", "bob", "-reason", "new operator"})
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "ROLE")
+}
@@ -0,0 +1,28 @@
+// Package cli implements the subcommands of the demo-bank binary. Running the
+// binary without arguments starts the HTTP server instead.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aulas/demo-bank/util"
+)
+
+func Run(config *util.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("no command provided")
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runMigrate(config, args[1:], out)
+	case "admin":
+		return runAdmin(config, args[1:], out)
+	case "token":
+		return runToken(args[1:], out)
+	}
+
+	return fmt.Errorf("unknown command %q", args[0])
+}
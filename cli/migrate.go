@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/aulas/demo-bank/db/migrations"
+	"github.com/aulas/demo-bank/util"
+)
+
+const migrateUsage = "usage: migrate up | down [-steps N | -all] | status"
+
+func runMigrate(config *util.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		err := migrations.Up(config.DBSource)
+		if err != nil {
+			return err
+		}
+
+		return printMigrateStatus(config, out)
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ContinueOnError)
+		steps := flags.Int("steps", 1, "number of migrations to revert")
+		all := flags.Bool("all", false, "revert every migration")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		n := *steps
+		if *all {
+			n = 0
+		} else if n <= 0 {
+			return errors.New("steps must be positive")
+		}
+
+		err := migrations.Down(config.DBSource, n)
+		if err != nil {
+			return err
+		}
+
+		return printMigrateStatus(config, out)
+	case "status":
+		return printMigrateStatus(config, out)
+	}
+
+	return errors.New(migrateUsage)
+}
+
+func printMigrateStatus(config *util.Config, out io.Writer) error {
+	status, err := migrations.GetStatus(config.DBSource)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "version: %d, latest: %d, dirty: %t\n", status.Version, status.Latest, status.Dirty)
+	return err
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+// table is the tabular rendering of a command result.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) append(row ...any) {
+	cells := make([]string, len(row))
+	for i, cell := range row {
+		cells[i] = fmt.Sprint(cell)
+	}
+
+	t.rows = append(t.rows, cells)
+}
+
+// render writes v as indented JSON, or t as an aligned table.
+func render(out io.Writer, format string, v any, t *table) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case formatTable:
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, strings.Join(t.header, "\t"))
+		for _, row := range t.rows {
+			fmt.Fprintln(w, strings.Join(row, "\t"))
+		}
+
+		return w.Flush()
+	}
+
+	return fmt.Errorf("unsupported output format %q", format)
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/aulas/demo-bank/token"
+)
+
+const tokenUsage = "usage: token keygen [-id ID]"
+
+func runToken(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(tokenUsage)
+	}
+
+	switch args[0] {
+	case "keygen":
+		flags := flag.NewFlagSet("token keygen", flag.ContinueOnError)
+		id := flags.String("id", time.Now().UTC().Format("2006-01-02"), "id of the new key")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		secretKey, publicKey := token.GenerateSigningKey()
+		_, err := fmt.Fprintf(out,
+			"# signer\nTOKEN_SIGNING_KEY_ID=%s\nTOKEN_SIGNING_KEY=%s\n# verifiers, appended to TOKEN_VERIFICATION_KEYS\n%s:%s\n",
+			*id, secretKey, *id, publicKey)
+		return err
+	}
+
+	return errors.New(tokenUsage)
+}
@@ -0,0 +1,270 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+)
+
+// Files in every archive.
+const (
+	ProfileFile   = "profile.json"
+	AccountsFile  = "accounts.csv"
+	EntriesFile   = "entries.csv"
+	TransfersFile = "transfers.csv"
+	SessionsFile  = "sessions.json"
+)
+
+type profile struct {
+	Username          string      `json:"username"`
+	FullName          string      `json:"full_name"`
+	Email             string      `json:"email"`
+	IsEmailVerified   bool        `json:"is_email_verified"`
+	Role              db.UserRole `json:"role"`
+	PasswordChangedAt time.Time   `json:"password_changed_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+// session is an OAuth grant. Access tokens from logging in are not stored, so
+// grants are the only sessions there are records of.
+type session struct {
+	ID        string     `json:"id"`
+	ClientID  string     `json:"client_id"`
+	Scopes    []string   `json:"scopes"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type archiveWriter struct {
+	store     db.Store
+	zip       *zip.Writer
+	username  string
+	batchSize int32
+}
+
+// WriteArchive writes a ZIP of everything stored about the user to w: their
+// profile, accounts with their entries, the transfers they took part in and
+// their sessions. Password hashes and other secrets are left out.
+func WriteArchive(ctx context.Context, store db.Store, username string, batchSize int32, w io.Writer) error {
+	a := &archiveWriter{
+		store:     store,
+		zip:       zip.NewWriter(w),
+		username:  username,
+		batchSize: batchSize,
+	}
+
+	for _, write := range []func(context.Context) error{
+		a.writeProfile,
+		a.writeAccounts,
+		a.writeEntries,
+		a.writeTransfers,
+		a.writeSessions,
+	} {
+		if err := write(ctx); err != nil {
+			return err
+		}
+	}
+
+	return a.zip.Close()
+}
+
+func (a *archiveWriter) writeJSON(name string, v any) error {
+	f, err := a.zip.Create(name)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (a *archiveWriter) writeProfile(ctx context.Context) error {
+	user, err := a.store.GetUser(ctx, a.username)
+	if err != nil {
+		return err
+	}
+
+	return a.writeJSON(ProfileFile, profile{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
+		Role:              user.Role,
+		PasswordChangedAt: user.PasswordChangedAt,
+		UpdatedAt:         user.UpdatedAt,
+		CreatedAt:         user.CreatedAt,
+	})
+}
+
+func (a *archiveWriter) writeSessions(ctx context.Context) error {
+	grants, err := a.store.ListUserOAuthGrants(ctx, a.username)
+	if err != nil {
+		return err
+	}
+
+	sessions := make([]session, len(grants))
+	for i, grant := range grants {
+		sessions[i] = session{
+			ID:        grant.ID.String(),
+			ClientID:  grant.ClientID,
+			Scopes:    grant.Scopes,
+			CreatedAt: grant.CreatedAt,
+		}
+		if grant.RevokedAt.Valid {
+			sessions[i].RevokedAt = &grant.RevokedAt.Time
+		}
+	}
+
+	return a.writeJSON(SessionsFile, sessions)
+}
+
+func (a *archiveWriter) createCSV(name string, header ...string) (*csv.Writer, error) {
+	f, err := a.zip.Create(name)
+	if err != nil {
+		return nil, err
+	}
+
+	w := csv.NewWriter(f)
+	return w, w.Write(header)
+}
+
+func (a *archiveWriter) writeAccounts(ctx context.Context) error {
+	w, err := a.createCSV(AccountsFile, "id", "currency", "product", "balance", "overdraft_limit", "status", "created_at")
+	if err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		accounts, err := a.store.ListAllAccounts(ctx, db.ListAllAccountsParams{
+			AfterID: afterID,
+			Owner:   sql.NullString{String: a.username, Valid: true},
+			Size:    a.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, acc := range accounts {
+			err = w.Write([]string{
+				formatInt(acc.ID),
+				acc.Currency,
+				acc.Product,
+				formatInt(acc.Balance),
+				formatInt(acc.OverdraftLimit),
+				string(acc.Status),
+				formatTime(acc.CreatedAt),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(accounts) < int(a.batchSize) {
+			w.Flush()
+			return w.Error()
+		}
+
+		afterID = accounts[len(accounts)-1].ID
+	}
+}
+
+func (a *archiveWriter) writeEntries(ctx context.Context) error {
+	w, err := a.createCSV(EntriesFile, "id", "account_id", "transfer_id", "amount", "created_at")
+	if err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		entries, err := a.store.ListOwnerEntries(ctx, db.ListOwnerEntriesParams{
+			Owner:   a.username,
+			AfterID: afterID,
+			Size:    a.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			var transferID string
+			if entry.TransferID.Valid {
+				transferID = formatInt(entry.TransferID.Int64)
+			}
+
+			err = w.Write([]string{
+				formatInt(entry.ID),
+				formatInt(entry.AccountID),
+				transferID,
+				formatInt(entry.Amount),
+				formatTime(entry.CreatedAt),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(entries) < int(a.batchSize) {
+			w.Flush()
+			return w.Error()
+		}
+
+		afterID = entries[len(entries)-1].ID
+	}
+}
+
+func (a *archiveWriter) writeTransfers(ctx context.Context) error {
+	w, err := a.createCSV(TransfersFile, "id", "from_account_id", "to_account_id", "amount", "created_at")
+	if err != nil {
+		return err
+	}
+
+	var afterID int64
+	for {
+		transfers, err := a.store.ListOwnerTransfers(ctx, db.ListOwnerTransfersParams{
+			Owner:   a.username,
+			AfterID: afterID,
+			Size:    a.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, transfer := range transfers {
+			err = w.Write([]string{
+				formatInt(transfer.ID),
+				formatInt(transfer.FromAccountID),
+				formatInt(transfer.ToAccountID),
+				formatInt(transfer.Amount),
+				formatTime(transfer.CreatedAt),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(transfers) < int(a.batchSize) {
+			w.Flush()
+			return w.Error()
+		}
+
+		afterID = transfers[len(transfers)-1].ID
+	}
+}
+
+func formatInt(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
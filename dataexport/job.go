@@ -0,0 +1,144 @@
+// Package dataexport hands users everything stored about them. Exports are
+// requested through the API and built in the background into a ZIP archive,
+// which stays downloadable until it expires.
+package dataexport
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+)
+
+const (
+	defaultBatchSize = 500
+	defaultRetention = 7 * 24 * time.Hour
+	maxErrorLength   = 500
+
+	// claimTimeout is how long a running export may take before another
+	// worker takes it over.
+	claimTimeout = 30 * time.Minute
+)
+
+var (
+	completedTotal = expvar.NewInt("data_exports_completed_total")
+	failedTotal    = expvar.NewInt("data_exports_failed_total")
+)
+
+type Job struct {
+	store     db.Store
+	batchSize int32
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewJob returns a job building pending exports. Archives can be downloaded
+// for retention after they are built.
+func NewJob(store db.Store, batchSize int32, retention time.Duration) *Job {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+
+	return &Job{
+		store:     store,
+		batchSize: batchSize,
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// RunOnce builds the oldest pending export, or one whose worker has not
+// finished within claimTimeout. ok is false when there was none. An export
+// that cannot be built is marked failed, so it is not retried.
+func (j *Job) RunOnce(ctx context.Context) (export db.DataExport, ok bool, err error) {
+	export, err = j.store.ClaimDataExport(ctx, j.now().Add(-claimTimeout))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return export, false, nil
+		}
+
+		return export, false, fmt.Errorf("cannot claim data export: %w", err)
+	}
+
+	var archive bytes.Buffer
+	err = WriteArchive(ctx, j.store, export.Username, j.batchSize, &archive)
+	if err != nil {
+		failedTotal.Add(1)
+
+		msg := err.Error()
+		if len(msg) > maxErrorLength {
+			msg = msg[:maxErrorLength]
+		}
+
+		_, failErr := j.store.FailDataExport(ctx, db.FailDataExportParams{
+			ID:    export.ID,
+			Error: msg,
+		})
+		if failErr != nil {
+			return export, true, fmt.Errorf("cannot mark data export %d failed: %w", export.ID, failErr)
+		}
+
+		return export, true, fmt.Errorf("cannot build data export %d: %w", export.ID, err)
+	}
+
+	export, err = j.store.CompleteDataExportTx(ctx, db.CompleteDataExportParams{
+		ID:        export.ID,
+		Archive:   archive.Bytes(),
+		ExpiresAt: sql.NullTime{Time: j.now().Add(j.retention), Valid: true},
+	})
+	if err != nil {
+		return export, true, fmt.Errorf("cannot complete data export %d: %w", export.ID, err)
+	}
+
+	completedTotal.Add(1)
+	return export, true, nil
+}
+
+// Run builds pending exports until there are none left, then clears the
+// archives of expired ones.
+func (j *Job) Run(ctx context.Context) error {
+	for {
+		export, ok, err := j.RunOnce(ctx)
+		if err != nil {
+			log.Printf("data export %d failed: %v", export.ID, err)
+		}
+
+		if !ok {
+			break
+		}
+	}
+
+	_, err := j.store.PurgeExpiredDataExports(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot purge expired data exports: %w", err)
+	}
+
+	return nil
+}
+
+// Schedule runs the job every interval until ctx is cancelled.
+func (j *Job) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := j.Run(ctx); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package dataexport
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func readZipFile(t *testing.T, r *zip.Reader, name string) []byte {
+	f, err := r.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	return data
+}
+
+func readCSV(t *testing.T, r *zip.Reader, name string) [][]string {
+	records, err := csv.NewReader(bytes.NewReader(readZipFile(t, r, name))).ReadAll()
+	require.NoError(t, err)
+	return records
+}
+
+func TestRunOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	job := NewJob(store, 2, time.Hour)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	user := db.User{
+		Username:       "alice",
+		HashedPassword: "secret-hash",
+		FullName:       "Alice Doe",
+		Email:          "alice@mail.com",
+		CreatedAt:      now,
+	}
+	export := db.DataExport{ID: 3, Username: user.Username, Status: db.DataExportStatusRunning}
+
+	// exports claimed before now-claimTimeout are taken over
+	store.EXPECT().ClaimDataExport(gomock.Any(), gomock.Eq(now.Add(-claimTimeout))).Times(1).Return(export, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Eq(user.Username)).Times(1).Return(user, nil)
+
+	// the first batch is full, so the job has to ask for the next one
+	owner := sql.NullString{String: user.Username, Valid: true}
+	gomock.InOrder(
+		store.EXPECT().
+			ListAllAccounts(gomock.Any(), gomock.Eq(db.ListAllAccountsParams{AfterID: 0, Owner: owner, Size: 2})).
+			Return([]db.Account{
+				{ID: 1, Owner: user.Username, Currency: "USD", Status: db.AccountStatusClosed, CreatedAt: now},
+				{ID: 4, Owner: user.Username, Currency: "EUR", Balance: 20, Status: db.AccountStatusActive, CreatedAt: now},
+			}, nil),
+		store.EXPECT().
+			ListAllAccounts(gomock.Any(), gomock.Eq(db.ListAllAccountsParams{AfterID: 4, Owner: owner, Size: 2})).
+			Return([]db.Account{}, nil),
+	)
+
+	store.EXPECT().
+		ListOwnerEntries(gomock.Any(), gomock.Eq(db.ListOwnerEntriesParams{Owner: user.Username, AfterID: 0, Size: 2})).
+		Return([]db.Entry{
+			{ID: 9, AccountID: 4, Amount: 20, TransferID: sql.NullInt64{Int64: 5, Valid: true}, CreatedAt: now},
+		}, nil)
+	store.EXPECT().
+		ListOwnerTransfers(gomock.Any(), gomock.Eq(db.ListOwnerTransfersParams{Owner: user.Username, AfterID: 0, Size: 2})).
+		Return([]db.Transfer{{ID: 5, FromAccountID: 7, ToAccountID: 4, Amount: 20, CreatedAt: now}}, nil)
+
+	grant := db.OauthGrant{ID: uuid.New(), ClientID: "cli_1", Username: user.Username, Scopes: []string{"accounts:read"}, CreatedAt: now}
+	store.EXPECT().ListUserOAuthGrants(gomock.Any(), gomock.Eq(user.Username)).Return([]db.OauthGrant{grant}, nil)
+
+	var archive []byte
+	store.EXPECT().
+		CompleteDataExportTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.CompleteDataExportParams) (db.DataExport, error) {
+			require.Equal(t, export.ID, arg.ID)
+			require.Equal(t, sql.NullTime{Time: now.Add(time.Hour), Valid: true}, arg.ExpiresAt)
+			archive = arg.Archive
+
+			export.Status = db.DataExportStatusCompleted
+			return export, nil
+		})
+
+	got, ok, err := job.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, db.DataExportStatusCompleted, got.Status)
+
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	require.NoError(t, err)
+
+	var gotProfile map[string]any
+	require.NoError(t, json.Unmarshal(readZipFile(t, r, ProfileFile), &gotProfile))
+	require.Equal(t, user.Email, gotProfile["email"])
+	require.NotContains(t, gotProfile, "hashed_password")
+
+	accounts := readCSV(t, r, AccountsFile)
+	require.Len(t, accounts, 3)
+	require.Equal(t, []string{"4", "EUR", "", "20", "0", "active", "2024-03-01T12:00:00Z"}, accounts[2])
+
+	entries := readCSV(t, r, EntriesFile)
+	require.Equal(t, []string{"9", "4", "5", "20", "2024-03-01T12:00:00Z"}, entries[1])
+
+	transfers := readCSV(t, r, TransfersFile)
+	require.Equal(t, []string{"5", "7", "4", "20", "2024-03-01T12:00:00Z"}, transfers[1])
+
+	var sessions []session
+	require.NoError(t, json.Unmarshal(readZipFile(t, r, SessionsFile), &sessions))
+	require.Len(t, sessions, 1)
+	require.Equal(t, grant.ID.String(), sessions[0].ID)
+	require.Nil(t, sessions[0].RevokedAt)
+}
+
+func TestRunOnceNothingPending(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	job := NewJob(store, 0, 0)
+
+	store.EXPECT().ClaimDataExport(gomock.Any(), gomock.Any()).Times(1).Return(db.DataExport{}, sql.ErrNoRows)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(0)
+
+	_, ok, err := job.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestRunOnceFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	job := NewJob(store, 0, 0)
+
+	export := db.DataExport{ID: 3, Username: "alice", Status: db.DataExportStatusRunning}
+	store.EXPECT().ClaimDataExport(gomock.Any(), gomock.Any()).Times(1).Return(export, nil)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+	store.EXPECT().
+		FailDataExport(gomock.Any(), gomock.Eq(db.FailDataExportParams{ID: export.ID, Error: sql.ErrConnDone.Error()})).
+		Times(1)
+	store.EXPECT().CompleteDataExportTx(gomock.Any(), gomock.Any()).Times(0)
+
+	_, ok, err := job.RunOnce(context.Background())
+	require.ErrorIs(t, err, sql.ErrConnDone)
+	require.True(t, ok)
+}
+
+func TestRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	job := NewJob(store, 0, 0)
+
+	// a failed export does not stop the job from building the next one
+	gomock.InOrder(
+		store.EXPECT().ClaimDataExport(gomock.Any(), gomock.Any()).Return(db.DataExport{ID: 1, Username: "alice"}, nil),
+		store.EXPECT().ClaimDataExport(gomock.Any(), gomock.Any()).Return(db.DataExport{}, sql.ErrNoRows),
+		store.EXPECT().PurgeExpiredDataExports(gomock.Any()).Return(int64(2), nil),
+	)
+	store.EXPECT().GetUser(gomock.Any(), gomock.Any()).Times(1).Return(db.User{}, sql.ErrConnDone)
+	store.EXPECT().FailDataExport(gomock.Any(), gomock.Any()).Times(1)
+
+	err := job.Run(context.Background())
+	require.NoError(t, err)
+}
@@ -0,0 +1,20 @@
+CREATE TYPE "account_status" AS ENUM (
+  'active',
+  'frozen'
+);
+
+ALTER TABLE "accounts" ADD COLUMN "status" account_status NOT NULL DEFAULT 'active';
+
+CREATE TABLE "audit_logs" (
+  "id" bigserial PRIMARY KEY,
+  "actor" varchar NOT NULL,
+  "action" varchar NOT NULL,
+  "target" varchar NOT NULL,
+  "reason" varchar NOT NULL,
+  "details" jsonb NOT NULL DEFAULT '{}',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "audit_logs" ("target");
+
+COMMENT ON COLUMN "audit_logs"."target" IS 'e.g. account:42, user:alice, transfer:7';
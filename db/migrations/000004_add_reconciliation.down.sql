@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS reconciliation_discrepancies;
+DROP TABLE IF EXISTS reconciliation_runs;
+DROP TYPE IF EXISTS reconciliation_status;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+DROP TYPE IF EXISTS user_role;
+ALTER TABLE entries DROP COLUMN IF EXISTS transfer_id;
@@ -0,0 +1,59 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
+
+-- TransferTx inserts a transfer and its two entries in one transaction, so
+-- they share created_at (now() is the transaction start time).
+UPDATE "entries" e
+SET "transfer_id" = t."id"
+FROM "transfers" t
+WHERE e."transfer_id" IS NULL
+   AND e."created_at" = t."created_at"
+   AND (
+      (e."account_id" = t."from_account_id" AND e."amount" = -t."amount")
+      OR (e."account_id" = t."to_account_id" AND e."amount" = t."amount")
+   );
+
+CREATE TYPE "user_role" AS ENUM (
+  'customer',
+  'admin'
+);
+
+ALTER TABLE "users" ADD COLUMN "role" user_role NOT NULL DEFAULT 'customer';
+
+CREATE TYPE "reconciliation_status" AS ENUM (
+  'running',
+  'completed',
+  'failed'
+);
+
+CREATE TABLE "reconciliation_runs" (
+  "id" bigserial PRIMARY KEY,
+  "status" reconciliation_status NOT NULL DEFAULT 'running',
+  "accounts_checked" bigint NOT NULL DEFAULT 0,
+  "transfers_checked" bigint NOT NULL DEFAULT 0,
+  "discrepancies" bigint NOT NULL DEFAULT 0,
+  "error" varchar NOT NULL DEFAULT '',
+  "started_at" timestamptz NOT NULL DEFAULT (now()),
+  "finished_at" timestamptz
+);
+
+CREATE TABLE "reconciliation_discrepancies" (
+  "id" bigserial PRIMARY KEY,
+  "run_id" bigint NOT NULL,
+  "kind" varchar NOT NULL,
+  "account_id" bigint,
+  "transfer_id" bigint,
+  "expected" bigint NOT NULL,
+  "actual" bigint NOT NULL,
+  "detail" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "reconciliation_discrepancies" ("run_id");
+
+COMMENT ON COLUMN "reconciliation_discrepancies"."kind" IS 'account_balance or transfer_entries';
+
+ALTER TABLE "reconciliation_discrepancies" ADD FOREIGN KEY ("run_id") REFERENCES "reconciliation_runs" ("id");
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS adjustments;
+DROP TYPE IF EXISTS adjustment_reason;
+DELETE FROM accounts WHERE owner = 'suspense';
+DELETE FROM users WHERE username = 'suspense';
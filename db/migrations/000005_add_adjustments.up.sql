@@ -0,0 +1,35 @@
+-- Internal accounts are owned by reserved users that cannot log in: their
+-- password hash is empty, so no password ever matches it.
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email") VALUES
+  ('suspense', '', 'Suspense', 'suspense@system.invalid');
+
+INSERT INTO "accounts" ("owner", "balance", "currency") VALUES
+  ('suspense', 0, 'USD'),
+  ('suspense', 0, 'EUR'),
+  ('suspense', 0, 'BRL');
+
+CREATE TYPE "adjustment_reason" AS ENUM (
+  'correction',
+  'goodwill',
+  'chargeback',
+  'fee_refund'
+);
+
+CREATE TABLE "adjustments" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "transfer_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "reason_code" adjustment_reason NOT NULL,
+  "note" varchar NOT NULL DEFAULT '',
+  "operator" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "adjustments" ("account_id");
+
+COMMENT ON COLUMN "adjustments"."amount" IS 'signed, from the point of view of the account';
+
+ALTER TABLE "adjustments" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "adjustments" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
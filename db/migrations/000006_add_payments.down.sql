@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS payments;
+DROP TYPE IF EXISTS payment_direction;
+ALTER TABLE accounts DROP COLUMN IF EXISTS overdraft_limit;
+DELETE FROM accounts WHERE owner = 'settlement';
+DELETE FROM users WHERE username = 'settlement';
@@ -0,0 +1,38 @@
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email") VALUES
+  ('settlement', '', 'Settlement', 'settlement@system.invalid');
+
+INSERT INTO "accounts" ("owner", "balance", "currency") VALUES
+  ('settlement', 0, 'USD'),
+  ('settlement', 0, 'EUR'),
+  ('settlement', 0, 'BRL');
+
+ALTER TABLE "accounts" ADD COLUMN "overdraft_limit" bigint NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN "accounts"."overdraft_limit" IS 'how far below zero withdrawals may take the balance';
+
+CREATE TYPE "payment_direction" AS ENUM (
+  'deposit',
+  'withdrawal'
+);
+
+CREATE TABLE "payments" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "transfer_id" bigint NOT NULL,
+  "direction" payment_direction NOT NULL,
+  "amount" bigint NOT NULL,
+  "external_reference" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "payments" ("account_id");
+
+CREATE UNIQUE INDEX ON "payments" ("direction", "external_reference");
+
+COMMENT ON COLUMN "payments"."amount" IS 'must be positive';
+
+COMMENT ON COLUMN "payments"."external_reference" IS 'id of the operation on the upstream payment rail';
+
+ALTER TABLE "payments" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "payments" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS transfer_fees;
+DELETE FROM accounts WHERE owner = 'revenue';
+DELETE FROM users WHERE username = 'revenue';
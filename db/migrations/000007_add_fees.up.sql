@@ -0,0 +1,24 @@
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email") VALUES
+  ('revenue', '', 'Revenue', 'revenue@system.invalid');
+
+INSERT INTO "accounts" ("owner", "balance", "currency") VALUES
+  ('revenue', 0, 'USD'),
+  ('revenue', 0, 'EUR'),
+  ('revenue', 0, 'BRL');
+
+CREATE TABLE "transfer_fees" (
+  "transfer_id" bigint PRIMARY KEY,
+  "fee_transfer_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "transfer_fees"."transfer_id" IS 'the transfer the fee was charged on';
+
+COMMENT ON COLUMN "transfer_fees"."fee_transfer_id" IS 'the transfer moving the fee to the revenue account';
+
+COMMENT ON COLUMN "transfer_fees"."amount" IS 'must be positive';
+
+ALTER TABLE "transfer_fees" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "transfer_fees" ADD FOREIGN KEY ("fee_transfer_id") REFERENCES "transfers" ("id");
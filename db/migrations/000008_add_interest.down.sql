@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS interest_postings;
+DROP TABLE IF EXISTS interest_accruals;
+DELETE FROM accounts WHERE owner = 'interest';
+DELETE FROM users WHERE username = 'interest';
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS owner_currency_product_key;
+-- owner_currency_key is not restored: owners may by now have a checking and a
+-- savings account in the same currency, which it would reject.
+ALTER TABLE accounts DROP COLUMN IF EXISTS product;
+DROP TABLE IF EXISTS products;
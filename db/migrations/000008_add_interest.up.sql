@@ -0,0 +1,67 @@
+CREATE TABLE "products" (
+  "code" varchar PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "annual_rate_bps" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "products"."annual_rate_bps" IS 'annual interest rate in basis points, 250 = 2.5%';
+
+INSERT INTO "products" ("code", "name", "annual_rate_bps") VALUES
+  ('checking', 'Checking', 0),
+  ('savings', 'Savings', 200);
+
+ALTER TABLE "accounts" ADD COLUMN "product" varchar NOT NULL DEFAULT 'checking';
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("product") REFERENCES "products" ("code");
+
+ALTER TABLE "accounts" DROP CONSTRAINT "owner_currency_key";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "owner_currency_product_key" UNIQUE ("owner", "currency", "product");
+
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email") VALUES
+  ('interest', '', 'Interest expense', 'interest@system.invalid');
+
+INSERT INTO "accounts" ("owner", "balance", "currency") VALUES
+  ('interest', 0, 'USD'),
+  ('interest', 0, 'EUR'),
+  ('interest', 0, 'BRL');
+
+CREATE TABLE "interest_accruals" (
+  "account_id" bigint NOT NULL,
+  "day" date NOT NULL,
+  "balance" bigint NOT NULL,
+  "annual_rate_bps" bigint NOT NULL,
+  "amount_micros" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "day")
+);
+
+COMMENT ON COLUMN "interest_accruals"."balance" IS 'end-of-day balance the interest was computed on';
+
+COMMENT ON COLUMN "interest_accruals"."amount_micros" IS 'accrued interest in millionths of the minor unit';
+
+CREATE TABLE "interest_postings" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "period" date NOT NULL,
+  "accrued_micros" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "carry_micros" bigint NOT NULL,
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "interest_postings" ("account_id", "period");
+
+COMMENT ON COLUMN "interest_postings"."period" IS 'first day of the month being posted';
+
+COMMENT ON COLUMN "interest_postings"."carry_micros" IS 'sub-unit remainder carried into the next posting';
+
+COMMENT ON COLUMN "interest_postings"."transfer_id" IS 'null when the posted amount rounds down to zero';
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "interest_postings" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
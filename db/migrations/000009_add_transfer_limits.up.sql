@@ -0,0 +1,24 @@
+CREATE TYPE "limit_scope" AS ENUM (
+  'product',
+  'user',
+  'account'
+);
+
+CREATE TABLE "transfer_limits" (
+  "id" bigserial PRIMARY KEY,
+  "scope" limit_scope NOT NULL,
+  "scope_ref" varchar NOT NULL,
+  "max_per_transfer" bigint,
+  "daily_total" bigint,
+  "monthly_total" bigint,
+  "hourly_count" bigint,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "transfer_limits" ("scope", "scope_ref");
+
+CREATE INDEX ON "transfers" ("from_account_id", "created_at");
+
+COMMENT ON COLUMN "transfer_limits"."scope_ref" IS 'product code, username or account id, depending on scope';
+
+COMMENT ON COLUMN "transfer_limits"."max_per_transfer" IS 'null leaves the limit to a less specific scope';
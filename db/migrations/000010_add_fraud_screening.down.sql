@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS fraud_decisions;
+DROP TABLE IF EXISTS held_transfers;
+DROP TYPE IF EXISTS held_transfer_status;
+DROP TYPE IF EXISTS fraud_verdict;
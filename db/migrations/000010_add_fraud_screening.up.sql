@@ -0,0 +1,56 @@
+CREATE TYPE "fraud_verdict" AS ENUM (
+  'allow',
+  'review',
+  'deny'
+);
+
+CREATE TYPE "held_transfer_status" AS ENUM (
+  'pending',
+  'approved',
+  'rejected'
+);
+
+CREATE TABLE "held_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "fee" bigint NOT NULL DEFAULT 0,
+  "status" held_transfer_status NOT NULL DEFAULT 'pending',
+  "transfer_id" bigint,
+  "reviewer" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "decided_at" timestamptz
+);
+
+CREATE INDEX ON "held_transfers" ("status", "id");
+
+COMMENT ON COLUMN "held_transfers"."amount" IS 'gross amount, fee included';
+
+COMMENT ON COLUMN "held_transfers"."transfer_id" IS 'set once the held transfer is approved and posted';
+
+CREATE TABLE "fraud_decisions" (
+  "id" bigserial PRIMARY KEY,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "verdict" fraud_verdict NOT NULL,
+  "rules" varchar[] NOT NULL,
+  "actor" varchar NOT NULL,
+  "held_transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "fraud_decisions" ("held_transfer_id");
+
+COMMENT ON COLUMN "fraud_decisions"."rules" IS 'names of the rules that fired';
+
+COMMENT ON COLUMN "fraud_decisions"."actor" IS 'screening for automatic decisions, the reviewer otherwise';
+
+ALTER TABLE "held_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "held_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "held_transfers" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+ALTER TABLE "fraud_decisions" ADD FOREIGN KEY ("held_transfer_id") REFERENCES "held_transfers" ("id");
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS entries_notify_created ON entries;
+DROP FUNCTION IF EXISTS notify_entry_created;
+DROP TRIGGER IF EXISTS accounts_notify_balance ON accounts;
+DROP FUNCTION IF EXISTS notify_balance_change;
@@ -0,0 +1,45 @@
+-- Notifications are only delivered when the transaction commits, so listeners
+-- never see changes that were rolled back.
+
+CREATE FUNCTION notify_balance_change() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('account_changes', json_build_object(
+    'kind', 'balance',
+    'owner', NEW.owner,
+    'account_id', NEW.id,
+    'balance', NEW.balance,
+    'currency', NEW.currency
+  )::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "accounts_notify_balance"
+  AFTER UPDATE OF "balance" ON "accounts"
+  FOR EACH ROW
+  WHEN (OLD.balance IS DISTINCT FROM NEW.balance)
+  EXECUTE FUNCTION notify_balance_change();
+
+CREATE FUNCTION notify_entry_created() RETURNS trigger AS $$
+DECLARE
+  account accounts%ROWTYPE;
+BEGIN
+  SELECT * INTO account FROM accounts WHERE id = NEW.account_id;
+  PERFORM pg_notify('account_changes', json_build_object(
+    'kind', 'entry',
+    'owner', account.owner,
+    'account_id', NEW.account_id,
+    'currency', account.currency,
+    'entry_id', NEW.id,
+    'amount', NEW.amount,
+    'transfer_id', NEW.transfer_id,
+    'created_at', NEW.created_at
+  )::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "entries_notify_created"
+  AFTER INSERT ON "entries"
+  FOR EACH ROW
+  EXECUTE FUNCTION notify_entry_created();
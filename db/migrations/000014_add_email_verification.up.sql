@@ -0,0 +1,19 @@
+-- users that signed up before verification existed are grandfathered in
+ALTER TABLE "users" ADD COLUMN "is_email_verified" boolean NOT NULL DEFAULT true;
+ALTER TABLE "users" ALTER COLUMN "is_email_verified" SET DEFAULT false;
+
+CREATE TABLE "email_verifications" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "token_hash" varchar UNIQUE NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "email_verifications" ("username");
+
+COMMENT ON COLUMN "email_verifications"."token_hash" IS 'SHA-256 of the token sent by email; the token itself is not stored';
+
+ALTER TABLE "email_verifications" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
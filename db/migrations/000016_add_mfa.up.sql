@@ -0,0 +1,27 @@
+CREATE TABLE "user_mfa" (
+  "username" varchar PRIMARY KEY,
+  "totp_secret" varchar NOT NULL,
+  "confirmed_at" timestamptz,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "user_mfa"."totp_secret" IS 'base32 TOTP key; kept in the clear since codes are computed from it';
+
+COMMENT ON COLUMN "user_mfa"."confirmed_at" IS 'null until the user enters a first code, and MFA is not enforced until then';
+
+COMMENT ON COLUMN "user_mfa"."last_used_step" IS 'TOTP time step of the last accepted code, so a code cannot be replayed';
+
+CREATE TABLE "mfa_recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "mfa_recovery_codes" ("username", "code_hash");
+
+ALTER TABLE "user_mfa" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "mfa_recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
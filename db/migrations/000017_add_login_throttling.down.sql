@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS lockout_events;
+DROP TABLE IF EXISTS login_throttles;
+DROP TYPE IF EXISTS login_throttle_scope;
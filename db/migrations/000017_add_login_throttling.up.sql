@@ -0,0 +1,30 @@
+CREATE TYPE "login_throttle_scope" AS ENUM (
+  'username',
+  'ip'
+);
+
+CREATE TABLE "login_throttles" (
+  "scope" login_throttle_scope NOT NULL,
+  "key" varchar NOT NULL,
+  "failures" int NOT NULL DEFAULT 0,
+  "window_started_at" timestamptz NOT NULL DEFAULT (now()),
+  "lockouts" int NOT NULL DEFAULT 0,
+  "locked_until" timestamptz,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("scope", "key")
+);
+
+COMMENT ON COLUMN "login_throttles"."key" IS 'username as typed, whether or not the user exists, or client IP';
+
+COMMENT ON COLUMN "login_throttles"."lockouts" IS 'consecutive lockouts; each one lasts twice as long as the last';
+
+CREATE TABLE "lockout_events" (
+  "id" bigserial PRIMARY KEY,
+  "scope" login_throttle_scope NOT NULL,
+  "key" varchar NOT NULL,
+  "failures" int NOT NULL,
+  "locked_until" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "lockout_events" ("scope", "key");
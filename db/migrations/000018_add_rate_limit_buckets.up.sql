@@ -0,0 +1,10 @@
+CREATE TABLE "rate_limit_buckets" (
+  "key" varchar PRIMARY KEY,
+  "tokens" float8 NOT NULL,
+  "allowed" boolean NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "rate_limit_buckets"."allowed" IS 'whether the last request took a token';
+
+CREATE INDEX ON "rate_limit_buckets" ("updated_at");
@@ -0,0 +1,26 @@
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar UNIQUE NOT NULL,
+  "secret_hash" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "account_ids" bigint[] NOT NULL DEFAULT '{}',
+  "max_transfer_amount" bigint NOT NULL DEFAULT 0,
+  "expires_at" timestamptz,
+  "last_used_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'shown to the owner to tell keys apart and used to look the key up';
+
+COMMENT ON COLUMN "api_keys"."secret_hash" IS 'sha256 of the secret part; the secret itself is only shown once';
+
+COMMENT ON COLUMN "api_keys"."account_ids" IS 'the only accounts the key may act on; empty allows all of the owner''s accounts';
+
+COMMENT ON COLUMN "api_keys"."max_transfer_amount" IS 'largest single transfer the key may make; 0 means no cap';
+
+CREATE INDEX ON "api_keys" ("owner");
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
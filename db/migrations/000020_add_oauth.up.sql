@@ -0,0 +1,69 @@
+CREATE TABLE "oauth_clients" (
+  "id" varchar PRIMARY KEY,
+  "name" varchar NOT NULL,
+  "secret_hash" varchar NOT NULL DEFAULT '',
+  "redirect_uris" varchar[] NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "created_by" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "oauth_clients"."secret_hash" IS 'sha256 of the client secret; empty for public clients, which only have PKCE';
+
+COMMENT ON COLUMN "oauth_clients"."scopes" IS 'the most a user can grant the client';
+
+CREATE TABLE "oauth_grants" (
+  "id" uuid PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "oauth_grants"."id" IS 'session ID of every access token issued under the grant';
+
+CREATE TABLE "oauth_authorization_codes" (
+  "code_hash" varchar PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "redirect_uri" varchar NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "code_challenge" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "grant_id" uuid,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_challenge" IS 'PKCE S256 challenge';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."grant_id" IS 'grant the code was exchanged for, revoked if the code is replayed';
+
+CREATE TABLE "oauth_refresh_tokens" (
+  "token_hash" varchar PRIMARY KEY,
+  "grant_id" uuid NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "oauth_refresh_tokens"."used_at" IS 'refresh tokens rotate on use; using one twice revokes its grant';
+
+CREATE INDEX ON "oauth_grants" ("username");
+
+CREATE INDEX ON "oauth_refresh_tokens" ("grant_id");
+
+ALTER TABLE "oauth_clients" ADD FOREIGN KEY ("created_by") REFERENCES "users" ("username");
+
+ALTER TABLE "oauth_grants" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_grants" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("grant_id") REFERENCES "oauth_grants" ("id");
+
+ALTER TABLE "oauth_refresh_tokens" ADD FOREIGN KEY ("grant_id") REFERENCES "oauth_grants" ("id");
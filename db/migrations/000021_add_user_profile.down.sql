@@ -0,0 +1,13 @@
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE users DROP COLUMN IF EXISTS updated_at;
+
+UPDATE accounts SET status = 'frozen' WHERE status = 'closed';
+ALTER TABLE accounts ALTER COLUMN status DROP DEFAULT;
+ALTER TYPE account_status RENAME TO account_status_old;
+CREATE TYPE account_status AS ENUM (
+  'active',
+  'frozen'
+);
+ALTER TABLE accounts ALTER COLUMN status TYPE account_status USING status::text::account_status;
+ALTER TABLE accounts ALTER COLUMN status SET DEFAULT 'active';
+DROP TYPE account_status_old;
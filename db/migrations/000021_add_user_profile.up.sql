@@ -0,0 +1,6 @@
+ALTER TYPE "account_status" ADD VALUE 'closed';
+
+ALTER TABLE "users" ADD COLUMN "updated_at" timestamptz NOT NULL DEFAULT (now());
+ALTER TABLE "users" ADD COLUMN "deleted_at" timestamptz;
+
+COMMENT ON COLUMN "users"."deleted_at" IS 'set when the user deletes their profile; deleted users cannot log in';
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS erased_at;
+DROP TABLE IF EXISTS data_exports;
+DROP TYPE IF EXISTS data_export_status;
@@ -0,0 +1,30 @@
+CREATE TYPE "data_export_status" AS ENUM (
+  'pending',
+  'running',
+  'completed',
+  'failed'
+);
+
+CREATE TABLE "data_exports" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "status" data_export_status NOT NULL DEFAULT 'pending',
+  "archive" bytea,
+  "error" varchar NOT NULL DEFAULT '',
+  "started_at" timestamptz,
+  "completed_at" timestamptz,
+  "expires_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "data_exports"."archive" IS 'ZIP of the user''s data; cleared once expires_at passes or the user is erased';
+
+CREATE INDEX ON "data_exports" ("username");
+
+CREATE INDEX ON "data_exports" ("id") WHERE "status" = 'pending';
+
+ALTER TABLE "data_exports" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "users" ADD COLUMN "erased_at" timestamptz;
+
+COMMENT ON COLUMN "users"."erased_at" IS 'set when the user''s personal data was pseudonymized; the ledger is kept';
@@ -0,0 +1 @@
+ALTER TABLE held_transfers DROP COLUMN IF EXISTS external_reference;
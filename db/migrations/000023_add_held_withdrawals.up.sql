@@ -0,0 +1,3 @@
+ALTER TABLE "held_transfers" ADD COLUMN "external_reference" varchar;
+
+COMMENT ON COLUMN "held_transfers"."external_reference" IS 'set for withdrawals; approving the hold posts them as a payment';
@@ -0,0 +1,3 @@
+ALTER TABLE "outbox_events" ADD COLUMN "claimed_until" timestamptz;
+
+COMMENT ON COLUMN "outbox_events"."claimed_until" IS 'a relay is publishing the event until then; it is claimed again once this passes';
@@ -0,0 +1,5 @@
+ALTER TABLE "data_exports" ADD COLUMN "claimed_at" timestamptz;
+
+COMMENT ON COLUMN "data_exports"."claimed_at" IS 'when a worker last claimed the export; a running export claimed too long ago is claimed again';
+
+UPDATE "data_exports" SET "claimed_at" = "started_at" WHERE "status" = 'running';
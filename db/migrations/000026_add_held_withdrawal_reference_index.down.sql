@@ -0,0 +1 @@
+DROP INDEX IF EXISTS held_transfers_pending_reference_idx;
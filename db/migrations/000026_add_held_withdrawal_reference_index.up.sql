@@ -0,0 +1 @@
+CREATE UNIQUE INDEX "held_transfers_pending_reference_idx" ON "held_transfers" ("external_reference") WHERE "status" = 'pending';
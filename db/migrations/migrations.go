@@ -0,0 +1,165 @@
+// Package migrations embeds the SQL schema migrations into the binary and
+// applies them with golang-migrate, so a build always carries the schema it
+// was written against.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// lockTimeout bounds how long a replica waits for another one to finish
+// migrating before giving up.
+const lockTimeout = 5 * time.Minute
+
+var (
+	ErrDirtySchema    = errors.New("database schema is dirty")
+	ErrSchemaTooNew   = errors.New("database schema is newer than this binary supports")
+	ErrSchemaOutdated = errors.New("database schema is behind this binary")
+)
+
+type Status struct {
+	Version uint `json:"version"`
+	Latest  uint `json:"latest"`
+	Dirty   bool `json:"dirty"`
+}
+
+func newSource() (source.Driver, error) {
+	return iofs.New(files, ".")
+}
+
+func newMigrate(dbSource string) (*migrate.Migrate, error) {
+	src, err := newSource()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+
+	m, err := migrate.NewWithSourceInstance("iofs", src, dbSource)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create migrate instance: %w", err)
+	}
+
+	m.LockTimeout = lockTimeout
+	return m, nil
+}
+
+// Up applies every pending migration. The postgres driver holds a
+// pg_advisory_lock while migrating, so replicas that start at the same time
+// wait for each other instead of racing.
+func Up(dbSource string) error {
+	m, err := newMigrate(dbSource)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	err = m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("cannot apply migrations: %w", err)
+	}
+
+	return nil
+}
+
+// Down reverts the last n applied migrations, or all of them when n <= 0.
+func Down(dbSource string, n int) error {
+	m, err := newMigrate(dbSource)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if n <= 0 {
+		err = m.Down()
+	} else {
+		err = m.Steps(-n)
+	}
+
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("cannot revert migrations: %w", err)
+	}
+
+	return nil
+}
+
+func GetStatus(dbSource string) (Status, error) {
+	latest, err := LatestVersion()
+	if err != nil {
+		return Status{}, err
+	}
+
+	m, err := newMigrate(dbSource)
+	if err != nil {
+		return Status{}, err
+	}
+	defer m.Close()
+
+	version, dirty, err := m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return Status{}, fmt.Errorf("cannot read schema version: %w", err)
+	}
+
+	return Status{
+		Version: version,
+		Latest:  latest,
+		Dirty:   dirty,
+	}, nil
+}
+
+// CheckVersion fails unless the database is exactly at the latest embedded
+// migration and not dirty.
+func CheckVersion(dbSource string) error {
+	status, err := GetStatus(dbSource)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case status.Dirty:
+		return fmt.Errorf("%w at version %d", ErrDirtySchema, status.Version)
+	case status.Version > status.Latest:
+		return fmt.Errorf("%w: %d > %d", ErrSchemaTooNew, status.Version, status.Latest)
+	case status.Version < status.Latest:
+		return fmt.Errorf("%w: %d < %d", ErrSchemaOutdated, status.Version, status.Latest)
+	}
+
+	return nil
+}
+
+// LatestVersion returns the highest migration version embedded in the binary.
+func LatestVersion() (uint, error) {
+	src, err := newSource()
+	if err != nil {
+		return 0, fmt.Errorf("cannot read embedded migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return 0, err
+	}
+
+	for {
+		next, err := src.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		version = next
+	}
+}
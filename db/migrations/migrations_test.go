@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersion(t *testing.T) {
+	names, err := fs.Glob(files, "*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, names)
+
+	var expected uint
+	for _, name := range names {
+		v, err := strconv.ParseUint(strings.SplitN(name, "_", 2)[0], 10, 64)
+		require.NoError(t, err)
+
+		if uint(v) > expected {
+			expected = uint(v)
+		}
+	}
+
+	latest, err := LatestVersion()
+	require.NoError(t, err)
+	require.Equal(t, expected, latest)
+}
+
+func TestEveryUpHasDown(t *testing.T) {
+	names, err := fs.Glob(files, "*.up.sql")
+	require.NoError(t, err)
+
+	for _, name := range names {
+		down := strings.TrimSuffix(name, ".up.sql") + ".down.sql"
+		_, err := fs.Stat(files, down)
+		require.NoError(t, err, "missing %s", down)
+	}
+}
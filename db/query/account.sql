@@ -2,9 +2,10 @@
 INSERT INTO accounts (
    owner, 
    balance,
-   currency
+   currency,
+   product
 ) VALUES (
-   $1, $2, $3
+   $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetAccount :one
@@ -16,6 +17,16 @@ SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE;
 
+-- name: GetAccountByOwner :one
+SELECT * FROM accounts
+WHERE owner = $1 AND currency = $2 LIMIT 1;
+
+-- name: ListAccountsByOwnerForUpdate :many
+SELECT * FROM accounts
+WHERE owner = $1
+ORDER BY id
+FOR NO KEY UPDATE;
+
 -- name: ListAccount :many
 SELECT * FROM accounts
 WHERE owner = $1
@@ -23,12 +34,6 @@ ORDER BY id
 LIMIT $2
 OFFSET $3; 
 
--- name: UpdateAccount :one
-UPDATE accounts
-SET balance = $2
-WHERE id = $1
-RETURNING *;
-
 -- name: UpdateAccountBalance :one
 UPDATE accounts
 SET balance = sqlc.arg(amount) + balance
@@ -37,4 +42,41 @@ RETURNING *;
 
 -- name: DeleteAccount :exec
 DELETE FROM accounts 
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: ListAllAccounts :many
+SELECT * FROM accounts
+WHERE id > sqlc.arg(after_id)
+   AND (sqlc.narg(owner)::varchar IS NULL OR owner = sqlc.narg(owner))
+ORDER BY id
+LIMIT sqlc.arg(size);
+
+-- name: SetAccountStatus :one
+-- SetAccountStatus leaves closed accounts alone: closing is final.
+UPDATE accounts
+SET status = $2
+WHERE id = $1 AND status <> 'closed'
+RETURNING *;
+
+-- name: ListAccountEntrySums :many
+SELECT a.id, a.owner, a.currency, a.balance,
+   COALESCE(SUM(e.amount), 0)::bigint AS entries_sum
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id > sqlc.arg(after_id)
+GROUP BY a.id
+ORDER BY a.id
+LIMIT sqlc.arg(size);
+
+-- name: SetAccountOverdraftLimit :one
+UPDATE accounts
+SET overdraft_limit = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: CloseAccount :one
+-- CloseAccount only closes accounts with nothing left in them.
+UPDATE accounts
+SET status = 'closed'
+WHERE id = $1 AND balance = 0 AND status <> 'closed'
+RETURNING *;
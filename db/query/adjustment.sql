@@ -0,0 +1,18 @@
+-- name: CreateAdjustment :one
+INSERT INTO adjustments (
+   account_id,
+   transfer_id,
+   amount,
+   reason_code,
+   note,
+   operator
+) VALUES (
+   $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListAdjustments :many
+SELECT * FROM adjustments
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
@@ -0,0 +1,45 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+   owner,
+   name,
+   prefix,
+   secret_hash,
+   scopes,
+   account_ids,
+   max_transfer_amount,
+   expires_at
+) VALUES (
+   $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetAPIKey :one
+SELECT * FROM api_keys
+WHERE id = $1 LIMIT 1;
+
+-- name: GetAPIKeyByPrefix :one
+SELECT * FROM api_keys
+WHERE prefix = $1 LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys
+WHERE owner = $1
+ORDER BY id;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: TouchAPIKey :exec
+-- TouchAPIKey records that the key was used, at most once a minute so busy
+-- integrations don't write on every request.
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1
+   AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute');
+
+-- name: RevokeUserAPIKeys :exec
+UPDATE api_keys
+SET revoked_at = now()
+WHERE owner = $1 AND revoked_at IS NULL;
@@ -0,0 +1,17 @@
+-- name: CreateAuditLog :one
+INSERT INTO audit_logs (
+   actor,
+   action,
+   target,
+   reason,
+   details
+) VALUES (
+   $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ListAuditLogs :many
+SELECT * FROM audit_logs
+WHERE target = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
@@ -0,0 +1,79 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (
+   username
+) VALUES (
+   $1
+) RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1 LIMIT 1;
+
+-- name: ListDataExports :many
+SELECT * FROM data_exports
+WHERE username = $1
+ORDER BY id DESC
+LIMIT $2;
+
+-- name: ClaimDataExport :one
+-- ClaimDataExport picks the oldest pending export, skipping ones another
+-- worker holds. A running export claimed before stale_before is picked too:
+-- its worker is taken to have died.
+UPDATE data_exports
+SET status = 'running',
+   started_at = now(),
+   claimed_at = now()
+WHERE id = (
+   SELECT e.id FROM data_exports e
+   WHERE e.status = 'pending'
+      OR (e.status = 'running' AND e.claimed_at < sqlc.arg(stale_before)::timestamptz)
+   ORDER BY e.id
+   LIMIT 1
+   FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteDataExport :one
+UPDATE data_exports
+SET status = 'completed',
+   archive = sqlc.arg(archive),
+   completed_at = now(),
+   expires_at = sqlc.arg(expires_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: FailDataExport :one
+UPDATE data_exports
+SET status = 'failed',
+   error = sqlc.arg(error),
+   completed_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: PurgeExpiredDataExports :execrows
+UPDATE data_exports
+SET archive = NULL
+WHERE archive IS NOT NULL AND expires_at < now();
+
+-- name: PurgeUserDataExports :exec
+UPDATE data_exports
+SET archive = NULL
+WHERE username = $1 AND archive IS NOT NULL;
+
+-- name: ListOwnerEntries :many
+SELECT e.* FROM entries e
+JOIN accounts a ON a.id = e.account_id
+WHERE a.owner = sqlc.arg(owner) AND e.id > sqlc.arg(after_id)
+ORDER BY e.id
+LIMIT sqlc.arg(size);
+
+-- name: ListOwnerTransfers :many
+SELECT t.* FROM transfers t
+WHERE t.id > sqlc.arg(after_id)
+   AND EXISTS (
+      SELECT 1 FROM accounts a
+      WHERE a.owner = sqlc.arg(owner)
+         AND a.id IN (t.from_account_id, t.to_account_id)
+   )
+ORDER BY t.id
+LIMIT sqlc.arg(size);
@@ -1,9 +1,10 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
    account_id, 
-   amount
+   amount,
+   transfer_id
 ) VALUES (
-   $1, $2
+   $1, $2, $3
 ) RETURNING *;
 
 -- name: GetEntry :one
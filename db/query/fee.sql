@@ -0,0 +1,12 @@
+-- name: CreateTransferFee :one
+INSERT INTO transfer_fees (
+   transfer_id,
+   fee_transfer_id,
+   amount
+) VALUES (
+   $1, $2, $3
+) RETURNING *;
+
+-- name: GetTransferFee :one
+SELECT * FROM transfer_fees
+WHERE transfer_id = $1 LIMIT 1;
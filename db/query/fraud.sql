@@ -0,0 +1,59 @@
+-- name: CreateHeldTransfer :one
+INSERT INTO held_transfers (
+   from_account_id,
+   to_account_id,
+   amount,
+   fee,
+   external_reference
+) VALUES (
+   $1, $2, $3, $4, sqlc.narg(external_reference)
+) RETURNING *;
+
+-- name: GetHeldTransfer :one
+SELECT * FROM held_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHeldTransferForUpdate :one
+SELECT * FROM held_transfers
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: ListHeldTransfers :many
+SELECT * FROM held_transfers
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: DecideHeldTransfer :one
+UPDATE held_transfers
+SET status = $2,
+   transfer_id = $3,
+   reviewer = $4,
+   decided_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateFraudDecision :one
+INSERT INTO fraud_decisions (
+   from_account_id,
+   to_account_id,
+   amount,
+   verdict,
+   rules,
+   actor,
+   held_transfer_id
+) VALUES (
+   $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListFraudDecisions :many
+SELECT * FROM fraud_decisions
+WHERE held_transfer_id = $1
+ORDER BY id;
+
+-- name: CountTransfersBetween :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+   AND to_account_id = $2
+   AND created_at >= $3;
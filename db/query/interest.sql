@@ -0,0 +1,86 @@
+-- name: ListProducts :many
+SELECT * FROM products
+ORDER BY code;
+
+-- name: GetProduct :one
+SELECT * FROM products
+WHERE code = $1 LIMIT 1;
+
+-- name: SetProductRate :one
+UPDATE products
+SET annual_rate_bps = $2
+WHERE code = $1
+RETURNING *;
+
+-- name: ListAccrualBalances :many
+SELECT a.id, p.annual_rate_bps,
+   COALESCE(SUM(e.amount), 0)::bigint AS balance
+FROM accounts a
+JOIN products p ON p.code = a.product
+LEFT JOIN entries e ON e.account_id = a.id AND e.created_at < sqlc.arg(day_end)
+WHERE p.annual_rate_bps > 0
+   AND a.created_at < sqlc.arg(day_end)
+   AND a.id > sqlc.arg(after_id)
+GROUP BY a.id, p.annual_rate_bps
+ORDER BY a.id
+LIMIT sqlc.arg(size);
+
+-- name: CreateInterestAccrual :execrows
+INSERT INTO interest_accruals (
+   account_id,
+   day,
+   balance,
+   annual_rate_bps,
+   amount_micros
+) VALUES (
+   $1, $2, $3, $4, $5
+) ON CONFLICT (account_id, day) DO NOTHING;
+
+-- name: GetLastAccrualDay :one
+SELECT COALESCE(MAX(day), '0001-01-01')::date AS day
+FROM interest_accruals;
+
+-- name: ListInterestAccrualSums :many
+SELECT a.account_id, SUM(a.amount_micros)::bigint AS accrued_micros
+FROM interest_accruals a
+WHERE a.day >= sqlc.arg(period_start) AND a.day < sqlc.arg(period_end)
+   AND a.account_id > sqlc.arg(after_id)
+   AND NOT EXISTS (
+      SELECT 1 FROM interest_postings p
+      WHERE p.account_id = a.account_id AND p.period = sqlc.arg(period_start)
+   )
+GROUP BY a.account_id
+ORDER BY a.account_id
+LIMIT sqlc.arg(size);
+
+-- name: GetInterestCarry :one
+SELECT COALESCE((
+   SELECT carry_micros FROM interest_postings
+   WHERE account_id = $1 AND period < $2
+   ORDER BY period DESC
+   LIMIT 1
+), 0)::bigint AS carry_micros;
+
+-- name: CreateInterestPosting :one
+INSERT INTO interest_postings (
+   account_id,
+   period,
+   accrued_micros,
+   amount,
+   carry_micros
+) VALUES (
+   $1, $2, $3, $4, $5
+) ON CONFLICT (account_id, period) DO NOTHING
+RETURNING *;
+
+-- name: SetInterestPostingTransfer :one
+UPDATE interest_postings
+SET transfer_id = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: ListInterestPostings :many
+SELECT * FROM interest_postings
+WHERE account_id = $1
+ORDER BY period DESC
+LIMIT $2;
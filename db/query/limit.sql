@@ -0,0 +1,63 @@
+-- name: UpsertTransferLimit :one
+INSERT INTO transfer_limits (
+   scope,
+   scope_ref,
+   max_per_transfer,
+   daily_total,
+   monthly_total,
+   hourly_count
+) VALUES (
+   $1, $2, $3, $4, $5, $6
+) ON CONFLICT (scope, scope_ref) DO UPDATE SET
+   max_per_transfer = EXCLUDED.max_per_transfer,
+   daily_total = EXCLUDED.daily_total,
+   monthly_total = EXCLUDED.monthly_total,
+   hourly_count = EXCLUDED.hourly_count,
+   updated_at = now()
+RETURNING *;
+
+-- name: DeleteTransferLimit :exec
+DELETE FROM transfer_limits
+WHERE scope = $1 AND scope_ref = $2;
+
+-- name: ListTransferLimits :many
+SELECT * FROM transfer_limits
+ORDER BY scope, scope_ref;
+
+-- name: ListTransferLimitsForAccount :many
+SELECT * FROM transfer_limits
+WHERE (scope = 'account' AND scope_ref = sqlc.arg(account_id)::bigint::varchar)
+   OR (scope = 'user' AND scope_ref = sqlc.arg(owner)::varchar)
+   OR (scope = 'product' AND scope_ref = sqlc.arg(product)::varchar);
+
+-- name: GetOutgoingUsage :one
+-- GetOutgoingUsage counts transfers and withdrawals sent from the account.
+-- Fee legs are included in the transfer they belong to; adjustments are left
+-- out.
+SELECT COALESCE(SUM(t.amount + COALESCE(f.amount, 0)), 0)::bigint AS total,
+   COUNT(*)::bigint AS count
+FROM transfers t
+LEFT JOIN transfer_fees f ON f.transfer_id = t.id
+WHERE t.from_account_id = $1
+   AND t.created_at >= $2
+   AND NOT EXISTS (SELECT 1 FROM transfer_fees ff WHERE ff.fee_transfer_id = t.id)
+   AND NOT EXISTS (SELECT 1 FROM adjustments a WHERE a.transfer_id = t.id);
+
+-- name: GetOwnerOutgoingUsage :one
+-- GetOwnerOutgoingUsage is GetOutgoingUsage summed over all of the owner's
+-- accounts in currency.
+SELECT COALESCE(SUM(t.amount + COALESCE(f.amount, 0)), 0)::bigint AS total,
+   COUNT(*)::bigint AS count
+FROM transfers t
+JOIN accounts a ON a.id = t.from_account_id
+LEFT JOIN transfer_fees f ON f.transfer_id = t.id
+WHERE a.owner = sqlc.arg(owner)
+   AND a.currency = sqlc.arg(currency)
+   AND t.created_at >= sqlc.arg(created_at)
+   AND NOT EXISTS (SELECT 1 FROM transfer_fees ff WHERE ff.fee_transfer_id = t.id)
+   AND NOT EXISTS (SELECT 1 FROM adjustments ad WHERE ad.transfer_id = t.id);
+
+-- name: LockOwnerLimits :exec
+-- LockOwnerLimits makes limit checks on the owner's transfers wait for each
+-- other until the transaction ends.
+SELECT pg_advisory_xact_lock(hashtext('transfer_limits'), hashtext(sqlc.arg(owner)::text));
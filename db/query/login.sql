@@ -0,0 +1,47 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE scope = $1 AND key = $2 LIMIT 1;
+
+-- name: EnsureLoginThrottle :exec
+INSERT INTO login_throttles (scope, key)
+VALUES ($1, $2)
+ON CONFLICT (scope, key) DO NOTHING;
+
+-- name: GetLoginThrottleForUpdate :one
+SELECT * FROM login_throttles
+WHERE scope = $1 AND key = $2 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: UpdateLoginThrottle :one
+UPDATE login_throttles
+SET failures = $3,
+   window_started_at = $4,
+   lockouts = $5,
+   locked_until = $6,
+   updated_at = now()
+WHERE scope = $1 AND key = $2
+RETURNING *;
+
+-- name: ResetLoginThrottle :execrows
+UPDATE login_throttles
+SET failures = 0,
+   lockouts = 0,
+   locked_until = NULL,
+   updated_at = now()
+WHERE scope = $1 AND key = $2;
+
+-- name: CreateLockoutEvent :one
+INSERT INTO lockout_events (
+   scope,
+   key,
+   failures,
+   locked_until
+) VALUES (
+   $1, $2, $3, $4
+) RETURNING *;
+
+-- name: ListLockoutEvents :many
+SELECT * FROM lockout_events
+WHERE scope = $1 AND key = $2
+ORDER BY id DESC
+LIMIT $3;
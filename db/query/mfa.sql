@@ -0,0 +1,50 @@
+-- name: GetUserMFA :one
+SELECT * FROM user_mfa
+WHERE username = $1 LIMIT 1;
+
+-- name: StartMFAEnrollment :one
+-- Replaces an unconfirmed enrollment; returns no rows if MFA is already on.
+INSERT INTO user_mfa (
+   username,
+   totp_secret
+) VALUES (
+   $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret,
+   last_used_step = 0,
+   created_at = now()
+WHERE user_mfa.confirmed_at IS NULL
+RETURNING *;
+
+-- name: ConfirmMFA :one
+UPDATE user_mfa
+SET confirmed_at = now()
+WHERE username = $1 AND confirmed_at IS NULL
+RETURNING *;
+
+-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE username = $1;
+
+-- name: UseTOTPStep :execrows
+UPDATE user_mfa
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_used_step < sqlc.arg(step);
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (
+   username,
+   code_hash
+) VALUES (
+   $1, $2
+);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE username = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL;
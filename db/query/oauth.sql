@@ -0,0 +1,95 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+   id,
+   name,
+   secret_hash,
+   redirect_uris,
+   scopes,
+   created_by
+) VALUES (
+   $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients
+ORDER BY created_at;
+
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+   code_hash,
+   client_id,
+   username,
+   redirect_uri,
+   scopes,
+   code_challenge,
+   expires_at
+) VALUES (
+   $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetOAuthAuthorizationCodeForUpdate :one
+SELECT * FROM oauth_authorization_codes
+WHERE code_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: MarkOAuthAuthorizationCodeUsed :exec
+UPDATE oauth_authorization_codes
+SET used_at = now(),
+   grant_id = $2
+WHERE code_hash = $1;
+
+-- name: CreateOAuthGrant :one
+INSERT INTO oauth_grants (
+   id,
+   client_id,
+   username,
+   scopes
+) VALUES (
+   $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetOAuthGrant :one
+SELECT * FROM oauth_grants
+WHERE id = $1 LIMIT 1;
+
+-- name: ListUserOAuthGrants :many
+SELECT * FROM oauth_grants
+WHERE username = $1
+ORDER BY created_at;
+
+-- name: RevokeOAuthGrant :exec
+UPDATE oauth_grants
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserOAuthGrants :exec
+UPDATE oauth_grants
+SET revoked_at = now()
+WHERE username = $1 AND revoked_at IS NULL;
+
+-- name: CreateOAuthRefreshToken :one
+INSERT INTO oauth_refresh_tokens (
+   token_hash,
+   grant_id,
+   expires_at
+) VALUES (
+   $1, $2, $3
+) RETURNING *;
+
+-- name: GetOAuthRefreshToken :one
+SELECT * FROM oauth_refresh_tokens
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: GetOAuthRefreshTokenForUpdate :one
+SELECT * FROM oauth_refresh_tokens
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: MarkOAuthRefreshTokenUsed :exec
+UPDATE oauth_refresh_tokens
+SET used_at = now()
+WHERE token_hash = $1;
@@ -0,0 +1,55 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+   aggregate_type,
+   aggregate_id,
+   event_type,
+   schema_version,
+   payload
+) VALUES (
+   $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ClaimOutboxEvents :many
+-- ClaimOutboxEvents claims the oldest unpublished events for lease_seconds.
+-- Nothing is claimed while an earlier claim is live, so a single relay
+-- publishes at a time.
+UPDATE outbox_events
+SET claimed_until = now() + sqlc.arg(lease_seconds)::bigint * interval '1 second'
+WHERE id IN (
+   SELECT id FROM outbox_events
+   WHERE published_at IS NULL
+   ORDER BY id
+   LIMIT sqlc.arg(size)
+)
+   AND NOT EXISTS (
+      SELECT 1 FROM outbox_events
+      WHERE published_at IS NULL
+         AND claimed_until > now()
+   )
+RETURNING *;
+
+-- name: MarkOutboxEventsPublished :exec
+UPDATE outbox_events
+SET published_at = now()
+WHERE id = ANY(sqlc.arg(ids)::bigint[]);
+
+-- name: ReleaseOutboxEvents :exec
+UPDATE outbox_events
+SET claimed_until = NULL
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+   AND published_at IS NULL;
+
+-- name: TryOutboxLock :one
+SELECT pg_try_advisory_xact_lock(sqlc.arg(key)::bigint) AS locked;
+
+-- name: ScrubUserRegisteredEvents :exec
+-- ScrubUserRegisteredEvents overwrites the personal data copied into the
+-- user.registered events of an erased user.
+UPDATE outbox_events
+SET payload = payload || jsonb_build_object(
+   'full_name', sqlc.arg(full_name)::text,
+   'email', sqlc.arg(email)::text
+)
+WHERE aggregate_type = 'user'
+   AND aggregate_id = sqlc.arg(username)
+   AND event_type = 'user.registered';
@@ -0,0 +1,20 @@
+-- name: CreatePayment :one
+INSERT INTO payments (
+   account_id,
+   transfer_id,
+   direction,
+   amount,
+   external_reference
+) VALUES (
+   $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetPayment :one
+SELECT * FROM payments
+WHERE id = $1 LIMIT 1;
+
+-- name: PaymentReferenceExists :one
+SELECT EXISTS (
+   SELECT 1 FROM payments
+   WHERE direction = $1 AND external_reference = $2
+);
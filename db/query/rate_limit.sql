@@ -0,0 +1,24 @@
+-- name: TakeRateLimitToken :one
+-- Refills the bucket for the time since it was last used, then takes one
+-- token if there is one. allowed says whether this request got it.
+INSERT INTO rate_limit_buckets AS b (
+   key,
+   tokens,
+   allowed,
+   updated_at
+) VALUES (
+   sqlc.arg(key), sqlc.arg(burst)::float8 - 1, true, now()
+)
+ON CONFLICT (key) DO UPDATE
+SET tokens = CASE
+      WHEN LEAST(sqlc.arg(burst)::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * sqlc.arg(rate)::float8) >= 1
+      THEN LEAST(sqlc.arg(burst)::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * sqlc.arg(rate)::float8) - 1
+      ELSE LEAST(sqlc.arg(burst)::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * sqlc.arg(rate)::float8)
+   END,
+   allowed = LEAST(sqlc.arg(burst)::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * sqlc.arg(rate)::float8) >= 1,
+   updated_at = now()
+RETURNING *;
+
+-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1;
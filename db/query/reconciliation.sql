@@ -0,0 +1,44 @@
+-- name: CreateReconciliationRun :one
+INSERT INTO reconciliation_runs DEFAULT VALUES
+RETURNING *;
+
+-- name: FinishReconciliationRun :one
+UPDATE reconciliation_runs
+SET status = $2,
+   accounts_checked = $3,
+   transfers_checked = $4,
+   discrepancies = $5,
+   error = $6,
+   finished_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetReconciliationRun :one
+SELECT * FROM reconciliation_runs
+WHERE id = $1 LIMIT 1;
+
+-- name: ListReconciliationRuns :many
+SELECT * FROM reconciliation_runs
+ORDER BY id DESC
+LIMIT $1
+OFFSET $2;
+
+-- name: CreateReconciliationDiscrepancy :one
+INSERT INTO reconciliation_discrepancies (
+   run_id,
+   kind,
+   account_id,
+   transfer_id,
+   expected,
+   actual,
+   detail
+) VALUES (
+   $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: ListReconciliationDiscrepancies :many
+SELECT * FROM reconciliation_discrepancies
+WHERE run_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
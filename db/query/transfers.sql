@@ -16,3 +16,20 @@ SELECT * FROM transfers
 ORDER BY id 
 LIMIT $1
 OFFSET $2;
+
+-- name: ListTransferEntryChecks :many
+SELECT t.id, t.from_account_id, t.to_account_id, t.amount,
+   COUNT(e.id)::bigint AS entry_count,
+   COALESCE(SUM(e.amount), 0)::bigint AS entries_sum,
+   COUNT(e.id) FILTER (
+      WHERE e.account_id = t.from_account_id AND e.amount = -t.amount
+   )::bigint AS from_entries,
+   COUNT(e.id) FILTER (
+      WHERE e.account_id = t.to_account_id AND e.amount = t.amount
+   )::bigint AS to_entries
+FROM transfers t
+LEFT JOIN entries e ON e.transfer_id = t.id
+WHERE t.id > sqlc.arg(after_id)
+GROUP BY t.id
+ORDER BY t.id
+LIMIT sqlc.arg(size);
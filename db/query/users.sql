@@ -10,4 +10,135 @@ INSERT INTO users (
 
 -- name: GetUser :one
 SELECT * FROM users 
-WHERE username = $1 LIMIT 1;
+WHERE username = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: SetUserEmailVerified :one
+UPDATE users
+SET is_email_verified = true,
+   updated_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: CreateEmailVerification :one
+INSERT INTO email_verifications (
+   username,
+   email,
+   token_hash,
+   expires_at
+) VALUES (
+   $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetEmailVerificationForUpdate :one
+SELECT * FROM email_verifications
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: MarkEmailVerificationUsed :one
+UPDATE email_verifications
+SET used_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: GetUserPasswordChangedAt :one
+SELECT password_changed_at FROM users
+WHERE username = $1 AND deleted_at IS NULL LIMIT 1;
+
+-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $2,
+   password_changed_at = now(),
+   updated_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateUserProfile :one
+-- UpdateUserProfile leaves NULL fields as they are. A new email address has
+-- to be verified again.
+UPDATE users
+SET full_name = COALESCE(sqlc.narg(full_name), full_name),
+   email = COALESCE(sqlc.narg(email), email),
+   is_email_verified = is_email_verified AND COALESCE(sqlc.narg(email), email) = email,
+   updated_at = now()
+WHERE username = sqlc.arg(username) AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2,
+   updated_at = now()
+WHERE username = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: SoftDeleteUser :one
+UPDATE users
+SET deleted_at = now(),
+   updated_at = now()
+WHERE username = $1 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RehashUserPassword :execrows
+-- RehashUserPassword swaps in a stronger hash of the same password. It leaves
+-- password_changed_at alone, so sessions stay valid, and does nothing if the
+-- password changed since old_hashed_password was read.
+UPDATE users
+SET hashed_password = sqlc.arg(hashed_password)
+WHERE username = sqlc.arg(username)
+   AND hashed_password = sqlc.arg(old_hashed_password);
+
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+   username,
+   token_hash,
+   expires_at
+) VALUES (
+   $1, $2, $3
+) RETURNING *;
+
+-- name: GetPasswordResetForUpdate :one
+SELECT * FROM password_resets
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: MarkPasswordResetUsed :one
+UPDATE password_resets
+SET used_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+SET used_at = now()
+WHERE username = $1 AND used_at IS NULL;
+
+-- name: GetUserForUpdate :one
+-- GetUserForUpdate also finds deleted users.
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: EraseUser :one
+-- EraseUser replaces the user's personal data with pseudonyms. The username
+-- stays, since the ledger refers to it.
+UPDATE users
+SET full_name = sqlc.arg(full_name),
+   email = sqlc.arg(email),
+   hashed_password = '',
+   is_email_verified = false,
+   deleted_at = COALESCE(deleted_at, now()),
+   erased_at = now(),
+   updated_at = now()
+WHERE username = sqlc.arg(username)
+RETURNING *;
+
+-- name: DeleteUserEmailVerifications :exec
+DELETE FROM email_verifications
+WHERE username = $1;
+
+-- name: DeleteUserPasswordResets :exec
+DELETE FROM password_resets
+WHERE username = $1;
@@ -0,0 +1,73 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+   owner,
+   url,
+   event_types,
+   secret
+) VALUES (
+   $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE owner = $1
+ORDER BY id;
+
+-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+SELECT s.id, sqlc.arg(event_id), sqlc.arg(event_type)::varchar, sqlc.arg(payload)
+FROM webhook_subscriptions s
+WHERE s.owner = sqlc.arg(owner)
+   AND sqlc.arg(event_type)::varchar = ANY(s.event_types)
+ON CONFLICT (subscription_id, event_id, event_type) DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = sqlc.arg(lease_until)
+WHERE id IN (
+   SELECT id FROM webhook_deliveries
+   WHERE status = 'pending' AND next_attempt_at <= now()
+   ORDER BY id
+   LIMIT sqlc.arg(size)
+   FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: MarkWebhookDelivered :one
+UPDATE webhook_deliveries
+SET status = 'delivered',
+   attempts = attempts + 1,
+   last_status_code = $2,
+   last_error = '',
+   delivered_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: RecordWebhookFailure :one
+UPDATE webhook_deliveries
+SET status = $2,
+   attempts = attempts + 1,
+   next_attempt_at = $3,
+   last_status_code = $4,
+   last_error = $5
+WHERE id = $1
+RETURNING *;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: DeleteUserWebhookSubscriptions :exec
+DELETE FROM webhook_subscriptions
+WHERE owner = $1;
@@ -1,32 +1,64 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.21.0
+//   sqlc v1.22.0
 // source: account.sql
 
 package db
 
 import (
 	"context"
+	"database/sql"
 )
 
+const closeAccount = `-- name: CloseAccount :one
+UPDATE accounts
+SET status = 'closed'
+WHERE id = $1 AND balance = 0 AND status <> 'closed'
+RETURNING id, owner, balance, currency, created_at, status, overdraft_limit, product
+`
+
+// CloseAccount only closes accounts with nothing left in them.
+func (q *Queries) CloseAccount(ctx context.Context, id int64) (Account, error) {
+	row := q.db.QueryRowContext(ctx, closeAccount, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Status,
+		&i.OverdraftLimit,
+		&i.Product,
+	)
+	return i, err
+}
+
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO accounts (
    owner, 
    balance,
-   currency
+   currency,
+   product
 ) VALUES (
-   $1, $2, $3
-) RETURNING id, owner, balance, currency, created_at
+   $1, $2, $3, $4
+) RETURNING id, owner, balance, currency, created_at, status, overdraft_limit, product
 `
 
 type CreateAccountParams struct {
 	Owner    string `json:"owner"`
 	Balance  int64  `json:"balance"`
 	Currency string `json:"currency"`
+	Product  string `json:"product"`
 }
 
 func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, createAccount, arg.Owner, arg.Balance, arg.Currency)
+	row := q.db.QueryRowContext(ctx, createAccount,
+		arg.Owner,
+		arg.Balance,
+		arg.Currency,
+		arg.Product,
+	)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -34,6 +66,9 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.OverdraftLimit,
+		&i.Product,
 	)
 	return i, err
 }
@@ -49,7 +84,7 @@ func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts 
+SELECT id, owner, balance, currency, created_at, status, overdraft_limit, product FROM accounts 
 WHERE id = $1 LIMIT 1
 `
 
@@ -62,12 +97,41 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.OverdraftLimit,
+		&i.Product,
+	)
+	return i, err
+}
+
+const getAccountByOwner = `-- name: GetAccountByOwner :one
+SELECT id, owner, balance, currency, created_at, status, overdraft_limit, product FROM accounts
+WHERE owner = $1 AND currency = $2 LIMIT 1
+`
+
+type GetAccountByOwnerParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+}
+
+func (q *Queries) GetAccountByOwner(ctx context.Context, arg GetAccountByOwnerParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, getAccountByOwner, arg.Owner, arg.Currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Status,
+		&i.OverdraftLimit,
+		&i.Product,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts 
+SELECT id, owner, balance, currency, created_at, status, overdraft_limit, product FROM accounts 
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE
 `
@@ -81,12 +145,15 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.OverdraftLimit,
+		&i.Product,
 	)
 	return i, err
 }
 
 const listAccount = `-- name: ListAccount :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, status, overdraft_limit, product FROM accounts
 WHERE owner = $1
 ORDER BY id
 LIMIT $2
@@ -114,6 +181,62 @@ func (q *Queries) ListAccount(ctx context.Context, arg ListAccountParams) ([]Acc
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.Status,
+			&i.OverdraftLimit,
+			&i.Product,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountEntrySums = `-- name: ListAccountEntrySums :many
+SELECT a.id, a.owner, a.currency, a.balance,
+   COALESCE(SUM(e.amount), 0)::bigint AS entries_sum
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id > $1
+GROUP BY a.id
+ORDER BY a.id
+LIMIT $2
+`
+
+type ListAccountEntrySumsParams struct {
+	AfterID int64 `json:"after_id"`
+	Size    int32 `json:"size"`
+}
+
+type ListAccountEntrySumsRow struct {
+	ID         int64  `json:"id"`
+	Owner      string `json:"owner"`
+	Currency   string `json:"currency"`
+	Balance    int64  `json:"balance"`
+	EntriesSum int64  `json:"entries_sum"`
+}
+
+func (q *Queries) ListAccountEntrySums(ctx context.Context, arg ListAccountEntrySumsParams) ([]ListAccountEntrySumsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountEntrySums, arg.AfterID, arg.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountEntrySumsRow{}
+	for rows.Next() {
+		var i ListAccountEntrySumsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Currency,
+			&i.Balance,
+			&i.EntriesSum,
 		); err != nil {
 			return nil, err
 		}
@@ -128,20 +251,134 @@ func (q *Queries) ListAccount(ctx context.Context, arg ListAccountParams) ([]Acc
 	return items, nil
 }
 
-const updateAccount = `-- name: UpdateAccount :one
+const listAccountsByOwnerForUpdate = `-- name: ListAccountsByOwnerForUpdate :many
+SELECT id, owner, balance, currency, created_at, status, overdraft_limit, product FROM accounts
+WHERE owner = $1
+ORDER BY id
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) ListAccountsByOwnerForUpdate(ctx context.Context, owner string) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsByOwnerForUpdate, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.Status,
+			&i.OverdraftLimit,
+			&i.Product,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAllAccounts = `-- name: ListAllAccounts :many
+SELECT id, owner, balance, currency, created_at, status, overdraft_limit, product FROM accounts
+WHERE id > $1
+   AND ($2::varchar IS NULL OR owner = $2)
+ORDER BY id
+LIMIT $3
+`
+
+type ListAllAccountsParams struct {
+	AfterID int64          `json:"after_id"`
+	Owner   sql.NullString `json:"owner"`
+	Size    int32          `json:"size"`
+}
+
+func (q *Queries) ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAllAccounts, arg.AfterID, arg.Owner, arg.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.Status,
+			&i.OverdraftLimit,
+			&i.Product,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setAccountOverdraftLimit = `-- name: SetAccountOverdraftLimit :one
 UPDATE accounts
-SET balance = $2
+SET overdraft_limit = $2
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, status, overdraft_limit, product
+`
+
+type SetAccountOverdraftLimitParams struct {
+	ID             int64 `json:"id"`
+	OverdraftLimit int64 `json:"overdraft_limit"`
+}
+
+func (q *Queries) SetAccountOverdraftLimit(ctx context.Context, arg SetAccountOverdraftLimitParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, setAccountOverdraftLimit, arg.ID, arg.OverdraftLimit)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Status,
+		&i.OverdraftLimit,
+		&i.Product,
+	)
+	return i, err
+}
+
+const setAccountStatus = `-- name: SetAccountStatus :one
+UPDATE accounts
+SET status = $2
+WHERE id = $1 AND status <> 'closed'
+RETURNING id, owner, balance, currency, created_at, status, overdraft_limit, product
 `
 
-type UpdateAccountParams struct {
-	ID      int64 `json:"id"`
-	Balance int64 `json:"balance"`
+type SetAccountStatusParams struct {
+	ID     int64         `json:"id"`
+	Status AccountStatus `json:"status"`
 }
 
-func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error) {
-	row := q.db.QueryRowContext(ctx, updateAccount, arg.ID, arg.Balance)
+// SetAccountStatus leaves closed accounts alone: closing is final.
+func (q *Queries) SetAccountStatus(ctx context.Context, arg SetAccountStatusParams) (Account, error) {
+	row := q.db.QueryRowContext(ctx, setAccountStatus, arg.ID, arg.Status)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -149,6 +386,9 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.OverdraftLimit,
+		&i.Product,
 	)
 	return i, err
 }
@@ -157,7 +397,7 @@ const updateAccountBalance = `-- name: UpdateAccountBalance :one
 UPDATE accounts
 SET balance = $1 + balance
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, status, overdraft_limit, product
 `
 
 type UpdateAccountBalanceParams struct {
@@ -174,6 +414,9 @@ func (q *Queries) UpdateAccountBalance(ctx context.Context, arg UpdateAccountBal
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Status,
+		&i.OverdraftLimit,
+		&i.Product,
 	)
 	return i, err
 }
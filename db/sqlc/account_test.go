@@ -17,6 +17,7 @@ func createRandomAccount(t *testing.T) Account {
 		Owner:    user.Username,
 		Balance:  util.RandomMoney(),
 		Currency: util.RandomCurrency(),
+		Product:  "checking",
 	}
 
 	acc, err := testQueries.CreateAccount(context.Background(), arg)
@@ -27,6 +28,7 @@ func createRandomAccount(t *testing.T) Account {
 	require.Equal(t, arg.Owner, acc.Owner)
 	require.Equal(t, arg.Balance, acc.Balance)
 	require.Equal(t, arg.Currency, acc.Currency)
+	require.Equal(t, arg.Product, acc.Product)
 
 	require.NotZero(t, acc.ID)
 	require.NotZero(t, acc.CreatedAt)
@@ -52,24 +54,15 @@ func TestGetAccount(t *testing.T) {
 	require.WithinDuration(t, acc1.CreatedAt, acc2.CreatedAt, time.Second)
 }
 
-func TestUpdateAccount(t *testing.T) {
+func TestGetAccountByOwner(t *testing.T) {
 	acc1 := createRandomAccount(t)
-	balance := util.RandomMoney()
-	arg := UpdateAccountParams{
-		ID:      acc1.ID,
-		Balance: balance,
-	}
 
-	acc2, err := testQueries.UpdateAccount(context.Background(), arg)
+	acc2, err := testQueries.GetAccountByOwner(context.Background(), GetAccountByOwnerParams{
+		Owner:    acc1.Owner,
+		Currency: acc1.Currency,
+	})
 	require.NoError(t, err)
-	require.NotEmpty(t, acc2)
-
-	require.Equal(t, acc2.ID, acc1.ID)
-	require.Equal(t, acc2.Owner, acc1.Owner)
-	require.Equal(t, acc2.Currency, acc1.Currency)
-	require.WithinDuration(t, acc1.CreatedAt, acc2.CreatedAt, time.Second)
-
-	require.Equal(t, acc2.Balance, balance)
+	require.Equal(t, acc1.ID, acc2.ID)
 }
 
 func TestDeleteAccount(t *testing.T) {
@@ -106,3 +99,75 @@ func TestListAccounts(t *testing.T) {
 	}
 
 }
+
+func TestSetAccountStatus(t *testing.T) {
+	acc1 := createRandomAccount(t)
+	require.Equal(t, AccountStatusActive, acc1.Status)
+
+	acc2, err := testQueries.SetAccountStatus(context.Background(), SetAccountStatusParams{
+		ID:     acc1.ID,
+		Status: AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+	require.Equal(t, acc1.ID, acc2.ID)
+	require.Equal(t, AccountStatusFrozen, acc2.Status)
+
+	// a closed account stays closed
+	_, err = testQueries.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{
+		ID:     acc1.ID,
+		Amount: -acc1.Balance,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.CloseAccount(context.Background(), acc1.ID)
+	require.NoError(t, err)
+
+	_, err = testQueries.SetAccountStatus(context.Background(), SetAccountStatusParams{
+		ID:     acc1.ID,
+		Status: AccountStatusActive,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCloseAccount(t *testing.T) {
+	acc := createRandomAccount(t)
+
+	// only empty accounts can be closed
+	_, err := testQueries.CloseAccount(context.Background(), acc.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testQueries.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{
+		ID:     acc.ID,
+		Amount: -acc.Balance,
+	})
+	require.NoError(t, err)
+
+	closed, err := testQueries.CloseAccount(context.Background(), acc.ID)
+	require.NoError(t, err)
+	require.Equal(t, AccountStatusClosed, closed.Status)
+	require.Zero(t, closed.Balance)
+
+	_, err = testQueries.CloseAccount(context.Background(), acc.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestListAccountEntrySums(t *testing.T) {
+	acc := createRandomAccount(t)
+
+	_, err := testQueries.CreateEntry(context.Background(), CreateEntryParams{
+		AccountID: acc.ID,
+		Amount:    acc.Balance,
+	})
+	require.NoError(t, err)
+
+	rows, err := testQueries.ListAccountEntrySums(context.Background(), ListAccountEntrySumsParams{
+		AfterID: acc.ID - 1,
+		Size:    1,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	require.Equal(t, acc.ID, rows[0].ID)
+	require.Equal(t, acc.Balance, rows[0].Balance)
+	require.Equal(t, acc.Balance, rows[0].EntriesSum)
+}
@@ -0,0 +1,101 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: adjustment.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createAdjustment = `-- name: CreateAdjustment :one
+INSERT INTO adjustments (
+   account_id,
+   transfer_id,
+   amount,
+   reason_code,
+   note,
+   operator
+) VALUES (
+   $1, $2, $3, $4, $5, $6
+) RETURNING id, account_id, transfer_id, amount, reason_code, note, operator, created_at
+`
+
+type CreateAdjustmentParams struct {
+	AccountID  int64            `json:"account_id"`
+	TransferID int64            `json:"transfer_id"`
+	Amount     int64            `json:"amount"`
+	ReasonCode AdjustmentReason `json:"reason_code"`
+	Note       string           `json:"note"`
+	Operator   string           `json:"operator"`
+}
+
+func (q *Queries) CreateAdjustment(ctx context.Context, arg CreateAdjustmentParams) (Adjustment, error) {
+	row := q.db.QueryRowContext(ctx, createAdjustment,
+		arg.AccountID,
+		arg.TransferID,
+		arg.Amount,
+		arg.ReasonCode,
+		arg.Note,
+		arg.Operator,
+	)
+	var i Adjustment
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.TransferID,
+		&i.Amount,
+		&i.ReasonCode,
+		&i.Note,
+		&i.Operator,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAdjustments = `-- name: ListAdjustments :many
+SELECT id, account_id, transfer_id, amount, reason_code, note, operator, created_at FROM adjustments
+WHERE account_id = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListAdjustmentsParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+func (q *Queries) ListAdjustments(ctx context.Context, arg ListAdjustmentsParams) ([]Adjustment, error) {
+	rows, err := q.db.QueryContext(ctx, listAdjustments, arg.AccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Adjustment{}
+	for rows.Next() {
+		var i Adjustment
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.TransferID,
+			&i.Amount,
+			&i.ReasonCode,
+			&i.Note,
+			&i.Operator,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
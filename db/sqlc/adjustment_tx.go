@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SuspenseOwner owns the per-currency suspense accounts that absorb the other
+// side of every manual adjustment.
+const SuspenseOwner = "suspense"
+
+var ErrInternalAccount = errors.New("cannot adjust an internal account")
+
+// IsInternalOwner reports whether owner is one of the system users whose
+// accounts back the ledger rather than hold customer money.
+func IsInternalOwner(owner string) bool {
+	switch owner {
+	case SuspenseOwner, SettlementOwner, RevenueOwner, InterestOwner:
+		return true
+	}
+
+	return false
+}
+
+type AdjustmentTxParams struct {
+	AccountID  int64            `json:"account_id"`
+	Amount     int64            `json:"amount"`
+	ReasonCode AdjustmentReason `json:"reason_code"`
+	Note       string           `json:"note"`
+	Operator   string           `json:"operator"`
+}
+
+type AdjustmentTxResult struct {
+	Adjustment Adjustment `json:"adjustment"`
+	Transfer   Transfer   `json:"transfer"`
+	Account    Account    `json:"account"`
+	Entry      Entry      `json:"entry"`
+}
+
+// AdjustmentTx posts a manual adjustment and records it in the audit log in a
+// single transaction.
+func (s *SQLStore) AdjustmentTx(ctx context.Context, arg AdjustmentTxParams) (AdjustmentTxResult, error) {
+	var result AdjustmentTxResult
+
+	details, err := json.Marshal(arg)
+	if err != nil {
+		return result, err
+	}
+
+	reason := string(arg.ReasonCode)
+	if arg.Note != "" {
+		reason += ": " + arg.Note
+	}
+
+	audit := CreateAuditLogParams{
+		Actor:   arg.Operator,
+		Action:  "account.adjust",
+		Target:  fmt.Sprintf("account:%d", arg.AccountID),
+		Reason:  reason,
+		Details: details,
+	}
+
+	_, err = s.AuditTx(ctx, audit, func(q *Queries) error {
+		var err error
+		result, err = q.PostAdjustment(ctx, arg)
+		return err
+	})
+
+	return result, err
+}
+
+// PostAdjustment moves arg.Amount between the account and the suspense
+// account of its currency: a positive amount credits the account, a negative
+// one debits it. Like PostTransfer, it must run inside a transaction.
+func (q *Queries) PostAdjustment(ctx context.Context, arg AdjustmentTxParams) (AdjustmentTxResult, error) {
+	var result AdjustmentTxResult
+
+	account, err := q.GetAccount(ctx, arg.AccountID)
+	if err != nil {
+		return result, err
+	}
+
+	if IsInternalOwner(account.Owner) {
+		return result, ErrInternalAccount
+	}
+
+	suspense, err := q.GetAccountByOwner(ctx, GetAccountByOwnerParams{
+		Owner:    SuspenseOwner,
+		Currency: account.Currency,
+	})
+	if err != nil {
+		return result, fmt.Errorf("cannot get %s suspense account: %w", account.Currency, err)
+	}
+
+	credit := arg.Amount > 0
+	transferArg := TransferTxParams{
+		FromAccountID: suspense.ID,
+		ToAccountID:   account.ID,
+		Amount:        arg.Amount,
+	}
+
+	if !credit {
+		transferArg = TransferTxParams{
+			FromAccountID: account.ID,
+			ToAccountID:   suspense.ID,
+			Amount:        -arg.Amount,
+		}
+	}
+
+	transfer, err := q.PostTransfer(ctx, transferArg)
+	if err != nil {
+		return result, err
+	}
+
+	result.Transfer = transfer.Transfer
+	if credit {
+		result.Account, result.Entry = transfer.ToAccount, transfer.ToEntry
+	} else {
+		result.Account, result.Entry = transfer.FromAccount, transfer.FromEntry
+	}
+
+	result.Adjustment, err = q.CreateAdjustment(ctx, CreateAdjustmentParams{
+		AccountID:  account.ID,
+		TransferID: transfer.Transfer.ID,
+		Amount:     arg.Amount,
+		ReasonCode: arg.ReasonCode,
+		Note:       arg.Note,
+		Operator:   arg.Operator,
+	})
+
+	return result, err
+}
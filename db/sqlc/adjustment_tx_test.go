@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdjustmentTx(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomAccount(t)
+
+	suspense, err := testQueries.GetAccountByOwner(context.Background(), GetAccountByOwnerParams{
+		Owner:    SuspenseOwner,
+		Currency: acc.Currency,
+	})
+	require.NoError(t, err)
+
+	for _, amount := range []int64{25, -10} {
+		arg := AdjustmentTxParams{
+			AccountID:  acc.ID,
+			Amount:     amount,
+			ReasonCode: AdjustmentReasonGoodwill,
+			Note:       util.RandomString(10),
+			Operator:   util.RandomOnwer(),
+		}
+
+		result, err := store.AdjustmentTx(context.Background(), arg)
+		require.NoError(t, err)
+
+		require.Equal(t, acc.ID, result.Account.ID)
+		require.Equal(t, amount, result.Entry.Amount)
+		require.Equal(t, result.Transfer.ID, result.Entry.TransferID.Int64)
+		require.Equal(t, amount, result.Adjustment.Amount)
+		require.Equal(t, arg.Operator, result.Adjustment.Operator)
+		require.Equal(t, result.Transfer.ID, result.Adjustment.TransferID)
+
+		if amount > 0 {
+			require.Equal(t, suspense.ID, result.Transfer.FromAccountID)
+		} else {
+			require.Equal(t, suspense.ID, result.Transfer.ToAccountID)
+		}
+	}
+
+	updated, err := testQueries.GetAccount(context.Background(), acc.ID)
+	require.NoError(t, err)
+	require.Equal(t, acc.Balance+15, updated.Balance)
+
+	logs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Target: fmt.Sprintf("account:%d", acc.ID),
+		Limit:  5,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+}
+
+func TestAdjustmentTxInternalAccount(t *testing.T) {
+	store := NewStore(testDB)
+
+	for _, owner := range []string{SuspenseOwner, SettlementOwner, RevenueOwner, InterestOwner} {
+		internal, err := testQueries.GetAccountByOwner(context.Background(), GetAccountByOwnerParams{
+			Owner:    owner,
+			Currency: util.RandomCurrency(),
+		})
+		require.NoError(t, err)
+
+		_, err = store.AdjustmentTx(context.Background(), AdjustmentTxParams{
+			AccountID:  internal.ID,
+			Amount:     10,
+			ReasonCode: AdjustmentReasonCorrection,
+			Operator:   util.RandomOnwer(),
+		})
+		require.ErrorIs(t, err, ErrInternalAccount, owner)
+	}
+}
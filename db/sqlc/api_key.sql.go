@@ -0,0 +1,212 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: api_key.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+   owner,
+   name,
+   prefix,
+   secret_hash,
+   scopes,
+   account_ids,
+   max_transfer_amount,
+   expires_at
+) VALUES (
+   $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING id, owner, name, prefix, secret_hash, scopes, account_ids, max_transfer_amount, expires_at, last_used_at, revoked_at, created_at
+`
+
+type CreateAPIKeyParams struct {
+	Owner             string       `json:"owner"`
+	Name              string       `json:"name"`
+	Prefix            string       `json:"prefix"`
+	SecretHash        string       `json:"secret_hash"`
+	Scopes            []string     `json:"scopes"`
+	AccountIds        []int64      `json:"account_ids"`
+	MaxTransferAmount int64        `json:"max_transfer_amount"`
+	ExpiresAt         sql.NullTime `json:"expires_at"`
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, createAPIKey,
+		arg.Owner,
+		arg.Name,
+		arg.Prefix,
+		arg.SecretHash,
+		pq.Array(arg.Scopes),
+		pq.Array(arg.AccountIds),
+		arg.MaxTransferAmount,
+		arg.ExpiresAt,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Name,
+		&i.Prefix,
+		&i.SecretHash,
+		pq.Array(&i.Scopes),
+		pq.Array(&i.AccountIds),
+		&i.MaxTransferAmount,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAPIKey = `-- name: GetAPIKey :one
+SELECT id, owner, name, prefix, secret_hash, scopes, account_ids, max_transfer_amount, expires_at, last_used_at, revoked_at, created_at FROM api_keys
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetAPIKey(ctx context.Context, id int64) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, getAPIKey, id)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Name,
+		&i.Prefix,
+		&i.SecretHash,
+		pq.Array(&i.Scopes),
+		pq.Array(&i.AccountIds),
+		&i.MaxTransferAmount,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getAPIKeyByPrefix = `-- name: GetAPIKeyByPrefix :one
+SELECT id, owner, name, prefix, secret_hash, scopes, account_ids, max_transfer_amount, expires_at, last_used_at, revoked_at, created_at FROM api_keys
+WHERE prefix = $1 LIMIT 1
+`
+
+func (q *Queries) GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, getAPIKeyByPrefix, prefix)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Name,
+		&i.Prefix,
+		&i.SecretHash,
+		pq.Array(&i.Scopes),
+		pq.Array(&i.AccountIds),
+		&i.MaxTransferAmount,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAPIKeys = `-- name: ListAPIKeys :many
+SELECT id, owner, name, prefix, secret_hash, scopes, account_ids, max_transfer_amount, expires_at, last_used_at, revoked_at, created_at FROM api_keys
+WHERE owner = $1
+ORDER BY id
+`
+
+func (q *Queries) ListAPIKeys(ctx context.Context, owner string) ([]ApiKey, error) {
+	rows, err := q.db.QueryContext(ctx, listAPIKeys, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiKey{}
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Name,
+			&i.Prefix,
+			&i.SecretHash,
+			pq.Array(&i.Scopes),
+			pq.Array(&i.AccountIds),
+			&i.MaxTransferAmount,
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.RevokedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :one
+UPDATE api_keys
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL
+RETURNING id, owner, name, prefix, secret_hash, scopes, account_ids, max_transfer_amount, expires_at, last_used_at, revoked_at, created_at
+`
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, id int64) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, revokeAPIKey, id)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Name,
+		&i.Prefix,
+		&i.SecretHash,
+		pq.Array(&i.Scopes),
+		pq.Array(&i.AccountIds),
+		&i.MaxTransferAmount,
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const revokeUserAPIKeys = `-- name: RevokeUserAPIKeys :exec
+UPDATE api_keys
+SET revoked_at = now()
+WHERE owner = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserAPIKeys(ctx context.Context, owner string) error {
+	_, err := q.db.ExecContext(ctx, revokeUserAPIKeys, owner)
+	return err
+}
+
+const touchAPIKey = `-- name: TouchAPIKey :exec
+UPDATE api_keys
+SET last_used_at = now()
+WHERE id = $1
+   AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')
+`
+
+// TouchAPIKey records that the key was used, at most once a minute so busy
+// integrations don't write on every request.
+func (q *Queries) TouchAPIKey(ctx context.Context, id int64) error {
+	_, err := q.db.ExecContext(ctx, touchAPIKey, id)
+	return err
+}
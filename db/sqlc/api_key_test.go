@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+
+	key, err := testQueries.CreateAPIKey(ctx, CreateAPIKeyParams{
+		Owner:             user.Username,
+		Name:              "payroll",
+		Prefix:            "dbk_" + util.RandomString(12),
+		SecretHash:        util.RandomString(64),
+		Scopes:            []string{"accounts:read"},
+		AccountIds:        []int64{1, 2},
+		MaxTransferAmount: 500,
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, key.AccountIds)
+	require.False(t, key.ExpiresAt.Valid)
+	require.False(t, key.LastUsedAt.Valid)
+
+	found, err := testQueries.GetAPIKeyByPrefix(ctx, key.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, found.ID)
+
+	require.NoError(t, testQueries.TouchAPIKey(ctx, key.ID))
+	touched, err := testQueries.GetAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	require.True(t, touched.LastUsedAt.Valid)
+
+	// a second use within the minute does not write
+	require.NoError(t, testQueries.TouchAPIKey(ctx, key.ID))
+	again, err := testQueries.GetAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	require.Equal(t, touched.LastUsedAt, again.LastUsedAt)
+
+	revoked, err := testQueries.RevokeAPIKey(ctx, key.ID)
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	_, err = testQueries.RevokeAPIKey(ctx, key.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	keys, err := testQueries.ListAPIKeys(ctx, user.Username)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+}
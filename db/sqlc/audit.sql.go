@@ -0,0 +1,97 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: audit.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const createAuditLog = `-- name: CreateAuditLog :one
+INSERT INTO audit_logs (
+   actor,
+   action,
+   target,
+   reason,
+   details
+) VALUES (
+   $1, $2, $3, $4, $5
+) RETURNING id, actor, action, target, reason, details, created_at
+`
+
+type CreateAuditLogParams struct {
+	Actor   string          `json:"actor"`
+	Action  string          `json:"action"`
+	Target  string          `json:"target"`
+	Reason  string          `json:"reason"`
+	Details json.RawMessage `json:"details"`
+}
+
+func (q *Queries) CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error) {
+	row := q.db.QueryRowContext(ctx, createAuditLog,
+		arg.Actor,
+		arg.Action,
+		arg.Target,
+		arg.Reason,
+		arg.Details,
+	)
+	var i AuditLog
+	err := row.Scan(
+		&i.ID,
+		&i.Actor,
+		&i.Action,
+		&i.Target,
+		&i.Reason,
+		&i.Details,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAuditLogs = `-- name: ListAuditLogs :many
+SELECT id, actor, action, target, reason, details, created_at FROM audit_logs
+WHERE target = $1
+ORDER BY id DESC
+LIMIT $2
+OFFSET $3
+`
+
+type ListAuditLogsParams struct {
+	Target string `json:"target"`
+	Limit  int32  `json:"limit"`
+	Offset int32  `json:"offset"`
+}
+
+func (q *Queries) ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error) {
+	rows, err := q.db.QueryContext(ctx, listAuditLogs, arg.Target, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []AuditLog{}
+	for rows.Next() {
+		var i AuditLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.Actor,
+			&i.Action,
+			&i.Target,
+			&i.Reason,
+			&i.Details,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAuditLog(t *testing.T, target string) AuditLog {
+	arg := CreateAuditLogParams{
+		Actor:   util.RandomOnwer(),
+		Action:  "account.adjust",
+		Target:  target,
+		Reason:  util.RandomString(20),
+		Details: []byte(`{"amount": 10}`),
+	}
+
+	log, err := testQueries.CreateAuditLog(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, log)
+
+	require.Equal(t, arg.Actor, log.Actor)
+	require.Equal(t, arg.Action, log.Action)
+	require.Equal(t, arg.Target, log.Target)
+	require.Equal(t, arg.Reason, log.Reason)
+	require.JSONEq(t, string(arg.Details), string(log.Details))
+	require.NotZero(t, log.ID)
+	require.NotZero(t, log.CreatedAt)
+
+	return log
+}
+
+func TestCreateAuditLog(t *testing.T) {
+	createRandomAuditLog(t, "account:"+util.RandomString(6))
+}
+
+func TestListAuditLogs(t *testing.T) {
+	target := "account:" + util.RandomString(6)
+	for i := 0; i < 3; i++ {
+		createRandomAuditLog(t, target)
+	}
+
+	logs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Target: target,
+		Limit:  5,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 3)
+
+	for _, log := range logs {
+		require.Equal(t, target, log.Target)
+	}
+}
+
+func TestAuditTxRollback(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomAccount(t)
+	target := fmt.Sprintf("account:%d", acc.ID)
+
+	_, err := store.AuditTx(context.Background(), CreateAuditLogParams{
+		Actor:  util.RandomOnwer(),
+		Action: "account.adjust",
+		Target: target,
+		Reason: "test",
+	}, func(q *Queries) error {
+		_, err := q.PostAdjustment(context.Background(), AdjustmentTxParams{
+			AccountID:  acc.ID,
+			Amount:     10,
+			ReasonCode: AdjustmentReasonCorrection,
+			Operator:   "test",
+		})
+		require.NoError(t, err)
+
+		return fmt.Errorf("abort")
+	})
+	require.EqualError(t, err, "abort")
+
+	updated, err := testQueries.GetAccount(context.Background(), acc.ID)
+	require.NoError(t, err)
+	require.Equal(t, acc.Balance, updated.Balance)
+
+	logs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Target: target,
+		Limit:  5,
+	})
+	require.NoError(t, err)
+	require.Empty(t, logs)
+}
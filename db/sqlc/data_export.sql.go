@@ -0,0 +1,325 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: data_export.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const claimDataExport = `-- name: ClaimDataExport :one
+UPDATE data_exports
+SET status = 'running',
+   started_at = now(),
+   claimed_at = now()
+WHERE id = (
+   SELECT e.id FROM data_exports e
+   WHERE e.status = 'pending'
+      OR (e.status = 'running' AND e.claimed_at < $1::timestamptz)
+   ORDER BY e.id
+   LIMIT 1
+   FOR UPDATE SKIP LOCKED
+)
+RETURNING id, username, status, archive, error, started_at, completed_at, expires_at, created_at, claimed_at
+`
+
+// ClaimDataExport picks the oldest pending export, skipping ones another
+// worker holds. A running export claimed before stale_before is picked too:
+// its worker is taken to have died.
+func (q *Queries) ClaimDataExport(ctx context.Context, staleBefore time.Time) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, claimDataExport, staleBefore)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Status,
+		&i.Archive,
+		&i.Error,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const completeDataExport = `-- name: CompleteDataExport :one
+UPDATE data_exports
+SET status = 'completed',
+   archive = $1,
+   completed_at = now(),
+   expires_at = $2
+WHERE id = $3
+RETURNING id, username, status, archive, error, started_at, completed_at, expires_at, created_at, claimed_at
+`
+
+type CompleteDataExportParams struct {
+	Archive   []byte       `json:"archive"`
+	ExpiresAt sql.NullTime `json:"expires_at"`
+	ID        int64        `json:"id"`
+}
+
+func (q *Queries) CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, completeDataExport, arg.Archive, arg.ExpiresAt, arg.ID)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Status,
+		&i.Archive,
+		&i.Error,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const createDataExport = `-- name: CreateDataExport :one
+INSERT INTO data_exports (
+   username
+) VALUES (
+   $1
+) RETURNING id, username, status, archive, error, started_at, completed_at, expires_at, created_at, claimed_at
+`
+
+func (q *Queries) CreateDataExport(ctx context.Context, username string) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, createDataExport, username)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Status,
+		&i.Archive,
+		&i.Error,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const failDataExport = `-- name: FailDataExport :one
+UPDATE data_exports
+SET status = 'failed',
+   error = $1,
+   completed_at = now()
+WHERE id = $2
+RETURNING id, username, status, archive, error, started_at, completed_at, expires_at, created_at, claimed_at
+`
+
+type FailDataExportParams struct {
+	Error string `json:"error"`
+	ID    int64  `json:"id"`
+}
+
+func (q *Queries) FailDataExport(ctx context.Context, arg FailDataExportParams) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, failDataExport, arg.Error, arg.ID)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Status,
+		&i.Archive,
+		&i.Error,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const getDataExport = `-- name: GetDataExport :one
+SELECT id, username, status, archive, error, started_at, completed_at, expires_at, created_at, claimed_at FROM data_exports
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetDataExport(ctx context.Context, id int64) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, getDataExport, id)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Status,
+		&i.Archive,
+		&i.Error,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.ClaimedAt,
+	)
+	return i, err
+}
+
+const listDataExports = `-- name: ListDataExports :many
+SELECT id, username, status, archive, error, started_at, completed_at, expires_at, created_at, claimed_at FROM data_exports
+WHERE username = $1
+ORDER BY id DESC
+LIMIT $2
+`
+
+type ListDataExportsParams struct {
+	Username string `json:"username"`
+	Limit    int32  `json:"limit"`
+}
+
+func (q *Queries) ListDataExports(ctx context.Context, arg ListDataExportsParams) ([]DataExport, error) {
+	rows, err := q.db.QueryContext(ctx, listDataExports, arg.Username, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []DataExport{}
+	for rows.Next() {
+		var i DataExport
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Status,
+			&i.Archive,
+			&i.Error,
+			&i.StartedAt,
+			&i.CompletedAt,
+			&i.ExpiresAt,
+			&i.CreatedAt,
+			&i.ClaimedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOwnerEntries = `-- name: ListOwnerEntries :many
+SELECT e.id, e.account_id, e.amount, e.created_at, e.transfer_id FROM entries e
+JOIN accounts a ON a.id = e.account_id
+WHERE a.owner = $1 AND e.id > $2
+ORDER BY e.id
+LIMIT $3
+`
+
+type ListOwnerEntriesParams struct {
+	Owner   string `json:"owner"`
+	AfterID int64  `json:"after_id"`
+	Size    int32  `json:"size"`
+}
+
+func (q *Queries) ListOwnerEntries(ctx context.Context, arg ListOwnerEntriesParams) ([]Entry, error) {
+	rows, err := q.db.QueryContext(ctx, listOwnerEntries, arg.Owner, arg.AfterID, arg.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Entry{}
+	for rows.Next() {
+		var i Entry
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.TransferID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOwnerTransfers = `-- name: ListOwnerTransfers :many
+SELECT t.id, t.from_account_id, t.to_account_id, t.amount, t.created_at FROM transfers t
+WHERE t.id > $1
+   AND EXISTS (
+      SELECT 1 FROM accounts a
+      WHERE a.owner = $2
+         AND a.id IN (t.from_account_id, t.to_account_id)
+   )
+ORDER BY t.id
+LIMIT $3
+`
+
+type ListOwnerTransfersParams struct {
+	AfterID int64  `json:"after_id"`
+	Owner   string `json:"owner"`
+	Size    int32  `json:"size"`
+}
+
+func (q *Queries) ListOwnerTransfers(ctx context.Context, arg ListOwnerTransfersParams) ([]Transfer, error) {
+	rows, err := q.db.QueryContext(ctx, listOwnerTransfers, arg.AfterID, arg.Owner, arg.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transfer{}
+	for rows.Next() {
+		var i Transfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const purgeExpiredDataExports = `-- name: PurgeExpiredDataExports :execrows
+UPDATE data_exports
+SET archive = NULL
+WHERE archive IS NOT NULL AND expires_at < now()
+`
+
+func (q *Queries) PurgeExpiredDataExports(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeExpiredDataExports)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeUserDataExports = `-- name: PurgeUserDataExports :exec
+UPDATE data_exports
+SET archive = NULL
+WHERE username = $1 AND archive IS NOT NULL
+`
+
+func (q *Queries) PurgeUserDataExports(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, purgeUserDataExports, username)
+	return err
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DataExportActor is recorded in the audit log for exports the background
+// job completes.
+const DataExportActor = "system:data-export"
+
+// RequestDataExportTx queues an export of the user's data and records the
+// request in the audit log.
+func (s *SQLStore) RequestDataExportTx(ctx context.Context, username string) (DataExport, error) {
+	var export DataExport
+
+	audit := CreateAuditLogParams{
+		Actor:  username,
+		Action: "user.export_request",
+		Target: "user:" + username,
+		Reason: "requested by the user",
+	}
+
+	_, err := s.AuditTx(ctx, audit, func(q *Queries) error {
+		var err error
+		export, err = q.CreateDataExport(ctx, username)
+		return err
+	})
+
+	return export, err
+}
+
+// CompleteDataExportTx stores the finished archive and records the export in
+// the audit log.
+func (s *SQLStore) CompleteDataExportTx(ctx context.Context, arg CompleteDataExportParams) (DataExport, error) {
+	var export DataExport
+
+	details, err := json.Marshal(map[string]any{
+		"export_id": arg.ID,
+		"size":      len(arg.Archive),
+	})
+	if err != nil {
+		return export, err
+	}
+
+	err = s.execTx(ctx, func(q *Queries) error {
+		var err error
+		export, err = q.CompleteDataExport(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateAuditLog(ctx, CreateAuditLogParams{
+			Actor:   DataExportActor,
+			Action:  "user.export",
+			Target:  "user:" + export.Username,
+			Reason:  fmt.Sprintf("data export %d", export.ID),
+			Details: details,
+		})
+		return err
+	})
+
+	return export, err
+}
@@ -0,0 +1,135 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExportTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	export, err := store.RequestDataExportTx(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.Username, export.Username)
+	require.Equal(t, DataExportStatusPending, export.Status)
+
+	completed, err := store.CompleteDataExportTx(context.Background(), CompleteDataExportParams{
+		ID:        export.ID,
+		Archive:   []byte("archive"),
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, DataExportStatusCompleted, completed.Status)
+	require.Equal(t, []byte("archive"), completed.Archive)
+	require.True(t, completed.CompletedAt.Valid)
+
+	logs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Target: "user:" + user.Username,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+
+	// the archive has expired, so it is purged but the export is kept
+	_, err = testQueries.PurgeExpiredDataExports(context.Background())
+	require.NoError(t, err)
+
+	got, err := testQueries.GetDataExport(context.Background(), export.ID)
+	require.NoError(t, err)
+	require.Nil(t, got.Archive)
+	require.Equal(t, DataExportStatusCompleted, got.Status)
+}
+
+func TestListOwnerEntriesAndTransfers(t *testing.T) {
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+	acc3 := createRandomAccount(t)
+
+	for _, to := range []int64{acc2.ID, acc3.ID} {
+		_, err := store.TransferTx(context.Background(), TransferTxParams{
+			FromAccountID: acc1.ID,
+			ToAccountID:   to,
+			Amount:        10,
+		})
+		require.NoError(t, err)
+	}
+
+	entries, err := testQueries.ListOwnerEntries(context.Background(), ListOwnerEntriesParams{
+		Owner: acc1.Owner,
+		Size:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		require.Equal(t, acc1.ID, entry.AccountID)
+	}
+
+	transfers, err := testQueries.ListOwnerTransfers(context.Background(), ListOwnerTransfersParams{
+		Owner: acc1.Owner,
+		Size:  1,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+
+	transfers, err = testQueries.ListOwnerTransfers(context.Background(), ListOwnerTransfersParams{
+		Owner:   acc1.Owner,
+		AfterID: transfers[0].ID,
+		Size:    10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, acc3.ID, transfers[0].ToAccountID)
+
+	transfers, err = testQueries.ListOwnerTransfers(context.Background(), ListOwnerTransfersParams{
+		Owner: acc2.Owner,
+		Size:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, transfers, 1)
+	require.Equal(t, acc1.ID, transfers[0].FromAccountID)
+}
+
+func TestClaimDataExportReclaimsStale(t *testing.T) {
+	user := createRandomUser(t)
+	export, err := testQueries.CreateDataExport(context.Background(), user.Username)
+	require.NoError(t, err)
+
+	// claim every pending export, ours among them
+	var claimed DataExport
+	for {
+		got, err := testQueries.ClaimDataExport(context.Background(), time.Time{})
+		if err == sql.ErrNoRows {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, DataExportStatusRunning, got.Status)
+		require.True(t, got.ClaimedAt.Valid)
+
+		if got.ID == export.ID {
+			claimed = got
+		}
+	}
+	require.Equal(t, export.ID, claimed.ID)
+
+	// the worker holding it dies; exports claimed earlier are taken over
+	// first, each only once
+	staleBefore := claimed.ClaimedAt.Time.Add(time.Microsecond)
+	for {
+		got, err := testQueries.ClaimDataExport(context.Background(), staleBefore)
+		require.NoError(t, err)
+
+		if got.ID == export.ID {
+			require.True(t, got.ClaimedAt.Time.After(claimed.ClaimedAt.Time))
+			break
+		}
+	}
+
+	_, err = testQueries.ClaimDataExport(context.Background(), staleBefore)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,55 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: fee.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createTransferFee = `-- name: CreateTransferFee :one
+INSERT INTO transfer_fees (
+   transfer_id,
+   fee_transfer_id,
+   amount
+) VALUES (
+   $1, $2, $3
+) RETURNING transfer_id, fee_transfer_id, amount, created_at
+`
+
+type CreateTransferFeeParams struct {
+	TransferID    int64 `json:"transfer_id"`
+	FeeTransferID int64 `json:"fee_transfer_id"`
+	Amount        int64 `json:"amount"`
+}
+
+func (q *Queries) CreateTransferFee(ctx context.Context, arg CreateTransferFeeParams) (TransferFee, error) {
+	row := q.db.QueryRowContext(ctx, createTransferFee, arg.TransferID, arg.FeeTransferID, arg.Amount)
+	var i TransferFee
+	err := row.Scan(
+		&i.TransferID,
+		&i.FeeTransferID,
+		&i.Amount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getTransferFee = `-- name: GetTransferFee :one
+SELECT transfer_id, fee_transfer_id, amount, created_at FROM transfer_fees
+WHERE transfer_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetTransferFee(ctx context.Context, transferID int64) (TransferFee, error) {
+	row := q.db.QueryRowContext(ctx, getTransferFee, transferID)
+	var i TransferFee
+	err := row.Scan(
+		&i.TransferID,
+		&i.FeeTransferID,
+		&i.Amount,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,306 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: fraud.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const countTransfersBetween = `-- name: CountTransfersBetween :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1
+   AND to_account_id = $2
+   AND created_at >= $3
+`
+
+type CountTransfersBetweenParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (q *Queries) CountTransfersBetween(ctx context.Context, arg CountTransfersBetweenParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countTransfersBetween, arg.FromAccountID, arg.ToAccountID, arg.CreatedAt)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createFraudDecision = `-- name: CreateFraudDecision :one
+INSERT INTO fraud_decisions (
+   from_account_id,
+   to_account_id,
+   amount,
+   verdict,
+   rules,
+   actor,
+   held_transfer_id
+) VALUES (
+   $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, from_account_id, to_account_id, amount, verdict, rules, actor, held_transfer_id, created_at
+`
+
+type CreateFraudDecisionParams struct {
+	FromAccountID  int64         `json:"from_account_id"`
+	ToAccountID    int64         `json:"to_account_id"`
+	Amount         int64         `json:"amount"`
+	Verdict        FraudVerdict  `json:"verdict"`
+	Rules          []string      `json:"rules"`
+	Actor          string        `json:"actor"`
+	HeldTransferID sql.NullInt64 `json:"held_transfer_id"`
+}
+
+func (q *Queries) CreateFraudDecision(ctx context.Context, arg CreateFraudDecisionParams) (FraudDecision, error) {
+	row := q.db.QueryRowContext(ctx, createFraudDecision,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Verdict,
+		pq.Array(arg.Rules),
+		arg.Actor,
+		arg.HeldTransferID,
+	)
+	var i FraudDecision
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Verdict,
+		pq.Array(&i.Rules),
+		&i.Actor,
+		&i.HeldTransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createHeldTransfer = `-- name: CreateHeldTransfer :one
+INSERT INTO held_transfers (
+   from_account_id,
+   to_account_id,
+   amount,
+   fee,
+   external_reference
+) VALUES (
+   $1, $2, $3, $4, $5
+) RETURNING id, from_account_id, to_account_id, amount, fee, status, transfer_id, reviewer, created_at, decided_at, external_reference
+`
+
+type CreateHeldTransferParams struct {
+	FromAccountID     int64          `json:"from_account_id"`
+	ToAccountID       int64          `json:"to_account_id"`
+	Amount            int64          `json:"amount"`
+	Fee               int64          `json:"fee"`
+	ExternalReference sql.NullString `json:"external_reference"`
+}
+
+func (q *Queries) CreateHeldTransfer(ctx context.Context, arg CreateHeldTransferParams) (HeldTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createHeldTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.Fee,
+		arg.ExternalReference,
+	)
+	var i HeldTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Fee,
+		&i.Status,
+		&i.TransferID,
+		&i.Reviewer,
+		&i.CreatedAt,
+		&i.DecidedAt,
+		&i.ExternalReference,
+	)
+	return i, err
+}
+
+const decideHeldTransfer = `-- name: DecideHeldTransfer :one
+UPDATE held_transfers
+SET status = $2,
+   transfer_id = $3,
+   reviewer = $4,
+   decided_at = now()
+WHERE id = $1
+RETURNING id, from_account_id, to_account_id, amount, fee, status, transfer_id, reviewer, created_at, decided_at, external_reference
+`
+
+type DecideHeldTransferParams struct {
+	ID         int64              `json:"id"`
+	Status     HeldTransferStatus `json:"status"`
+	TransferID sql.NullInt64      `json:"transfer_id"`
+	Reviewer   string             `json:"reviewer"`
+}
+
+func (q *Queries) DecideHeldTransfer(ctx context.Context, arg DecideHeldTransferParams) (HeldTransfer, error) {
+	row := q.db.QueryRowContext(ctx, decideHeldTransfer,
+		arg.ID,
+		arg.Status,
+		arg.TransferID,
+		arg.Reviewer,
+	)
+	var i HeldTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Fee,
+		&i.Status,
+		&i.TransferID,
+		&i.Reviewer,
+		&i.CreatedAt,
+		&i.DecidedAt,
+		&i.ExternalReference,
+	)
+	return i, err
+}
+
+const getHeldTransfer = `-- name: GetHeldTransfer :one
+SELECT id, from_account_id, to_account_id, amount, fee, status, transfer_id, reviewer, created_at, decided_at, external_reference FROM held_transfers
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetHeldTransfer(ctx context.Context, id int64) (HeldTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getHeldTransfer, id)
+	var i HeldTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Fee,
+		&i.Status,
+		&i.TransferID,
+		&i.Reviewer,
+		&i.CreatedAt,
+		&i.DecidedAt,
+		&i.ExternalReference,
+	)
+	return i, err
+}
+
+const getHeldTransferForUpdate = `-- name: GetHeldTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, fee, status, transfer_id, reviewer, created_at, decided_at, external_reference FROM held_transfers
+WHERE id = $1 LIMIT 1
+FOR UPDATE
+`
+
+func (q *Queries) GetHeldTransferForUpdate(ctx context.Context, id int64) (HeldTransfer, error) {
+	row := q.db.QueryRowContext(ctx, getHeldTransferForUpdate, id)
+	var i HeldTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.Fee,
+		&i.Status,
+		&i.TransferID,
+		&i.Reviewer,
+		&i.CreatedAt,
+		&i.DecidedAt,
+		&i.ExternalReference,
+	)
+	return i, err
+}
+
+const listFraudDecisions = `-- name: ListFraudDecisions :many
+SELECT id, from_account_id, to_account_id, amount, verdict, rules, actor, held_transfer_id, created_at FROM fraud_decisions
+WHERE held_transfer_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListFraudDecisions(ctx context.Context, heldTransferID sql.NullInt64) ([]FraudDecision, error) {
+	rows, err := q.db.QueryContext(ctx, listFraudDecisions, heldTransferID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FraudDecision{}
+	for rows.Next() {
+		var i FraudDecision
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Verdict,
+			pq.Array(&i.Rules),
+			&i.Actor,
+			&i.HeldTransferID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHeldTransfers = `-- name: ListHeldTransfers :many
+SELECT id, from_account_id, to_account_id, amount, fee, status, transfer_id, reviewer, created_at, decided_at, external_reference FROM held_transfers
+WHERE status = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListHeldTransfersParams struct {
+	Status HeldTransferStatus `json:"status"`
+	Limit  int32              `json:"limit"`
+	Offset int32              `json:"offset"`
+}
+
+func (q *Queries) ListHeldTransfers(ctx context.Context, arg ListHeldTransfersParams) ([]HeldTransfer, error) {
+	rows, err := q.db.QueryContext(ctx, listHeldTransfers, arg.Status, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []HeldTransfer{}
+	for rows.Next() {
+		var i HeldTransfer
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Fee,
+			&i.Status,
+			&i.TransferID,
+			&i.Reviewer,
+			&i.CreatedAt,
+			&i.DecidedAt,
+			&i.ExternalReference,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ScreeningActor is recorded as the actor of decisions taken by the fraud
+// screening itself rather than by a reviewer.
+const ScreeningActor = "screening"
+
+var (
+	ErrHeldTransferDecided = errors.New("held transfer was already decided")
+	ErrAccountNotActive    = errors.New("account is not active")
+	ErrDuplicateReference  = errors.New("external reference was already used")
+)
+
+type HoldTransferTxParams struct {
+	Transfer TransferTxParams `json:"transfer"`
+	Rules    []string         `json:"rules"`
+	// ExternalReference is set for withdrawals, whose ToAccountID is the
+	// settlement account.
+	ExternalReference string `json:"external_reference,omitempty"`
+}
+
+// HoldTransferTx parks a transfer or withdrawal the screening sent to review.
+// No money moves until a reviewer approves it. A withdrawal whose reference
+// was already paid out fails with ErrDuplicateReference; one that is already
+// held fails with a unique violation.
+func (s *SQLStore) HoldTransferTx(ctx context.Context, arg HoldTransferTxParams) (HeldTransfer, error) {
+	var held HeldTransfer
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		if arg.ExternalReference != "" {
+			exists, err := q.PaymentReferenceExists(ctx, PaymentReferenceExistsParams{
+				Direction:         PaymentDirectionWithdrawal,
+				ExternalReference: arg.ExternalReference,
+			})
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				return ErrDuplicateReference
+			}
+		}
+
+		var err error
+		held, err = q.CreateHeldTransfer(ctx, CreateHeldTransferParams{
+			FromAccountID: arg.Transfer.FromAccountID,
+			ToAccountID:   arg.Transfer.ToAccountID,
+			Amount:        arg.Transfer.Amount,
+			Fee:           arg.Transfer.Fee,
+			ExternalReference: sql.NullString{
+				String: arg.ExternalReference,
+				Valid:  arg.ExternalReference != "",
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateFraudDecision(ctx, CreateFraudDecisionParams{
+			FromAccountID:  held.FromAccountID,
+			ToAccountID:    held.ToAccountID,
+			Amount:         held.Amount,
+			Verdict:        FraudVerdictReview,
+			Rules:          arg.Rules,
+			Actor:          ScreeningActor,
+			HeldTransferID: sql.NullInt64{Int64: held.ID, Valid: true},
+		})
+
+		return err
+	})
+
+	return held, err
+}
+
+type DecideHeldTransferTxParams struct {
+	ID       int64  `json:"id"`
+	Approve  bool   `json:"approve"`
+	Reviewer string `json:"reviewer"`
+}
+
+type DecideHeldTransferTxResult struct {
+	HeldTransfer HeldTransfer      `json:"held_transfer"`
+	Transfer     *TransferTxResult `json:"transfer,omitempty"`
+	Payment      *PaymentTxResult  `json:"payment,omitempty"`
+}
+
+// DecideHeldTransferTx approves or rejects a pending held transfer. Approval
+// posts the transfer as TransferTx would, or a held withdrawal as PaymentTx
+// would, limits included, and fails with ErrAccountNotActive unless both
+// accounts are still active. The decision is logged with the rules that
+// originally fired.
+func (s *SQLStore) DecideHeldTransferTx(ctx context.Context, arg DecideHeldTransferTxParams) (DecideHeldTransferTxResult, error) {
+	var result DecideHeldTransferTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		held, err := q.GetHeldTransferForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		if held.Status != HeldTransferStatusPending {
+			return ErrHeldTransferDecided
+		}
+
+		decide := DecideHeldTransferParams{
+			ID:       held.ID,
+			Status:   HeldTransferStatusRejected,
+			Reviewer: arg.Reviewer,
+		}
+		verdict := FraudVerdictDeny
+
+		if arg.Approve {
+			var transferID int64
+			if held.ExternalReference.Valid {
+				payment, err := q.postPayment(ctx, PaymentTxParams{
+					AccountID:         held.FromAccountID,
+					Direction:         PaymentDirectionWithdrawal,
+					Amount:            held.Amount,
+					ExternalReference: held.ExternalReference.String,
+				})
+				if err != nil {
+					return err
+				}
+
+				result.Payment = &payment
+				transferID = payment.Transfer.ID
+			} else {
+				transfer, err := q.postCustomerTransfer(ctx, TransferTxParams{
+					FromAccountID: held.FromAccountID,
+					ToAccountID:   held.ToAccountID,
+					Amount:        held.Amount,
+					Fee:           held.Fee,
+				})
+				if err != nil {
+					return err
+				}
+
+				result.Transfer = &transfer
+				transferID = transfer.Transfer.ID
+			}
+
+			// The accounts may have been frozen or closed while the transfer
+			// was held. Posting locked both rows, so their status can no
+			// longer change before this transaction ends.
+			err = q.requireActive(ctx, held.FromAccountID, held.ToAccountID)
+			if err != nil {
+				return err
+			}
+
+			decide.Status = HeldTransferStatusApproved
+			decide.TransferID = sql.NullInt64{Int64: transferID, Valid: true}
+			verdict = FraudVerdictAllow
+		}
+
+		result.HeldTransfer, err = q.DecideHeldTransfer(ctx, decide)
+		if err != nil {
+			return err
+		}
+
+		rules := []string{}
+		previous, err := q.ListFraudDecisions(ctx, sql.NullInt64{Int64: held.ID, Valid: true})
+		if err != nil {
+			return err
+		}
+
+		if len(previous) > 0 {
+			rules = previous[0].Rules
+		}
+
+		_, err = q.CreateFraudDecision(ctx, CreateFraudDecisionParams{
+			FromAccountID:  held.FromAccountID,
+			ToAccountID:    held.ToAccountID,
+			Amount:         held.Amount,
+			Verdict:        verdict,
+			Rules:          rules,
+			Actor:          arg.Reviewer,
+			HeldTransferID: sql.NullInt64{Int64: held.ID, Valid: true},
+		})
+
+		return err
+	})
+
+	return result, err
+}
+
+func (q *Queries) requireActive(ctx context.Context, ids ...int64) error {
+	for _, id := range ids {
+		account, err := q.GetAccount(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if account.Status != AccountStatusActive {
+			return fmt.Errorf("%w: account [%d] is %s", ErrAccountNotActive, account.ID, account.Status)
+		}
+	}
+
+	return nil
+}
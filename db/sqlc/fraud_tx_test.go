@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecideHeldTransferTxApprove(t *testing.T) {
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	held, err := store.HoldTransferTx(context.Background(), HoldTransferTxParams{
+		Transfer: TransferTxParams{FromAccountID: acc1.ID, ToAccountID: acc2.ID, Amount: 10},
+		Rules:    []string{"round_trip"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, HeldTransferStatusPending, held.Status)
+
+	result, err := store.DecideHeldTransferTx(context.Background(), DecideHeldTransferTxParams{
+		ID:       held.ID,
+		Approve:  true,
+		Reviewer: "reviewer",
+	})
+	require.NoError(t, err)
+	require.Equal(t, HeldTransferStatusApproved, result.HeldTransfer.Status)
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, result.Transfer.Transfer.ID, result.HeldTransfer.TransferID.Int64)
+	require.Equal(t, acc1.Balance-10, result.Transfer.FromAccount.Balance)
+
+	decisions, err := testQueries.ListFraudDecisions(context.Background(), sql.NullInt64{Int64: held.ID, Valid: true})
+	require.NoError(t, err)
+	require.Len(t, decisions, 2)
+	require.Equal(t, FraudVerdictReview, decisions[0].Verdict)
+	require.Equal(t, ScreeningActor, decisions[0].Actor)
+	require.Equal(t, FraudVerdictAllow, decisions[1].Verdict)
+	require.Equal(t, "reviewer", decisions[1].Actor)
+	require.Equal(t, []string{"round_trip"}, decisions[1].Rules)
+
+	_, err = store.DecideHeldTransferTx(context.Background(), DecideHeldTransferTxParams{
+		ID:       held.ID,
+		Reviewer: "reviewer",
+	})
+	require.ErrorIs(t, err, ErrHeldTransferDecided)
+}
+
+func TestDecideHeldTransferTxReject(t *testing.T) {
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	held, err := store.HoldTransferTx(context.Background(), HoldTransferTxParams{
+		Transfer: TransferTxParams{FromAccountID: acc1.ID, ToAccountID: acc2.ID, Amount: 10},
+		Rules:    []string{"rapid_fire"},
+	})
+	require.NoError(t, err)
+
+	result, err := store.DecideHeldTransferTx(context.Background(), DecideHeldTransferTxParams{
+		ID:       held.ID,
+		Reviewer: "reviewer",
+	})
+	require.NoError(t, err)
+	require.Equal(t, HeldTransferStatusRejected, result.HeldTransfer.Status)
+	require.Nil(t, result.Transfer)
+	require.False(t, result.HeldTransfer.TransferID.Valid)
+
+	account, err := testQueries.GetAccount(context.Background(), acc1.ID)
+	require.NoError(t, err)
+	require.Equal(t, acc1.Balance, account.Balance)
+}
+
+func TestDecideHeldTransferTxApproveWithdrawal(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomAccount(t)
+
+	settlement, err := testQueries.GetAccountByOwner(context.Background(), GetAccountByOwnerParams{
+		Owner:    SettlementOwner,
+		Currency: acc.Currency,
+	})
+	require.NoError(t, err)
+
+	held, err := store.HoldTransferTx(context.Background(), HoldTransferTxParams{
+		Transfer:          TransferTxParams{FromAccountID: acc.ID, ToAccountID: settlement.ID, Amount: 10},
+		Rules:             []string{"new_payee_large_amount"},
+		ExternalReference: util.RandomString(16),
+	})
+	require.NoError(t, err)
+	require.True(t, held.ExternalReference.Valid)
+
+	result, err := store.DecideHeldTransferTx(context.Background(), DecideHeldTransferTxParams{
+		ID:       held.ID,
+		Approve:  true,
+		Reviewer: "reviewer",
+	})
+	require.NoError(t, err)
+	require.Nil(t, result.Transfer)
+	require.NotNil(t, result.Payment)
+	require.Equal(t, PaymentDirectionWithdrawal, result.Payment.Payment.Direction)
+	require.Equal(t, held.ExternalReference.String, result.Payment.Payment.ExternalReference)
+	require.Equal(t, result.Payment.Transfer.ID, result.HeldTransfer.TransferID.Int64)
+	require.Equal(t, acc.Balance-10, result.Payment.Account.Balance)
+}
+
+func TestHoldTransferTxDuplicateReference(t *testing.T) {
+	store := NewStore(testDB)
+	acc, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  100,
+		Currency: util.RandomCurrency(),
+		Product:  "checking",
+	})
+	require.NoError(t, err)
+
+	settlement, err := testQueries.GetAccountByOwner(context.Background(), GetAccountByOwnerParams{
+		Owner:    SettlementOwner,
+		Currency: acc.Currency,
+	})
+	require.NoError(t, err)
+
+	hold := HoldTransferTxParams{
+		Transfer:          TransferTxParams{FromAccountID: acc.ID, ToAccountID: settlement.ID, Amount: 10},
+		Rules:             []string{"new_payee_large_amount"},
+		ExternalReference: util.RandomString(16),
+	}
+
+	// the reference is already held
+	held, err := store.HoldTransferTx(context.Background(), hold)
+	require.NoError(t, err)
+
+	_, err = store.HoldTransferTx(context.Background(), hold)
+	var pqErr *pq.Error
+	require.ErrorAs(t, err, &pqErr)
+	require.Equal(t, "unique_violation", pqErr.Code.Name())
+
+	// and once paid out, it can't be held again
+	_, err = store.DecideHeldTransferTx(context.Background(), DecideHeldTransferTxParams{
+		ID:       held.ID,
+		Approve:  true,
+		Reviewer: "reviewer",
+	})
+	require.NoError(t, err)
+
+	_, err = store.HoldTransferTx(context.Background(), hold)
+	require.ErrorIs(t, err, ErrDuplicateReference)
+}
+
+func TestDecideHeldTransferTxApproveFrozenAccount(t *testing.T) {
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	held, err := store.HoldTransferTx(context.Background(), HoldTransferTxParams{
+		Transfer: TransferTxParams{FromAccountID: acc1.ID, ToAccountID: acc2.ID, Amount: 10},
+		Rules:    []string{"round_trip"},
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.SetAccountStatus(context.Background(), SetAccountStatusParams{
+		ID:     acc2.ID,
+		Status: AccountStatusFrozen,
+	})
+	require.NoError(t, err)
+
+	_, err = store.DecideHeldTransferTx(context.Background(), DecideHeldTransferTxParams{
+		ID:       held.ID,
+		Approve:  true,
+		Reviewer: "reviewer",
+	})
+	require.ErrorIs(t, err, ErrAccountNotActive)
+
+	pending, err := testQueries.GetHeldTransfer(context.Background(), held.ID)
+	require.NoError(t, err)
+	require.Equal(t, HeldTransferStatusPending, pending.Status)
+
+	account, err := testQueries.GetAccount(context.Background(), acc1.ID)
+	require.NoError(t, err)
+	require.Equal(t, acc1.Balance, account.Balance)
+}
@@ -0,0 +1,371 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: interest.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createInterestAccrual = `-- name: CreateInterestAccrual :execrows
+INSERT INTO interest_accruals (
+   account_id,
+   day,
+   balance,
+   annual_rate_bps,
+   amount_micros
+) VALUES (
+   $1, $2, $3, $4, $5
+) ON CONFLICT (account_id, day) DO NOTHING
+`
+
+type CreateInterestAccrualParams struct {
+	AccountID     int64     `json:"account_id"`
+	Day           time.Time `json:"day"`
+	Balance       int64     `json:"balance"`
+	AnnualRateBps int64     `json:"annual_rate_bps"`
+	AmountMicros  int64     `json:"amount_micros"`
+}
+
+func (q *Queries) CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createInterestAccrual,
+		arg.AccountID,
+		arg.Day,
+		arg.Balance,
+		arg.AnnualRateBps,
+		arg.AmountMicros,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const createInterestPosting = `-- name: CreateInterestPosting :one
+INSERT INTO interest_postings (
+   account_id,
+   period,
+   accrued_micros,
+   amount,
+   carry_micros
+) VALUES (
+   $1, $2, $3, $4, $5
+) ON CONFLICT (account_id, period) DO NOTHING
+RETURNING id, account_id, period, accrued_micros, amount, carry_micros, transfer_id, created_at
+`
+
+type CreateInterestPostingParams struct {
+	AccountID     int64     `json:"account_id"`
+	Period        time.Time `json:"period"`
+	AccruedMicros int64     `json:"accrued_micros"`
+	Amount        int64     `json:"amount"`
+	CarryMicros   int64     `json:"carry_micros"`
+}
+
+func (q *Queries) CreateInterestPosting(ctx context.Context, arg CreateInterestPostingParams) (InterestPosting, error) {
+	row := q.db.QueryRowContext(ctx, createInterestPosting,
+		arg.AccountID,
+		arg.Period,
+		arg.AccruedMicros,
+		arg.Amount,
+		arg.CarryMicros,
+	)
+	var i InterestPosting
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Period,
+		&i.AccruedMicros,
+		&i.Amount,
+		&i.CarryMicros,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getInterestCarry = `-- name: GetInterestCarry :one
+SELECT COALESCE((
+   SELECT carry_micros FROM interest_postings
+   WHERE account_id = $1 AND period < $2
+   ORDER BY period DESC
+   LIMIT 1
+), 0)::bigint AS carry_micros
+`
+
+type GetInterestCarryParams struct {
+	AccountID int64     `json:"account_id"`
+	Period    time.Time `json:"period"`
+}
+
+func (q *Queries) GetInterestCarry(ctx context.Context, arg GetInterestCarryParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getInterestCarry, arg.AccountID, arg.Period)
+	var carry_micros int64
+	err := row.Scan(&carry_micros)
+	return carry_micros, err
+}
+
+const getLastAccrualDay = `-- name: GetLastAccrualDay :one
+SELECT COALESCE(MAX(day), '0001-01-01')::date AS day
+FROM interest_accruals
+`
+
+func (q *Queries) GetLastAccrualDay(ctx context.Context) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getLastAccrualDay)
+	var day time.Time
+	err := row.Scan(&day)
+	return day, err
+}
+
+const getProduct = `-- name: GetProduct :one
+SELECT code, name, annual_rate_bps, created_at FROM products
+WHERE code = $1 LIMIT 1
+`
+
+func (q *Queries) GetProduct(ctx context.Context, code string) (Product, error) {
+	row := q.db.QueryRowContext(ctx, getProduct, code)
+	var i Product
+	err := row.Scan(
+		&i.Code,
+		&i.Name,
+		&i.AnnualRateBps,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listAccrualBalances = `-- name: ListAccrualBalances :many
+SELECT a.id, p.annual_rate_bps,
+   COALESCE(SUM(e.amount), 0)::bigint AS balance
+FROM accounts a
+JOIN products p ON p.code = a.product
+LEFT JOIN entries e ON e.account_id = a.id AND e.created_at < $1
+WHERE p.annual_rate_bps > 0
+   AND a.created_at < $1
+   AND a.id > $2
+GROUP BY a.id, p.annual_rate_bps
+ORDER BY a.id
+LIMIT $3
+`
+
+type ListAccrualBalancesParams struct {
+	DayEnd  time.Time `json:"day_end"`
+	AfterID int64     `json:"after_id"`
+	Size    int32     `json:"size"`
+}
+
+type ListAccrualBalancesRow struct {
+	ID            int64 `json:"id"`
+	AnnualRateBps int64 `json:"annual_rate_bps"`
+	Balance       int64 `json:"balance"`
+}
+
+func (q *Queries) ListAccrualBalances(ctx context.Context, arg ListAccrualBalancesParams) ([]ListAccrualBalancesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listAccrualBalances, arg.DayEnd, arg.AfterID, arg.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccrualBalancesRow{}
+	for rows.Next() {
+		var i ListAccrualBalancesRow
+		if err := rows.Scan(&i.ID, &i.AnnualRateBps, &i.Balance); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInterestAccrualSums = `-- name: ListInterestAccrualSums :many
+SELECT a.account_id, SUM(a.amount_micros)::bigint AS accrued_micros
+FROM interest_accruals a
+WHERE a.day >= $1 AND a.day < $2
+   AND a.account_id > $3
+   AND NOT EXISTS (
+      SELECT 1 FROM interest_postings p
+      WHERE p.account_id = a.account_id AND p.period = $1
+   )
+GROUP BY a.account_id
+ORDER BY a.account_id
+LIMIT $4
+`
+
+type ListInterestAccrualSumsParams struct {
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	AfterID     int64     `json:"after_id"`
+	Size        int32     `json:"size"`
+}
+
+type ListInterestAccrualSumsRow struct {
+	AccountID     int64 `json:"account_id"`
+	AccruedMicros int64 `json:"accrued_micros"`
+}
+
+func (q *Queries) ListInterestAccrualSums(ctx context.Context, arg ListInterestAccrualSumsParams) ([]ListInterestAccrualSumsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listInterestAccrualSums,
+		arg.PeriodStart,
+		arg.PeriodEnd,
+		arg.AfterID,
+		arg.Size,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListInterestAccrualSumsRow{}
+	for rows.Next() {
+		var i ListInterestAccrualSumsRow
+		if err := rows.Scan(&i.AccountID, &i.AccruedMicros); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInterestPostings = `-- name: ListInterestPostings :many
+SELECT id, account_id, period, accrued_micros, amount, carry_micros, transfer_id, created_at FROM interest_postings
+WHERE account_id = $1
+ORDER BY period DESC
+LIMIT $2
+`
+
+type ListInterestPostingsParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+}
+
+func (q *Queries) ListInterestPostings(ctx context.Context, arg ListInterestPostingsParams) ([]InterestPosting, error) {
+	rows, err := q.db.QueryContext(ctx, listInterestPostings, arg.AccountID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []InterestPosting{}
+	for rows.Next() {
+		var i InterestPosting
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.Period,
+			&i.AccruedMicros,
+			&i.Amount,
+			&i.CarryMicros,
+			&i.TransferID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listProducts = `-- name: ListProducts :many
+SELECT code, name, annual_rate_bps, created_at FROM products
+ORDER BY code
+`
+
+func (q *Queries) ListProducts(ctx context.Context) ([]Product, error) {
+	rows, err := q.db.QueryContext(ctx, listProducts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Product{}
+	for rows.Next() {
+		var i Product
+		if err := rows.Scan(
+			&i.Code,
+			&i.Name,
+			&i.AnnualRateBps,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setInterestPostingTransfer = `-- name: SetInterestPostingTransfer :one
+UPDATE interest_postings
+SET transfer_id = $2
+WHERE id = $1
+RETURNING id, account_id, period, accrued_micros, amount, carry_micros, transfer_id, created_at
+`
+
+type SetInterestPostingTransferParams struct {
+	ID         int64         `json:"id"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+}
+
+func (q *Queries) SetInterestPostingTransfer(ctx context.Context, arg SetInterestPostingTransferParams) (InterestPosting, error) {
+	row := q.db.QueryRowContext(ctx, setInterestPostingTransfer, arg.ID, arg.TransferID)
+	var i InterestPosting
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.Period,
+		&i.AccruedMicros,
+		&i.Amount,
+		&i.CarryMicros,
+		&i.TransferID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const setProductRate = `-- name: SetProductRate :one
+UPDATE products
+SET annual_rate_bps = $2
+WHERE code = $1
+RETURNING code, name, annual_rate_bps, created_at
+`
+
+type SetProductRateParams struct {
+	Code          string `json:"code"`
+	AnnualRateBps int64  `json:"annual_rate_bps"`
+}
+
+func (q *Queries) SetProductRate(ctx context.Context, arg SetProductRateParams) (Product, error) {
+	row := q.db.QueryRowContext(ctx, setProductRate, arg.Code, arg.AnnualRateBps)
+	var i Product
+	err := row.Scan(
+		&i.Code,
+		&i.Name,
+		&i.AnnualRateBps,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// InterestOwner owns the per-currency interest expense accounts that pay
+// interest out to savings accounts.
+const InterestOwner = "interest"
+
+// MicrosPerUnit is the number of accrual units in one minor unit of currency.
+// Daily accruals are kept in micros so that rounding only happens when
+// interest is posted, and the remainder is carried into the next posting.
+const MicrosPerUnit = 1_000_000
+
+var ErrInterestAlreadyPosted = errors.New("interest already posted for this period")
+
+type InterestPostingTxParams struct {
+	AccountID     int64     `json:"account_id"`
+	Period        time.Time `json:"period"`
+	AccruedMicros int64     `json:"accrued_micros"`
+}
+
+type InterestPostingTxResult struct {
+	Posting  InterestPosting `json:"posting"`
+	Transfer *Transfer       `json:"transfer,omitempty"`
+	Account  Account         `json:"account"`
+}
+
+// InterestPostingTx credits the whole minor units of the accrued interest,
+// plus the carry of the previous posting, from the interest expense account.
+// The posting row is inserted first, so a second run for the same account and
+// period fails with ErrInterestAlreadyPosted instead of paying twice.
+func (s *SQLStore) InterestPostingTx(ctx context.Context, arg InterestPostingTxParams) (InterestPostingTxResult, error) {
+	var result InterestPostingTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		carry, err := q.GetInterestCarry(ctx, GetInterestCarryParams{
+			AccountID: arg.AccountID,
+			Period:    arg.Period,
+		})
+		if err != nil {
+			return err
+		}
+
+		total := arg.AccruedMicros + carry
+		result.Posting, err = q.CreateInterestPosting(ctx, CreateInterestPostingParams{
+			AccountID:     arg.AccountID,
+			Period:        arg.Period,
+			AccruedMicros: arg.AccruedMicros,
+			Amount:        total / MicrosPerUnit,
+			CarryMicros:   total % MicrosPerUnit,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrInterestAlreadyPosted
+			}
+
+			return err
+		}
+
+		result.Account, err = q.GetAccount(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if result.Posting.Amount == 0 {
+			return nil
+		}
+
+		expense, err := q.GetAccountByOwner(ctx, GetAccountByOwnerParams{
+			Owner:    InterestOwner,
+			Currency: result.Account.Currency,
+		})
+		if err != nil {
+			return fmt.Errorf("cannot get %s interest account: %w", result.Account.Currency, err)
+		}
+
+		transfer, err := q.PostTransfer(ctx, TransferTxParams{
+			FromAccountID: expense.ID,
+			ToAccountID:   arg.AccountID,
+			Amount:        result.Posting.Amount,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Transfer = &transfer.Transfer
+		result.Account = transfer.ToAccount
+		result.Posting, err = q.SetInterestPostingTransfer(ctx, SetInterestPostingTransferParams{
+			ID:         result.Posting.ID,
+			TransferID: sql.NullInt64{Int64: transfer.Transfer.ID, Valid: true},
+		})
+
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSavingsAccount(t *testing.T) Account {
+	user := createRandomUser(t)
+
+	acc, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  0,
+		Currency: util.RandomCurrency(),
+		Product:  "savings",
+	})
+	require.NoError(t, err)
+
+	return acc
+}
+
+func TestCreateInterestAccrualIdempotent(t *testing.T) {
+	acc := createRandomSavingsAccount(t)
+	day := time.Date(2023, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	arg := CreateInterestAccrualParams{
+		AccountID:     acc.ID,
+		Day:           day,
+		Balance:       1000,
+		AnnualRateBps: 200,
+		AmountMicros:  547945,
+	}
+
+	n, err := testQueries.CreateInterestAccrual(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	n, err = testQueries.CreateInterestAccrual(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, n)
+}
+
+func TestInterestPostingTx(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomSavingsAccount(t)
+
+	january := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	result, err := store.InterestPostingTx(context.Background(), InterestPostingTxParams{
+		AccountID:     acc.ID,
+		Period:        january,
+		AccruedMicros: 2_500_000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Posting.Amount)
+	require.Equal(t, int64(500_000), result.Posting.CarryMicros)
+	require.NotNil(t, result.Transfer)
+	require.Equal(t, acc.ID, result.Transfer.ToAccountID)
+	require.Equal(t, acc.Balance+2, result.Account.Balance)
+
+	_, err = store.InterestPostingTx(context.Background(), InterestPostingTxParams{
+		AccountID:     acc.ID,
+		Period:        january,
+		AccruedMicros: 2_500_000,
+	})
+	require.ErrorIs(t, err, ErrInterestAlreadyPosted)
+
+	// The carry of January tops February up to a whole unit.
+	result, err = store.InterestPostingTx(context.Background(), InterestPostingTxParams{
+		AccountID:     acc.ID,
+		Period:        january.AddDate(0, 1, 0),
+		AccruedMicros: 600_000,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.Posting.Amount)
+	require.Equal(t, int64(100_000), result.Posting.CarryMicros)
+	require.Equal(t, acc.Balance+3, result.Account.Balance)
+}
+
+func TestInterestPostingTxBelowOneUnit(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomSavingsAccount(t)
+
+	result, err := store.InterestPostingTx(context.Background(), InterestPostingTxParams{
+		AccountID:     acc.ID,
+		Period:        time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		AccruedMicros: 999_999,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.Posting.Amount)
+	require.Nil(t, result.Transfer)
+	require.False(t, result.Posting.TransferID.Valid)
+	require.Equal(t, acc.Balance, result.Account.Balance)
+}
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	LimitMaxPerTransfer = "max_per_transfer"
+	LimitDailyTotal     = "daily_total"
+	LimitMonthlyTotal   = "monthly_total"
+	LimitHourlyCount    = "hourly_count"
+)
+
+// Limits holds the effective transfer limits of an account. A nil field means
+// the limit is not set at any scope.
+type Limits struct {
+	MaxPerTransfer *int64 `json:"max_per_transfer"`
+	DailyTotal     *int64 `json:"daily_total"`
+	MonthlyTotal   *int64 `json:"monthly_total"`
+	HourlyCount    *int64 `json:"hourly_count"`
+}
+
+// LimitUsage is what has already been sent against each limit: totals since
+// the start of the UTC day and month, and the number of transfers in the last
+// hour. Limits set for the user count every account the user has in the
+// currency; the others count the account alone.
+type LimitUsage struct {
+	DailyTotal   int64 `json:"daily_total"`
+	MonthlyTotal int64 `json:"monthly_total"`
+	HourlyCount  int64 `json:"hourly_count"`
+}
+
+type LimitStatus struct {
+	Limits    Limits     `json:"limits"`
+	Usage     LimitUsage `json:"usage"`
+	Remaining Limits     `json:"remaining"`
+}
+
+// LimitExceededError reports which limit a transfer would break and how much
+// of it was left before the transfer.
+type LimitExceededError struct {
+	Limit     string `json:"limit"`
+	Max       int64  `json:"max"`
+	Remaining int64  `json:"remaining"`
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("transfer limit exceeded: %s (max %d, remaining %d)", e.Limit, e.Max, e.Remaining)
+}
+
+// GetLimitStatus resolves the limits of account and its current usage. For
+// every limit the most specific scope that sets it wins: account, then user,
+// then product. Totals are in minor units of the account's currency and
+// count the gross amount of customer transfers, fees included, and of
+// withdrawals. A user limit is shared by all of the user's accounts in the
+// currency. An owner has at most one account per product and currency, so a
+// product limit only ever counts the account itself.
+func (q *Queries) GetLimitStatus(ctx context.Context, account Account, now time.Time) (LimitStatus, error) {
+	var status LimitStatus
+
+	rows, err := q.ListTransferLimitsForAccount(ctx, ListTransferLimitsForAccountParams{
+		AccountID: account.ID,
+		Owner:     account.Owner,
+		Product:   account.Product,
+	})
+	if err != nil {
+		return status, err
+	}
+
+	scopes := make(map[string]LimitScope)
+	for _, scope := range []LimitScope{LimitScopeProduct, LimitScopeUser, LimitScopeAccount} {
+		for _, row := range rows {
+			if row.Scope == scope {
+				status.Limits.override(row, scopes)
+			}
+		}
+	}
+
+	status.Usage, err = q.outgoingUsage(ctx, account, false, now)
+	if err != nil {
+		return status, err
+	}
+
+	if scopes[LimitDailyTotal] == LimitScopeUser || scopes[LimitMonthlyTotal] == LimitScopeUser || scopes[LimitHourlyCount] == LimitScopeUser {
+		owner, err := q.outgoingUsage(ctx, account, true, now)
+		if err != nil {
+			return status, err
+		}
+
+		if scopes[LimitDailyTotal] == LimitScopeUser {
+			status.Usage.DailyTotal = owner.DailyTotal
+		}
+
+		if scopes[LimitMonthlyTotal] == LimitScopeUser {
+			status.Usage.MonthlyTotal = owner.MonthlyTotal
+		}
+
+		if scopes[LimitHourlyCount] == LimitScopeUser {
+			status.Usage.HourlyCount = owner.HourlyCount
+		}
+	}
+
+	status.Remaining = Limits{
+		MaxPerTransfer: status.Limits.MaxPerTransfer,
+		DailyTotal:     remaining(status.Limits.DailyTotal, status.Usage.DailyTotal),
+		MonthlyTotal:   remaining(status.Limits.MonthlyTotal, status.Usage.MonthlyTotal),
+		HourlyCount:    remaining(status.Limits.HourlyCount, status.Usage.HourlyCount),
+	}
+
+	return status, nil
+}
+
+// outgoingUsage returns what the account sent, or with byOwner what all of
+// its owner's accounts in its currency sent.
+func (q *Queries) outgoingUsage(ctx context.Context, account Account, byOwner bool, now time.Time) (LimitUsage, error) {
+	var usage LimitUsage
+
+	now = now.UTC()
+	y, m, d := now.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	windows := []struct {
+		since time.Time
+		set   func(GetOutgoingUsageRow)
+	}{
+		{day, func(r GetOutgoingUsageRow) { usage.DailyTotal = r.Total }},
+		{time.Date(y, m, 1, 0, 0, 0, 0, time.UTC), func(r GetOutgoingUsageRow) { usage.MonthlyTotal = r.Total }},
+		{now.Add(-time.Hour), func(r GetOutgoingUsageRow) { usage.HourlyCount = r.Count }},
+	}
+
+	for _, w := range windows {
+		var row GetOutgoingUsageRow
+		var err error
+		if byOwner {
+			var owner GetOwnerOutgoingUsageRow
+			owner, err = q.GetOwnerOutgoingUsage(ctx, GetOwnerOutgoingUsageParams{
+				Owner:     account.Owner,
+				Currency:  account.Currency,
+				CreatedAt: w.since,
+			})
+			row = GetOutgoingUsageRow(owner)
+		} else {
+			row, err = q.GetOutgoingUsage(ctx, GetOutgoingUsageParams{
+				FromAccountID: account.ID,
+				CreatedAt:     w.since,
+			})
+		}
+		if err != nil {
+			return usage, err
+		}
+
+		w.set(row)
+	}
+
+	return usage, nil
+}
+
+// check returns a *LimitExceededError if the usage, which already includes a
+// transfer of amount, breaks one of the limits.
+func (s LimitStatus) check(amount int64) error {
+	checks := []struct {
+		name  string
+		max   *int64
+		used  int64
+		delta int64
+	}{
+		{LimitMaxPerTransfer, s.Limits.MaxPerTransfer, amount, amount},
+		{LimitDailyTotal, s.Limits.DailyTotal, s.Usage.DailyTotal, amount},
+		{LimitMonthlyTotal, s.Limits.MonthlyTotal, s.Usage.MonthlyTotal, amount},
+		{LimitHourlyCount, s.Limits.HourlyCount, s.Usage.HourlyCount, 1},
+	}
+
+	for _, c := range checks {
+		if c.max == nil || c.used <= *c.max {
+			continue
+		}
+
+		left := *c.max
+		if c.name != LimitMaxPerTransfer {
+			left = *remaining(c.max, c.used-c.delta)
+		}
+
+		return &LimitExceededError{Limit: c.name, Max: *c.max, Remaining: left}
+	}
+
+	return nil
+}
+
+// override takes the limits row sets and records its scope for each of them.
+func (l *Limits) override(row TransferLimit, scopes map[string]LimitScope) {
+	if row.MaxPerTransfer.Valid {
+		l.MaxPerTransfer = &row.MaxPerTransfer.Int64
+		scopes[LimitMaxPerTransfer] = row.Scope
+	}
+
+	if row.DailyTotal.Valid {
+		l.DailyTotal = &row.DailyTotal.Int64
+		scopes[LimitDailyTotal] = row.Scope
+	}
+
+	if row.MonthlyTotal.Valid {
+		l.MonthlyTotal = &row.MonthlyTotal.Int64
+		scopes[LimitMonthlyTotal] = row.Scope
+	}
+
+	if row.HourlyCount.Valid {
+		l.HourlyCount = &row.HourlyCount.Int64
+		scopes[LimitHourlyCount] = row.Scope
+	}
+}
+
+func remaining(max *int64, used int64) *int64 {
+	if max == nil {
+		return nil
+	}
+
+	left := *max - used
+	if left < 0 {
+		left = 0
+	}
+
+	return &left
+}
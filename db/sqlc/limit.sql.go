@@ -0,0 +1,235 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: limit.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const deleteTransferLimit = `-- name: DeleteTransferLimit :exec
+DELETE FROM transfer_limits
+WHERE scope = $1 AND scope_ref = $2
+`
+
+type DeleteTransferLimitParams struct {
+	Scope    LimitScope `json:"scope"`
+	ScopeRef string     `json:"scope_ref"`
+}
+
+func (q *Queries) DeleteTransferLimit(ctx context.Context, arg DeleteTransferLimitParams) error {
+	_, err := q.db.ExecContext(ctx, deleteTransferLimit, arg.Scope, arg.ScopeRef)
+	return err
+}
+
+const getOutgoingUsage = `-- name: GetOutgoingUsage :one
+SELECT COALESCE(SUM(t.amount + COALESCE(f.amount, 0)), 0)::bigint AS total,
+   COUNT(*)::bigint AS count
+FROM transfers t
+LEFT JOIN transfer_fees f ON f.transfer_id = t.id
+WHERE t.from_account_id = $1
+   AND t.created_at >= $2
+   AND NOT EXISTS (SELECT 1 FROM transfer_fees ff WHERE ff.fee_transfer_id = t.id)
+   AND NOT EXISTS (SELECT 1 FROM adjustments a WHERE a.transfer_id = t.id)
+`
+
+type GetOutgoingUsageParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type GetOutgoingUsageRow struct {
+	Total int64 `json:"total"`
+	Count int64 `json:"count"`
+}
+
+// GetOutgoingUsage counts transfers and withdrawals sent from the account.
+// Fee legs are included in the transfer they belong to; adjustments are left
+// out.
+func (q *Queries) GetOutgoingUsage(ctx context.Context, arg GetOutgoingUsageParams) (GetOutgoingUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getOutgoingUsage, arg.FromAccountID, arg.CreatedAt)
+	var i GetOutgoingUsageRow
+	err := row.Scan(&i.Total, &i.Count)
+	return i, err
+}
+
+const getOwnerOutgoingUsage = `-- name: GetOwnerOutgoingUsage :one
+SELECT COALESCE(SUM(t.amount + COALESCE(f.amount, 0)), 0)::bigint AS total,
+   COUNT(*)::bigint AS count
+FROM transfers t
+JOIN accounts a ON a.id = t.from_account_id
+LEFT JOIN transfer_fees f ON f.transfer_id = t.id
+WHERE a.owner = $1
+   AND a.currency = $2
+   AND t.created_at >= $3
+   AND NOT EXISTS (SELECT 1 FROM transfer_fees ff WHERE ff.fee_transfer_id = t.id)
+   AND NOT EXISTS (SELECT 1 FROM adjustments ad WHERE ad.transfer_id = t.id)
+`
+
+type GetOwnerOutgoingUsageParams struct {
+	Owner     string    `json:"owner"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type GetOwnerOutgoingUsageRow struct {
+	Total int64 `json:"total"`
+	Count int64 `json:"count"`
+}
+
+// GetOwnerOutgoingUsage is GetOutgoingUsage summed over all of the owner's
+// accounts in currency.
+func (q *Queries) GetOwnerOutgoingUsage(ctx context.Context, arg GetOwnerOutgoingUsageParams) (GetOwnerOutgoingUsageRow, error) {
+	row := q.db.QueryRowContext(ctx, getOwnerOutgoingUsage, arg.Owner, arg.Currency, arg.CreatedAt)
+	var i GetOwnerOutgoingUsageRow
+	err := row.Scan(&i.Total, &i.Count)
+	return i, err
+}
+
+const listTransferLimits = `-- name: ListTransferLimits :many
+SELECT id, scope, scope_ref, max_per_transfer, daily_total, monthly_total, hourly_count, updated_at FROM transfer_limits
+ORDER BY scope, scope_ref
+`
+
+func (q *Queries) ListTransferLimits(ctx context.Context) ([]TransferLimit, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferLimits)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TransferLimit{}
+	for rows.Next() {
+		var i TransferLimit
+		if err := rows.Scan(
+			&i.ID,
+			&i.Scope,
+			&i.ScopeRef,
+			&i.MaxPerTransfer,
+			&i.DailyTotal,
+			&i.MonthlyTotal,
+			&i.HourlyCount,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransferLimitsForAccount = `-- name: ListTransferLimitsForAccount :many
+SELECT id, scope, scope_ref, max_per_transfer, daily_total, monthly_total, hourly_count, updated_at FROM transfer_limits
+WHERE (scope = 'account' AND scope_ref = $1::bigint::varchar)
+   OR (scope = 'user' AND scope_ref = $2::varchar)
+   OR (scope = 'product' AND scope_ref = $3::varchar)
+`
+
+type ListTransferLimitsForAccountParams struct {
+	AccountID int64  `json:"account_id"`
+	Owner     string `json:"owner"`
+	Product   string `json:"product"`
+}
+
+func (q *Queries) ListTransferLimitsForAccount(ctx context.Context, arg ListTransferLimitsForAccountParams) ([]TransferLimit, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferLimitsForAccount, arg.AccountID, arg.Owner, arg.Product)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []TransferLimit{}
+	for rows.Next() {
+		var i TransferLimit
+		if err := rows.Scan(
+			&i.ID,
+			&i.Scope,
+			&i.ScopeRef,
+			&i.MaxPerTransfer,
+			&i.DailyTotal,
+			&i.MonthlyTotal,
+			&i.HourlyCount,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockOwnerLimits = `-- name: LockOwnerLimits :exec
+SELECT pg_advisory_xact_lock(hashtext('transfer_limits'), hashtext($1::text))
+`
+
+// LockOwnerLimits makes limit checks on the owner's transfers wait for each
+// other until the transaction ends.
+func (q *Queries) LockOwnerLimits(ctx context.Context, owner string) error {
+	_, err := q.db.ExecContext(ctx, lockOwnerLimits, owner)
+	return err
+}
+
+const upsertTransferLimit = `-- name: UpsertTransferLimit :one
+INSERT INTO transfer_limits (
+   scope,
+   scope_ref,
+   max_per_transfer,
+   daily_total,
+   monthly_total,
+   hourly_count
+) VALUES (
+   $1, $2, $3, $4, $5, $6
+) ON CONFLICT (scope, scope_ref) DO UPDATE SET
+   max_per_transfer = EXCLUDED.max_per_transfer,
+   daily_total = EXCLUDED.daily_total,
+   monthly_total = EXCLUDED.monthly_total,
+   hourly_count = EXCLUDED.hourly_count,
+   updated_at = now()
+RETURNING id, scope, scope_ref, max_per_transfer, daily_total, monthly_total, hourly_count, updated_at
+`
+
+type UpsertTransferLimitParams struct {
+	Scope          LimitScope    `json:"scope"`
+	ScopeRef       string        `json:"scope_ref"`
+	MaxPerTransfer sql.NullInt64 `json:"max_per_transfer"`
+	DailyTotal     sql.NullInt64 `json:"daily_total"`
+	MonthlyTotal   sql.NullInt64 `json:"monthly_total"`
+	HourlyCount    sql.NullInt64 `json:"hourly_count"`
+}
+
+func (q *Queries) UpsertTransferLimit(ctx context.Context, arg UpsertTransferLimitParams) (TransferLimit, error) {
+	row := q.db.QueryRowContext(ctx, upsertTransferLimit,
+		arg.Scope,
+		arg.ScopeRef,
+		arg.MaxPerTransfer,
+		arg.DailyTotal,
+		arg.MonthlyTotal,
+		arg.HourlyCount,
+	)
+	var i TransferLimit
+	err := row.Scan(
+		&i.ID,
+		&i.Scope,
+		&i.ScopeRef,
+		&i.MaxPerTransfer,
+		&i.DailyTotal,
+		&i.MonthlyTotal,
+		&i.HourlyCount,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
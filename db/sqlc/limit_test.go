@@ -0,0 +1,172 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func int64Ptr(v int64) *int64 {
+	return &v
+}
+
+func TestLimitStatusCheck(t *testing.T) {
+	status := LimitStatus{
+		Limits: Limits{
+			MaxPerTransfer: int64Ptr(100),
+			DailyTotal:     int64Ptr(250),
+			HourlyCount:    int64Ptr(3),
+		},
+		Usage: LimitUsage{DailyTotal: 200, MonthlyTotal: 5000, HourlyCount: 2},
+	}
+
+	require.NoError(t, status.check(50))
+
+	err := status.check(101)
+	var limitErr *LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, LimitMaxPerTransfer, limitErr.Limit)
+	require.Equal(t, int64(100), limitErr.Remaining)
+
+	status.Usage.DailyTotal = 280
+	err = status.check(80)
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, LimitDailyTotal, limitErr.Limit)
+	require.Equal(t, int64(50), limitErr.Remaining)
+
+	status.Usage.DailyTotal = 0
+	status.Usage.HourlyCount = 4
+	err = status.check(1)
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, LimitHourlyCount, limitErr.Limit)
+	require.Equal(t, int64(0), limitErr.Remaining)
+}
+
+func TestGetLimitStatusMostSpecificScopeWins(t *testing.T) {
+	acc := createRandomAccount(t)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Scope:       LimitScopeUser,
+		ScopeRef:    acc.Owner,
+		DailyTotal:  sql.NullInt64{Int64: 500, Valid: true},
+		HourlyCount: sql.NullInt64{Int64: 10, Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Scope:      LimitScopeAccount,
+		ScopeRef:   fmt.Sprint(acc.ID),
+		DailyTotal: sql.NullInt64{Int64: 1000, Valid: true},
+	})
+	require.NoError(t, err)
+
+	status, err := testQueries.GetLimitStatus(context.Background(), acc, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1000), *status.Limits.DailyTotal)
+	require.Equal(t, int64(10), *status.Limits.HourlyCount)
+	require.Nil(t, status.Limits.MonthlyTotal)
+	require.Zero(t, status.Usage.DailyTotal)
+}
+
+func TestTransferTxLimitExceeded(t *testing.T) {
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Scope:      LimitScopeAccount,
+		ScopeRef:   fmt.Sprint(acc1.ID),
+		DailyTotal: sql.NullInt64{Int64: 25, Valid: true},
+	})
+	require.NoError(t, err)
+
+	// concurrent transfers must not go over the daily total together
+	n := 5
+	errs := make(chan error)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: acc1.ID,
+				ToAccountID:   acc2.ID,
+				Amount:        10,
+			})
+			errs <- err
+		}()
+	}
+
+	var succeeded int
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+
+		var limitErr *LimitExceededError
+		require.True(t, errors.As(err, &limitErr))
+		require.Equal(t, LimitDailyTotal, limitErr.Limit)
+		require.Equal(t, int64(5), limitErr.Remaining)
+	}
+	require.Equal(t, 2, succeeded)
+
+	updated, err := testQueries.GetAccount(context.Background(), acc1.ID)
+	require.NoError(t, err)
+	require.Equal(t, acc1.Balance-20, updated.Balance)
+}
+
+func TestTransferTxUserLimitSpansAccounts(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	recipient := createRandomAccount(t)
+
+	checking, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  100,
+		Currency: recipient.Currency,
+		Product:  "checking",
+	})
+	require.NoError(t, err)
+
+	savings, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  100,
+		Currency: recipient.Currency,
+		Product:  "savings",
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Scope:      LimitScopeUser,
+		ScopeRef:   checking.Owner,
+		DailyTotal: sql.NullInt64{Int64: 15, Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: savings.ID,
+		ToAccountID:   recipient.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// the savings transfer already used most of the user's daily total
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: checking.ID,
+		ToAccountID:   recipient.ID,
+		Amount:        10,
+	})
+	var limitErr *LimitExceededError
+	require.True(t, errors.As(err, &limitErr))
+	require.Equal(t, LimitDailyTotal, limitErr.Limit)
+	require.Equal(t, int64(5), limitErr.Remaining)
+
+	status, err := testQueries.GetLimitStatus(context.Background(), checking, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(10), status.Usage.DailyTotal)
+	require.Zero(t, status.Usage.HourlyCount)
+}
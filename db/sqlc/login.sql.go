@@ -0,0 +1,223 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: login.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const createLockoutEvent = `-- name: CreateLockoutEvent :one
+INSERT INTO lockout_events (
+   scope,
+   key,
+   failures,
+   locked_until
+) VALUES (
+   $1, $2, $3, $4
+) RETURNING id, scope, key, failures, locked_until, created_at
+`
+
+type CreateLockoutEventParams struct {
+	Scope       LoginThrottleScope `json:"scope"`
+	Key         string             `json:"key"`
+	Failures    int32              `json:"failures"`
+	LockedUntil time.Time          `json:"locked_until"`
+}
+
+func (q *Queries) CreateLockoutEvent(ctx context.Context, arg CreateLockoutEventParams) (LockoutEvent, error) {
+	row := q.db.QueryRowContext(ctx, createLockoutEvent,
+		arg.Scope,
+		arg.Key,
+		arg.Failures,
+		arg.LockedUntil,
+	)
+	var i LockoutEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Scope,
+		&i.Key,
+		&i.Failures,
+		&i.LockedUntil,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const ensureLoginThrottle = `-- name: EnsureLoginThrottle :exec
+INSERT INTO login_throttles (scope, key)
+VALUES ($1, $2)
+ON CONFLICT (scope, key) DO NOTHING
+`
+
+type EnsureLoginThrottleParams struct {
+	Scope LoginThrottleScope `json:"scope"`
+	Key   string             `json:"key"`
+}
+
+func (q *Queries) EnsureLoginThrottle(ctx context.Context, arg EnsureLoginThrottleParams) error {
+	_, err := q.db.ExecContext(ctx, ensureLoginThrottle, arg.Scope, arg.Key)
+	return err
+}
+
+const getLoginThrottle = `-- name: GetLoginThrottle :one
+SELECT scope, key, failures, window_started_at, lockouts, locked_until, updated_at FROM login_throttles
+WHERE scope = $1 AND key = $2 LIMIT 1
+`
+
+type GetLoginThrottleParams struct {
+	Scope LoginThrottleScope `json:"scope"`
+	Key   string             `json:"key"`
+}
+
+func (q *Queries) GetLoginThrottle(ctx context.Context, arg GetLoginThrottleParams) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, getLoginThrottle, arg.Scope, arg.Key)
+	var i LoginThrottle
+	err := row.Scan(
+		&i.Scope,
+		&i.Key,
+		&i.Failures,
+		&i.WindowStartedAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getLoginThrottleForUpdate = `-- name: GetLoginThrottleForUpdate :one
+SELECT scope, key, failures, window_started_at, lockouts, locked_until, updated_at FROM login_throttles
+WHERE scope = $1 AND key = $2 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+type GetLoginThrottleForUpdateParams struct {
+	Scope LoginThrottleScope `json:"scope"`
+	Key   string             `json:"key"`
+}
+
+func (q *Queries) GetLoginThrottleForUpdate(ctx context.Context, arg GetLoginThrottleForUpdateParams) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, getLoginThrottleForUpdate, arg.Scope, arg.Key)
+	var i LoginThrottle
+	err := row.Scan(
+		&i.Scope,
+		&i.Key,
+		&i.Failures,
+		&i.WindowStartedAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listLockoutEvents = `-- name: ListLockoutEvents :many
+SELECT id, scope, key, failures, locked_until, created_at FROM lockout_events
+WHERE scope = $1 AND key = $2
+ORDER BY id DESC
+LIMIT $3
+`
+
+type ListLockoutEventsParams struct {
+	Scope LoginThrottleScope `json:"scope"`
+	Key   string             `json:"key"`
+	Limit int32              `json:"limit"`
+}
+
+func (q *Queries) ListLockoutEvents(ctx context.Context, arg ListLockoutEventsParams) ([]LockoutEvent, error) {
+	rows, err := q.db.QueryContext(ctx, listLockoutEvents, arg.Scope, arg.Key, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []LockoutEvent{}
+	for rows.Next() {
+		var i LockoutEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.Scope,
+			&i.Key,
+			&i.Failures,
+			&i.LockedUntil,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resetLoginThrottle = `-- name: ResetLoginThrottle :execrows
+UPDATE login_throttles
+SET failures = 0,
+   lockouts = 0,
+   locked_until = NULL,
+   updated_at = now()
+WHERE scope = $1 AND key = $2
+`
+
+type ResetLoginThrottleParams struct {
+	Scope LoginThrottleScope `json:"scope"`
+	Key   string             `json:"key"`
+}
+
+func (q *Queries) ResetLoginThrottle(ctx context.Context, arg ResetLoginThrottleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resetLoginThrottle, arg.Scope, arg.Key)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateLoginThrottle = `-- name: UpdateLoginThrottle :one
+UPDATE login_throttles
+SET failures = $3,
+   window_started_at = $4,
+   lockouts = $5,
+   locked_until = $6,
+   updated_at = now()
+WHERE scope = $1 AND key = $2
+RETURNING scope, key, failures, window_started_at, lockouts, locked_until, updated_at
+`
+
+type UpdateLoginThrottleParams struct {
+	Scope           LoginThrottleScope `json:"scope"`
+	Key             string             `json:"key"`
+	Failures        int32              `json:"failures"`
+	WindowStartedAt time.Time          `json:"window_started_at"`
+	Lockouts        int32              `json:"lockouts"`
+	LockedUntil     sql.NullTime       `json:"locked_until"`
+}
+
+func (q *Queries) UpdateLoginThrottle(ctx context.Context, arg UpdateLoginThrottleParams) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, updateLoginThrottle,
+		arg.Scope,
+		arg.Key,
+		arg.Failures,
+		arg.WindowStartedAt,
+		arg.Lockouts,
+		arg.LockedUntil,
+	)
+	var i LoginThrottle
+	err := row.Scan(
+		&i.Scope,
+		&i.Key,
+		&i.Failures,
+		&i.WindowStartedAt,
+		&i.Lockouts,
+		&i.LockedUntil,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LoginPolicy decides when failed logins lock a username or an IP out. Every
+// MaxFailures failures within Window lock it out, for BaseLockout the first
+// time and twice as long each time after that, up to MaxLockout.
+type LoginPolicy struct {
+	MaxFailures int32
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+func (p LoginPolicy) lockoutDuration(lockouts int32) time.Duration {
+	d := p.BaseLockout
+	for i := int32(1); i < lockouts; i++ {
+		d *= 2
+		if d >= p.MaxLockout {
+			return p.MaxLockout
+		}
+	}
+
+	return d
+}
+
+// Locked reports whether t is locked out at now.
+func (t LoginThrottle) Locked(now time.Time) bool {
+	return t.LockedUntil.Valid && now.Before(t.LockedUntil.Time)
+}
+
+type RecordLoginFailureTxParams struct {
+	Scope  LoginThrottleScope
+	Key    string
+	Policy LoginPolicy
+	Now    time.Time
+}
+
+// RecordLoginFailureTx counts a failed login and locks the scope and key out
+// when the policy says so, recording a lockout event.
+func (s *SQLStore) RecordLoginFailureTx(ctx context.Context, arg RecordLoginFailureTxParams) (LoginThrottle, error) {
+	var throttle LoginThrottle
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		err := q.EnsureLoginThrottle(ctx, EnsureLoginThrottleParams{Scope: arg.Scope, Key: arg.Key})
+		if err != nil {
+			return err
+		}
+
+		throttle, err = q.GetLoginThrottleForUpdate(ctx, GetLoginThrottleForUpdateParams{Scope: arg.Scope, Key: arg.Key})
+		if err != nil {
+			return err
+		}
+
+		update := UpdateLoginThrottleParams{
+			Scope:           arg.Scope,
+			Key:             arg.Key,
+			Failures:        throttle.Failures + 1,
+			WindowStartedAt: throttle.WindowStartedAt,
+			Lockouts:        throttle.Lockouts,
+			LockedUntil:     throttle.LockedUntil,
+		}
+
+		if arg.Now.Sub(throttle.WindowStartedAt) > arg.Policy.Window {
+			update.Failures = 1
+			update.WindowStartedAt = arg.Now
+		}
+
+		if update.Failures >= arg.Policy.MaxFailures {
+			update.Lockouts++
+			until := arg.Now.Add(arg.Policy.lockoutDuration(update.Lockouts))
+			update.LockedUntil = sql.NullTime{Time: until, Valid: true}
+
+			_, err = q.CreateLockoutEvent(ctx, CreateLockoutEventParams{
+				Scope:       arg.Scope,
+				Key:         arg.Key,
+				Failures:    update.Failures,
+				LockedUntil: until,
+			})
+			if err != nil {
+				return err
+			}
+
+			update.Failures = 0
+			update.WindowStartedAt = arg.Now
+		}
+
+		throttle, err = q.UpdateLoginThrottle(ctx, update)
+		return err
+	})
+
+	return throttle, err
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLoginFailureTx(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testDB)
+	key := util.RandomOnwer()
+	policy := LoginPolicy{
+		MaxFailures: 3,
+		Window:      time.Minute,
+		BaseLockout: time.Minute,
+		MaxLockout:  3 * time.Minute,
+	}
+
+	record := func(now time.Time) LoginThrottle {
+		throttle, err := store.RecordLoginFailureTx(ctx, RecordLoginFailureTxParams{
+			Scope:  LoginThrottleScopeUsername,
+			Key:    key,
+			Policy: policy,
+			Now:    now,
+		})
+		require.NoError(t, err)
+		return throttle
+	}
+
+	now := time.Now()
+	for i := 1; i < 3; i++ {
+		throttle := record(now)
+		require.Equal(t, int32(i), throttle.Failures)
+		require.False(t, throttle.Locked(now))
+	}
+
+	throttle := record(now)
+	require.Zero(t, throttle.Failures)
+	require.Equal(t, int32(1), throttle.Lockouts)
+	require.True(t, throttle.Locked(now))
+	require.WithinDuration(t, now.Add(time.Minute), throttle.LockedUntil.Time, time.Second)
+
+	// each lockout lasts twice as long as the last, up to the cap
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute} {
+		for i := 0; i < 3; i++ {
+			throttle = record(now)
+		}
+		require.WithinDuration(t, now.Add(want), throttle.LockedUntil.Time, time.Second)
+	}
+
+	events, err := store.ListLockoutEvents(ctx, ListLockoutEventsParams{
+		Scope: LoginThrottleScopeUsername,
+		Key:   key,
+		Limit: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	// failures older than the window are forgotten
+	record(now)
+	throttle = record(now.Add(2 * time.Minute))
+	require.Equal(t, int32(1), throttle.Failures)
+
+	n, err := store.ResetLoginThrottle(ctx, ResetLoginThrottleParams{Scope: LoginThrottleScopeUsername, Key: key})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	throttle, err = store.GetLoginThrottle(ctx, GetLoginThrottleParams{Scope: LoginThrottleScopeUsername, Key: key})
+	require.NoError(t, err)
+	require.Zero(t, throttle.Lockouts)
+	require.False(t, throttle.Locked(now))
+}
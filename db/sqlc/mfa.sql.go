@@ -0,0 +1,159 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: mfa.sql
+
+package db
+
+import (
+	"context"
+)
+
+const confirmMFA = `-- name: ConfirmMFA :one
+UPDATE user_mfa
+SET confirmed_at = now()
+WHERE username = $1 AND confirmed_at IS NULL
+RETURNING username, totp_secret, confirmed_at, last_used_step, created_at
+`
+
+func (q *Queries) ConfirmMFA(ctx context.Context, username string) (UserMfa, error) {
+	row := q.db.QueryRowContext(ctx, confirmMFA, username)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.TotpSecret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createRecoveryCode = `-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (
+   username,
+   code_hash
+) VALUES (
+   $1, $2
+)
+`
+
+type CreateRecoveryCodeParams struct {
+	Username string `json:"username"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (q *Queries) CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error {
+	_, err := q.db.ExecContext(ctx, createRecoveryCode, arg.Username, arg.CodeHash)
+	return err
+}
+
+const deleteRecoveryCodes = `-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes
+WHERE username = $1
+`
+
+func (q *Queries) DeleteRecoveryCodes(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteRecoveryCodes, username)
+	return err
+}
+
+const deleteUserMFA = `-- name: DeleteUserMFA :exec
+DELETE FROM user_mfa
+WHERE username = $1
+`
+
+func (q *Queries) DeleteUserMFA(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteUserMFA, username)
+	return err
+}
+
+const getUserMFA = `-- name: GetUserMFA :one
+SELECT username, totp_secret, confirmed_at, last_used_step, created_at FROM user_mfa
+WHERE username = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserMFA(ctx context.Context, username string) (UserMfa, error) {
+	row := q.db.QueryRowContext(ctx, getUserMFA, username)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.TotpSecret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const startMFAEnrollment = `-- name: StartMFAEnrollment :one
+INSERT INTO user_mfa (
+   username,
+   totp_secret
+) VALUES (
+   $1, $2
+)
+ON CONFLICT (username) DO UPDATE
+SET totp_secret = EXCLUDED.totp_secret,
+   last_used_step = 0,
+   created_at = now()
+WHERE user_mfa.confirmed_at IS NULL
+RETURNING username, totp_secret, confirmed_at, last_used_step, created_at
+`
+
+type StartMFAEnrollmentParams struct {
+	Username   string `json:"username"`
+	TotpSecret string `json:"totp_secret"`
+}
+
+// Replaces an unconfirmed enrollment; returns no rows if MFA is already on.
+func (q *Queries) StartMFAEnrollment(ctx context.Context, arg StartMFAEnrollmentParams) (UserMfa, error) {
+	row := q.db.QueryRowContext(ctx, startMFAEnrollment, arg.Username, arg.TotpSecret)
+	var i UserMfa
+	err := row.Scan(
+		&i.Username,
+		&i.TotpSecret,
+		&i.ConfirmedAt,
+		&i.LastUsedStep,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const useRecoveryCode = `-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL
+`
+
+type UseRecoveryCodeParams struct {
+	Username string `json:"username"`
+	CodeHash string `json:"code_hash"`
+}
+
+func (q *Queries) UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useRecoveryCode, arg.Username, arg.CodeHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const useTOTPStep = `-- name: UseTOTPStep :execrows
+UPDATE user_mfa
+SET last_used_step = $1
+WHERE username = $2 AND last_used_step < $1
+`
+
+type UseTOTPStepParams struct {
+	Step     int64  `json:"step"`
+	Username string `json:"username"`
+}
+
+func (q *Queries) UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, useTOTPStep, arg.Step, arg.Username)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+// ConfirmMFATx turns on two-factor authentication once the user has entered a
+// first code, and replaces their recovery codes with the given hashes.
+func (s *SQLStore) ConfirmMFATx(ctx context.Context, username string, recoveryCodeHashes []string) (UserMfa, error) {
+	var mfa UserMfa
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		mfa, err = q.ConfirmMFA(ctx, username)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMFAAlreadyEnabled
+		}
+		if err != nil {
+			return err
+		}
+
+		return q.replaceRecoveryCodes(ctx, username, recoveryCodeHashes)
+	})
+
+	return mfa, err
+}
+
+// DisableMFATx turns off two-factor authentication and deletes the recovery
+// codes.
+func (s *SQLStore) DisableMFATx(ctx context.Context, username string) error {
+	return s.execTx(ctx, func(q *Queries) error {
+		err := q.DeleteRecoveryCodes(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteUserMFA(ctx, username)
+	})
+}
+
+func (q *Queries) replaceRecoveryCodes(ctx context.Context, username string, hashes []string) error {
+	err := q.DeleteRecoveryCodes(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+			Username: username,
+			CodeHash: hash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFAEnrollment(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	mfa, err := store.StartMFAEnrollment(ctx, StartMFAEnrollmentParams{Username: user.Username, TotpSecret: "FIRST"})
+	require.NoError(t, err)
+	require.False(t, mfa.ConfirmedAt.Valid)
+
+	// an unconfirmed enrollment can be restarted
+	mfa, err = store.StartMFAEnrollment(ctx, StartMFAEnrollmentParams{Username: user.Username, TotpSecret: "SECOND"})
+	require.NoError(t, err)
+	require.Equal(t, "SECOND", mfa.TotpSecret)
+
+	mfa, err = store.ConfirmMFATx(ctx, user.Username, []string{"hash-1", "hash-2"})
+	require.NoError(t, err)
+	require.True(t, mfa.ConfirmedAt.Valid)
+
+	_, err = store.ConfirmMFATx(ctx, user.Username, nil)
+	require.ErrorIs(t, err, ErrMFAAlreadyEnabled)
+
+	// a confirmed enrollment cannot be replaced
+	_, err = store.StartMFAEnrollment(ctx, StartMFAEnrollmentParams{Username: user.Username, TotpSecret: "THIRD"})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	n, err := store.UseTOTPStep(ctx, UseTOTPStepParams{Username: user.Username, Step: 100})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	n, err = store.UseTOTPStep(ctx, UseTOTPStepParams{Username: user.Username, Step: 100})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	n, err = store.UseRecoveryCode(ctx, UseRecoveryCodeParams{Username: user.Username, CodeHash: "hash-1"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	n, err = store.UseRecoveryCode(ctx, UseRecoveryCodeParams{Username: user.Username, CodeHash: "hash-1"})
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	require.NoError(t, store.DisableMFATx(ctx, user.Username))
+	_, err = store.GetUserMFA(ctx, user.Username)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -1,29 +1,787 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.21.0
+//   sqlc v1.22.0
 
 package db
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/google/uuid"
+)
+
+type AccountStatus string
+
+const (
+	AccountStatusActive AccountStatus = "active"
+	AccountStatusFrozen AccountStatus = "frozen"
+	AccountStatusClosed AccountStatus = "closed"
+)
+
+func (e *AccountStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AccountStatus(s)
+	case string:
+		*e = AccountStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AccountStatus: %T", src)
+	}
+	return nil
+}
+
+type NullAccountStatus struct {
+	AccountStatus AccountStatus `json:"account_status"`
+	Valid         bool          `json:"valid"` // Valid is true if AccountStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAccountStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.AccountStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AccountStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAccountStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AccountStatus), nil
+}
+
+type AdjustmentReason string
+
+const (
+	AdjustmentReasonCorrection AdjustmentReason = "correction"
+	AdjustmentReasonGoodwill   AdjustmentReason = "goodwill"
+	AdjustmentReasonChargeback AdjustmentReason = "chargeback"
+	AdjustmentReasonFeeRefund  AdjustmentReason = "fee_refund"
+)
+
+func (e *AdjustmentReason) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AdjustmentReason(s)
+	case string:
+		*e = AdjustmentReason(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AdjustmentReason: %T", src)
+	}
+	return nil
+}
+
+type NullAdjustmentReason struct {
+	AdjustmentReason AdjustmentReason `json:"adjustment_reason"`
+	Valid            bool             `json:"valid"` // Valid is true if AdjustmentReason is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAdjustmentReason) Scan(value interface{}) error {
+	if value == nil {
+		ns.AdjustmentReason, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AdjustmentReason.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAdjustmentReason) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AdjustmentReason), nil
+}
+
+type DataExportStatus string
+
+const (
+	DataExportStatusPending   DataExportStatus = "pending"
+	DataExportStatusRunning   DataExportStatus = "running"
+	DataExportStatusCompleted DataExportStatus = "completed"
+	DataExportStatusFailed    DataExportStatus = "failed"
+)
+
+func (e *DataExportStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = DataExportStatus(s)
+	case string:
+		*e = DataExportStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for DataExportStatus: %T", src)
+	}
+	return nil
+}
+
+type NullDataExportStatus struct {
+	DataExportStatus DataExportStatus `json:"data_export_status"`
+	Valid            bool             `json:"valid"` // Valid is true if DataExportStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullDataExportStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.DataExportStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.DataExportStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullDataExportStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.DataExportStatus), nil
+}
+
+type FraudVerdict string
+
+const (
+	FraudVerdictAllow  FraudVerdict = "allow"
+	FraudVerdictReview FraudVerdict = "review"
+	FraudVerdictDeny   FraudVerdict = "deny"
+)
+
+func (e *FraudVerdict) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = FraudVerdict(s)
+	case string:
+		*e = FraudVerdict(s)
+	default:
+		return fmt.Errorf("unsupported scan type for FraudVerdict: %T", src)
+	}
+	return nil
+}
+
+type NullFraudVerdict struct {
+	FraudVerdict FraudVerdict `json:"fraud_verdict"`
+	Valid        bool         `json:"valid"` // Valid is true if FraudVerdict is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullFraudVerdict) Scan(value interface{}) error {
+	if value == nil {
+		ns.FraudVerdict, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.FraudVerdict.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullFraudVerdict) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.FraudVerdict), nil
+}
+
+type HeldTransferStatus string
+
+const (
+	HeldTransferStatusPending  HeldTransferStatus = "pending"
+	HeldTransferStatusApproved HeldTransferStatus = "approved"
+	HeldTransferStatusRejected HeldTransferStatus = "rejected"
 )
 
+func (e *HeldTransferStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = HeldTransferStatus(s)
+	case string:
+		*e = HeldTransferStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for HeldTransferStatus: %T", src)
+	}
+	return nil
+}
+
+type NullHeldTransferStatus struct {
+	HeldTransferStatus HeldTransferStatus `json:"held_transfer_status"`
+	Valid              bool               `json:"valid"` // Valid is true if HeldTransferStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullHeldTransferStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.HeldTransferStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.HeldTransferStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullHeldTransferStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.HeldTransferStatus), nil
+}
+
+type LimitScope string
+
+const (
+	LimitScopeProduct LimitScope = "product"
+	LimitScopeUser    LimitScope = "user"
+	LimitScopeAccount LimitScope = "account"
+)
+
+func (e *LimitScope) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = LimitScope(s)
+	case string:
+		*e = LimitScope(s)
+	default:
+		return fmt.Errorf("unsupported scan type for LimitScope: %T", src)
+	}
+	return nil
+}
+
+type NullLimitScope struct {
+	LimitScope LimitScope `json:"limit_scope"`
+	Valid      bool       `json:"valid"` // Valid is true if LimitScope is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullLimitScope) Scan(value interface{}) error {
+	if value == nil {
+		ns.LimitScope, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.LimitScope.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullLimitScope) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.LimitScope), nil
+}
+
+type LoginThrottleScope string
+
+const (
+	LoginThrottleScopeUsername LoginThrottleScope = "username"
+	LoginThrottleScopeIp       LoginThrottleScope = "ip"
+)
+
+func (e *LoginThrottleScope) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = LoginThrottleScope(s)
+	case string:
+		*e = LoginThrottleScope(s)
+	default:
+		return fmt.Errorf("unsupported scan type for LoginThrottleScope: %T", src)
+	}
+	return nil
+}
+
+type NullLoginThrottleScope struct {
+	LoginThrottleScope LoginThrottleScope `json:"login_throttle_scope"`
+	Valid              bool               `json:"valid"` // Valid is true if LoginThrottleScope is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullLoginThrottleScope) Scan(value interface{}) error {
+	if value == nil {
+		ns.LoginThrottleScope, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.LoginThrottleScope.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullLoginThrottleScope) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.LoginThrottleScope), nil
+}
+
+type PaymentDirection string
+
+const (
+	PaymentDirectionDeposit    PaymentDirection = "deposit"
+	PaymentDirectionWithdrawal PaymentDirection = "withdrawal"
+)
+
+func (e *PaymentDirection) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = PaymentDirection(s)
+	case string:
+		*e = PaymentDirection(s)
+	default:
+		return fmt.Errorf("unsupported scan type for PaymentDirection: %T", src)
+	}
+	return nil
+}
+
+type NullPaymentDirection struct {
+	PaymentDirection PaymentDirection `json:"payment_direction"`
+	Valid            bool             `json:"valid"` // Valid is true if PaymentDirection is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullPaymentDirection) Scan(value interface{}) error {
+	if value == nil {
+		ns.PaymentDirection, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.PaymentDirection.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullPaymentDirection) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.PaymentDirection), nil
+}
+
+type ReconciliationStatus string
+
+const (
+	ReconciliationStatusRunning   ReconciliationStatus = "running"
+	ReconciliationStatusCompleted ReconciliationStatus = "completed"
+	ReconciliationStatusFailed    ReconciliationStatus = "failed"
+)
+
+func (e *ReconciliationStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReconciliationStatus(s)
+	case string:
+		*e = ReconciliationStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReconciliationStatus: %T", src)
+	}
+	return nil
+}
+
+type NullReconciliationStatus struct {
+	ReconciliationStatus ReconciliationStatus `json:"reconciliation_status"`
+	Valid                bool                 `json:"valid"` // Valid is true if ReconciliationStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReconciliationStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReconciliationStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReconciliationStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReconciliationStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReconciliationStatus), nil
+}
+
+type UserRole string
+
+const (
+	UserRoleCustomer UserRole = "customer"
+	UserRoleAdmin    UserRole = "admin"
+)
+
+func (e *UserRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = UserRole(s)
+	case string:
+		*e = UserRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for UserRole: %T", src)
+	}
+	return nil
+}
+
+type NullUserRole struct {
+	UserRole UserRole `json:"user_role"`
+	Valid    bool     `json:"valid"` // Valid is true if UserRole is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullUserRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.UserRole, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.UserRole.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullUserRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.UserRole), nil
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusDead      WebhookDeliveryStatus = "dead"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveryStatus struct {
+	WebhookDeliveryStatus WebhookDeliveryStatus `json:"webhook_delivery_status"`
+	Valid                 bool                  `json:"valid"` // Valid is true if WebhookDeliveryStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveryStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveryStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveryStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveryStatus), nil
+}
+
 type Account struct {
-	ID        int64     `json:"id"`
-	Owner     string    `json:"owner"`
-	Balance   int64     `json:"balance"`
-	Currency  string    `json:"currency"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int64         `json:"id"`
+	Owner     string        `json:"owner"`
+	Balance   int64         `json:"balance"`
+	Currency  string        `json:"currency"`
+	CreatedAt time.Time     `json:"created_at"`
+	Status    AccountStatus `json:"status"`
+	// how far below zero withdrawals may take the balance
+	OverdraftLimit int64  `json:"overdraft_limit"`
+	Product        string `json:"product"`
+}
+
+type Adjustment struct {
+	ID         int64 `json:"id"`
+	AccountID  int64 `json:"account_id"`
+	TransferID int64 `json:"transfer_id"`
+	// signed, from the point of view of the account
+	Amount     int64            `json:"amount"`
+	ReasonCode AdjustmentReason `json:"reason_code"`
+	Note       string           `json:"note"`
+	Operator   string           `json:"operator"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// shown to the owner to tell keys apart and used to look the key up
+	Prefix string `json:"prefix"`
+	// sha256 of the secret part; the secret itself is only shown once
+	SecretHash string   `json:"secret_hash"`
+	Scopes     []string `json:"scopes"`
+	// the only accounts the key may act on; empty allows all of the owner's accounts
+	AccountIds []int64 `json:"account_ids"`
+	// largest single transfer the key may make; 0 means no cap
+	MaxTransferAmount int64        `json:"max_transfer_amount"`
+	ExpiresAt         sql.NullTime `json:"expires_at"`
+	LastUsedAt        sql.NullTime `json:"last_used_at"`
+	RevokedAt         sql.NullTime `json:"revoked_at"`
+	CreatedAt         time.Time    `json:"created_at"`
+}
+
+type AuditLog struct {
+	ID     int64  `json:"id"`
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+	// e.g. account:42, user:alice, transfer:7
+	Target    string          `json:"target"`
+	Reason    string          `json:"reason"`
+	Details   json.RawMessage `json:"details"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type DataExport struct {
+	ID       int64            `json:"id"`
+	Username string           `json:"username"`
+	Status   DataExportStatus `json:"status"`
+	// ZIP of the user's data; cleared once expires_at passes or the user is erased
+	Archive     []byte       `json:"archive"`
+	Error       string       `json:"error"`
+	StartedAt   sql.NullTime `json:"started_at"`
+	CompletedAt sql.NullTime `json:"completed_at"`
+	ExpiresAt   sql.NullTime `json:"expires_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+	// when a worker last claimed the export; a running export claimed too long ago is claimed again
+	ClaimedAt sql.NullTime `json:"claimed_at"`
+}
+
+type EmailVerification struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	// SHA-256 of the token sent by email; the token itself is not stored
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
 }
 
 type Entry struct {
 	ID        int64 `json:"id"`
 	AccountID int64 `json:"account_id"`
 	// can be negative or positive
-	Amount    int64     `json:"amount"`
+	Amount     int64         `json:"amount"`
+	CreatedAt  time.Time     `json:"created_at"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+}
+
+type FraudDecision struct {
+	ID            int64        `json:"id"`
+	FromAccountID int64        `json:"from_account_id"`
+	ToAccountID   int64        `json:"to_account_id"`
+	Amount        int64        `json:"amount"`
+	Verdict       FraudVerdict `json:"verdict"`
+	// names of the rules that fired
+	Rules []string `json:"rules"`
+	// screening for automatic decisions, the reviewer otherwise
+	Actor          string        `json:"actor"`
+	HeldTransferID sql.NullInt64 `json:"held_transfer_id"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+type HeldTransfer struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	// gross amount, fee included
+	Amount int64              `json:"amount"`
+	Fee    int64              `json:"fee"`
+	Status HeldTransferStatus `json:"status"`
+	// set once the held transfer is approved and posted
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Reviewer   string        `json:"reviewer"`
+	CreatedAt  time.Time     `json:"created_at"`
+	DecidedAt  sql.NullTime  `json:"decided_at"`
+	// set for withdrawals; approving the hold posts them as a payment
+	ExternalReference sql.NullString `json:"external_reference"`
+}
+
+type InterestAccrual struct {
+	AccountID int64     `json:"account_id"`
+	Day       time.Time `json:"day"`
+	// end-of-day balance the interest was computed on
+	Balance       int64 `json:"balance"`
+	AnnualRateBps int64 `json:"annual_rate_bps"`
+	// accrued interest in millionths of the minor unit
+	AmountMicros int64     `json:"amount_micros"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type InterestPosting struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	// first day of the month being posted
+	Period        time.Time `json:"period"`
+	AccruedMicros int64     `json:"accrued_micros"`
+	Amount        int64     `json:"amount"`
+	// sub-unit remainder carried into the next posting
+	CarryMicros int64 `json:"carry_micros"`
+	// null when the posted amount rounds down to zero
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type LockoutEvent struct {
+	ID          int64              `json:"id"`
+	Scope       LoginThrottleScope `json:"scope"`
+	Key         string             `json:"key"`
+	Failures    int32              `json:"failures"`
+	LockedUntil time.Time          `json:"locked_until"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Scope LoginThrottleScope `json:"scope"`
+	// username as typed, whether or not the user exists, or client IP
+	Key             string    `json:"key"`
+	Failures        int32     `json:"failures"`
+	WindowStartedAt time.Time `json:"window_started_at"`
+	// consecutive lockouts; each one lasts twice as long as the last
+	Lockouts    int32        `json:"lockouts"`
+	LockedUntil sql.NullTime `json:"locked_until"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID        int64        `json:"id"`
+	Username  string       `json:"username"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash    string   `json:"code_hash"`
+	ClientID    string   `json:"client_id"`
+	Username    string   `json:"username"`
+	RedirectUri string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+	// PKCE S256 challenge
+	CodeChallenge string       `json:"code_challenge"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	UsedAt        sql.NullTime `json:"used_at"`
+	// grant the code was exchanged for, revoked if the code is replayed
+	GrantID   uuid.NullUUID `json:"grant_id"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// sha256 of the client secret; empty for public clients, which only have PKCE
+	SecretHash   string   `json:"secret_hash"`
+	RedirectUris []string `json:"redirect_uris"`
+	// the most a user can grant the client
+	Scopes    []string  `json:"scopes"`
+	CreatedBy string    `json:"created_by"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type OauthGrant struct {
+	// session ID of every access token issued under the grant
+	ID        uuid.UUID    `json:"id"`
+	ClientID  string       `json:"client_id"`
+	Username  string       `json:"username"`
+	Scopes    []string     `json:"scopes"`
+	RevokedAt sql.NullTime `json:"revoked_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type OauthRefreshToken struct {
+	TokenHash string    `json:"token_hash"`
+	GrantID   uuid.UUID `json:"grant_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// refresh tokens rotate on use; using one twice revokes its grant
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type OutboxEvent struct {
+	ID            int64           `json:"id"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	SchemaVersion int32           `json:"schema_version"`
+	Payload       json.RawMessage `json:"payload"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// set by the relay once the sink accepted the event
+	PublishedAt sql.NullTime `json:"published_at"`
+	// a relay is publishing the event until then; it is claimed again once this passes
+	ClaimedUntil sql.NullTime `json:"claimed_until"`
+}
+
+type PasswordReset struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// SHA-256 of the token sent by email; the token itself is not stored
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type Payment struct {
+	ID         int64            `json:"id"`
+	AccountID  int64            `json:"account_id"`
+	TransferID int64            `json:"transfer_id"`
+	Direction  PaymentDirection `json:"direction"`
+	// must be positive
+	Amount int64 `json:"amount"`
+	// id of the operation on the upstream payment rail
+	ExternalReference string    `json:"external_reference"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type Product struct {
+	Code string `json:"code"`
+	Name string `json:"name"`
+	// annual interest rate in basis points, 250 = 2.5%
+	AnnualRateBps int64     `json:"annual_rate_bps"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type RateLimitBucket struct {
+	Key    string  `json:"key"`
+	Tokens float64 `json:"tokens"`
+	// whether the last request took a token
+	Allowed   bool      `json:"allowed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReconciliationDiscrepancy struct {
+	ID    int64 `json:"id"`
+	RunID int64 `json:"run_id"`
+	// account_balance or transfer_entries
+	Kind       string        `json:"kind"`
+	AccountID  sql.NullInt64 `json:"account_id"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Expected   int64         `json:"expected"`
+	Actual     int64         `json:"actual"`
+	Detail     string        `json:"detail"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+type ReconciliationRun struct {
+	ID               int64                `json:"id"`
+	Status           ReconciliationStatus `json:"status"`
+	AccountsChecked  int64                `json:"accounts_checked"`
+	TransfersChecked int64                `json:"transfers_checked"`
+	Discrepancies    int64                `json:"discrepancies"`
+	Error            string               `json:"error"`
+	StartedAt        time.Time            `json:"started_at"`
+	FinishedAt       sql.NullTime         `json:"finished_at"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
@@ -33,6 +791,29 @@ type Transfer struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type TransferFee struct {
+	// the transfer the fee was charged on
+	TransferID int64 `json:"transfer_id"`
+	// the transfer moving the fee to the revenue account
+	FeeTransferID int64 `json:"fee_transfer_id"`
+	// must be positive
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TransferLimit struct {
+	ID    int64      `json:"id"`
+	Scope LimitScope `json:"scope"`
+	// product code, username or account id, depending on scope
+	ScopeRef string `json:"scope_ref"`
+	// null leaves the limit to a less specific scope
+	MaxPerTransfer sql.NullInt64 `json:"max_per_transfer"`
+	DailyTotal     sql.NullInt64 `json:"daily_total"`
+	MonthlyTotal   sql.NullInt64 `json:"monthly_total"`
+	HourlyCount    sql.NullInt64 `json:"hourly_count"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
 type User struct {
 	Username          string    `json:"username"`
 	HashedPassword    string    `json:"hashed_password"`
@@ -40,4 +821,49 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	Role              UserRole  `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// set when the user deletes their profile; deleted users cannot log in
+	DeletedAt sql.NullTime `json:"deleted_at"`
+	// set when the user's personal data was pseudonymized; the ledger is kept
+	ErasedAt sql.NullTime `json:"erased_at"`
+}
+
+type UserMfa struct {
+	Username string `json:"username"`
+	// base32 TOTP key; kept in the clear since codes are computed from it
+	TotpSecret string `json:"totp_secret"`
+	// null until the user enters a first code, and MFA is not enforced until then
+	ConfirmedAt sql.NullTime `json:"confirmed_at"`
+	// TOTP time step of the last accepted code, so a code cannot be replayed
+	LastUsedStep int64     `json:"last_used_step"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64 `json:"id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	// outbox event the delivery was fanned out from
+	EventID       int64                 `json:"event_id"`
+	EventType     string                `json:"event_type"`
+	Payload       json.RawMessage       `json:"payload"`
+	Status        WebhookDeliveryStatus `json:"status"`
+	Attempts      int32                 `json:"attempts"`
+	NextAttemptAt time.Time             `json:"next_attempt_at"`
+	// 0 when the receiver could not be reached
+	LastStatusCode int32        `json:"last_status_code"`
+	LastError      string       `json:"last_error"`
+	DeliveredAt    sql.NullTime `json:"delivered_at"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID         int64    `json:"id"`
+	Owner      string   `json:"owner"`
+	Url        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	// HMAC-SHA256 key the payloads are signed with
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,396 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: oauth.sql
+
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createOAuthAuthorizationCode = `-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+   code_hash,
+   client_id,
+   username,
+   redirect_uri,
+   scopes,
+   code_challenge,
+   expires_at
+) VALUES (
+   $1, $2, $3, $4, $5, $6, $7
+) RETURNING code_hash, client_id, username, redirect_uri, scopes, code_challenge, expires_at, used_at, grant_id, created_at
+`
+
+type CreateOAuthAuthorizationCodeParams struct {
+	CodeHash      string    `json:"code_hash"`
+	ClientID      string    `json:"client_id"`
+	Username      string    `json:"username"`
+	RedirectUri   string    `json:"redirect_uri"`
+	Scopes        []string  `json:"scopes"`
+	CodeChallenge string    `json:"code_challenge"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthAuthorizationCode,
+		arg.CodeHash,
+		arg.ClientID,
+		arg.Username,
+		arg.RedirectUri,
+		pq.Array(arg.Scopes),
+		arg.CodeChallenge,
+		arg.ExpiresAt,
+	)
+	var i OauthAuthorizationCode
+	err := row.Scan(
+		&i.CodeHash,
+		&i.ClientID,
+		&i.Username,
+		&i.RedirectUri,
+		pq.Array(&i.Scopes),
+		&i.CodeChallenge,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.GrantID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createOAuthClient = `-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+   id,
+   name,
+   secret_hash,
+   redirect_uris,
+   scopes,
+   created_by
+) VALUES (
+   $1, $2, $3, $4, $5, $6
+) RETURNING id, name, secret_hash, redirect_uris, scopes, created_by, created_at
+`
+
+type CreateOAuthClientParams struct {
+	ID           string   `json:"id"`
+	Name         string   `json:"name"`
+	SecretHash   string   `json:"secret_hash"`
+	RedirectUris []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	CreatedBy    string   `json:"created_by"`
+}
+
+func (q *Queries) CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthClient,
+		arg.ID,
+		arg.Name,
+		arg.SecretHash,
+		pq.Array(arg.RedirectUris),
+		pq.Array(arg.Scopes),
+		arg.CreatedBy,
+	)
+	var i OauthClient
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.SecretHash,
+		pq.Array(&i.RedirectUris),
+		pq.Array(&i.Scopes),
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createOAuthGrant = `-- name: CreateOAuthGrant :one
+INSERT INTO oauth_grants (
+   id,
+   client_id,
+   username,
+   scopes
+) VALUES (
+   $1, $2, $3, $4
+) RETURNING id, client_id, username, scopes, revoked_at, created_at
+`
+
+type CreateOAuthGrantParams struct {
+	ID       uuid.UUID `json:"id"`
+	ClientID string    `json:"client_id"`
+	Username string    `json:"username"`
+	Scopes   []string  `json:"scopes"`
+}
+
+func (q *Queries) CreateOAuthGrant(ctx context.Context, arg CreateOAuthGrantParams) (OauthGrant, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthGrant,
+		arg.ID,
+		arg.ClientID,
+		arg.Username,
+		pq.Array(arg.Scopes),
+	)
+	var i OauthGrant
+	err := row.Scan(
+		&i.ID,
+		&i.ClientID,
+		&i.Username,
+		pq.Array(&i.Scopes),
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createOAuthRefreshToken = `-- name: CreateOAuthRefreshToken :one
+INSERT INTO oauth_refresh_tokens (
+   token_hash,
+   grant_id,
+   expires_at
+) VALUES (
+   $1, $2, $3
+) RETURNING token_hash, grant_id, expires_at, used_at, created_at
+`
+
+type CreateOAuthRefreshTokenParams struct {
+	TokenHash string    `json:"token_hash"`
+	GrantID   uuid.UUID `json:"grant_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (OauthRefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthRefreshToken, arg.TokenHash, arg.GrantID, arg.ExpiresAt)
+	var i OauthRefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.GrantID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOAuthAuthorizationCodeForUpdate = `-- name: GetOAuthAuthorizationCodeForUpdate :one
+SELECT code_hash, client_id, username, redirect_uri, scopes, code_challenge, expires_at, used_at, grant_id, created_at FROM oauth_authorization_codes
+WHERE code_hash = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetOAuthAuthorizationCodeForUpdate(ctx context.Context, codeHash string) (OauthAuthorizationCode, error) {
+	row := q.db.QueryRowContext(ctx, getOAuthAuthorizationCodeForUpdate, codeHash)
+	var i OauthAuthorizationCode
+	err := row.Scan(
+		&i.CodeHash,
+		&i.ClientID,
+		&i.Username,
+		&i.RedirectUri,
+		pq.Array(&i.Scopes),
+		&i.CodeChallenge,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.GrantID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOAuthClient = `-- name: GetOAuthClient :one
+SELECT id, name, secret_hash, redirect_uris, scopes, created_by, created_at FROM oauth_clients
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetOAuthClient(ctx context.Context, id string) (OauthClient, error) {
+	row := q.db.QueryRowContext(ctx, getOAuthClient, id)
+	var i OauthClient
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.SecretHash,
+		pq.Array(&i.RedirectUris),
+		pq.Array(&i.Scopes),
+		&i.CreatedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOAuthGrant = `-- name: GetOAuthGrant :one
+SELECT id, client_id, username, scopes, revoked_at, created_at FROM oauth_grants
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetOAuthGrant(ctx context.Context, id uuid.UUID) (OauthGrant, error) {
+	row := q.db.QueryRowContext(ctx, getOAuthGrant, id)
+	var i OauthGrant
+	err := row.Scan(
+		&i.ID,
+		&i.ClientID,
+		&i.Username,
+		pq.Array(&i.Scopes),
+		&i.RevokedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOAuthRefreshToken = `-- name: GetOAuthRefreshToken :one
+SELECT token_hash, grant_id, expires_at, used_at, created_at FROM oauth_refresh_tokens
+WHERE token_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetOAuthRefreshToken(ctx context.Context, tokenHash string) (OauthRefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getOAuthRefreshToken, tokenHash)
+	var i OauthRefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.GrantID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOAuthRefreshTokenForUpdate = `-- name: GetOAuthRefreshTokenForUpdate :one
+SELECT token_hash, grant_id, expires_at, used_at, created_at FROM oauth_refresh_tokens
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetOAuthRefreshTokenForUpdate(ctx context.Context, tokenHash string) (OauthRefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getOAuthRefreshTokenForUpdate, tokenHash)
+	var i OauthRefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.GrantID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listOAuthClients = `-- name: ListOAuthClients :many
+SELECT id, name, secret_hash, redirect_uris, scopes, created_by, created_at FROM oauth_clients
+ORDER BY created_at
+`
+
+func (q *Queries) ListOAuthClients(ctx context.Context) ([]OauthClient, error) {
+	rows, err := q.db.QueryContext(ctx, listOAuthClients)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OauthClient{}
+	for rows.Next() {
+		var i OauthClient
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.SecretHash,
+			pq.Array(&i.RedirectUris),
+			pq.Array(&i.Scopes),
+			&i.CreatedBy,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserOAuthGrants = `-- name: ListUserOAuthGrants :many
+SELECT id, client_id, username, scopes, revoked_at, created_at FROM oauth_grants
+WHERE username = $1
+ORDER BY created_at
+`
+
+func (q *Queries) ListUserOAuthGrants(ctx context.Context, username string) ([]OauthGrant, error) {
+	rows, err := q.db.QueryContext(ctx, listUserOAuthGrants, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OauthGrant{}
+	for rows.Next() {
+		var i OauthGrant
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClientID,
+			&i.Username,
+			pq.Array(&i.Scopes),
+			&i.RevokedAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOAuthAuthorizationCodeUsed = `-- name: MarkOAuthAuthorizationCodeUsed :exec
+UPDATE oauth_authorization_codes
+SET used_at = now(),
+   grant_id = $2
+WHERE code_hash = $1
+`
+
+type MarkOAuthAuthorizationCodeUsedParams struct {
+	CodeHash string        `json:"code_hash"`
+	GrantID  uuid.NullUUID `json:"grant_id"`
+}
+
+func (q *Queries) MarkOAuthAuthorizationCodeUsed(ctx context.Context, arg MarkOAuthAuthorizationCodeUsedParams) error {
+	_, err := q.db.ExecContext(ctx, markOAuthAuthorizationCodeUsed, arg.CodeHash, arg.GrantID)
+	return err
+}
+
+const markOAuthRefreshTokenUsed = `-- name: MarkOAuthRefreshTokenUsed :exec
+UPDATE oauth_refresh_tokens
+SET used_at = now()
+WHERE token_hash = $1
+`
+
+func (q *Queries) MarkOAuthRefreshTokenUsed(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, markOAuthRefreshTokenUsed, tokenHash)
+	return err
+}
+
+const revokeOAuthGrant = `-- name: RevokeOAuthGrant :exec
+UPDATE oauth_grants
+SET revoked_at = now()
+WHERE id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeOAuthGrant(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeOAuthGrant, id)
+	return err
+}
+
+const revokeUserOAuthGrants = `-- name: RevokeUserOAuthGrants :exec
+UPDATE oauth_grants
+SET revoked_at = now()
+WHERE username = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserOAuthGrants(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, revokeUserOAuthGrants, username)
+	return err
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrOAuthCodeUsed            = errors.New("authorization code was already used")
+	ErrOAuthCodeExpired         = errors.New("authorization code has expired")
+	ErrOAuthRefreshTokenUsed    = errors.New("refresh token was already used")
+	ErrOAuthRefreshTokenExpired = errors.New("refresh token has expired")
+	ErrOAuthGrantRevoked        = errors.New("grant was revoked")
+	ErrOAuthWrongClient         = errors.New("grant was issued to another client")
+)
+
+type ExchangeOAuthCodeTxParams struct {
+	CodeHash string
+	ClientID string
+	// Verify runs once the code is locked, to check what the client sent
+	// against it. An error leaves the code unused.
+	Verify           func(code OauthAuthorizationCode) error
+	GrantID          uuid.UUID
+	RefreshTokenHash string
+	RefreshExpiresAt time.Time
+	Now              time.Time
+}
+
+type OAuthTokenTxResult struct {
+	Grant        OauthGrant
+	RefreshToken OauthRefreshToken
+}
+
+// ExchangeOAuthCodeTx consumes an authorization code, creating the grant and
+// its first refresh token. Replaying a used code revokes the grant it was
+// exchanged for and fails with ErrOAuthCodeUsed.
+func (s *SQLStore) ExchangeOAuthCodeTx(ctx context.Context, arg ExchangeOAuthCodeTxParams) (OAuthTokenTxResult, error) {
+	var result OAuthTokenTxResult
+	var replayed bool
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		code, err := q.GetOAuthAuthorizationCodeForUpdate(ctx, arg.CodeHash)
+		if err != nil {
+			return err
+		}
+
+		if code.ClientID != arg.ClientID {
+			return ErrOAuthWrongClient
+		}
+
+		if code.UsedAt.Valid {
+			replayed = true
+			if !code.GrantID.Valid {
+				return nil
+			}
+
+			return q.RevokeOAuthGrant(ctx, code.GrantID.UUID)
+		}
+
+		if arg.Now.After(code.ExpiresAt) {
+			return ErrOAuthCodeExpired
+		}
+
+		if arg.Verify != nil {
+			if err := arg.Verify(code); err != nil {
+				return err
+			}
+		}
+
+		result.Grant, err = q.CreateOAuthGrant(ctx, CreateOAuthGrantParams{
+			ID:       arg.GrantID,
+			ClientID: code.ClientID,
+			Username: code.Username,
+			Scopes:   code.Scopes,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.MarkOAuthAuthorizationCodeUsed(ctx, MarkOAuthAuthorizationCodeUsedParams{
+			CodeHash: arg.CodeHash,
+			GrantID:  uuid.NullUUID{UUID: result.Grant.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		result.RefreshToken, err = q.CreateOAuthRefreshToken(ctx, CreateOAuthRefreshTokenParams{
+			TokenHash: arg.RefreshTokenHash,
+			GrantID:   result.Grant.ID,
+			ExpiresAt: arg.RefreshExpiresAt,
+		})
+		return err
+	})
+	if err == nil && replayed {
+		err = ErrOAuthCodeUsed
+	}
+
+	return result, err
+}
+
+type RefreshOAuthTokenTxParams struct {
+	TokenHash        string
+	ClientID         string
+	NewTokenHash     string
+	RefreshExpiresAt time.Time
+	Now              time.Time
+}
+
+// RefreshOAuthTokenTx rotates a refresh token. Using a refresh token twice
+// means it leaked, so it revokes the whole grant and fails with
+// ErrOAuthRefreshTokenUsed.
+func (s *SQLStore) RefreshOAuthTokenTx(ctx context.Context, arg RefreshOAuthTokenTxParams) (OAuthTokenTxResult, error) {
+	var result OAuthTokenTxResult
+	var replayed bool
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		refresh, err := q.GetOAuthRefreshTokenForUpdate(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		result.Grant, err = q.GetOAuthGrant(ctx, refresh.GrantID)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case result.Grant.ClientID != arg.ClientID:
+			return ErrOAuthWrongClient
+		case result.Grant.RevokedAt.Valid:
+			return ErrOAuthGrantRevoked
+		case refresh.UsedAt.Valid:
+			replayed = true
+			return q.RevokeOAuthGrant(ctx, result.Grant.ID)
+		case arg.Now.After(refresh.ExpiresAt):
+			return ErrOAuthRefreshTokenExpired
+		}
+
+		err = q.MarkOAuthRefreshTokenUsed(ctx, arg.TokenHash)
+		if err != nil {
+			return err
+		}
+
+		result.RefreshToken, err = q.CreateOAuthRefreshToken(ctx, CreateOAuthRefreshTokenParams{
+			TokenHash: arg.NewTokenHash,
+			GrantID:   result.Grant.ID,
+			ExpiresAt: arg.RefreshExpiresAt,
+		})
+		return err
+	})
+	if err == nil && replayed {
+		err = ErrOAuthRefreshTokenUsed
+	}
+
+	return result, err
+}
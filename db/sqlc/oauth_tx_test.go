@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthCode(t *testing.T) (OauthClient, OauthAuthorizationCode) {
+	user := createRandomUser(t)
+
+	client, err := testQueries.CreateOAuthClient(context.Background(), CreateOAuthClientParams{
+		ID:           "cli_" + util.RandomString(24),
+		Name:         "Budget App",
+		RedirectUris: []string{"https://budget.example.com/callback"},
+		Scopes:       []string{"accounts:read"},
+		CreatedBy:    user.Username,
+	})
+	require.NoError(t, err)
+
+	code, err := testQueries.CreateOAuthAuthorizationCode(context.Background(), CreateOAuthAuthorizationCodeParams{
+		CodeHash:      util.RandomString(64),
+		ClientID:      client.ID,
+		Username:      user.Username,
+		RedirectUri:   client.RedirectUris[0],
+		Scopes:        client.Scopes,
+		CodeChallenge: util.RandomString(43),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	})
+	require.NoError(t, err)
+
+	return client, code
+}
+
+func exchangeParams(client OauthClient, code OauthAuthorizationCode) ExchangeOAuthCodeTxParams {
+	return ExchangeOAuthCodeTxParams{
+		CodeHash:         code.CodeHash,
+		ClientID:         client.ID,
+		GrantID:          uuid.New(),
+		RefreshTokenHash: util.RandomString(64),
+		RefreshExpiresAt: time.Now().Add(time.Hour),
+		Now:              time.Now(),
+	}
+}
+
+func TestExchangeOAuthCodeTxReplayRevokesGrant(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testDB)
+	client, code := createRandomOAuthCode(t)
+
+	result, err := store.ExchangeOAuthCodeTx(ctx, exchangeParams(client, code))
+	require.NoError(t, err)
+	require.Equal(t, code.Username, result.Grant.Username)
+	require.Equal(t, code.Scopes, result.Grant.Scopes)
+	require.Equal(t, result.Grant.ID, result.RefreshToken.GrantID)
+
+	_, err = store.ExchangeOAuthCodeTx(ctx, exchangeParams(client, code))
+	require.ErrorIs(t, err, ErrOAuthCodeUsed)
+
+	grant, err := testQueries.GetOAuthGrant(ctx, result.Grant.ID)
+	require.NoError(t, err)
+	require.True(t, grant.RevokedAt.Valid)
+}
+
+func TestExchangeOAuthCodeTxVerifyFailureKeepsCode(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testDB)
+	client, code := createRandomOAuthCode(t)
+
+	arg := exchangeParams(client, code)
+	arg.Verify = func(OauthAuthorizationCode) error { return ErrOAuthWrongClient }
+	_, err := store.ExchangeOAuthCodeTx(ctx, arg)
+	require.ErrorIs(t, err, ErrOAuthWrongClient)
+
+	_, err = store.ExchangeOAuthCodeTx(ctx, exchangeParams(client, code))
+	require.NoError(t, err)
+}
+
+func TestRefreshOAuthTokenTxRotates(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(testDB)
+	client, code := createRandomOAuthCode(t)
+
+	exchanged, err := store.ExchangeOAuthCodeTx(ctx, exchangeParams(client, code))
+	require.NoError(t, err)
+
+	arg := RefreshOAuthTokenTxParams{
+		TokenHash:        exchanged.RefreshToken.TokenHash,
+		ClientID:         client.ID,
+		NewTokenHash:     util.RandomString(64),
+		RefreshExpiresAt: time.Now().Add(time.Hour),
+		Now:              time.Now(),
+	}
+
+	_, err = store.RefreshOAuthTokenTx(ctx, RefreshOAuthTokenTxParams{
+		TokenHash: arg.TokenHash,
+		ClientID:  "cli_other",
+		Now:       arg.Now,
+	})
+	require.ErrorIs(t, err, ErrOAuthWrongClient)
+
+	refreshed, err := store.RefreshOAuthTokenTx(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, exchanged.Grant.ID, refreshed.Grant.ID)
+	require.Equal(t, arg.NewTokenHash, refreshed.RefreshToken.TokenHash)
+
+	// the rotated-out token leaked: using it again revokes the grant
+	arg.NewTokenHash = util.RandomString(64)
+	_, err = store.RefreshOAuthTokenTx(ctx, arg)
+	require.ErrorIs(t, err, ErrOAuthRefreshTokenUsed)
+
+	_, err = store.RefreshOAuthTokenTx(ctx, RefreshOAuthTokenTxParams{
+		TokenHash:        refreshed.RefreshToken.TokenHash,
+		ClientID:         client.ID,
+		NewTokenHash:     util.RandomString(64),
+		RefreshExpiresAt: time.Now().Add(time.Hour),
+		Now:              time.Now(),
+	})
+	require.ErrorIs(t, err, ErrOAuthGrantRevoked)
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/aulas/demo-bank/events"
+)
+
+// outboxLockKey is the advisory lock that makes a single relay claim events at
+// a time. Together with the claims it keeps events in order.
+const outboxLockKey = 0x6f7574626f78
+
+// outboxClaimLease is how long a relay may take to publish a batch before
+// another relay claims it again.
+const outboxClaimLease = 5 * time.Minute
+
+// RecordEvent writes p to the outbox. q is expected to be bound to the
+// transaction making the change the event describes.
+func (q *Queries) RecordEvent(ctx context.Context, p events.Payload) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		AggregateType: p.AggregateType(),
+		AggregateID:   p.AggregateID(),
+		EventType:     p.EventType(),
+		SchemaVersion: p.SchemaVersion(),
+		Payload:       data,
+	})
+
+	return err
+}
+
+// CreateAccountTx creates the account and its account.created event.
+func (s *SQLStore) CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error) {
+	var account Account
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return q.RecordEvent(ctx, events.AccountCreated{
+			AccountID: account.ID,
+			Owner:     account.Owner,
+			Currency:  account.Currency,
+			Product:   account.Product,
+			CreatedAt: account.CreatedAt,
+		})
+	})
+
+	return account, err
+}
+
+func (q *Queries) CreateUserWithEvent(ctx context.Context, arg CreateUserParams) (User, error) {
+	user, err := q.CreateUser(ctx, arg)
+	if err != nil {
+		return user, err
+	}
+
+	return user, q.RecordEvent(ctx, events.UserRegistered{
+		Username:     user.Username,
+		FullName:     user.FullName,
+		Email:        user.Email,
+		RegisteredAt: user.CreatedAt,
+	})
+}
+
+// RelayOutboxTx hands up to size unpublished events to publish, in order, and
+// marks the ones it accepted as published. It stops at the first event publish
+// fails on, so that event and the ones after it are retried by the next call:
+// delivery is at least once and never out of order. If another relay holds a
+// claim, nothing is published.
+//
+// The events are claimed in one transaction and marked in another, so publish
+// runs without holding a connection or locks. A relay that dies in between
+// leaves its claim to expire after outboxClaimLease.
+func (s *SQLStore) RelayOutboxTx(ctx context.Context, size int32, publish func(OutboxEvent) error) (int, error) {
+	var pending []OutboxEvent
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		locked, err := q.TryOutboxLock(ctx, outboxLockKey)
+		if err != nil || !locked {
+			return err
+		}
+
+		pending, err = q.ClaimOutboxEvents(ctx, ClaimOutboxEventsParams{
+			LeaseSeconds: int64(outboxClaimLease / time.Second),
+			Size:         size,
+		})
+
+		return err
+	})
+	if err != nil || len(pending) == 0 {
+		return 0, err
+	}
+
+	sort.Slice(pending, func(i, j int) bool { return pending[i].ID < pending[j].ID })
+
+	var published, unpublished []int64
+	var publishErr error
+	for _, event := range pending {
+		if publishErr == nil {
+			publishErr = publish(event)
+		}
+
+		if publishErr != nil {
+			unpublished = append(unpublished, event.ID)
+			continue
+		}
+
+		published = append(published, event.ID)
+	}
+
+	err = s.execTx(ctx, func(q *Queries) error {
+		if len(published) > 0 {
+			err := q.MarkOutboxEventsPublished(ctx, published)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(unpublished) == 0 {
+			return nil
+		}
+
+		return q.ReleaseOutboxEvents(ctx, unpublished)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(published), publishErr
+}
@@ -0,0 +1,172 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: outbox.sql
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/lib/pq"
+)
+
+const claimOutboxEvents = `-- name: ClaimOutboxEvents :many
+UPDATE outbox_events
+SET claimed_until = now() + $1::bigint * interval '1 second'
+WHERE id IN (
+   SELECT id FROM outbox_events
+   WHERE published_at IS NULL
+   ORDER BY id
+   LIMIT $2
+)
+   AND NOT EXISTS (
+      SELECT 1 FROM outbox_events
+      WHERE published_at IS NULL
+         AND claimed_until > now()
+   )
+RETURNING id, aggregate_type, aggregate_id, event_type, schema_version, payload, created_at, published_at, claimed_until
+`
+
+type ClaimOutboxEventsParams struct {
+	LeaseSeconds int64 `json:"lease_seconds"`
+	Size         int32 `json:"size"`
+}
+
+// ClaimOutboxEvents claims the oldest unpublished events for lease_seconds.
+// Nothing is claimed while an earlier claim is live, so a single relay
+// publishes at a time.
+func (q *Queries) ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]OutboxEvent, error) {
+	rows, err := q.db.QueryContext(ctx, claimOutboxEvents, arg.LeaseSeconds, arg.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OutboxEvent{}
+	for rows.Next() {
+		var i OutboxEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.AggregateType,
+			&i.AggregateID,
+			&i.EventType,
+			&i.SchemaVersion,
+			&i.Payload,
+			&i.CreatedAt,
+			&i.PublishedAt,
+			&i.ClaimedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const createOutboxEvent = `-- name: CreateOutboxEvent :one
+INSERT INTO outbox_events (
+   aggregate_type,
+   aggregate_id,
+   event_type,
+   schema_version,
+   payload
+) VALUES (
+   $1, $2, $3, $4, $5
+) RETURNING id, aggregate_type, aggregate_id, event_type, schema_version, payload, created_at, published_at, claimed_until
+`
+
+type CreateOutboxEventParams struct {
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventType     string          `json:"event_type"`
+	SchemaVersion int32           `json:"schema_version"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+func (q *Queries) CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error) {
+	row := q.db.QueryRowContext(ctx, createOutboxEvent,
+		arg.AggregateType,
+		arg.AggregateID,
+		arg.EventType,
+		arg.SchemaVersion,
+		arg.Payload,
+	)
+	var i OutboxEvent
+	err := row.Scan(
+		&i.ID,
+		&i.AggregateType,
+		&i.AggregateID,
+		&i.EventType,
+		&i.SchemaVersion,
+		&i.Payload,
+		&i.CreatedAt,
+		&i.PublishedAt,
+		&i.ClaimedUntil,
+	)
+	return i, err
+}
+
+const markOutboxEventsPublished = `-- name: MarkOutboxEventsPublished :exec
+UPDATE outbox_events
+SET published_at = now()
+WHERE id = ANY($1::bigint[])
+`
+
+func (q *Queries) MarkOutboxEventsPublished(ctx context.Context, ids []int64) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEventsPublished, pq.Array(ids))
+	return err
+}
+
+const releaseOutboxEvents = `-- name: ReleaseOutboxEvents :exec
+UPDATE outbox_events
+SET claimed_until = NULL
+WHERE id = ANY($1::bigint[])
+   AND published_at IS NULL
+`
+
+func (q *Queries) ReleaseOutboxEvents(ctx context.Context, ids []int64) error {
+	_, err := q.db.ExecContext(ctx, releaseOutboxEvents, pq.Array(ids))
+	return err
+}
+
+const scrubUserRegisteredEvents = `-- name: ScrubUserRegisteredEvents :exec
+UPDATE outbox_events
+SET payload = payload || jsonb_build_object(
+   'full_name', $1::text,
+   'email', $2::text
+)
+WHERE aggregate_type = 'user'
+   AND aggregate_id = $3
+   AND event_type = 'user.registered'
+`
+
+type ScrubUserRegisteredEventsParams struct {
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// ScrubUserRegisteredEvents overwrites the personal data copied into the
+// user.registered events of an erased user.
+func (q *Queries) ScrubUserRegisteredEvents(ctx context.Context, arg ScrubUserRegisteredEventsParams) error {
+	_, err := q.db.ExecContext(ctx, scrubUserRegisteredEvents, arg.FullName, arg.Email, arg.Username)
+	return err
+}
+
+const tryOutboxLock = `-- name: TryOutboxLock :one
+SELECT pg_try_advisory_xact_lock($1::bigint) AS locked
+`
+
+func (q *Queries) TryOutboxLock(ctx context.Context, key int64) (bool, error) {
+	row := q.db.QueryRowContext(ctx, tryOutboxLock, key)
+	var locked bool
+	err := row.Scan(&locked)
+	return locked, err
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/events"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// drainOutbox marks every pending event as published so a test only sees the
+// events it causes.
+func drainOutbox(t *testing.T, store Store) {
+	for {
+		n, err := store.RelayOutboxTx(context.Background(), 1000, func(OutboxEvent) error { return nil })
+		require.NoError(t, err)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func TestTransferTxRecordsEvent(t *testing.T) {
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+	drainOutbox(t, store)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	var published []OutboxEvent
+	_, err = store.RelayOutboxTx(context.Background(), 1000, func(e OutboxEvent) error {
+		published = append(published, e)
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, published)
+
+	var found bool
+	for _, e := range published {
+		if e.EventType != events.TypeTransferPosted {
+			continue
+		}
+
+		var payload events.TransferPosted
+		require.NoError(t, json.Unmarshal(e.Payload, &payload))
+		if payload.TransferID == result.Transfer.ID {
+			found = true
+			require.Equal(t, fmt.Sprint(acc1.ID), e.AggregateID)
+			require.Equal(t, result.FromAccount.Balance, payload.FromBalance)
+		}
+	}
+	require.True(t, found)
+}
+
+func TestCreateUserTxRecordsEvent(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	user, err := store.CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomUsername(),
+			HashedPassword: "secret",
+			FullName:       util.RandomFullName(),
+			Email:          util.RandomEmail(),
+		},
+		VerificationTokenHash: util.RandomString(64),
+		VerificationExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	var types []string
+	_, err = store.RelayOutboxTx(context.Background(), 1000, func(e OutboxEvent) error {
+		if e.AggregateID == user.Username {
+			types = append(types, e.EventType)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{events.TypeUserRegistered}, types)
+}
+
+func TestRelayOutboxTxRetriesFailedEvent(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	_, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: util.RandomCurrency(),
+		Product:  "checking",
+	})
+	require.NoError(t, err)
+
+	n, err := store.RelayOutboxTx(context.Background(), 1000, func(OutboxEvent) error {
+		return errors.New("sink unavailable")
+	})
+	require.Error(t, err)
+	require.Zero(t, n)
+
+	var attempts int
+	n, err = store.RelayOutboxTx(context.Background(), 1000, func(OutboxEvent) error {
+		attempts++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, 1, attempts)
+}
+
+func TestRelayOutboxTxReclaimsExpiredClaim(t *testing.T) {
+	store := NewStore(testDB)
+	drainOutbox(t, store)
+
+	_, err := store.CreateAccountTx(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: util.RandomCurrency(),
+		Product:  "checking",
+	})
+	require.NoError(t, err)
+
+	// a relay is still publishing the event
+	claimed, err := testQueries.ClaimOutboxEvents(context.Background(), ClaimOutboxEventsParams{
+		LeaseSeconds: 3600,
+		Size:         1000,
+	})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	n, err := store.RelayOutboxTx(context.Background(), 1000, func(OutboxEvent) error { return nil })
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// the next relay died and its claim ran out
+	require.NoError(t, testQueries.ReleaseOutboxEvents(context.Background(), []int64{claimed[0].ID}))
+	claimed, err = testQueries.ClaimOutboxEvents(context.Background(), ClaimOutboxEventsParams{
+		LeaseSeconds: -1,
+		Size:         1000,
+	})
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	var published []int64
+	n, err = store.RelayOutboxTx(context.Background(), 1000, func(e OutboxEvent) error {
+		published = append(published, e.ID)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, []int64{claimed[0].ID}, published)
+}
@@ -0,0 +1,90 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: payment.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createPayment = `-- name: CreatePayment :one
+INSERT INTO payments (
+   account_id,
+   transfer_id,
+   direction,
+   amount,
+   external_reference
+) VALUES (
+   $1, $2, $3, $4, $5
+) RETURNING id, account_id, transfer_id, direction, amount, external_reference, created_at
+`
+
+type CreatePaymentParams struct {
+	AccountID         int64            `json:"account_id"`
+	TransferID        int64            `json:"transfer_id"`
+	Direction         PaymentDirection `json:"direction"`
+	Amount            int64            `json:"amount"`
+	ExternalReference string           `json:"external_reference"`
+}
+
+func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, createPayment,
+		arg.AccountID,
+		arg.TransferID,
+		arg.Direction,
+		arg.Amount,
+		arg.ExternalReference,
+	)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.TransferID,
+		&i.Direction,
+		&i.Amount,
+		&i.ExternalReference,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPayment = `-- name: GetPayment :one
+SELECT id, account_id, transfer_id, direction, amount, external_reference, created_at FROM payments
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetPayment(ctx context.Context, id int64) (Payment, error) {
+	row := q.db.QueryRowContext(ctx, getPayment, id)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.AccountID,
+		&i.TransferID,
+		&i.Direction,
+		&i.Amount,
+		&i.ExternalReference,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const paymentReferenceExists = `-- name: PaymentReferenceExists :one
+SELECT EXISTS (
+   SELECT 1 FROM payments
+   WHERE direction = $1 AND external_reference = $2
+)
+`
+
+type PaymentReferenceExistsParams struct {
+	Direction         PaymentDirection `json:"direction"`
+	ExternalReference string           `json:"external_reference"`
+}
+
+func (q *Queries) PaymentReferenceExists(ctx context.Context, arg PaymentReferenceExistsParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, paymentReferenceExists, arg.Direction, arg.ExternalReference)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// SettlementOwner owns the per-currency settlement accounts that mirror the
+// money held at the upstream payment rail.
+const SettlementOwner = "settlement"
+
+var ErrInsufficientFunds = errors.New("insufficient funds")
+
+type PaymentTxParams struct {
+	AccountID         int64            `json:"account_id"`
+	Direction         PaymentDirection `json:"direction"`
+	Amount            int64            `json:"amount"`
+	ExternalReference string           `json:"external_reference"`
+}
+
+type PaymentTxResult struct {
+	Payment  Payment  `json:"payment"`
+	Transfer Transfer `json:"transfer"`
+	Account  Account  `json:"account"`
+	Entry    Entry    `json:"entry"`
+}
+
+// PaymentTx moves money between an account and the settlement account of its
+// currency: deposits credit the account, withdrawals debit it. A withdrawal
+// fails with ErrInsufficientFunds if it would take the balance below the
+// account's overdraft limit, and with a *LimitExceededError if it breaks one
+// of the account's transfer limits.
+func (s *SQLStore) PaymentTx(ctx context.Context, arg PaymentTxParams) (PaymentTxResult, error) {
+	var result PaymentTxResult
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		result, err = q.postPayment(ctx, arg)
+		return err
+	})
+
+	return result, err
+}
+
+func (q *Queries) postPayment(ctx context.Context, arg PaymentTxParams) (PaymentTxResult, error) {
+	var result PaymentTxResult
+
+	account, err := q.GetAccount(ctx, arg.AccountID)
+	if err != nil {
+		return result, err
+	}
+
+	settlement, err := q.GetAccountByOwner(ctx, GetAccountByOwnerParams{
+		Owner:    SettlementOwner,
+		Currency: account.Currency,
+	})
+	if err != nil {
+		return result, fmt.Errorf("cannot get %s settlement account: %w", account.Currency, err)
+	}
+
+	var transfer TransferTxResult
+	if arg.Direction == PaymentDirectionDeposit {
+		transfer, err = q.PostTransfer(ctx, TransferTxParams{
+			FromAccountID: settlement.ID,
+			ToAccountID:   account.ID,
+			Amount:        arg.Amount,
+		})
+		if err != nil {
+			return result, err
+		}
+
+		result.Account, result.Entry = transfer.ToAccount, transfer.ToEntry
+	} else {
+		// money leaving the bank counts against the limits like a transfer
+		transfer, err = q.postCustomerTransfer(ctx, TransferTxParams{
+			FromAccountID: account.ID,
+			ToAccountID:   settlement.ID,
+			Amount:        arg.Amount,
+		})
+		if err != nil {
+			return result, err
+		}
+
+		result.Account, result.Entry = transfer.FromAccount, transfer.FromEntry
+
+		// The balance is checked after the update rather than with a prior
+		// SELECT ... FOR UPDATE so that rows are still locked in id order by
+		// PostTransfer; the transaction is rolled back on failure.
+		if result.Account.Balance < -result.Account.OverdraftLimit {
+			return result, ErrInsufficientFunds
+		}
+	}
+
+	result.Transfer = transfer.Transfer
+	result.Payment, err = q.CreatePayment(ctx, CreatePaymentParams{
+		AccountID:         account.ID,
+		TransferID:        transfer.Transfer.ID,
+		Direction:         arg.Direction,
+		Amount:            arg.Amount,
+		ExternalReference: arg.ExternalReference,
+	})
+
+	return result, err
+}
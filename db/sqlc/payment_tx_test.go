@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentTx(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomAccount(t)
+
+	deposit, err := store.PaymentTx(context.Background(), PaymentTxParams{
+		AccountID:         acc.ID,
+		Direction:         PaymentDirectionDeposit,
+		Amount:            100,
+		ExternalReference: util.RandomString(16),
+	})
+	require.NoError(t, err)
+	require.Equal(t, acc.Balance+100, deposit.Account.Balance)
+	require.Equal(t, int64(100), deposit.Entry.Amount)
+	require.Equal(t, deposit.Transfer.ID, deposit.Payment.TransferID)
+
+	withdrawal, err := store.PaymentTx(context.Background(), PaymentTxParams{
+		AccountID:         acc.ID,
+		Direction:         PaymentDirectionWithdrawal,
+		Amount:            40,
+		ExternalReference: util.RandomString(16),
+	})
+	require.NoError(t, err)
+	require.Equal(t, acc.Balance+60, withdrawal.Account.Balance)
+	require.Equal(t, int64(-40), withdrawal.Entry.Amount)
+}
+
+func TestPaymentTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomAccount(t)
+
+	_, err := testQueries.SetAccountOverdraftLimit(context.Background(), SetAccountOverdraftLimitParams{
+		ID:             acc.ID,
+		OverdraftLimit: 50,
+	})
+	require.NoError(t, err)
+
+	_, err = store.PaymentTx(context.Background(), PaymentTxParams{
+		AccountID:         acc.ID,
+		Direction:         PaymentDirectionWithdrawal,
+		Amount:            acc.Balance + 51,
+		ExternalReference: util.RandomString(16),
+	})
+	require.ErrorIs(t, err, ErrInsufficientFunds)
+
+	_, err = store.PaymentTx(context.Background(), PaymentTxParams{
+		AccountID:         acc.ID,
+		Direction:         PaymentDirectionWithdrawal,
+		Amount:            acc.Balance + 50,
+		ExternalReference: util.RandomString(16),
+	})
+	require.NoError(t, err)
+
+	updated, err := testQueries.GetAccount(context.Background(), acc.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-50), updated.Balance)
+}
+
+func TestPaymentTxWithdrawalLimits(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomAccount(t)
+
+	_, err := testQueries.UpsertTransferLimit(context.Background(), UpsertTransferLimitParams{
+		Scope:      LimitScopeAccount,
+		ScopeRef:   fmt.Sprint(acc.ID),
+		DailyTotal: sql.NullInt64{Int64: 30, Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = store.PaymentTx(context.Background(), PaymentTxParams{
+		AccountID:         acc.ID,
+		Direction:         PaymentDirectionWithdrawal,
+		Amount:            20,
+		ExternalReference: util.RandomString(16),
+	})
+	require.NoError(t, err)
+
+	// the first withdrawal counts towards the daily total
+	_, err = store.PaymentTx(context.Background(), PaymentTxParams{
+		AccountID:         acc.ID,
+		Direction:         PaymentDirectionWithdrawal,
+		Amount:            20,
+		ExternalReference: util.RandomString(16),
+	})
+	var limitErr *LimitExceededError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, LimitDailyTotal, limitErr.Limit)
+	require.Equal(t, int64(10), limitErr.Remaining)
+}
@@ -1,30 +1,196 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.21.0
+//   sqlc v1.22.0
 
 package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
+	// ClaimDataExport picks the oldest pending export, skipping ones another
+	// worker holds. A running export claimed before stale_before is picked too:
+	// its worker is taken to have died.
+	ClaimDataExport(ctx context.Context, staleBefore time.Time) (DataExport, error)
+	// ClaimOutboxEvents claims the oldest unpublished events for lease_seconds.
+	// Nothing is claimed while an earlier claim is live, so a single relay
+	// publishes at a time.
+	ClaimOutboxEvents(ctx context.Context, arg ClaimOutboxEventsParams) ([]OutboxEvent, error)
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	// CloseAccount only closes accounts with nothing left in them.
+	CloseAccount(ctx context.Context, id int64) (Account, error)
+	CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) (DataExport, error)
+	ConfirmMFA(ctx context.Context, username string) (UserMfa, error)
+	CountTransfersBetween(ctx context.Context, arg CountTransfersBetweenParams) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAdjustment(ctx context.Context, arg CreateAdjustmentParams) (Adjustment, error)
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) (AuditLog, error)
+	CreateDataExport(ctx context.Context, username string) (DataExport, error)
+	CreateEmailVerification(ctx context.Context, arg CreateEmailVerificationParams) (EmailVerification, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFraudDecision(ctx context.Context, arg CreateFraudDecisionParams) (FraudDecision, error)
+	CreateHeldTransfer(ctx context.Context, arg CreateHeldTransferParams) (HeldTransfer, error)
+	CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (int64, error)
+	CreateInterestPosting(ctx context.Context, arg CreateInterestPostingParams) (InterestPosting, error)
+	CreateLockoutEvent(ctx context.Context, arg CreateLockoutEventParams) (LockoutEvent, error)
+	CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateOAuthGrant(ctx context.Context, arg CreateOAuthGrantParams) (OauthGrant, error)
+	CreateOAuthRefreshToken(ctx context.Context, arg CreateOAuthRefreshTokenParams) (OauthRefreshToken, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (OutboxEvent, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error)
+	CreateReconciliationDiscrepancy(ctx context.Context, arg CreateReconciliationDiscrepancyParams) (ReconciliationDiscrepancy, error)
+	CreateReconciliationRun(ctx context.Context) (ReconciliationRun, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferFee(ctx context.Context, arg CreateTransferFeeParams) (TransferFee, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DecideHeldTransfer(ctx context.Context, arg DecideHeldTransferParams) (HeldTransfer, error)
 	DeleteAccount(ctx context.Context, id int64) error
 	DeleteEntry(ctx context.Context, id int64) error
+	DeleteIdleRateLimitBuckets(ctx context.Context, updatedAt time.Time) (int64, error)
+	DeleteRecoveryCodes(ctx context.Context, username string) error
+	DeleteTransferLimit(ctx context.Context, arg DeleteTransferLimitParams) error
+	DeleteUserEmailVerifications(ctx context.Context, username string) error
+	DeleteUserMFA(ctx context.Context, username string) error
+	DeleteUserPasswordResets(ctx context.Context, username string) error
+	DeleteUserWebhookSubscriptions(ctx context.Context, owner string) error
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
+	EnqueueWebhookDeliveries(ctx context.Context, arg EnqueueWebhookDeliveriesParams) (int64, error)
+	EnsureLoginThrottle(ctx context.Context, arg EnsureLoginThrottleParams) error
+	// EraseUser replaces the user's personal data with pseudonyms. The username
+	// stays, since the ledger refers to it.
+	EraseUser(ctx context.Context, arg EraseUserParams) (User, error)
+	FailDataExport(ctx context.Context, arg FailDataExportParams) (DataExport, error)
+	FinishReconciliationRun(ctx context.Context, arg FinishReconciliationRunParams) (ReconciliationRun, error)
+	GetAPIKey(ctx context.Context, id int64) (ApiKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	GetAccountByOwner(ctx context.Context, arg GetAccountByOwnerParams) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetDataExport(ctx context.Context, id int64) (DataExport, error)
+	GetEmailVerificationForUpdate(ctx context.Context, tokenHash string) (EmailVerification, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetHeldTransfer(ctx context.Context, id int64) (HeldTransfer, error)
+	GetHeldTransferForUpdate(ctx context.Context, id int64) (HeldTransfer, error)
+	GetInterestCarry(ctx context.Context, arg GetInterestCarryParams) (int64, error)
+	GetLastAccrualDay(ctx context.Context) (time.Time, error)
+	GetLoginThrottle(ctx context.Context, arg GetLoginThrottleParams) (LoginThrottle, error)
+	GetLoginThrottleForUpdate(ctx context.Context, arg GetLoginThrottleForUpdateParams) (LoginThrottle, error)
+	GetOAuthAuthorizationCodeForUpdate(ctx context.Context, codeHash string) (OauthAuthorizationCode, error)
+	GetOAuthClient(ctx context.Context, id string) (OauthClient, error)
+	GetOAuthGrant(ctx context.Context, id uuid.UUID) (OauthGrant, error)
+	GetOAuthRefreshToken(ctx context.Context, tokenHash string) (OauthRefreshToken, error)
+	GetOAuthRefreshTokenForUpdate(ctx context.Context, tokenHash string) (OauthRefreshToken, error)
+	// GetOutgoingUsage counts transfers and withdrawals sent from the account.
+	// Fee legs are included in the transfer they belong to; adjustments are left
+	// out.
+	GetOutgoingUsage(ctx context.Context, arg GetOutgoingUsageParams) (GetOutgoingUsageRow, error)
+	// GetOwnerOutgoingUsage is GetOutgoingUsage summed over all of the owner's
+	// accounts in currency.
+	GetOwnerOutgoingUsage(ctx context.Context, arg GetOwnerOutgoingUsageParams) (GetOwnerOutgoingUsageRow, error)
+	GetPasswordResetForUpdate(ctx context.Context, tokenHash string) (PasswordReset, error)
+	GetPayment(ctx context.Context, id int64) (Payment, error)
+	GetProduct(ctx context.Context, code string) (Product, error)
+	GetReconciliationRun(ctx context.Context, id int64) (ReconciliationRun, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferFee(ctx context.Context, transferID int64) (TransferFee, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	// GetUserForUpdate also finds deleted users.
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetUserMFA(ctx context.Context, username string) (UserMfa, error)
+	GetUserPasswordChangedAt(ctx context.Context, username string) (time.Time, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	InvalidatePasswordResets(ctx context.Context, username string) error
+	ListAPIKeys(ctx context.Context, owner string) ([]ApiKey, error)
 	ListAccount(ctx context.Context, arg ListAccountParams) ([]Account, error)
+	ListAccountEntrySums(ctx context.Context, arg ListAccountEntrySumsParams) ([]ListAccountEntrySumsRow, error)
+	ListAccountsByOwnerForUpdate(ctx context.Context, owner string) ([]Account, error)
+	ListAccrualBalances(ctx context.Context, arg ListAccrualBalancesParams) ([]ListAccrualBalancesRow, error)
+	ListAdjustments(ctx context.Context, arg ListAdjustmentsParams) ([]Adjustment, error)
+	ListAllAccounts(ctx context.Context, arg ListAllAccountsParams) ([]Account, error)
+	ListAuditLogs(ctx context.Context, arg ListAuditLogsParams) ([]AuditLog, error)
+	ListDataExports(ctx context.Context, arg ListDataExportsParams) ([]DataExport, error)
 	ListEntry(ctx context.Context, arg ListEntryParams) ([]Entry, error)
+	ListFraudDecisions(ctx context.Context, heldTransferID sql.NullInt64) ([]FraudDecision, error)
+	ListHeldTransfers(ctx context.Context, arg ListHeldTransfersParams) ([]HeldTransfer, error)
+	ListInterestAccrualSums(ctx context.Context, arg ListInterestAccrualSumsParams) ([]ListInterestAccrualSumsRow, error)
+	ListInterestPostings(ctx context.Context, arg ListInterestPostingsParams) ([]InterestPosting, error)
+	ListLockoutEvents(ctx context.Context, arg ListLockoutEventsParams) ([]LockoutEvent, error)
+	ListOAuthClients(ctx context.Context) ([]OauthClient, error)
+	ListOwnerEntries(ctx context.Context, arg ListOwnerEntriesParams) ([]Entry, error)
+	ListOwnerTransfers(ctx context.Context, arg ListOwnerTransfersParams) ([]Transfer, error)
+	ListProducts(ctx context.Context) ([]Product, error)
+	ListReconciliationDiscrepancies(ctx context.Context, arg ListReconciliationDiscrepanciesParams) ([]ReconciliationDiscrepancy, error)
+	ListReconciliationRuns(ctx context.Context, arg ListReconciliationRunsParams) ([]ReconciliationRun, error)
 	ListTransfer(ctx context.Context, arg ListTransferParams) ([]Transfer, error)
-	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	ListTransferEntryChecks(ctx context.Context, arg ListTransferEntryChecksParams) ([]ListTransferEntryChecksRow, error)
+	ListTransferLimits(ctx context.Context) ([]TransferLimit, error)
+	ListTransferLimitsForAccount(ctx context.Context, arg ListTransferLimitsForAccountParams) ([]TransferLimit, error)
+	ListUserOAuthGrants(ctx context.Context, username string) ([]OauthGrant, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookSubscriptions(ctx context.Context, owner string) ([]WebhookSubscription, error)
+	// LockOwnerLimits makes limit checks on the owner's transfers wait for each
+	// other until the transaction ends.
+	LockOwnerLimits(ctx context.Context, owner string) error
+	MarkEmailVerificationUsed(ctx context.Context, id int64) (EmailVerification, error)
+	MarkOAuthAuthorizationCodeUsed(ctx context.Context, arg MarkOAuthAuthorizationCodeUsedParams) error
+	MarkOAuthRefreshTokenUsed(ctx context.Context, tokenHash string) error
+	MarkOutboxEventsPublished(ctx context.Context, ids []int64) error
+	MarkPasswordResetUsed(ctx context.Context, id int64) (PasswordReset, error)
+	MarkWebhookDelivered(ctx context.Context, arg MarkWebhookDeliveredParams) (WebhookDelivery, error)
+	PaymentReferenceExists(ctx context.Context, arg PaymentReferenceExistsParams) (bool, error)
+	PurgeExpiredDataExports(ctx context.Context) (int64, error)
+	PurgeUserDataExports(ctx context.Context, username string) error
+	RecordWebhookFailure(ctx context.Context, arg RecordWebhookFailureParams) (WebhookDelivery, error)
+	// RehashUserPassword swaps in a stronger hash of the same password. It leaves
+	// password_changed_at alone, so sessions stay valid, and does nothing if the
+	// password changed since old_hashed_password was read.
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error)
+	ReleaseOutboxEvents(ctx context.Context, ids []int64) error
+	ResetLoginThrottle(ctx context.Context, arg ResetLoginThrottleParams) (int64, error)
+	RevokeAPIKey(ctx context.Context, id int64) (ApiKey, error)
+	RevokeOAuthGrant(ctx context.Context, id uuid.UUID) error
+	RevokeUserAPIKeys(ctx context.Context, owner string) error
+	RevokeUserOAuthGrants(ctx context.Context, username string) error
+	// ScrubUserRegisteredEvents overwrites the personal data copied into the
+	// user.registered events of an erased user.
+	ScrubUserRegisteredEvents(ctx context.Context, arg ScrubUserRegisteredEventsParams) error
+	SetAccountOverdraftLimit(ctx context.Context, arg SetAccountOverdraftLimitParams) (Account, error)
+	// SetAccountStatus leaves closed accounts alone: closing is final.
+	SetAccountStatus(ctx context.Context, arg SetAccountStatusParams) (Account, error)
+	SetInterestPostingTransfer(ctx context.Context, arg SetInterestPostingTransferParams) (InterestPosting, error)
+	SetProductRate(ctx context.Context, arg SetProductRateParams) (Product, error)
+	SetUserEmailVerified(ctx context.Context, username string) (User, error)
+	SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error)
+	SoftDeleteUser(ctx context.Context, username string) (User, error)
+	// Replaces an unconfirmed enrollment; returns no rows if MFA is already on.
+	StartMFAEnrollment(ctx context.Context, arg StartMFAEnrollmentParams) (UserMfa, error)
+	// Refills the bucket for the time since it was last used, then takes one
+	// token if there is one. allowed says whether this request got it.
+	TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (RateLimitBucket, error)
+	// TouchAPIKey records that the key was used, at most once a minute so busy
+	// integrations don't write on every request.
+	TouchAPIKey(ctx context.Context, id int64) error
+	TryOutboxLock(ctx context.Context, key int64) (bool, error)
 	UpdateAccountBalance(ctx context.Context, arg UpdateAccountBalanceParams) (Account, error)
+	UpdateLoginThrottle(ctx context.Context, arg UpdateLoginThrottleParams) (LoginThrottle, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	// UpdateUserProfile leaves NULL fields as they are. A new email address has
+	// to be verified again.
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error)
+	UpsertTransferLimit(ctx context.Context, arg UpsertTransferLimitParams) (TransferLimit, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,64 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: rate_limit.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const deleteIdleRateLimitBuckets = `-- name: DeleteIdleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE updated_at < $1
+`
+
+func (q *Queries) DeleteIdleRateLimitBuckets(ctx context.Context, updatedAt time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteIdleRateLimitBuckets, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const takeRateLimitToken = `-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets AS b (
+   key,
+   tokens,
+   allowed,
+   updated_at
+) VALUES (
+   $1, $2::float8 - 1, true, now()
+)
+ON CONFLICT (key) DO UPDATE
+SET tokens = CASE
+      WHEN LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8) >= 1
+      THEN LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8) - 1
+      ELSE LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8)
+   END,
+   allowed = LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8) >= 1,
+   updated_at = now()
+RETURNING key, tokens, allowed, updated_at
+`
+
+type TakeRateLimitTokenParams struct {
+	Key   string  `json:"key"`
+	Burst float64 `json:"burst"`
+	Rate  float64 `json:"rate"`
+}
+
+// Refills the bucket for the time since it was last used, then takes one
+// token if there is one. allowed says whether this request got it.
+func (q *Queries) TakeRateLimitToken(ctx context.Context, arg TakeRateLimitTokenParams) (RateLimitBucket, error) {
+	row := q.db.QueryRowContext(ctx, takeRateLimitToken, arg.Key, arg.Burst, arg.Rate)
+	var i RateLimitBucket
+	err := row.Scan(
+		&i.Key,
+		&i.Tokens,
+		&i.Allowed,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
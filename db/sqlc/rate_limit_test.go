@@ -0,0 +1,44 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTakeRateLimitToken(t *testing.T) {
+	ctx := context.Background()
+	arg := TakeRateLimitTokenParams{
+		Key:   "test:" + util.RandomString(12),
+		Burst: 2,
+		// slow enough that the bucket doesn't refill during the test
+		Rate: 1.0 / 3600,
+	}
+
+	bucket, err := testQueries.TakeRateLimitToken(ctx, arg)
+	require.NoError(t, err)
+	require.True(t, bucket.Allowed)
+	require.InDelta(t, 1, bucket.Tokens, 0.01)
+
+	bucket, err = testQueries.TakeRateLimitToken(ctx, arg)
+	require.NoError(t, err)
+	require.True(t, bucket.Allowed)
+	require.InDelta(t, 0, bucket.Tokens, 0.01)
+
+	bucket, err = testQueries.TakeRateLimitToken(ctx, arg)
+	require.NoError(t, err)
+	require.False(t, bucket.Allowed)
+	require.GreaterOrEqual(t, bucket.Tokens, 0.0)
+
+	n, err := testQueries.DeleteIdleRateLimitBuckets(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, n, int64(1))
+
+	// a deleted bucket starts out full
+	bucket, err = testQueries.TakeRateLimitToken(ctx, arg)
+	require.NoError(t, err)
+	require.True(t, bucket.Allowed)
+}
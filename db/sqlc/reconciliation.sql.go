@@ -0,0 +1,237 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.22.0
+// source: reconciliation.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createReconciliationDiscrepancy = `-- name: CreateReconciliationDiscrepancy :one
+INSERT INTO reconciliation_discrepancies (
+   run_id,
+   kind,
+   account_id,
+   transfer_id,
+   expected,
+   actual,
+   detail
+) VALUES (
+   $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, run_id, kind, account_id, transfer_id, expected, actual, detail, created_at
+`
+
+type CreateReconciliationDiscrepancyParams struct {
+	RunID      int64         `json:"run_id"`
+	Kind       string        `json:"kind"`
+	AccountID  sql.NullInt64 `json:"account_id"`
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	Expected   int64         `json:"expected"`
+	Actual     int64         `json:"actual"`
+	Detail     string        `json:"detail"`
+}
+
+func (q *Queries) CreateReconciliationDiscrepancy(ctx context.Context, arg CreateReconciliationDiscrepancyParams) (ReconciliationDiscrepancy, error) {
+	row := q.db.QueryRowContext(ctx, createReconciliationDiscrepancy,
+		arg.RunID,
+		arg.Kind,
+		arg.AccountID,
+		arg.TransferID,
+		arg.Expected,
+		arg.Actual,
+		arg.Detail,
+	)
+	var i ReconciliationDiscrepancy
+	err := row.Scan(
+		&i.ID,
+		&i.RunID,
+		&i.Kind,
+		&i.AccountID,
+		&i.TransferID,
+		&i.Expected,
+		&i.Actual,
+		&i.Detail,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createReconciliationRun = `-- name: CreateReconciliationRun :one
+INSERT INTO reconciliation_runs DEFAULT VALUES
+RETURNING id, status, accounts_checked, transfers_checked, discrepancies, error, started_at, finished_at
+`
+
+func (q *Queries) CreateReconciliationRun(ctx context.Context) (ReconciliationRun, error) {
+	row := q.db.QueryRowContext(ctx, createReconciliationRun)
+	var i ReconciliationRun
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.AccountsChecked,
+		&i.TransfersChecked,
+		&i.Discrepancies,
+		&i.Error,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const finishReconciliationRun = `-- name: FinishReconciliationRun :one
+UPDATE reconciliation_runs
+SET status = $2,
+   accounts_checked = $3,
+   transfers_checked = $4,
+   discrepancies = $5,
+   error = $6,
+   finished_at = now()
+WHERE id = $1
+RETURNING id, status, accounts_checked, transfers_checked, discrepancies, error, started_at, finished_at
+`
+
+type FinishReconciliationRunParams struct {
+	ID               int64                `json:"id"`
+	Status           ReconciliationStatus `json:"status"`
+	AccountsChecked  int64                `json:"accounts_checked"`
+	TransfersChecked int64                `json:"transfers_checked"`
+	Discrepancies    int64                `json:"discrepancies"`
+	Error            string               `json:"error"`
+}
+
+func (q *Queries) FinishReconciliationRun(ctx context.Context, arg FinishReconciliationRunParams) (ReconciliationRun, error) {
+	row := q.db.QueryRowContext(ctx, finishReconciliationRun,
+		arg.ID,
+		arg.Status,
+		arg.AccountsChecked,
+		arg.TransfersChecked,
+		arg.Discrepancies,
+		arg.Error,
+	)
+	var i ReconciliationRun
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.AccountsChecked,
+		&i.TransfersChecked,
+		&i.Discrepancies,
+		&i.Error,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const getReconciliationRun = `-- name: GetReconciliationRun :one
+SELECT id, status, accounts_checked, transfers_checked, discrepancies, error, started_at, finished_at FROM reconciliation_runs
+WHERE id = $1 LIMIT 1
+`
+
+func (q *Queries) GetReconciliationRun(ctx context.Context, id int64) (ReconciliationRun, error) {
+	row := q.db.QueryRowContext(ctx, getReconciliationRun, id)
+	var i ReconciliationRun
+	err := row.Scan(
+		&i.ID,
+		&i.Status,
+		&i.AccountsChecked,
+		&i.TransfersChecked,
+		&i.Discrepancies,
+		&i.Error,
+		&i.StartedAt,
+		&i.FinishedAt,
+	)
+	return i, err
+}
+
+const listReconciliationDiscrepancies = `-- name: ListReconciliationDiscrepancies :many
+SELECT id, run_id, kind, account_id, transfer_id, expected, actual, detail, created_at FROM reconciliation_discrepancies
+WHERE run_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3
+`
+
+type ListReconciliationDiscrepanciesParams struct {
+	RunID  int64 `json:"run_id"`
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListReconciliationDiscrepancies(ctx context.Context, arg ListReconciliationDiscrepanciesParams) ([]ReconciliationDiscrepancy, error) {
+	rows, err := q.db.QueryContext(ctx, listReconciliationDiscrepancies, arg.RunID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconciliationDiscrepancy{}
+	for rows.Next() {
+		var i ReconciliationDiscrepancy
+		if err := rows.Scan(
+			&i.ID,
+			&i.RunID,
+			&i.Kind,
+			&i.AccountID,
+			&i.TransferID,
+			&i.Expected,
+			&i.Actual,
+			&i.Detail,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReconciliationRuns = `-- name: ListReconciliationRuns :many
+SELECT id, status, accounts_checked, transfers_checked, discrepancies, error, started_at, finished_at FROM reconciliation_runs
+ORDER BY id DESC
+LIMIT $1
+OFFSET $2
+`
+
+type ListReconciliationRunsParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListReconciliationRuns(ctx context.Context, arg ListReconciliationRunsParams) ([]ReconciliationRun, error) {
+	rows, err := q.db.QueryContext(ctx, listReconciliationRuns, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ReconciliationRun{}
+	for rows.Next() {
+		var i ReconciliationRun
+		if err := rows.Scan(
+			&i.ID,
+			&i.Status,
+			&i.AccountsChecked,
+			&i.TransfersChecked,
+			&i.Discrepancies,
+			&i.Error,
+			&i.StartedAt,
+			&i.FinishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
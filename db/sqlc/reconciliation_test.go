@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconciliationRun(t *testing.T) {
+	run, err := testQueries.CreateReconciliationRun(context.Background())
+	require.NoError(t, err)
+	require.NotZero(t, run.ID)
+	require.Equal(t, ReconciliationStatusRunning, run.Status)
+	require.False(t, run.FinishedAt.Valid)
+
+	acc := createRandomAccount(t)
+	discrepancy, err := testQueries.CreateReconciliationDiscrepancy(context.Background(), CreateReconciliationDiscrepancyParams{
+		RunID:     run.ID,
+		Kind:      "account_balance",
+		AccountID: sql.NullInt64{Int64: acc.ID, Valid: true},
+		Expected:  0,
+		Actual:    acc.Balance,
+		Detail:    "balance differs from the sum of entries",
+	})
+	require.NoError(t, err)
+	require.Equal(t, run.ID, discrepancy.RunID)
+
+	finished, err := testQueries.FinishReconciliationRun(context.Background(), FinishReconciliationRunParams{
+		ID:              run.ID,
+		Status:          ReconciliationStatusCompleted,
+		AccountsChecked: 1,
+		Discrepancies:   1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ReconciliationStatusCompleted, finished.Status)
+	require.True(t, finished.FinishedAt.Valid)
+
+	discrepancies, err := testQueries.ListReconciliationDiscrepancies(context.Background(), ListReconciliationDiscrepanciesParams{
+		RunID: run.ID,
+		Limit: 5,
+	})
+	require.NoError(t, err)
+	require.Len(t, discrepancies, 1)
+	require.Equal(t, discrepancy.ID, discrepancies[0].ID)
+}
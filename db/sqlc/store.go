@@ -3,12 +3,44 @@ package db
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/aulas/demo-bank/events"
 )
 
+// RevenueOwner owns the per-currency accounts collecting transfer fees.
+const RevenueOwner = "revenue"
+
+var ErrFeeExceedsAmount = errors.New("fee must be less than the transfer amount")
+
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	AuditTx(ctx context.Context, arg CreateAuditLogParams, fn func(*Queries) error) (AuditLog, error)
+	AdjustmentTx(ctx context.Context, arg AdjustmentTxParams) (AdjustmentTxResult, error)
+	PaymentTx(ctx context.Context, arg PaymentTxParams) (PaymentTxResult, error)
+	InterestPostingTx(ctx context.Context, arg InterestPostingTxParams) (InterestPostingTxResult, error)
+	GetLimitStatus(ctx context.Context, account Account, now time.Time) (LimitStatus, error)
+	HoldTransferTx(ctx context.Context, arg HoldTransferTxParams) (HeldTransfer, error)
+	DecideHeldTransferTx(ctx context.Context, arg DecideHeldTransferTxParams) (DecideHeldTransferTxResult, error)
+	CreateAccountTx(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (User, error)
+	VerifyEmailTx(ctx context.Context, tokenHash string, now time.Time) (User, error)
+	ChangePasswordTx(ctx context.Context, username string, hashedPassword string) (User, error)
+	ResetPasswordTx(ctx context.Context, tokenHash string, hashedPassword string, now time.Time) (User, error)
+	UpdateUserProfileTx(ctx context.Context, arg UpdateUserProfileTxParams) (User, error)
+	DeleteUserTx(ctx context.Context, username string) (User, error)
+	EraseUserTx(ctx context.Context, arg EraseUserTxParams) (User, error)
+	RequestDataExportTx(ctx context.Context, username string) (DataExport, error)
+	CompleteDataExportTx(ctx context.Context, arg CompleteDataExportParams) (DataExport, error)
+	ConfirmMFATx(ctx context.Context, username string, recoveryCodeHashes []string) (UserMfa, error)
+	DisableMFATx(ctx context.Context, username string) error
+	RecordLoginFailureTx(ctx context.Context, arg RecordLoginFailureTxParams) (LoginThrottle, error)
+	RelayOutboxTx(ctx context.Context, size int32, publish func(OutboxEvent) error) (int, error)
+	ExchangeOAuthCodeTx(ctx context.Context, arg ExchangeOAuthCodeTxParams) (OAuthTokenTxResult, error)
+	RefreshOAuthTokenTx(ctx context.Context, arg RefreshOAuthTokenTxParams) (OAuthTokenTxResult, error)
 }
 
 type SQLStore struct {
@@ -42,67 +74,186 @@ func (s *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
 	return tx.Commit()
 }
 
+// TransferTxParams.Amount is the gross amount debited from the sender. Fee is
+// taken out of it, so the recipient is credited Amount - Fee.
 type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	Fee           int64 `json:"fee"`
 }
 
 type TransferTxResult struct {
-	Transfer    Transfer `json:"transfer"`
-	FromAccount Account  `json:"from_account"`
-	FromEntry   Entry    `json:"from_entry"`
-	ToAccount   Account  `json:"to_account"`
-	ToEntry     Entry    `json:"to_entry"`
+	Transfer    Transfer  `json:"transfer"`
+	FromAccount Account   `json:"from_account"`
+	FromEntry   Entry     `json:"from_entry"`
+	ToAccount   Account   `json:"to_account"`
+	ToEntry     Entry     `json:"to_entry"`
+	FeeTransfer *Transfer `json:"fee_transfer,omitempty"`
 }
 
+// TransferTx posts a customer transfer and enforces the transfer limits of the
+// sending account, failing with a *LimitExceededError. Limits are checked
+// after the balance updates: by then the sender's row is locked, so concurrent
+// transfers from the same account are serialized and each one sees the usage
+// of the others.
 func (s *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
 	err := s.execTx(ctx, func(q *Queries) error {
 		var err error
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+		result, err = q.postCustomerTransfer(ctx, arg)
+		return err
+	})
 
-		if err != nil {
-			return err
-		}
+	return result, err
+}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
-		}
+func (q *Queries) postCustomerTransfer(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	result, err := q.PostTransfer(ctx, arg)
+	if err != nil {
+		return result, err
+	}
+
+	// User limits span accounts, so the row locks PostTransfer took are not
+	// enough to keep two of the owner's transfers from both fitting. The lock
+	// is taken after the account rows, which keeps the lock order fixed.
+	err = q.LockOwnerLimits(ctx, result.FromAccount.Owner)
+	if err != nil {
+		return result, err
+	}
+
+	status, err := q.GetLimitStatus(ctx, result.FromAccount, time.Now())
+	if err != nil {
+		return result, err
+	}
+
+	return result, status.check(arg.Amount)
+}
+
+// PostTransfer records the transfer, both of its entries and the balance
+// updates. A non-zero fee is posted as a second transfer from the sender to
+// the revenue account of its currency. It does not open a transaction: q is
+// expected to be bound to one.
+func (q *Queries) PostTransfer(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
+	if arg.Fee < 0 || arg.Fee >= arg.Amount {
+		return TransferTxResult{}, ErrFeeExceedsAmount
+	}
+
+	result, err := q.postLegs(ctx, arg.FromAccountID, arg.ToAccountID, arg.Amount-arg.Fee)
+	if err != nil || arg.Fee == 0 {
+		return result, err
+	}
+
+	revenue, err := q.GetAccountByOwner(ctx, GetAccountByOwnerParams{
+		Owner:    RevenueOwner,
+		Currency: result.FromAccount.Currency,
+	})
+	if err != nil {
+		return result, fmt.Errorf("cannot get %s revenue account: %w", result.FromAccount.Currency, err)
+	}
+
+	// The sender is already locked by the first legs, so crediting the revenue
+	// account last cannot deadlock with another transfer.
+	fee, err := q.postLegs(ctx, arg.FromAccountID, revenue.ID, arg.Fee)
+	if err != nil {
+		return result, err
+	}
+
+	result.FeeTransfer = &fee.Transfer
+	result.FromAccount = fee.FromAccount
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+	_, err = q.CreateTransferFee(ctx, CreateTransferFeeParams{
+		TransferID:    result.Transfer.ID,
+		FeeTransferID: fee.Transfer.ID,
+		Amount:        arg.Fee,
+	})
+
+	return result, err
+}
+
+func (q *Queries) postLegs(ctx context.Context, fromAccountID, toAccountID, amount int64) (TransferTxResult, error) {
+	var result TransferTxResult
+	var err error
+
+	arg := TransferTxParams{
+		FromAccountID: fromAccountID,
+		ToAccountID:   toAccountID,
+		Amount:        amount,
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	transferID := sql.NullInt64{Int64: result.Transfer.ID, Valid: true}
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.FromAccountID,
+		Amount:     -arg.Amount,
+		TransferID: transferID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID:  arg.ToAccountID,
+		Amount:     arg.Amount,
+		TransferID: transferID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+	}
+	if err != nil {
+		return result, err
+	}
+
+	err = q.RecordEvent(ctx, events.TransferPosted{
+		TransferID:    result.Transfer.ID,
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		Currency:      result.FromAccount.Currency,
+		FromBalance:   result.FromAccount.Balance,
+		ToBalance:     result.ToAccount.Balance,
+		PostedAt:      result.Transfer.CreatedAt,
+	})
+
+	return result, err
+}
+
+// AuditTx runs fn and writes the audit log entry in the same transaction, so a
+// mutation is never committed without its audit record.
+func (s *SQLStore) AuditTx(ctx context.Context, arg CreateAuditLogParams, fn func(*Queries) error) (AuditLog, error) {
+	var auditLog AuditLog
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		err := fn(q)
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-			if err != nil {
-				return err
-			}
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
-			if err != nil {
-				return err
-			}
+		if arg.Details == nil {
+			arg.Details = []byte("{}")
 		}
 
-		return nil
+		auditLog, err = q.CreateAuditLog(ctx, arg)
+		return err
 	})
 
-	return result, err
+	return auditLog, err
 }
 
 func addMoney(
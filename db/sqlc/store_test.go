@@ -157,3 +157,44 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, acc1.Balance, updateAccount1.Balance)
 	require.Equal(t, acc2.Balance, updateAccount2.Balance)
 }
+
+func TestTransferTxWithFee(t *testing.T) {
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	revenue, err := testQueries.GetAccountByOwner(context.Background(), GetAccountByOwnerParams{
+		Owner:    RevenueOwner,
+		Currency: acc1.Currency,
+	})
+	require.NoError(t, err)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        10,
+		Fee:           3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(7), result.Transfer.Amount)
+	require.Equal(t, acc1.Balance-10, result.FromAccount.Balance)
+	require.Equal(t, acc2.Balance+7, result.ToAccount.Balance)
+
+	require.NotNil(t, result.FeeTransfer)
+	require.Equal(t, acc1.ID, result.FeeTransfer.FromAccountID)
+	require.Equal(t, revenue.ID, result.FeeTransfer.ToAccountID)
+	require.Equal(t, int64(3), result.FeeTransfer.Amount)
+
+	transferFee, err := testQueries.GetTransferFee(context.Background(), result.Transfer.ID)
+	require.NoError(t, err)
+	require.Equal(t, result.FeeTransfer.ID, transferFee.FeeTransferID)
+	require.Equal(t, int64(3), transferFee.Amount)
+
+	_, err = store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        3,
+		Fee:           3,
+	})
+	require.ErrorIs(t, err, ErrFeeExceedsAmount)
+}
@@ -1,6 +1,6 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.21.0
+//   sqlc v1.22.0
 // source: transfers.sql
 
 package db
@@ -96,3 +96,69 @@ func (q *Queries) ListTransfer(ctx context.Context, arg ListTransferParams) ([]T
 	}
 	return items, nil
 }
+
+const listTransferEntryChecks = `-- name: ListTransferEntryChecks :many
+SELECT t.id, t.from_account_id, t.to_account_id, t.amount,
+   COUNT(e.id)::bigint AS entry_count,
+   COALESCE(SUM(e.amount), 0)::bigint AS entries_sum,
+   COUNT(e.id) FILTER (
+      WHERE e.account_id = t.from_account_id AND e.amount = -t.amount
+   )::bigint AS from_entries,
+   COUNT(e.id) FILTER (
+      WHERE e.account_id = t.to_account_id AND e.amount = t.amount
+   )::bigint AS to_entries
+FROM transfers t
+LEFT JOIN entries e ON e.transfer_id = t.id
+WHERE t.id > $1
+GROUP BY t.id
+ORDER BY t.id
+LIMIT $2
+`
+
+type ListTransferEntryChecksParams struct {
+	AfterID int64 `json:"after_id"`
+	Size    int32 `json:"size"`
+}
+
+type ListTransferEntryChecksRow struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	EntryCount    int64 `json:"entry_count"`
+	EntriesSum    int64 `json:"entries_sum"`
+	FromEntries   int64 `json:"from_entries"`
+	ToEntries     int64 `json:"to_entries"`
+}
+
+func (q *Queries) ListTransferEntryChecks(ctx context.Context, arg ListTransferEntryChecksParams) ([]ListTransferEntryChecksRow, error) {
+	rows, err := q.db.QueryContext(ctx, listTransferEntryChecks, arg.AfterID, arg.Size)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransferEntryChecksRow{}
+	for rows.Next() {
+		var i ListTransferEntryChecksRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.EntryCount,
+			&i.EntriesSum,
+			&i.FromEntries,
+			&i.ToEntries,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
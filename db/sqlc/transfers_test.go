@@ -63,3 +63,30 @@ func TestListTransfer(t *testing.T) {
 	}
 
 }
+
+func TestListTransferEntryChecks(t *testing.T) {
+	store := NewStore(testDB)
+	acc1 := createRandomAccount(t)
+	acc2 := createRandomAccount(t)
+
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: acc1.ID,
+		ToAccountID:   acc2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	rows, err := testQueries.ListTransferEntryChecks(context.Background(), ListTransferEntryChecksParams{
+		AfterID: result.Transfer.ID - 1,
+		Size:    1,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+
+	row := rows[0]
+	require.Equal(t, result.Transfer.ID, row.ID)
+	require.Equal(t, int64(2), row.EntryCount)
+	require.Zero(t, row.EntriesSum)
+	require.Equal(t, int64(1), row.FromEntries)
+	require.Equal(t, int64(1), row.ToEntries)
+}
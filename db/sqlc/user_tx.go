@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	ErrVerificationTokenUsed    = errors.New("verification token was already used")
+	ErrVerificationTokenExpired = errors.New("verification token has expired")
+	ErrVerificationEmailChanged = errors.New("email changed since the verification token was sent")
+)
+
+type CreateUserTxParams struct {
+	CreateUserParams
+	// VerificationTokenHash is the hash of the email verification token sent
+	// to the user, valid until VerificationExpiresAt.
+	VerificationTokenHash string
+	VerificationExpiresAt time.Time
+}
+
+// CreateUserTx creates the user with its user.registered event and email
+// verification token.
+func (s *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.CreateUserWithEvent(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CreateEmailVerification(ctx, CreateEmailVerificationParams{
+			Username:  user.Username,
+			Email:     user.Email,
+			TokenHash: arg.VerificationTokenHash,
+			ExpiresAt: arg.VerificationExpiresAt,
+		})
+		return err
+	})
+
+	return user, err
+}
+
+// VerifyEmailTx consumes a verification token and marks its user's email as
+// verified. Tokens are single use and only valid for the address they were
+// sent to.
+func (s *SQLStore) VerifyEmailTx(ctx context.Context, tokenHash string, now time.Time) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		verification, err := q.GetEmailVerificationForUpdate(ctx, tokenHash)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case verification.UsedAt.Valid:
+			return ErrVerificationTokenUsed
+		case now.After(verification.ExpiresAt):
+			return ErrVerificationTokenExpired
+		}
+
+		user, err = q.GetUser(ctx, verification.Username)
+		if err != nil {
+			return err
+		}
+
+		if user.Email != verification.Email {
+			return ErrVerificationEmailChanged
+		}
+
+		_, err = q.MarkEmailVerificationUsed(ctx, verification.ID)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.SetUserEmailVerified(ctx, user.Username)
+		return err
+	})
+
+	return user, err
+}
+
+type UpdateUserProfileTxParams struct {
+	UpdateUserProfileParams
+	// VerificationTokenHash is the hash of the token sent to a new email
+	// address, valid until VerificationExpiresAt. It is only stored if the
+	// address changes.
+	VerificationTokenHash string
+	VerificationExpiresAt time.Time
+}
+
+// UpdateUserProfileTx updates the user's full name and email. A new email
+// address is unverified until the token sent to it is used.
+func (s *SQLStore) UpdateUserProfileTx(ctx context.Context, arg UpdateUserProfileTxParams) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		old, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.UpdateUserProfile(ctx, arg.UpdateUserProfileParams)
+		if err != nil {
+			return err
+		}
+
+		if user.Email == old.Email {
+			return nil
+		}
+
+		_, err = q.CreateEmailVerification(ctx, CreateEmailVerificationParams{
+			Username:  user.Username,
+			Email:     user.Email,
+			TokenHash: arg.VerificationTokenHash,
+			ExpiresAt: arg.VerificationExpiresAt,
+		})
+		return err
+	})
+
+	return user, err
+}
+
+var ErrUserHasOpenAccounts = errors.New("user has accounts that are not closed")
+
+// DeleteUserTx soft deletes the user once all of their accounts are closed,
+// and revokes their API keys and OAuth grants. Deleted users are hidden from
+// GetUser, so they can no longer log in and their access tokens stop being
+// accepted.
+func (s *SQLStore) DeleteUserTx(ctx context.Context, username string) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		accounts, err := q.ListAccountsByOwnerForUpdate(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			if account.Status != AccountStatusClosed || account.Balance != 0 {
+				return ErrUserHasOpenAccounts
+			}
+		}
+
+		user, err = q.SoftDeleteUser(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		err = q.RevokeUserAPIKeys(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		return q.RevokeUserOAuthGrants(ctx, username)
+	})
+
+	return user, err
+}
+
+var ErrUserErased = errors.New("user was already erased")
+
+type EraseUserTxParams struct {
+	Username string `json:"username"`
+	Operator string `json:"operator"`
+	Reason   string `json:"reason"`
+}
+
+// erasedPseudonym returns the full name and email address an erased user is
+// left with. They only depend on the username, which is kept anyway.
+func erasedPseudonym(username string) (fullName string, email string) {
+	sum := sha256.Sum256([]byte(username))
+	id := hex.EncodeToString(sum[:6])
+	return "Erased user " + id, id + "@erased.invalid"
+}
+
+// EraseUserTx pseudonymizes the user's full name and email and deletes the
+// rest of their personal data: pending email and password tokens, MFA
+// secrets, webhook subscriptions and export archives. API keys and OAuth
+// grants are revoked. Accounts, entries and transfers are kept for financial
+// record keeping, so every account must be closed first. The erasure is
+// audit logged in the same transaction.
+func (s *SQLStore) EraseUserTx(ctx context.Context, arg EraseUserTxParams) (User, error) {
+	var user User
+
+	fullName, email := erasedPseudonym(arg.Username)
+	details, err := json.Marshal(map[string]string{"full_name": fullName, "email": email})
+	if err != nil {
+		return user, err
+	}
+
+	audit := CreateAuditLogParams{
+		Actor:   arg.Operator,
+		Action:  "user.erase",
+		Target:  "user:" + arg.Username,
+		Reason:  arg.Reason,
+		Details: details,
+	}
+
+	_, err = s.AuditTx(ctx, audit, func(q *Queries) error {
+		current, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		if current.ErasedAt.Valid {
+			return ErrUserErased
+		}
+
+		accounts, err := q.ListAccountsByOwnerForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			if account.Status != AccountStatusClosed || account.Balance != 0 {
+				return ErrUserHasOpenAccounts
+			}
+		}
+
+		user, err = q.EraseUser(ctx, EraseUserParams{
+			Username: arg.Username,
+			FullName: fullName,
+			Email:    email,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.ScrubUserRegisteredEvents(ctx, ScrubUserRegisteredEventsParams{
+			Username: arg.Username,
+			FullName: fullName,
+			Email:    email,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, erase := range []func(context.Context, string) error{
+			q.DeleteUserEmailVerifications,
+			q.DeleteUserPasswordResets,
+			q.DeleteRecoveryCodes,
+			q.DeleteUserMFA,
+			q.DeleteUserWebhookSubscriptions,
+			q.PurgeUserDataExports,
+			q.RevokeUserAPIKeys,
+			q.RevokeUserOAuthGrants,
+		} {
+			if err := erase(ctx, arg.Username); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return user, err
+}
+
+var (
+	ErrResetTokenUsed    = errors.New("password reset token was already used")
+	ErrResetTokenExpired = errors.New("password reset token has expired")
+)
+
+// ChangePasswordTx sets a new password hash and revokes any outstanding reset
+// tokens. Access tokens issued before the change stop being accepted.
+func (s *SQLStore) ChangePasswordTx(ctx context.Context, username string, hashedPassword string) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.changePassword(ctx, username, hashedPassword)
+		return err
+	})
+
+	return user, err
+}
+
+// ResetPasswordTx consumes a password reset token and sets the new password
+// hash of its user.
+func (s *SQLStore) ResetPasswordTx(ctx context.Context, tokenHash string, hashedPassword string, now time.Time) (User, error) {
+	var user User
+
+	err := s.execTx(ctx, func(q *Queries) error {
+		reset, err := q.GetPasswordResetForUpdate(ctx, tokenHash)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case reset.UsedAt.Valid:
+			return ErrResetTokenUsed
+		case now.After(reset.ExpiresAt):
+			return ErrResetTokenExpired
+		}
+
+		user, err = q.changePassword(ctx, reset.Username, hashedPassword)
+		return err
+	})
+
+	return user, err
+}
+
+func (q *Queries) changePassword(ctx context.Context, username string, hashedPassword string) (User, error) {
+	user, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		Username:       username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		return user, err
+	}
+
+	return user, q.InvalidatePasswordResets(ctx, username)
+}
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createUserWithVerification(t *testing.T, tokenHash string, expiresAt time.Time) User {
+	user, err := NewStore(testDB).CreateUserTx(context.Background(), CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomUsername(),
+			HashedPassword: "secret",
+			FullName:       util.RandomFullName(),
+			Email:          util.RandomEmail(),
+		},
+		VerificationTokenHash: tokenHash,
+		VerificationExpiresAt: expiresAt,
+	})
+	require.NoError(t, err)
+	require.False(t, user.IsEmailVerified)
+
+	return user
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	tokenHash := util.RandomString(64)
+	user := createUserWithVerification(t, tokenHash, time.Now().Add(time.Hour))
+
+	verified, err := store.VerifyEmailTx(context.Background(), tokenHash, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, user.Username, verified.Username)
+	require.True(t, verified.IsEmailVerified)
+
+	// tokens are single use
+	_, err = store.VerifyEmailTx(context.Background(), tokenHash, time.Now())
+	require.ErrorIs(t, err, ErrVerificationTokenUsed)
+
+	_, err = store.VerifyEmailTx(context.Background(), util.RandomString(64), time.Now())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	tokenHash := util.RandomString(64)
+	user := createUserWithVerification(t, tokenHash, time.Now().Add(time.Hour))
+
+	_, err := store.VerifyEmailTx(context.Background(), tokenHash, time.Now().Add(2*time.Hour))
+	require.ErrorIs(t, err, ErrVerificationTokenExpired)
+
+	got, err := store.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.False(t, got.IsEmailVerified)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	tokenHash := util.RandomString(64)
+	_, err := store.CreatePasswordReset(context.Background(), CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	updated, err := store.ResetPasswordTx(context.Background(), tokenHash, "new-hash", time.Now())
+	require.NoError(t, err)
+	require.Equal(t, "new-hash", updated.HashedPassword)
+	require.True(t, updated.PasswordChangedAt.After(user.PasswordChangedAt))
+
+	_, err = store.ResetPasswordTx(context.Background(), tokenHash, "other-hash", time.Now())
+	require.ErrorIs(t, err, ErrResetTokenUsed)
+}
+
+func TestChangePasswordTxRevokesResetTokens(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	tokenHash := util.RandomString(64)
+	_, err := store.CreatePasswordReset(context.Background(), CreatePasswordResetParams{
+		Username:  user.Username,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = store.ChangePasswordTx(context.Background(), user.Username, "new-hash")
+	require.NoError(t, err)
+
+	changedAt, err := store.GetUserPasswordChangedAt(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now(), changedAt, time.Minute)
+
+	_, err = store.ResetPasswordTx(context.Background(), tokenHash, "other-hash", time.Now())
+	require.ErrorIs(t, err, ErrResetTokenUsed)
+}
+
+func TestUpdateUserProfileTx(t *testing.T) {
+	store := NewStore(testDB)
+	user, err := testQueries.SetUserEmailVerified(context.Background(), createRandomUser(t).Username)
+	require.NoError(t, err)
+
+	// a new full name keeps the email verified
+	fullName := util.RandomFullName()
+	updated, err := store.UpdateUserProfileTx(context.Background(), UpdateUserProfileTxParams{
+		UpdateUserProfileParams: UpdateUserProfileParams{
+			Username: user.Username,
+			FullName: sql.NullString{String: fullName, Valid: true},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, fullName, updated.FullName)
+	require.Equal(t, user.Email, updated.Email)
+	require.True(t, updated.IsEmailVerified)
+	require.True(t, updated.UpdatedAt.After(user.UpdatedAt))
+
+	// a new email has to be verified again
+	tokenHash := util.RandomString(64)
+	email := util.RandomEmail()
+	updated, err = store.UpdateUserProfileTx(context.Background(), UpdateUserProfileTxParams{
+		UpdateUserProfileParams: UpdateUserProfileParams{
+			Username: user.Username,
+			Email:    sql.NullString{String: email, Valid: true},
+		},
+		VerificationTokenHash: tokenHash,
+		VerificationExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, fullName, updated.FullName)
+	require.Equal(t, email, updated.Email)
+	require.False(t, updated.IsEmailVerified)
+
+	verified, err := store.VerifyEmailTx(context.Background(), tokenHash, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, email, verified.Email)
+	require.True(t, verified.IsEmailVerified)
+}
+
+func TestDeleteUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomAccount(t)
+
+	_, err := store.DeleteUserTx(context.Background(), acc.Owner)
+	require.ErrorIs(t, err, ErrUserHasOpenAccounts)
+
+	_, err = testQueries.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{
+		ID:     acc.ID,
+		Amount: -acc.Balance,
+	})
+	require.NoError(t, err)
+
+	// an empty account still has to be closed
+	_, err = store.DeleteUserTx(context.Background(), acc.Owner)
+	require.ErrorIs(t, err, ErrUserHasOpenAccounts)
+
+	_, err = testQueries.CloseAccount(context.Background(), acc.ID)
+	require.NoError(t, err)
+
+	deleted, err := store.DeleteUserTx(context.Background(), acc.Owner)
+	require.NoError(t, err)
+	require.True(t, deleted.DeletedAt.Valid)
+
+	_, err = store.GetUser(context.Background(), acc.Owner)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.GetUserPasswordChangedAt(context.Background(), acc.Owner)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = store.DeleteUserTx(context.Background(), acc.Owner)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestEraseUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	acc := createRandomAccount(t)
+	arg := EraseUserTxParams{
+		Username: acc.Owner,
+		Operator: util.RandomUsername(),
+		Reason:   "erasure request",
+	}
+
+	_, err := store.EraseUserTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrUserHasOpenAccounts)
+
+	_, err = testQueries.UpdateAccountBalance(context.Background(), UpdateAccountBalanceParams{
+		ID:     acc.ID,
+		Amount: -acc.Balance,
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.CloseAccount(context.Background(), acc.ID)
+	require.NoError(t, err)
+
+	erased, err := store.EraseUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, acc.Owner, erased.Username)
+	require.True(t, erased.ErasedAt.Valid)
+	require.True(t, erased.DeletedAt.Valid)
+	require.Contains(t, erased.Email, "@erased.invalid")
+	require.Contains(t, erased.FullName, "Erased user")
+
+	// the ledger is kept
+	got, err := testQueries.GetAccount(context.Background(), acc.ID)
+	require.NoError(t, err)
+	require.Equal(t, acc.Owner, got.Owner)
+
+	logs, err := testQueries.ListAuditLogs(context.Background(), ListAuditLogsParams{
+		Target: "user:" + acc.Owner,
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, "user.erase", logs[0].Action)
+	require.Equal(t, arg.Operator, logs[0].Actor)
+
+	_, err = store.EraseUserTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrUserErased)
+}
@@ -1,14 +1,84 @@
 // Code generated by sqlc. DO NOT EDIT.
 // versions:
-//   sqlc v1.21.0
+//   sqlc v1.22.0
 // source: users.sql
 
 package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
+const createEmailVerification = `-- name: CreateEmailVerification :one
+INSERT INTO email_verifications (
+   username,
+   email,
+   token_hash,
+   expires_at
+) VALUES (
+   $1, $2, $3, $4
+) RETURNING id, username, email, token_hash, expires_at, used_at, created_at
+`
+
+type CreateEmailVerificationParams struct {
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateEmailVerification(ctx context.Context, arg CreateEmailVerificationParams) (EmailVerification, error) {
+	row := q.db.QueryRowContext(ctx, createEmailVerification,
+		arg.Username,
+		arg.Email,
+		arg.TokenHash,
+		arg.ExpiresAt,
+	)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPasswordReset = `-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+   username,
+   token_hash,
+   expires_at
+) VALUES (
+   $1, $2, $3
+) RETURNING id, username, token_hash, expires_at, used_at, created_at
+`
+
+type CreatePasswordResetParams struct {
+	Username  string    `json:"username"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error) {
+	row := q.db.QueryRowContext(ctx, createPasswordReset, arg.Username, arg.TokenHash, arg.ExpiresAt)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
    username,
@@ -17,7 +87,7 @@ INSERT INTO users (
    email
 ) VALUES (
    $1, $2, $3, $4
-) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at
+) RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at
 `
 
 type CreateUserParams struct {
@@ -42,13 +112,119 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const deleteUserEmailVerifications = `-- name: DeleteUserEmailVerifications :exec
+DELETE FROM email_verifications
+WHERE username = $1
+`
+
+func (q *Queries) DeleteUserEmailVerifications(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteUserEmailVerifications, username)
+	return err
+}
+
+const deleteUserPasswordResets = `-- name: DeleteUserPasswordResets :exec
+DELETE FROM password_resets
+WHERE username = $1
+`
+
+func (q *Queries) DeleteUserPasswordResets(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, deleteUserPasswordResets, username)
+	return err
+}
+
+const eraseUser = `-- name: EraseUser :one
+UPDATE users
+SET full_name = $1,
+   email = $2,
+   hashed_password = '',
+   is_email_verified = false,
+   deleted_at = COALESCE(deleted_at, now()),
+   erased_at = now(),
+   updated_at = now()
+WHERE username = $3
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at
+`
+
+type EraseUserParams struct {
+	FullName string `json:"full_name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+// EraseUser replaces the user's personal data with pseudonyms. The username
+// stays, since the ledger refers to it.
+func (q *Queries) EraseUser(ctx context.Context, arg EraseUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, eraseUser, arg.FullName, arg.Email, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const getEmailVerificationForUpdate = `-- name: GetEmailVerificationForUpdate :one
+SELECT id, username, email, token_hash, expires_at, used_at, created_at FROM email_verifications
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetEmailVerificationForUpdate(ctx context.Context, tokenHash string) (EmailVerification, error) {
+	row := q.db.QueryRowContext(ctx, getEmailVerificationForUpdate, tokenHash)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPasswordResetForUpdate = `-- name: GetPasswordResetForUpdate :one
+SELECT id, username, token_hash, expires_at, used_at, created_at FROM password_resets
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetPasswordResetForUpdate(ctx context.Context, tokenHash string) (PasswordReset, error) {
+	row := q.db.QueryRowContext(ctx, getPasswordResetForUpdate, tokenHash)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT username, hashed_password, full_name, email, password_changed_at, created_at FROM users 
-WHERE username = $1 LIMIT 1
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at FROM users 
+WHERE username = $1 AND deleted_at IS NULL LIMIT 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
@@ -61,6 +237,307 @@ func (q *Queries) GetUser(ctx context.Context, username string) (User, error) {
 		&i.Email,
 		&i.PasswordChangedAt,
 		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at FROM users
+WHERE email = $1 AND deleted_at IS NULL LIMIT 1
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const getUserForUpdate = `-- name: GetUserForUpdate :one
+SELECT username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+// GetUserForUpdate also finds deleted users.
+func (q *Queries) GetUserForUpdate(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserForUpdate, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const getUserPasswordChangedAt = `-- name: GetUserPasswordChangedAt :one
+SELECT password_changed_at FROM users
+WHERE username = $1 AND deleted_at IS NULL LIMIT 1
+`
+
+func (q *Queries) GetUserPasswordChangedAt(ctx context.Context, username string) (time.Time, error) {
+	row := q.db.QueryRowContext(ctx, getUserPasswordChangedAt, username)
+	var password_changed_at time.Time
+	err := row.Scan(&password_changed_at)
+	return password_changed_at, err
+}
+
+const invalidatePasswordResets = `-- name: InvalidatePasswordResets :exec
+UPDATE password_resets
+SET used_at = now()
+WHERE username = $1 AND used_at IS NULL
+`
+
+func (q *Queries) InvalidatePasswordResets(ctx context.Context, username string) error {
+	_, err := q.db.ExecContext(ctx, invalidatePasswordResets, username)
+	return err
+}
+
+const markEmailVerificationUsed = `-- name: MarkEmailVerificationUsed :one
+UPDATE email_verifications
+SET used_at = now()
+WHERE id = $1
+RETURNING id, username, email, token_hash, expires_at, used_at, created_at
+`
+
+func (q *Queries) MarkEmailVerificationUsed(ctx context.Context, id int64) (EmailVerification, error) {
+	row := q.db.QueryRowContext(ctx, markEmailVerificationUsed, id)
+	var i EmailVerification
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.Email,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const markPasswordResetUsed = `-- name: MarkPasswordResetUsed :one
+UPDATE password_resets
+SET used_at = now()
+WHERE id = $1
+RETURNING id, username, token_hash, expires_at, used_at, created_at
+`
+
+func (q *Queries) MarkPasswordResetUsed(ctx context.Context, id int64) (PasswordReset, error) {
+	row := q.db.QueryRowContext(ctx, markPasswordResetUsed, id)
+	var i PasswordReset
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const rehashUserPassword = `-- name: RehashUserPassword :execrows
+UPDATE users
+SET hashed_password = $1
+WHERE username = $2
+   AND hashed_password = $3
+`
+
+type RehashUserPasswordParams struct {
+	HashedPassword    string `json:"hashed_password"`
+	Username          string `json:"username"`
+	OldHashedPassword string `json:"old_hashed_password"`
+}
+
+// RehashUserPassword swaps in a stronger hash of the same password. It leaves
+// password_changed_at alone, so sessions stay valid, and does nothing if the
+// password changed since old_hashed_password was read.
+func (q *Queries) RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, rehashUserPassword, arg.HashedPassword, arg.Username, arg.OldHashedPassword)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setUserEmailVerified = `-- name: SetUserEmailVerified :one
+UPDATE users
+SET is_email_verified = true,
+   updated_at = now()
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at
+`
+
+func (q *Queries) SetUserEmailVerified(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserEmailVerified, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2,
+   updated_at = now()
+WHERE username = $1 AND deleted_at IS NULL
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at
+`
+
+type SetUserRoleParams struct {
+	Username string   `json:"username"`
+	Role     UserRole `json:"role"`
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.Username, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :one
+UPDATE users
+SET deleted_at = now(),
+   updated_at = now()
+WHERE username = $1 AND deleted_at IS NULL
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at
+`
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, username string) (User, error) {
+	row := q.db.QueryRowContext(ctx, softDeleteUser, username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users
+SET hashed_password = $2,
+   password_changed_at = now(),
+   updated_at = now()
+WHERE username = $1
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at
+`
+
+type UpdateUserPasswordParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPassword, arg.Username, arg.HashedPassword)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET full_name = COALESCE($1, full_name),
+   email = COALESCE($2, email),
+   is_email_verified = is_email_verified AND COALESCE($2, email) = email,
+   updated_at = now()
+WHERE username = $3 AND deleted_at IS NULL
+RETURNING username, hashed_password, full_name, email, password_changed_at, created_at, role, is_email_verified, updated_at, deleted_at, erased_at
+`
+
+type UpdateUserProfileParams struct {
+	FullName sql.NullString `json:"full_name"`
+	Email    sql.NullString `json:"email"`
+	Username string         `json:"username"`
+}
+
+// UpdateUserProfile leaves NULL fields as they are. A new email address has
+// to be verified again.
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile, arg.FullName, arg.Email, arg.Username)
+	var i User
+	err := row.Scan(
+		&i.Username,
+		&i.HashedPassword,
+		&i.FullName,
+		&i.Email,
+		&i.PasswordChangedAt,
+		&i.CreatedAt,
+		&i.Role,
+		&i.IsEmailVerified,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.ErasedAt,
 	)
 	return i, err
 }
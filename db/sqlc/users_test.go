@@ -53,3 +53,15 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
 }
+
+func TestSetUserRole(t *testing.T) {
+	user := createRandomUser(t)
+	require.Equal(t, UserRoleCustomer, user.Role)
+
+	admin, err := testQueries.SetUserRole(context.Background(), SetUserRoleParams{
+		Username: user.Username,
+		Role:     UserRoleAdmin,
+	})
+	require.NoError(t, err)
+	require.Equal(t, UserRoleAdmin, admin.Role)
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/events"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveryLifecycle(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+
+	sub, err := testQueries.CreateWebhookSubscription(ctx, CreateWebhookSubscriptionParams{
+		Owner:      user.Username,
+		Url:        "https://example.com/hooks",
+		EventTypes: []string{"transfer.received"},
+		Secret:     "whsec_test",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"transfer.received"}, sub.EventTypes)
+
+	event, err := testQueries.CreateOutboxEvent(ctx, CreateOutboxEventParams{
+		AggregateType: events.AggregateUser,
+		AggregateID:   user.Username,
+		EventType:     events.TypeUserRegistered,
+		SchemaVersion: 1,
+		Payload:       json.RawMessage(`{}`),
+	})
+	require.NoError(t, err)
+
+	arg := EnqueueWebhookDeliveriesParams{
+		EventID:   event.ID,
+		EventType: "transfer.received",
+		Payload:   json.RawMessage(`{"event_id":1}`),
+		Owner:     user.Username,
+	}
+	n, err := testQueries.EnqueueWebhookDeliveries(ctx, arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// enqueueing is idempotent
+	n, err = testQueries.EnqueueWebhookDeliveries(ctx, arg)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	// only subscribed event types are enqueued
+	arg.EventType = "transfer.sent"
+	n, err = testQueries.EnqueueWebhookDeliveries(ctx, arg)
+	require.NoError(t, err)
+	require.Zero(t, n)
+
+	deliveries, err := testQueries.ListWebhookDeliveries(ctx, ListWebhookDeliveriesParams{
+		SubscriptionID: sub.ID,
+		Limit:          10,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	delivery := deliveries[0]
+	require.Equal(t, WebhookDeliveryStatusPending, delivery.Status)
+
+	failed, err := testQueries.RecordWebhookFailure(ctx, RecordWebhookFailureParams{
+		ID:             delivery.ID,
+		Status:         WebhookDeliveryStatusPending,
+		NextAttemptAt:  time.Now().Add(time.Hour),
+		LastStatusCode: 500,
+		LastError:      "receiver responded 500",
+	})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), failed.Attempts)
+
+	delivered, err := testQueries.MarkWebhookDelivered(ctx, MarkWebhookDeliveredParams{
+		ID:             delivery.ID,
+		LastStatusCode: 200,
+	})
+	require.NoError(t, err)
+	require.Equal(t, WebhookDeliveryStatusDelivered, delivered.Status)
+	require.Equal(t, int32(2), delivered.Attempts)
+	require.True(t, delivered.DeliveredAt.Valid)
+}
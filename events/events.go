@@ -0,0 +1,99 @@
+// Package events defines the domain events written to the outbox. Every event
+// type has a JSON schema per version under schemas/; a payload change that is
+// not backwards compatible gets a new version and a new schema file.
+package events
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	TypeTransferPosted = "transfer.posted"
+	TypeAccountCreated = "account.created"
+	TypeUserRegistered = "user.registered"
+)
+
+const (
+	AggregateAccount = "account"
+	AggregateUser    = "user"
+)
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// Payload is the data of a domain event.
+type Payload interface {
+	EventType() string
+	SchemaVersion() int32
+	AggregateType() string
+	AggregateID() string
+}
+
+// Event is the envelope sinks receive. Events of the same aggregate are
+// published in the order they were written.
+type Event struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	Version       int32           `json:"version"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	OccurredAt    time.Time       `json:"occurred_at"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// Schema returns the JSON schema of version v of an event type.
+func Schema(eventType string, v int32) ([]byte, error) {
+	data, err := schemas.ReadFile(fmt.Sprintf("schemas/%s.v%d.json", eventType, v))
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %s v%d", eventType, v)
+	}
+
+	return data, nil
+}
+
+// TransferPosted is emitted for every transfer, including fees, payments,
+// adjustments and interest. Its aggregate is the sending account.
+type TransferPosted struct {
+	TransferID    int64     `json:"transfer_id"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	FromBalance   int64     `json:"from_balance"`
+	ToBalance     int64     `json:"to_balance"`
+	PostedAt      time.Time `json:"posted_at"`
+}
+
+func (e TransferPosted) EventType() string     { return TypeTransferPosted }
+func (e TransferPosted) SchemaVersion() int32  { return 1 }
+func (e TransferPosted) AggregateType() string { return AggregateAccount }
+func (e TransferPosted) AggregateID() string   { return strconv.FormatInt(e.FromAccountID, 10) }
+
+type AccountCreated struct {
+	AccountID int64     `json:"account_id"`
+	Owner     string    `json:"owner"`
+	Currency  string    `json:"currency"`
+	Product   string    `json:"product"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (e AccountCreated) EventType() string     { return TypeAccountCreated }
+func (e AccountCreated) SchemaVersion() int32  { return 1 }
+func (e AccountCreated) AggregateType() string { return AggregateAccount }
+func (e AccountCreated) AggregateID() string   { return strconv.FormatInt(e.AccountID, 10) }
+
+type UserRegistered struct {
+	Username     string    `json:"username"`
+	FullName     string    `json:"full_name"`
+	Email        string    `json:"email"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+func (e UserRegistered) EventType() string     { return TypeUserRegistered }
+func (e UserRegistered) SchemaVersion() int32  { return 1 }
+func (e UserRegistered) AggregateType() string { return AggregateUser }
+func (e UserRegistered) AggregateID() string   { return e.Username }
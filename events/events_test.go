@@ -0,0 +1,57 @@
+package events
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestPayloadsMatchSchemas checks that every payload serializes to exactly
+// the properties its schema declares, all of them required.
+func TestPayloadsMatchSchemas(t *testing.T) {
+	payloads := []Payload{
+		TransferPosted{TransferID: 1, FromAccountID: 2, ToAccountID: 3, Amount: 10, Currency: "USD", PostedAt: time.Now()},
+		AccountCreated{AccountID: 1, Owner: "alice", Currency: "USD", Product: "checking", CreatedAt: time.Now()},
+		UserRegistered{Username: "alice", FullName: "Alice", Email: "alice@mail.com", RegisteredAt: time.Now()},
+	}
+
+	for _, p := range payloads {
+		t.Run(p.EventType(), func(t *testing.T) {
+			data, err := Schema(p.EventType(), p.SchemaVersion())
+			require.NoError(t, err)
+
+			var schema struct {
+				Required   []string                   `json:"required"`
+				Properties map[string]json.RawMessage `json:"properties"`
+			}
+			require.NoError(t, json.Unmarshal(data, &schema))
+
+			data, err = json.Marshal(p)
+			require.NoError(t, err)
+
+			var fields map[string]json.RawMessage
+			require.NoError(t, json.Unmarshal(data, &fields))
+
+			require.ElementsMatch(t, keys(schema.Properties), keys(fields))
+			require.ElementsMatch(t, schema.Required, keys(fields))
+		})
+	}
+}
+
+func TestSchemaUnknownVersion(t *testing.T) {
+	_, err := Schema(TypeTransferPosted, 99)
+	require.Error(t, err)
+}
+
+func keys(m map[string]json.RawMessage) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,194 @@
+// Package fee evaluates the transfer fee schedule. A schedule is a list of
+// rules loaded from a JSON file; every rule whose conditions match a transfer
+// contributes to the fee, which is deducted from the transferred amount.
+package fee
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+const (
+	TypeFlat       = "flat"
+	TypePercentage = "percentage"
+	TypeTiered     = "tiered"
+)
+
+// Tier prices transfers up to UpTo (inclusive). A zero UpTo means no upper
+// bound, so it only makes sense on the last tier.
+type Tier struct {
+	UpTo        int64 `json:"up_to"`
+	Flat        int64 `json:"flat"`
+	BasisPoints int64 `json:"basis_points"`
+}
+
+type Rule struct {
+	Name string `json:"name"`
+
+	// Conditions. An empty currency matches every currency.
+	Currency  string `json:"currency"`
+	Instant   bool   `json:"instant"`
+	MinAmount int64  `json:"min_amount"`
+
+	// Pricing. Min and Max cap the fee charged by this rule; zero disables
+	// the cap.
+	Type        string `json:"type"`
+	Flat        int64  `json:"flat"`
+	BasisPoints int64  `json:"basis_points"`
+	Tiers       []Tier `json:"tiers"`
+	Min         int64  `json:"min"`
+	Max         int64  `json:"max"`
+}
+
+type Schedule struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Transfer is what a fee is charged on. Both accounts of a transfer are in
+// the same currency.
+type Transfer struct {
+	Amount   int64
+	Currency string
+	Instant  bool
+}
+
+type Quote struct {
+	GrossAmount int64    `json:"gross_amount"`
+	Fee         int64    `json:"fee"`
+	NetAmount   int64    `json:"net_amount"`
+	Rules       []string `json:"rules"`
+}
+
+// LoadSchedule reads a schedule from a JSON file. An empty path yields an
+// empty schedule, which never charges anything.
+func LoadSchedule(path string) (*Schedule, error) {
+	schedule := &Schedule{}
+	if path == "" {
+		return schedule, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read fee schedule: %w", err)
+	}
+
+	// unknown fields are errors so that a misspelt or unsupported condition
+	// cannot turn into a rule matching every transfer
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse fee schedule: %w", err)
+	}
+
+	err = schedule.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+func (s *Schedule) Validate() error {
+	for i, rule := range s.Rules {
+		switch rule.Type {
+		case TypeFlat, TypePercentage:
+		case TypeTiered:
+			if len(rule.Tiers) == 0 {
+				return fmt.Errorf("fee rule %d (%s): tiered rule without tiers", i, rule.Name)
+			}
+		default:
+			return fmt.Errorf("fee rule %d (%s): unknown type %q", i, rule.Name, rule.Type)
+		}
+
+		if rule.Flat < 0 || rule.BasisPoints < 0 || rule.Min < 0 || rule.Max < 0 || rule.MinAmount < 0 {
+			return fmt.Errorf("fee rule %d (%s): amounts cannot be negative", i, rule.Name)
+		}
+
+		for _, tier := range rule.Tiers {
+			if tier.UpTo < 0 || tier.Flat < 0 || tier.BasisPoints < 0 {
+				return fmt.Errorf("fee rule %d (%s): tier amounts cannot be negative", i, rule.Name)
+			}
+		}
+
+		if rule.Max > 0 && rule.Min > rule.Max {
+			return fmt.Errorf("fee rule %d (%s): min is greater than max", i, rule.Name)
+		}
+	}
+
+	return nil
+}
+
+// Quote returns the fee charged on t. It never executes anything, so it backs
+// both the quote endpoint and the transfer itself.
+func (s *Schedule) Quote(t Transfer) Quote {
+	quote := Quote{
+		GrossAmount: t.Amount,
+		Rules:       []string{},
+	}
+
+	for _, rule := range s.Rules {
+		if !rule.matches(t) {
+			continue
+		}
+
+		quote.Fee += rule.fee(t.Amount)
+		quote.Rules = append(quote.Rules, rule.Name)
+	}
+
+	quote.NetAmount = quote.GrossAmount - quote.Fee
+	return quote
+}
+
+func (r Rule) matches(t Transfer) bool {
+	if r.Currency != "" && r.Currency != t.Currency {
+		return false
+	}
+
+	if r.Instant && !t.Instant {
+		return false
+	}
+
+	return t.Amount >= r.MinAmount
+}
+
+func (r Rule) fee(amount int64) int64 {
+	var fee int64
+	switch r.Type {
+	case TypeFlat:
+		fee = r.Flat
+	case TypePercentage:
+		fee = r.Flat + basisPoints(amount, r.BasisPoints)
+	case TypeTiered:
+		tier := r.Tiers[len(r.Tiers)-1]
+		for _, t := range r.Tiers {
+			if t.UpTo == 0 || amount <= t.UpTo {
+				tier = t
+				break
+			}
+		}
+
+		fee = tier.Flat + basisPoints(amount, tier.BasisPoints)
+	}
+
+	if fee < r.Min {
+		fee = r.Min
+	}
+
+	if r.Max > 0 && fee > r.Max {
+		fee = r.Max
+	}
+
+	return fee
+}
+
+// basisPoints returns amount * bps / 10000 rounded half up, computed with big
+// integers so large amounts cannot overflow.
+func basisPoints(amount int64, bps int64) int64 {
+	n := new(big.Int).Mul(big.NewInt(amount), big.NewInt(bps))
+	n.Add(n, big.NewInt(5000))
+	return n.Quo(n, big.NewInt(10000)).Int64()
+}
@@ -0,0 +1,132 @@
+package fee
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuote(t *testing.T) {
+	schedule, err := LoadSchedule("testdata/schedule.json")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		transfer Transfer
+		fee      int64
+		rules    []string
+	}{
+		{
+			name:     "NoFee",
+			transfer: Transfer{Amount: 1000, Currency: "USD"},
+			fee:      0,
+			rules:    []string{},
+		},
+		{
+			name:     "Instant",
+			transfer: Transfer{Amount: 1000, Currency: "USD", Instant: true},
+			fee:      25,
+			rules:    []string{"instant-usd"},
+		},
+		{
+			name:     "InstantOtherCurrency",
+			transfer: Transfer{Amount: 1000, Currency: "BRL", Instant: true},
+			fee:      0,
+			rules:    []string{},
+		},
+		{
+			name:     "PercentageMin",
+			transfer: Transfer{Amount: 1000, Currency: "EUR", Instant: true},
+			fee:      50,
+			rules:    []string{"instant-eur"},
+		},
+		{
+			name:     "Percentage",
+			transfer: Transfer{Amount: 10000, Currency: "EUR", Instant: true},
+			fee:      150,
+			rules:    []string{"instant-eur"},
+		},
+		{
+			name:     "FirstTier",
+			transfer: Transfer{Amount: 200000, Currency: "USD"},
+			fee:      200,
+			rules:    []string{"large-usd"},
+		},
+		{
+			name:     "LastTier",
+			transfer: Transfer{Amount: 1000000, Currency: "USD"},
+			fee:      600,
+			rules:    []string{"large-usd"},
+		},
+		{
+			name:     "MaxCap",
+			transfer: Transfer{Amount: 5000000, Currency: "USD"},
+			fee:      1000,
+			rules:    []string{"large-usd"},
+		},
+		{
+			name:     "Combined",
+			transfer: Transfer{Amount: 200000, Currency: "USD", Instant: true},
+			fee:      225,
+			rules:    []string{"instant-usd", "large-usd"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			quote := schedule.Quote(tc.transfer)
+			require.Equal(t, tc.transfer.Amount, quote.GrossAmount)
+			require.Equal(t, tc.fee, quote.Fee)
+			require.Equal(t, tc.transfer.Amount-tc.fee, quote.NetAmount)
+			require.Equal(t, tc.rules, quote.Rules)
+		})
+	}
+}
+
+func TestBasisPointsRounding(t *testing.T) {
+	require.Equal(t, int64(1), basisPoints(50, 150))
+	require.Equal(t, int64(0), basisPoints(33, 150))
+	require.Equal(t, int64(1383505805528216371), basisPoints(9223372036854775807, 1500))
+}
+
+func TestEmptySchedule(t *testing.T) {
+	schedule, err := LoadSchedule("")
+	require.NoError(t, err)
+
+	quote := schedule.Quote(Transfer{Amount: 100, Currency: "USD", Instant: true})
+	require.Zero(t, quote.Fee)
+	require.Equal(t, int64(100), quote.NetAmount)
+}
+
+func TestValidate(t *testing.T) {
+	schedule := &Schedule{Rules: []Rule{{Name: "bad", Type: "magic"}}}
+	require.Error(t, schedule.Validate())
+
+	schedule = &Schedule{Rules: []Rule{{Name: "bad", Type: TypeTiered}}}
+	require.Error(t, schedule.Validate())
+
+	schedule = &Schedule{Rules: []Rule{{Name: "bad", Type: TypeFlat, Min: 10, Max: 5}}}
+	require.Error(t, schedule.Validate())
+
+	for _, rule := range []Rule{
+		{Name: "bad", Type: TypeFlat, Flat: -1},
+		{Name: "bad", Type: TypePercentage, BasisPoints: -10},
+		{Name: "bad", Type: TypeFlat, Min: -5},
+		{Name: "bad", Type: TypeFlat, Max: -5},
+		{Name: "bad", Type: TypeTiered, Tiers: []Tier{{UpTo: 0, Flat: -1}}},
+	} {
+		schedule = &Schedule{Rules: []Rule{rule}}
+		require.Error(t, schedule.Validate(), "%+v", rule)
+	}
+}
+
+func TestLoadScheduleUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.json")
+	err := os.WriteFile(path, []byte(`{"rules":[{"name":"fx","cross_currency":true,"type":"flat","flat":10}]}`), 0o600)
+	require.NoError(t, err)
+
+	_, err = LoadSchedule(path)
+	require.ErrorContains(t, err, "cross_currency")
+}
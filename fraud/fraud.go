@@ -0,0 +1,90 @@
+// Package fraud screens transfers before they are posted. A Screener runs a
+// pipeline of rules; each rule returns allow, review or deny and the most
+// severe verdict wins. Reviewed transfers are held for an admin to approve or
+// reject, denied ones are never posted.
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+)
+
+type Input struct {
+	FromAccount db.Account
+	ToAccount   db.Account
+	Amount      int64
+	Now         time.Time
+}
+
+type Rule interface {
+	Name() string
+	Evaluate(ctx context.Context, store db.Store, in Input) (db.FraudVerdict, error)
+}
+
+type Result struct {
+	Verdict db.FraudVerdict `json:"verdict"`
+	Rules   []string        `json:"rules"`
+}
+
+type Screener struct {
+	store db.Store
+	rules []Rule
+}
+
+func NewScreener(store db.Store, rules ...Rule) *Screener {
+	return &Screener{
+		store: store,
+		rules: rules,
+	}
+}
+
+// Screen evaluates every rule and returns the most severe verdict together
+// with the names of the rules that did not allow the transfer.
+func (s *Screener) Screen(ctx context.Context, in Input) (Result, error) {
+	result := Result{
+		Verdict: db.FraudVerdictAllow,
+		Rules:   []string{},
+	}
+
+	for _, rule := range s.rules {
+		verdict, err := rule.Evaluate(ctx, s.store, in)
+		if err != nil {
+			return result, fmt.Errorf("fraud rule %s: %w", rule.Name(), err)
+		}
+
+		if verdict == db.FraudVerdictAllow {
+			continue
+		}
+
+		result.Rules = append(result.Rules, rule.Name())
+		if severity(verdict) > severity(result.Verdict) {
+			result.Verdict = verdict
+		}
+	}
+
+	return result, nil
+}
+
+func severity(v db.FraudVerdict) int {
+	switch v {
+	case db.FraudVerdictReview:
+		return 1
+	case db.FraudVerdictDeny:
+		return 2
+	}
+
+	return 0
+}
+
+// DefaultRules returns the rules the server screens transfers with.
+func DefaultRules() []Rule {
+	return []Rule{
+		NewPayeeLargeAmount{ReviewAmount: 100_000, DenyAmount: 1_000_000},
+		RapidFire{Window: time.Minute, ReviewCount: 5, DenyCount: 20},
+		RoundTrip{Window: 24 * time.Hour},
+		FirstAfterPasswordChange{Window: 24 * time.Hour},
+	}
+}
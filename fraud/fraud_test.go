@@ -0,0 +1,154 @@
+package fraud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type staticRule struct {
+	name    string
+	verdict db.FraudVerdict
+}
+
+func (r staticRule) Name() string {
+	return r.name
+}
+
+func (r staticRule) Evaluate(context.Context, db.Store, Input) (db.FraudVerdict, error) {
+	return r.verdict, nil
+}
+
+func TestScreenMostSevereVerdictWins(t *testing.T) {
+	screener := NewScreener(nil,
+		staticRule{"a", db.FraudVerdictAllow},
+		staticRule{"b", db.FraudVerdictReview},
+		staticRule{"c", db.FraudVerdictDeny},
+		staticRule{"d", db.FraudVerdictReview},
+	)
+
+	result, err := screener.Screen(context.Background(), Input{})
+	require.NoError(t, err)
+	require.Equal(t, db.FraudVerdictDeny, result.Verdict)
+	require.Equal(t, []string{"b", "c", "d"}, result.Rules)
+}
+
+func TestScreenNoRules(t *testing.T) {
+	result, err := NewScreener(nil).Screen(context.Background(), Input{})
+	require.NoError(t, err)
+	require.Equal(t, db.FraudVerdictAllow, result.Verdict)
+	require.Empty(t, result.Rules)
+}
+
+func TestNewPayeeLargeAmount(t *testing.T) {
+	rule := NewPayeeLargeAmount{ReviewAmount: 100, DenyAmount: 1000}
+	in := Input{
+		FromAccount: db.Account{ID: 1},
+		ToAccount:   db.Account{ID: 2},
+	}
+
+	testCases := []struct {
+		name     string
+		amount   int64
+		previous int64
+		calls    int
+		verdict  db.FraudVerdict
+	}{
+		{"SmallAmount", 99, 0, 0, db.FraudVerdictAllow},
+		{"KnownPayee", 5000, 3, 1, db.FraudVerdictAllow},
+		{"Review", 100, 0, 1, db.FraudVerdictReview},
+		{"Deny", 1000, 0, 1, db.FraudVerdictDeny},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mockdb.NewMockStore(gomock.NewController(t))
+			store.EXPECT().
+				CountTransfersBetween(gomock.Any(), gomock.Eq(db.CountTransfersBetweenParams{FromAccountID: 1, ToAccountID: 2})).
+				Times(tc.calls).
+				Return(tc.previous, nil)
+
+			in.Amount = tc.amount
+			verdict, err := rule.Evaluate(context.Background(), store, in)
+			require.NoError(t, err)
+			require.Equal(t, tc.verdict, verdict)
+		})
+	}
+}
+
+func TestRapidFire(t *testing.T) {
+	now := time.Now()
+	rule := RapidFire{Window: time.Minute, ReviewCount: 3, DenyCount: 5}
+	in := Input{FromAccount: db.Account{ID: 1}, Now: now}
+
+	for count, verdict := range map[int64]db.FraudVerdict{
+		1: db.FraudVerdictAllow,
+		2: db.FraudVerdictReview,
+		4: db.FraudVerdictDeny,
+	} {
+		store := mockdb.NewMockStore(gomock.NewController(t))
+		store.EXPECT().
+			GetOutgoingUsage(gomock.Any(), gomock.Eq(db.GetOutgoingUsageParams{FromAccountID: 1, CreatedAt: now.Add(-time.Minute)})).
+			Times(1).
+			Return(db.GetOutgoingUsageRow{Count: count}, nil)
+
+		got, err := rule.Evaluate(context.Background(), store, in)
+		require.NoError(t, err)
+		require.Equal(t, verdict, got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	now := time.Now()
+	rule := RoundTrip{Window: time.Hour}
+	in := Input{FromAccount: db.Account{ID: 1}, ToAccount: db.Account{ID: 2}, Now: now}
+
+	store := mockdb.NewMockStore(gomock.NewController(t))
+	store.EXPECT().
+		CountTransfersBetween(gomock.Any(), gomock.Eq(db.CountTransfersBetweenParams{
+			FromAccountID: 2,
+			ToAccountID:   1,
+			CreatedAt:     now.Add(-time.Hour),
+		})).
+		Times(1).
+		Return(int64(1), nil)
+
+	verdict, err := rule.Evaluate(context.Background(), store, in)
+	require.NoError(t, err)
+	require.Equal(t, db.FraudVerdictReview, verdict)
+}
+
+func TestFirstAfterPasswordChange(t *testing.T) {
+	now := time.Now()
+	rule := FirstAfterPasswordChange{Window: 24 * time.Hour}
+	in := Input{FromAccount: db.Account{ID: 1, Owner: "alice"}, Now: now}
+
+	t.Run("NeverChanged", func(t *testing.T) {
+		store := mockdb.NewMockStore(gomock.NewController(t))
+		store.EXPECT().GetUser(gomock.Any(), gomock.Eq("alice")).Times(1).Return(db.User{}, nil)
+		store.EXPECT().GetOutgoingUsage(gomock.Any(), gomock.Any()).Times(0)
+
+		verdict, err := rule.Evaluate(context.Background(), store, in)
+		require.NoError(t, err)
+		require.Equal(t, db.FraudVerdictAllow, verdict)
+	})
+
+	t.Run("FirstTransfer", func(t *testing.T) {
+		changed := now.Add(-time.Hour)
+		store := mockdb.NewMockStore(gomock.NewController(t))
+		store.EXPECT().GetUser(gomock.Any(), gomock.Eq("alice")).Times(1).Return(db.User{PasswordChangedAt: changed}, nil)
+		store.EXPECT().
+			GetOutgoingUsage(gomock.Any(), gomock.Eq(db.GetOutgoingUsageParams{FromAccountID: 1, CreatedAt: changed})).
+			Times(1).
+			Return(db.GetOutgoingUsageRow{}, nil)
+
+		verdict, err := rule.Evaluate(context.Background(), store, in)
+		require.NoError(t, err)
+		require.Equal(t, db.FraudVerdictReview, verdict)
+	})
+}
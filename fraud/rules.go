@@ -0,0 +1,125 @@
+package fraud
+
+import (
+	"context"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+)
+
+// NewPayeeLargeAmount flags large transfers to an account the sender has
+// never paid before. A zero DenyAmount never denies.
+type NewPayeeLargeAmount struct {
+	ReviewAmount int64
+	DenyAmount   int64
+}
+
+func (r NewPayeeLargeAmount) Name() string {
+	return "new_payee_large_amount"
+}
+
+func (r NewPayeeLargeAmount) Evaluate(ctx context.Context, store db.Store, in Input) (db.FraudVerdict, error) {
+	if in.Amount < r.ReviewAmount {
+		return db.FraudVerdictAllow, nil
+	}
+
+	previous, err := store.CountTransfersBetween(ctx, db.CountTransfersBetweenParams{
+		FromAccountID: in.FromAccount.ID,
+		ToAccountID:   in.ToAccount.ID,
+	})
+	if err != nil || previous > 0 {
+		return db.FraudVerdictAllow, err
+	}
+
+	if r.DenyAmount > 0 && in.Amount >= r.DenyAmount {
+		return db.FraudVerdictDeny, nil
+	}
+
+	return db.FraudVerdictReview, nil
+}
+
+// RapidFire flags senders making many transfers within Window. The counts
+// include the transfer being screened.
+type RapidFire struct {
+	Window      time.Duration
+	ReviewCount int64
+	DenyCount   int64
+}
+
+func (r RapidFire) Name() string {
+	return "rapid_fire"
+}
+
+func (r RapidFire) Evaluate(ctx context.Context, store db.Store, in Input) (db.FraudVerdict, error) {
+	usage, err := store.GetOutgoingUsage(ctx, db.GetOutgoingUsageParams{
+		FromAccountID: in.FromAccount.ID,
+		CreatedAt:     in.Now.Add(-r.Window),
+	})
+	if err != nil {
+		return db.FraudVerdictAllow, err
+	}
+
+	count := usage.Count + 1
+	switch {
+	case r.DenyCount > 0 && count >= r.DenyCount:
+		return db.FraudVerdictDeny, nil
+	case count >= r.ReviewCount:
+		return db.FraudVerdictReview, nil
+	}
+
+	return db.FraudVerdictAllow, nil
+}
+
+// RoundTrip flags transfers sending money back to an account that paid the
+// sender within Window.
+type RoundTrip struct {
+	Window time.Duration
+}
+
+func (r RoundTrip) Name() string {
+	return "round_trip"
+}
+
+func (r RoundTrip) Evaluate(ctx context.Context, store db.Store, in Input) (db.FraudVerdict, error) {
+	incoming, err := store.CountTransfersBetween(ctx, db.CountTransfersBetweenParams{
+		FromAccountID: in.ToAccount.ID,
+		ToAccountID:   in.FromAccount.ID,
+		CreatedAt:     in.Now.Add(-r.Window),
+	})
+	if err != nil || incoming == 0 {
+		return db.FraudVerdictAllow, err
+	}
+
+	return db.FraudVerdictReview, nil
+}
+
+// FirstAfterPasswordChange flags the first transfer a user makes after
+// changing their password, if the change happened within Window.
+type FirstAfterPasswordChange struct {
+	Window time.Duration
+}
+
+func (r FirstAfterPasswordChange) Name() string {
+	return "first_after_password_change"
+}
+
+func (r FirstAfterPasswordChange) Evaluate(ctx context.Context, store db.Store, in Input) (db.FraudVerdict, error) {
+	user, err := store.GetUser(ctx, in.FromAccount.Owner)
+	if err != nil {
+		return db.FraudVerdictAllow, err
+	}
+
+	if in.Now.Sub(user.PasswordChangedAt) > r.Window {
+		return db.FraudVerdictAllow, nil
+	}
+
+	usage, err := store.GetOutgoingUsage(ctx, db.GetOutgoingUsageParams{
+		FromAccountID: in.FromAccount.ID,
+		CreatedAt:     user.PasswordChangedAt,
+	})
+	if err != nil || usage.Count > 0 {
+		return db.FraudVerdictAllow, err
+	}
+
+	return db.FraudVerdictReview, nil
+}
@@ -0,0 +1,151 @@
+package interest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestDailyMicros(t *testing.T) {
+	// 1000.00 at 2% for one day is 5.479452... cents
+	require.Equal(t, int64(5_479_452), DailyMicros(100_000, 200))
+	require.Equal(t, int64(0), DailyMicros(-100_000, 200))
+	require.Equal(t, int64(0), DailyMicros(100_000, 0))
+
+	// a full year of accruals never pays more than the annual rate
+	var year int64
+	for i := 0; i < daysPerYear; i++ {
+		year += DailyMicros(100_000, 200)
+	}
+	require.LessOrEqual(t, year/db.MicrosPerUnit, int64(2_000))
+	require.Equal(t, int64(1_999), year/db.MicrosPerUnit)
+}
+
+func TestAccrue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	job := NewJob(store, 2)
+
+	day := time.Date(2023, 3, 14, 15, 0, 0, 0, time.UTC)
+	start := time.Date(2023, 3, 14, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccrualBalances(gomock.Any(), gomock.Eq(db.ListAccrualBalancesParams{DayEnd: end, AfterID: 0, Size: 2})).
+			Return([]db.ListAccrualBalancesRow{
+				{ID: 1, AnnualRateBps: 200, Balance: 100_000},
+				{ID: 2, AnnualRateBps: 200, Balance: -50},
+			}, nil),
+		store.EXPECT().
+			ListAccrualBalances(gomock.Any(), gomock.Eq(db.ListAccrualBalancesParams{DayEnd: end, AfterID: 2, Size: 2})).
+			Return([]db.ListAccrualBalancesRow{}, nil),
+	)
+
+	store.EXPECT().
+		CreateInterestAccrual(gomock.Any(), gomock.Eq(db.CreateInterestAccrualParams{
+			AccountID:     1,
+			Day:           start,
+			Balance:       100_000,
+			AnnualRateBps: 200,
+			AmountMicros:  5_479_452,
+		})).
+		Times(1).
+		Return(int64(1), nil)
+
+	// already accrued by an earlier run
+	store.EXPECT().
+		CreateInterestAccrual(gomock.Any(), gomock.Eq(db.CreateInterestAccrualParams{
+			AccountID:     2,
+			Day:           start,
+			Balance:       -50,
+			AnnualRateBps: 200,
+			AmountMicros:  0,
+		})).
+		Times(1).
+		Return(int64(0), nil)
+
+	written, err := job.Accrue(context.Background(), day)
+	require.NoError(t, err)
+	require.Equal(t, 1, written)
+}
+
+func TestPost(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	job := NewJob(store, 10)
+
+	period := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	store.EXPECT().
+		ListInterestAccrualSums(gomock.Any(), gomock.Eq(db.ListInterestAccrualSumsParams{
+			PeriodStart: period,
+			PeriodEnd:   period.AddDate(0, 1, 0),
+			AfterID:     0,
+			Size:        10,
+		})).
+		Times(1).
+		Return([]db.ListInterestAccrualSumsRow{
+			{AccountID: 1, AccruedMicros: 339_726_024},
+			{AccountID: 2, AccruedMicros: 100},
+		}, nil)
+
+	store.EXPECT().
+		InterestPostingTx(gomock.Any(), gomock.Eq(db.InterestPostingTxParams{AccountID: 1, Period: period, AccruedMicros: 339_726_024})).
+		Times(1)
+
+	store.EXPECT().
+		InterestPostingTx(gomock.Any(), gomock.Eq(db.InterestPostingTxParams{AccountID: 2, Period: period, AccruedMicros: 100})).
+		Times(1).
+		Return(db.InterestPostingTxResult{}, db.ErrInterestAlreadyPosted)
+
+	posted, err := job.Post(context.Background(), period.AddDate(0, 0, 30))
+	require.NoError(t, err)
+	require.Equal(t, 1, posted)
+}
+
+func TestCatchUp(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	job := NewJob(store, 10)
+	job.now = func() time.Time { return time.Date(2023, 4, 2, 9, 0, 0, 0, time.UTC) }
+
+	store.EXPECT().
+		GetLastAccrualDay(gomock.Any()).
+		Times(1).
+		Return(time.Date(2023, 3, 30, 0, 0, 0, 0, time.UTC), nil)
+
+	// March 30th is accrued again in case a crashed run left accounts out,
+	// then March 31st and April 1st. March is posted when its last day is
+	// done and once more as the last complete month.
+	for _, day := range []time.Time{
+		time.Date(2023, 3, 30, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 3, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		store.EXPECT().
+			ListAccrualBalances(gomock.Any(), gomock.Eq(db.ListAccrualBalancesParams{
+				DayEnd: day.AddDate(0, 0, 1),
+				Size:   10,
+			})).
+			Times(1).
+			Return([]db.ListAccrualBalancesRow{}, nil)
+	}
+
+	march := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	store.EXPECT().
+		ListInterestAccrualSums(gomock.Any(), gomock.Eq(db.ListInterestAccrualSumsParams{
+			PeriodStart: march,
+			PeriodEnd:   march.AddDate(0, 1, 0),
+			Size:        10,
+		})).
+		Times(2).
+		Return([]db.ListInterestAccrualSumsRow{}, nil)
+
+	err := job.CatchUp(context.Background())
+	require.NoError(t, err)
+}
@@ -0,0 +1,178 @@
+// Package mailer sends transactional email. The SMTP sender is meant for
+// production; the others let development and tests read what would be sent.
+package mailer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	KindStdout = "stdout"
+	KindFile   = "file"
+	KindSMTP   = "smtp"
+)
+
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a sender. Target is the file path for the
+// file sender.
+type Config struct {
+	Kind     string
+	Target   string
+	From     string
+	Host     string
+	Port     int
+	Username string
+	Password string
+}
+
+// NewSender builds the sender named by c.Kind; an empty kind writes to stdout.
+func NewSender(c Config) (Sender, error) {
+	switch c.Kind {
+	case "", KindStdout:
+		return NewWriterSender(os.Stdout), nil
+	case KindFile:
+		if c.Target == "" {
+			return nil, fmt.Errorf("file mailer needs a path")
+		}
+
+		return &FileSender{path: c.Target}, nil
+	case KindSMTP:
+		if c.Host == "" || c.From == "" {
+			return nil, fmt.Errorf("smtp mailer needs a host and a from address")
+		}
+
+		return NewSMTPSender(c.Host, c.Port, c.Username, c.Password, c.From), nil
+	}
+
+	return nil, fmt.Errorf("unknown mailer %q", c.Kind)
+}
+
+// WriterSender writes each message as a line of JSON.
+type WriterSender struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSender(w io.Writer) *WriterSender {
+	return &WriterSender{w: w}
+}
+
+func (s *WriterSender) Send(_ context.Context, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// FileSender appends messages as JSON lines to a file.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *FileSender) Send(_ context.Context, msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// MemorySender keeps messages in memory for tests.
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (s *MemorySender) Send(_ context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far.
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Message(nil), s.messages...)
+}
+
+// SMTPSender sends plain text mail through an SMTP server, authenticating
+// with PLAIN auth when a username is set.
+type SMTPSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPSender(host string, port int, username string, password string, from string) *SMTPSender {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPSender{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		auth: auth,
+		from: from,
+	}
+}
+
+func (s *SMTPSender) Send(_ context.Context, msg Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, []byte(b.String()))
+}
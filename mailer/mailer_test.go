@@ -0,0 +1,61 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSender(t *testing.T) {
+	_, err := NewSender(Config{Kind: "pigeon"})
+	require.Error(t, err)
+
+	_, err = NewSender(Config{Kind: KindFile})
+	require.Error(t, err)
+
+	_, err = NewSender(Config{Kind: KindSMTP, Host: "localhost"})
+	require.Error(t, err)
+
+	sender, err := NewSender(Config{})
+	require.NoError(t, err)
+	require.IsType(t, &WriterSender{}, sender)
+}
+
+func TestFileSender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	sender, err := NewSender(Config{Kind: KindFile, Target: path})
+	require.NoError(t, err)
+
+	msgs := []Message{
+		{To: "a@example.com", Subject: "one", Body: "first\nline"},
+		{To: "b@example.com", Subject: "two", Body: "second"},
+	}
+	for _, msg := range msgs {
+		require.NoError(t, sender.Send(context.Background(), msg))
+	}
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var got []Message
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &msg))
+		got = append(got, msg)
+	}
+	require.Equal(t, msgs, got)
+}
+
+func TestSMTPSenderRejectsHeaderInjection(t *testing.T) {
+	sender := NewSMTPSender("localhost", 25, "", "", "no-reply@example.com")
+
+	err := sender.Send(context.Background(), Message{To: "a@example.com\r\nBcc: b@example.com", Subject: "hi"})
+	require.Error(t, err)
+}
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"os"
 
 	"github.com/aulas/demo-bank/api"
+	"github.com/aulas/demo-bank/cli"
+	"github.com/aulas/demo-bank/dataexport"
+	"github.com/aulas/demo-bank/db/migrations"
 	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/interest"
+	"github.com/aulas/demo-bank/outbox"
+	"github.com/aulas/demo-bank/reconcile"
+	"github.com/aulas/demo-bank/stream"
 	"github.com/aulas/demo-bank/util"
+	"github.com/aulas/demo-bank/webhook"
 
 	_ "github.com/lib/pq"
 )
@@ -17,13 +27,75 @@ func main() {
 		log.Fatal("cannot read configurations:", err)
 	}
 
+	if len(os.Args) > 1 {
+		err = cli.Run(config, os.Args[1:], os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
+	if config.MigrateOnStartup {
+		err = migrations.Up(config.DBSource)
+		if err != nil {
+			log.Fatal("cannot migrate db:", err)
+		}
+	}
+
+	err = migrations.CheckVersion(config.DBSource)
+	if err != nil {
+		log.Fatal("refusing to serve: ", err)
+	}
+
 	conn, err := sql.Open(config.DBDriver, config.DBSource)
 	if err != nil {
 		log.Fatal("cannot open db connection:", err)
 	}
 
 	store := db.NewStore(conn)
-	server, err := api.NewServer(config, store)
+	if config.ReconciliationInterval > 0 {
+		job := reconcile.NewJob(store, config.ReconciliationBatchSize)
+		go job.Schedule(context.Background(), config.ReconciliationInterval)
+	}
+
+	if config.InterestInterval > 0 {
+		job := interest.NewJob(store, config.InterestBatchSize)
+		go job.Schedule(context.Background(), config.InterestInterval)
+	}
+
+	if config.OutboxInterval > 0 {
+		sinks := outbox.MultiSink{webhook.NewFanout(store)}
+		if config.OutboxSink != "" {
+			sink, err := outbox.NewSink(config.OutboxSink, config.OutboxTarget)
+			if err != nil {
+				log.Fatal("cannot create outbox sink:", err)
+			}
+
+			sinks = append(sinks, sink)
+		}
+
+		relay := outbox.NewRelay(store, sinks, config.OutboxBatchSize)
+		go relay.Schedule(context.Background(), config.OutboxInterval)
+	}
+
+	if config.WebhookInterval > 0 {
+		worker := webhook.NewWorker(store, config.WebhookBatchSize, config.WebhookMaxAttempts)
+		go worker.Schedule(context.Background(), config.WebhookInterval)
+	}
+
+	if config.DataExportInterval > 0 {
+		job := dataexport.NewJob(store, config.DataExportBatchSize, config.DataExportRetention)
+		go job.Schedule(context.Background(), config.DataExportInterval)
+	}
+
+	changes := stream.NewBroker()
+	err = stream.Listen(context.Background(), config.DBSource, changes)
+	if err != nil {
+		log.Fatal("cannot listen for account changes:", err)
+	}
+
+	server, err := api.NewServer(config, store, changes)
 	if err != nil {
 		log.Fatal("cannot create the server:", err)
 	}
@@ -0,0 +1,96 @@
+// Package outbox relays the events written to the outbox table to a Sink.
+// Events are written in the same transaction as the change they describe, so
+// no change is lost if the process dies before publishing; the relay then
+// delivers them at least once, in the order they were written.
+package outbox
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/events"
+)
+
+const defaultBatchSize = 100
+
+var (
+	publishedTotal = expvar.NewInt("outbox_published_total")
+	failuresTotal  = expvar.NewInt("outbox_publish_failures_total")
+)
+
+type Relay struct {
+	store     db.Store
+	sink      Sink
+	batchSize int32
+}
+
+func NewRelay(store db.Store, sink Sink, batchSize int32) *Relay {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &Relay{
+		store:     store,
+		sink:      sink,
+		batchSize: batchSize,
+	}
+}
+
+// RunOnce publishes one batch and returns how many events were published.
+func (r *Relay) RunOnce(ctx context.Context) (int, error) {
+	n, err := r.store.RelayOutboxTx(ctx, r.batchSize, func(e db.OutboxEvent) error {
+		return r.sink.Publish(ctx, toEvent(e))
+	})
+
+	publishedTotal.Add(int64(n))
+	if err != nil {
+		failuresTotal.Add(1)
+	}
+
+	return n, err
+}
+
+// Drain publishes batches until the outbox is empty or publishing fails.
+func (r *Relay) Drain(ctx context.Context) (int, error) {
+	var total int
+	for {
+		n, err := r.RunOnce(ctx)
+		total += n
+		if err != nil || n < int(r.batchSize) {
+			return total, err
+		}
+	}
+}
+
+// Schedule drains the outbox every interval until ctx is cancelled.
+func (r *Relay) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_, err := r.Drain(ctx)
+			if err != nil {
+				log.Printf("outbox relay failed: %v", err)
+			}
+		}
+	}
+}
+
+func toEvent(e db.OutboxEvent) events.Event {
+	return events.Event{
+		ID:            e.ID,
+		Type:          e.EventType,
+		Version:       e.SchemaVersion,
+		AggregateType: e.AggregateType,
+		AggregateID:   e.AggregateID,
+		OccurredAt:    e.CreatedAt,
+		Data:          e.Payload,
+	}
+}
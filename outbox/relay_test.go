@@ -0,0 +1,169 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/events"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type recordingSink struct {
+	events []events.Event
+	failOn int64
+}
+
+func (s *recordingSink) Publish(_ context.Context, event events.Event) error {
+	if event.ID == s.failOn {
+		return errors.New("sink unavailable")
+	}
+
+	s.events = append(s.events, event)
+	return nil
+}
+
+func outboxEvents(ids ...int64) []db.OutboxEvent {
+	result := make([]db.OutboxEvent, len(ids))
+	for i, id := range ids {
+		result[i] = db.OutboxEvent{
+			ID:            id,
+			AggregateType: events.AggregateAccount,
+			AggregateID:   "1",
+			EventType:     events.TypeTransferPosted,
+			SchemaVersion: 1,
+			Payload:       []byte(`{}`),
+			CreatedAt:     time.Now(),
+		}
+	}
+
+	return result
+}
+
+// relayStub behaves like RelayOutboxTx: it publishes in order and stops at the
+// first failure.
+func relayStub(pending []db.OutboxEvent) func(context.Context, int32, func(db.OutboxEvent) error) (int, error) {
+	return func(_ context.Context, size int32, publish func(db.OutboxEvent) error) (int, error) {
+		if len(pending) > int(size) {
+			pending = pending[:size]
+		}
+
+		var n int
+		for _, e := range pending {
+			if err := publish(e); err != nil {
+				return n, err
+			}
+			n++
+		}
+
+		return n, nil
+	}
+}
+
+func TestRelayPublishesInOrder(t *testing.T) {
+	store := mockdb.NewMockStore(gomock.NewController(t))
+	sink := &recordingSink{}
+	relay := NewRelay(store, sink, 2)
+
+	gomock.InOrder(
+		store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Eq(int32(2)), gomock.Any()).DoAndReturn(relayStub(outboxEvents(1, 2))),
+		store.EXPECT().RelayOutboxTx(gomock.Any(), gomock.Eq(int32(2)), gomock.Any()).DoAndReturn(relayStub(outboxEvents(3))),
+	)
+
+	n, err := relay.Drain(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Len(t, sink.events, 3)
+	for i, e := range sink.events {
+		require.Equal(t, int64(i+1), e.ID)
+		require.Equal(t, events.TypeTransferPosted, e.Type)
+	}
+}
+
+func TestRelayStopsAtFailure(t *testing.T) {
+	store := mockdb.NewMockStore(gomock.NewController(t))
+	sink := &recordingSink{failOn: 2}
+	relay := NewRelay(store, sink, 10)
+
+	store.EXPECT().
+		RelayOutboxTx(gomock.Any(), gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(relayStub(outboxEvents(1, 2, 3)))
+
+	n, err := relay.Drain(context.Background())
+	require.Error(t, err)
+	require.Equal(t, 1, n)
+	require.Len(t, sink.events, 1)
+}
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	require.NoError(t, sink.Publish(context.Background(), events.Event{ID: 1, Data: []byte(`{"a":1}`)}))
+	require.NoError(t, sink.Publish(context.Background(), events.Event{ID: 2, Data: []byte(`{"a":2}`)}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+
+	var event events.Event
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	require.Equal(t, int64(2), event.ID)
+	require.JSONEq(t, `{"a":2}`, string(event.Data))
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewSink(SinkFile, path)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.Publish(context.Background(), events.Event{ID: 1, Data: []byte(`{}`)}))
+	require.NoError(t, sink.Publish(context.Background(), events.Event{ID: 2, Data: []byte(`{}`)}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "\n"))
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received []string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, r.Header.Get("X-Event-ID"))
+		require.Equal(t, events.TypeAccountCreated, r.Header.Get("X-Event-Type"))
+		require.True(t, json.Valid(body))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkHTTP, server.URL)
+	require.NoError(t, err)
+
+	event := events.Event{ID: 7, Type: events.TypeAccountCreated, Data: []byte(`{}`)}
+	require.NoError(t, sink.Publish(context.Background(), event))
+
+	status = http.StatusServiceUnavailable
+	require.Error(t, sink.Publish(context.Background(), event))
+	require.Equal(t, []string{"7", "7"}, received)
+}
+
+func TestNewSinkUnknown(t *testing.T) {
+	_, err := NewSink("kafka", "")
+	require.Error(t, err)
+
+	_, err = NewSink(SinkHTTP, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aulas/demo-bank/events"
+)
+
+const (
+	SinkStdout = "stdout"
+	SinkFile   = "file"
+	SinkHTTP   = "http"
+)
+
+// Sink publishes events somewhere downstream systems can read them. Publish
+// must only return nil once the event is durably accepted.
+type Sink interface {
+	Publish(ctx context.Context, event events.Event) error
+}
+
+// NewSink builds the sink named kind. target is the file path for the file
+// sink and the URL for the HTTP sink.
+func NewSink(kind string, target string) (Sink, error) {
+	switch kind {
+	case SinkStdout:
+		return &WriterSink{w: os.Stdout}, nil
+	case SinkFile:
+		if target == "" {
+			return nil, fmt.Errorf("file sink needs a path")
+		}
+
+		return &FileSink{path: target}, nil
+	case SinkHTTP:
+		if target == "" {
+			return nil, fmt.Errorf("http sink needs a url")
+		}
+
+		return NewHTTPSink(target), nil
+	}
+
+	return nil, fmt.Errorf("unknown outbox sink %q", kind)
+}
+
+// WriterSink writes each event as a line of JSON.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Publish(_ context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// FileSink appends events as JSON lines to a file, syncing after each one.
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (s *FileSink) Publish(_ context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// HTTPSink POSTs each event to a URL and treats any 2xx response as accepted.
+// Receivers should deduplicate on the X-Event-ID header, since an event can
+// be delivered more than once.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string) *HTTPSink {
+	return &HTTPSink{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *HTTPSink) Publish(ctx context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("sink responded %s", resp.Status)
+	}
+
+	return nil
+}
+
+// MultiSink publishes to each sink in turn and fails if any of them does. A
+// retried event is published again to the sinks that already accepted it.
+type MultiSink []Sink
+
+func (s MultiSink) Publish(ctx context.Context, event events.Event) error {
+	for _, sink := range s {
+		if err := sink.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := Policy{Limit: 3, Period: 3 * time.Second}
+
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "alice", policy)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	// other keys have their own bucket
+	result, err = store.Take(ctx, "bob", policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	now = now.Add(time.Second)
+	result, err = store.Take(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+
+	// idle buckets never hold more than the limit
+	now = now.Add(time.Hour)
+	result, err = store.Take(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.Equal(t, 2, result.Remaining)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	policy := Policy{Limit: 2, Period: time.Second}
+
+	_, err := store.Take(ctx, "alice", policy)
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 1)
+
+	now = now.Add(sweepInterval)
+	_, err = store.Take(ctx, "bob", policy)
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 1)
+	require.Contains(t, store.buckets, "bob")
+}
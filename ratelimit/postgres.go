@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+)
+
+const (
+	// pruneInterval is how often PostgresStore deletes idle buckets.
+	pruneInterval = 10 * time.Minute
+	// pruneIdle is how long a bucket must be unused before it is deleted. It
+	// must be longer than any policy's period, or buckets would be deleted
+	// before they refill.
+	pruneIdle = 24 * time.Hour
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// instance of the API shares them. Buckets are refilled using the database
+// clock, so instances with skewed clocks still agree.
+type PostgresStore struct {
+	store db.Store
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func NewPostgresStore(store db.Store) *PostgresStore {
+	return &PostgresStore{store: store}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy) (Result, error) {
+	s.prune()
+
+	b, err := s.store.TakeRateLimitToken(ctx, db.TakeRateLimitTokenParams{
+		Key:   key,
+		Burst: float64(policy.Limit),
+		Rate:  policy.rate(),
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	return newResult(policy, b.Allowed, b.Tokens), nil
+}
+
+// prune deletes idle buckets in the background at most once per
+// pruneInterval.
+func (s *PostgresStore) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastPrune) < pruneInterval {
+		return
+	}
+	s.lastPrune = now
+
+	go func() {
+		_, err := s.store.DeleteIdleRateLimitBuckets(context.Background(), now.Add(-pruneIdle))
+		if err != nil {
+			log.Printf("cannot prune rate limit buckets: %v", err)
+		}
+	}()
+}
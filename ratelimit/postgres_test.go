@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestPostgresStore(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	policy := Policy{Limit: 10, Period: 10 * time.Second}
+
+	pruned := make(chan time.Time, 1)
+	store.EXPECT().
+		DeleteIdleRateLimitBuckets(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, before time.Time) (int64, error) {
+			pruned <- before
+			return 0, nil
+		})
+
+	store.EXPECT().
+		TakeRateLimitToken(gomock.Any(), gomock.Eq(db.TakeRateLimitTokenParams{Key: "alice", Burst: 10, Rate: 1})).
+		Times(2).
+		Return(db.RateLimitBucket{Key: "alice", Tokens: 0.5, Allowed: false}, nil)
+
+	limiter := NewPostgresStore(store)
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Take(context.Background(), "alice", policy)
+		require.NoError(t, err)
+		require.False(t, result.Allowed)
+		require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+	}
+
+	select {
+	case before := <-pruned:
+		require.WithinDuration(t, time.Now().Add(-pruneIdle), before, time.Second)
+	case <-time.After(time.Second):
+		t.Fatal("idle buckets were not pruned")
+	}
+}
@@ -0,0 +1,96 @@
+// Package ratelimit limits how often a client can call the API with token
+// buckets: each key gets a bucket of Policy.Limit tokens that refills evenly
+// over Policy.Period, and every request takes one token.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy allows Limit requests per Period, all of them at once if the bucket
+// has been idle for a whole period. The zero Policy allows everything.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParsePolicy reads a policy written as "<limit>/<period>", for example
+// "10/1m". An empty string is the zero Policy.
+func ParsePolicy(s string) (Policy, error) {
+	if s == "" {
+		return Policy{}, nil
+	}
+
+	limit, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q is not <limit>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 {
+		return Policy{}, fmt.Errorf("rate limit %q: limit must be a positive integer", s)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q: period must be a positive duration", s)
+	}
+
+	return Policy{Limit: n, Period: d}, nil
+}
+
+func (p Policy) Enabled() bool {
+	return p.Limit > 0 && p.Period > 0
+}
+
+// rate is how many tokens the bucket gains per second.
+func (p Policy) rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+// Result is what a Store decided about one request.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request would be allowed; zero
+	// when this one was.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+func newResult(p Policy, allowed bool, tokens float64) Result {
+	rate := p.rate()
+	result := Result{
+		Allowed:   allowed,
+		Limit:     p.Limit,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(p.Limit) - tokens) / rate),
+	}
+
+	if !allowed {
+		result.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+
+	return time.Duration(s * float64(time.Second))
+}
+
+// Store keeps the buckets. Take spends a token from the bucket for key, if
+// there is one, and reports what is left.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePolicy(t *testing.T) {
+	testCases := []struct {
+		in   string
+		want Policy
+		err  bool
+	}{
+		{in: "", want: Policy{}},
+		{in: "10/1m", want: Policy{Limit: 10, Period: time.Minute}},
+		{in: "300/1h", want: Policy{Limit: 300, Period: time.Hour}},
+		{in: "10", err: true},
+		{in: "0/1m", err: true},
+		{in: "ten/1m", err: true},
+		{in: "10/soon", err: true},
+		{in: "10/-1m", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParsePolicy(tc.in)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+
+	require.False(t, Policy{}.Enabled())
+}
+
+func TestNewResult(t *testing.T) {
+	policy := Policy{Limit: 10, Period: 10 * time.Second}
+
+	result := newResult(policy, true, 4.5)
+	require.True(t, result.Allowed)
+	require.Equal(t, 4, result.Remaining)
+	require.Equal(t, 5500*time.Millisecond, result.Reset)
+	require.Zero(t, result.RetryAfter)
+
+	result = newResult(policy, false, 0.25)
+	require.False(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+	require.Equal(t, 750*time.Millisecond, result.RetryAfter)
+}
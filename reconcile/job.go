@@ -0,0 +1,203 @@
+// Package reconcile checks that the ledger is internally consistent: every
+// account balance must equal the sum of its entries, and every transfer must
+// be backed by exactly two entries that cancel each other out.
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+)
+
+const defaultBatchSize = 500
+
+const (
+	KindAccountBalance  = "account_balance"
+	KindTransferEntries = "transfer_entries"
+)
+
+var (
+	runsTotal         = expvar.NewInt("reconciliation_runs_total")
+	lastRunID         = expvar.NewInt("reconciliation_last_run_id")
+	lastDiscrepancies = expvar.NewInt("reconciliation_last_run_discrepancies")
+)
+
+type Job struct {
+	store     db.Store
+	batchSize int32
+}
+
+func NewJob(store db.Store, batchSize int32) *Job {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	return &Job{
+		store:     store,
+		batchSize: batchSize,
+	}
+}
+
+// Run performs a full reconciliation pass in batches of batchSize rows and
+// records the outcome in reconciliation_runs.
+func (j *Job) Run(ctx context.Context) (db.ReconciliationRun, error) {
+	run, err := j.store.CreateReconciliationRun(ctx)
+	if err != nil {
+		return run, fmt.Errorf("cannot create reconciliation run: %w", err)
+	}
+
+	finish := db.FinishReconciliationRunParams{
+		ID:     run.ID,
+		Status: db.ReconciliationStatusCompleted,
+	}
+
+	err = j.checkAccounts(ctx, &finish)
+	if err == nil {
+		err = j.checkTransfers(ctx, &finish)
+	}
+
+	if err != nil {
+		finish.Status = db.ReconciliationStatusFailed
+		finish.Error = err.Error()
+	}
+
+	run, finishErr := j.store.FinishReconciliationRun(ctx, finish)
+	if finishErr != nil {
+		return run, fmt.Errorf("cannot finish reconciliation run: %w", finishErr)
+	}
+
+	runsTotal.Add(1)
+	lastRunID.Set(run.ID)
+	lastDiscrepancies.Set(run.Discrepancies)
+
+	return run, err
+}
+
+func (j *Job) checkAccounts(ctx context.Context, finish *db.FinishReconciliationRunParams) error {
+	var afterID int64
+	for {
+		rows, err := j.store.ListAccountEntrySums(ctx, db.ListAccountEntrySumsParams{
+			AfterID: afterID,
+			Size:    j.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			finish.AccountsChecked++
+			if row.Balance == row.EntriesSum {
+				continue
+			}
+
+			err = j.report(ctx, finish, db.CreateReconciliationDiscrepancyParams{
+				Kind:      KindAccountBalance,
+				AccountID: sql.NullInt64{Int64: row.ID, Valid: true},
+				Expected:  row.EntriesSum,
+				Actual:    row.Balance,
+				Detail:    "balance differs from the sum of entries",
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < int(j.batchSize) {
+			return nil
+		}
+
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+func (j *Job) checkTransfers(ctx context.Context, finish *db.FinishReconciliationRunParams) error {
+	var afterID int64
+	for {
+		rows, err := j.store.ListTransferEntryChecks(ctx, db.ListTransferEntryChecksParams{
+			AfterID: afterID,
+			Size:    j.batchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			finish.TransfersChecked++
+			detail := checkTransfer(row)
+			if detail == "" {
+				continue
+			}
+
+			err = j.report(ctx, finish, db.CreateReconciliationDiscrepancyParams{
+				Kind:       KindTransferEntries,
+				TransferID: sql.NullInt64{Int64: row.ID, Valid: true},
+				Expected:   0,
+				Actual:     row.EntriesSum,
+				Detail:     detail,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(rows) < int(j.batchSize) {
+			return nil
+		}
+
+		afterID = rows[len(rows)-1].ID
+	}
+}
+
+// checkTransfer returns why the transfer's entries are inconsistent, or an
+// empty string if they are fine.
+func checkTransfer(row db.ListTransferEntryChecksRow) string {
+	switch {
+	case row.EntryCount != 2:
+		return fmt.Sprintf("expected 2 entries, found %d", row.EntryCount)
+	case row.EntriesSum != 0:
+		return "entries do not sum to zero"
+	case row.FromEntries != 1 || row.ToEntries != 1:
+		return "entries do not match the transfer accounts and amount"
+	}
+
+	return ""
+}
+
+func (j *Job) report(ctx context.Context, finish *db.FinishReconciliationRunParams, arg db.CreateReconciliationDiscrepancyParams) error {
+	arg.RunID = finish.ID
+	_, err := j.store.CreateReconciliationDiscrepancy(ctx, arg)
+	if err != nil {
+		return fmt.Errorf("cannot record discrepancy: %w", err)
+	}
+
+	finish.Discrepancies++
+	return nil
+}
+
+// Schedule runs the job every interval until ctx is cancelled.
+func (j *Job) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run, err := j.Run(ctx)
+			if err != nil {
+				log.Printf("reconciliation run %d failed: %v", run.ID, err)
+				continue
+			}
+
+			if run.Discrepancies > 0 {
+				log.Printf("reconciliation run %d found %d discrepancies", run.ID, run.Discrepancies)
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	store := mockdb.NewMockStore(ctrl)
+	job := NewJob(store, 2)
+
+	run := db.ReconciliationRun{ID: 7, Status: db.ReconciliationStatusRunning}
+	store.EXPECT().CreateReconciliationRun(gomock.Any()).Times(1).Return(run, nil)
+
+	// the first batch is full, so the job has to ask for the next one
+	gomock.InOrder(
+		store.EXPECT().
+			ListAccountEntrySums(gomock.Any(), gomock.Eq(db.ListAccountEntrySumsParams{AfterID: 0, Size: 2})).
+			Return([]db.ListAccountEntrySumsRow{
+				{ID: 1, Balance: 10, EntriesSum: 10},
+				{ID: 2, Balance: 50, EntriesSum: 20},
+			}, nil),
+		store.EXPECT().
+			ListAccountEntrySums(gomock.Any(), gomock.Eq(db.ListAccountEntrySumsParams{AfterID: 2, Size: 2})).
+			Return([]db.ListAccountEntrySumsRow{}, nil),
+	)
+
+	store.EXPECT().
+		ListTransferEntryChecks(gomock.Any(), gomock.Eq(db.ListTransferEntryChecksParams{AfterID: 0, Size: 2})).
+		Return([]db.ListTransferEntryChecksRow{
+			{ID: 1, Amount: 10, EntryCount: 2, EntriesSum: 0, FromEntries: 1, ToEntries: 1},
+		}, nil)
+
+	store.EXPECT().
+		CreateReconciliationDiscrepancy(gomock.Any(), gomock.Eq(db.CreateReconciliationDiscrepancyParams{
+			RunID:     run.ID,
+			Kind:      KindAccountBalance,
+			AccountID: sql.NullInt64{Int64: 2, Valid: true},
+			Expected:  20,
+			Actual:    50,
+			Detail:    "balance differs from the sum of entries",
+		})).
+		Times(1)
+
+	expectedFinish := db.FinishReconciliationRunParams{
+		ID:               run.ID,
+		Status:           db.ReconciliationStatusCompleted,
+		AccountsChecked:  2,
+		TransfersChecked: 1,
+		Discrepancies:    1,
+	}
+	store.EXPECT().
+		FinishReconciliationRun(gomock.Any(), gomock.Eq(expectedFinish)).
+		Times(1).
+		Return(db.ReconciliationRun{ID: run.ID, Status: db.ReconciliationStatusCompleted, Discrepancies: 1}, nil)
+
+	result, err := job.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.Discrepancies)
+	require.Equal(t, run.ID, lastRunID.Value())
+}
+
+func TestCheckTransfer(t *testing.T) {
+	testCases := []struct {
+		name  string
+		row   db.ListTransferEntryChecksRow
+		valid bool
+	}{
+		{
+			name:  "OK",
+			row:   db.ListTransferEntryChecksRow{EntryCount: 2, FromEntries: 1, ToEntries: 1},
+			valid: true,
+		},
+		{
+			name: "MissingEntry",
+			row:  db.ListTransferEntryChecksRow{EntryCount: 1, EntriesSum: -10, FromEntries: 1},
+		},
+		{
+			name: "NonZeroSum",
+			row:  db.ListTransferEntryChecksRow{EntryCount: 2, EntriesSum: 5, FromEntries: 1},
+		},
+		{
+			name: "WrongAccounts",
+			row:  db.ListTransferEntryChecksRow{EntryCount: 2, FromEntries: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.valid, checkTransfer(tc.row) == "")
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Package stream pushes account changes to connected clients. Changes are
+// published by database triggers with NOTIFY when a transaction commits, so
+// every server instance sees every change regardless of which one made it.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Channel is the NOTIFY channel the account triggers publish on.
+const Channel = "account_changes"
+
+const (
+	KindBalance = "balance"
+	KindEntry   = "entry"
+)
+
+// subscriberBuffer is how many changes a client may fall behind by before it
+// is disconnected and has to reload its accounts.
+const subscriberBuffer = 64
+
+var (
+	subscribersGauge = expvar.NewInt("stream_subscribers")
+	droppedTotal     = expvar.NewInt("stream_dropped_subscribers_total")
+)
+
+// Change is a new balance or a new entry on one account. Balance is set for
+// balance changes; EntryID, Amount and TransferID for new entries.
+type Change struct {
+	Kind       string     `json:"kind"`
+	Owner      string     `json:"-"`
+	AccountID  int64      `json:"account_id"`
+	Currency   string     `json:"currency"`
+	Balance    *int64     `json:"balance,omitempty"`
+	EntryID    int64      `json:"entry_id,omitempty"`
+	Amount     int64      `json:"amount,omitempty"`
+	TransferID *int64     `json:"transfer_id,omitempty"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+}
+
+func (c *Change) UnmarshalJSON(data []byte) error {
+	type change Change
+	var raw struct {
+		change
+		Owner string `json:"owner"`
+	}
+
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*c = Change(raw.change)
+	c.Owner = raw.Owner
+	return nil
+}
+
+// Broker fans changes out to the subscribers of the accounts' owners.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan Change]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[string]map[chan Change]struct{})}
+}
+
+// Subscribe returns the changes to owner's accounts. The channel is closed
+// when the subscriber falls too far behind or changes may have been missed;
+// the client should then reload its accounts and subscribe again. cancel must
+// be called once the subscriber goes away.
+func (b *Broker) Subscribe(owner string) (changes <-chan Change, cancel func()) {
+	ch := make(chan Change, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[owner] == nil {
+		b.subscribers[owner] = make(map[chan Change]struct{})
+	}
+	b.subscribers[owner][ch] = struct{}{}
+	b.mu.Unlock()
+	subscribersGauge.Add(1)
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(owner, ch)
+	}
+}
+
+// Publish sends c to the subscribers of its owner, disconnecting any that are
+// not keeping up rather than blocking the others.
+func (b *Broker) Publish(c Change) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[c.Owner] {
+		select {
+		case ch <- c:
+		default:
+			droppedTotal.Add(1)
+			b.remove(c.Owner, ch)
+		}
+	}
+}
+
+// Reset disconnects every subscriber. It is called when the connection to the
+// database was lost, since notifications sent in the meantime are gone.
+func (b *Broker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for owner, chans := range b.subscribers {
+		for ch := range chans {
+			b.remove(owner, ch)
+		}
+	}
+}
+
+// remove must be called with b.mu held. It is a no-op for channels already
+// removed, so cancel can run after the broker has dropped the subscriber.
+func (b *Broker) remove(owner string, ch chan Change) {
+	chans, ok := b.subscribers[owner]
+	if !ok {
+		return
+	}
+
+	if _, ok := chans[ch]; !ok {
+		return
+	}
+
+	delete(chans, ch)
+	if len(chans) == 0 {
+		delete(b.subscribers, owner)
+	}
+
+	close(ch)
+	subscribersGauge.Add(-1)
+}
+
+// Run publishes notifications until ctx is cancelled or notifications is
+// closed. A nil notification means the listener reconnected.
+func (b *Broker) Run(ctx context.Context, notifications <-chan *pq.Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n, ok := <-notifications:
+			if !ok {
+				b.Reset()
+				return
+			}
+
+			if n == nil {
+				b.Reset()
+				continue
+			}
+
+			var c Change
+			if err := json.Unmarshal([]byte(n.Extra), &c); err != nil {
+				log.Printf("cannot decode account change: %v", err)
+				continue
+			}
+
+			b.Publish(c)
+		}
+	}
+}
+
+// Listen feeds b from the database until ctx is cancelled, reconnecting if
+// the connection drops.
+func Listen(ctx context.Context, dbSource string, b *Broker) error {
+	listener := pq.NewListener(dbSource, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("account change listener: %v", err)
+		}
+	})
+
+	if err := listener.Listen(Channel); err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		defer listener.Close()
+		b.Run(ctx, listener.Notify)
+	}()
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, changes <-chan Change) (Change, bool) {
+	select {
+	case c, ok := <-changes:
+		return c, ok
+	case <-time.After(time.Second):
+		t.Fatal("no change received")
+		return Change{}, false
+	}
+}
+
+func TestBrokerRoutesByOwner(t *testing.T) {
+	b := NewBroker()
+	alice, cancelAlice := b.Subscribe("alice")
+	defer cancelAlice()
+	bob, cancelBob := b.Subscribe("bob")
+	defer cancelBob()
+
+	balance := int64(90)
+	b.Publish(Change{Kind: KindBalance, Owner: "alice", AccountID: 1, Balance: &balance})
+
+	c, ok := receive(t, alice)
+	require.True(t, ok)
+	require.Equal(t, int64(1), c.AccountID)
+	require.Len(t, bob, 0)
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	b := NewBroker()
+	changes, cancel := b.Subscribe("alice")
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(Change{Kind: KindEntry, Owner: "alice", EntryID: int64(i)})
+	}
+
+	for i := 0; i < subscriberBuffer; i++ {
+		_, ok := <-changes
+		require.True(t, ok)
+	}
+	_, ok := <-changes
+	require.False(t, ok)
+
+	// cancelling after being dropped is harmless
+	cancel()
+}
+
+func TestBrokerRun(t *testing.T) {
+	b := NewBroker()
+	changes, cancel := b.Subscribe("alice")
+	defer cancel()
+
+	notifications := make(chan *pq.Notification)
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go b.Run(ctx, notifications)
+
+	notifications <- &pq.Notification{
+		Channel: Channel,
+		Extra:   `{"kind":"entry","owner":"alice","account_id":3,"currency":"USD","entry_id":8,"amount":-25,"transfer_id":null,"created_at":"2026-01-02T10:00:00.123456+00:00"}`,
+	}
+
+	c, ok := receive(t, changes)
+	require.True(t, ok)
+	require.Equal(t, KindEntry, c.Kind)
+	require.Equal(t, "alice", c.Owner)
+	require.Equal(t, int64(-25), c.Amount)
+	require.Nil(t, c.TransferID)
+	require.NotNil(t, c.CreatedAt)
+
+	// a reconnect means notifications may have been missed
+	notifications <- nil
+	_, ok = receive(t, changes)
+	require.False(t, ok)
+}
@@ -1,25 +1,86 @@
 package token
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 const minSecretKeySize = 32
 
 type JWTMaker struct {
 	secretKey string
+	claims    Claims
 }
 
-func NewJWTMaker(secretKey string) (Maker, error) {
+func NewJWTMaker(secretKey string, opts ...Option) (Maker, error) {
 	if len(secretKey) < minSecretKeySize {
 		return nil, fmt.Errorf("invalid secret key. Min size: %d", minSecretKeySize)
 	}
 
-	return &JWTMaker{secretKey}, nil
+	return &JWTMaker{secretKey: secretKey, claims: newClaims(opts)}, nil
+}
+
+// jwtClaims exposes a payload's registered claims to the jwt package and
+// writes them next to the payload's own fields, so other JWT libraries can
+// check them too.
+type jwtClaims struct {
+	*Payload
+}
+
+func (c jwtClaims) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		*Payload
+		Subject   string           `json:"sub"`
+		Issuer    string           `json:"iss,omitempty"`
+		Audience  jwt.ClaimStrings `json:"aud,omitempty"`
+		ExpiresAt *jwt.NumericDate `json:"exp,omitempty"`
+		NotBefore *jwt.NumericDate `json:"nbf,omitempty"`
+		IssuedAt  *jwt.NumericDate `json:"iat,omitempty"`
+	}{
+		Payload:   c.Payload,
+		Subject:   c.Username,
+		Issuer:    c.Payload.Issuer,
+		Audience:  c.Payload.Audience,
+		ExpiresAt: numericDate(c.Payload.ExpiresAt),
+		NotBefore: numericDate(c.Payload.NotBefore),
+		IssuedAt:  numericDate(c.Payload.IssuedAt),
+	})
+}
+
+func numericDate(t time.Time) *jwt.NumericDate {
+	if t.IsZero() {
+		return nil
+	}
+
+	return jwt.NewNumericDate(t)
+}
+
+func (c jwtClaims) GetExpirationTime() (*jwt.NumericDate, error) {
+	return numericDate(c.ExpiresAt), nil
+}
+
+func (c jwtClaims) GetIssuedAt() (*jwt.NumericDate, error) {
+	return numericDate(c.IssuedAt), nil
+}
+
+func (c jwtClaims) GetNotBefore() (*jwt.NumericDate, error) {
+	return numericDate(c.NotBefore), nil
+}
+
+func (c jwtClaims) GetIssuer() (string, error) {
+	return c.Issuer, nil
+}
+
+func (c jwtClaims) GetSubject() (string, error) {
+	return c.Username, nil
+}
+
+func (c jwtClaims) GetAudience() (jwt.ClaimStrings, error) {
+	return c.Audience, nil
 }
 
 func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
@@ -27,34 +88,54 @@ func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (str
 	if err != nil {
 		return "", err
 	}
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
+
+	return maker.CreateTokenFromPayload(payload)
+}
+
+func (maker *JWTMaker) CreateTokenFromPayload(payload *Payload) (string, error) {
+	maker.claims.stamp(payload)
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims{payload})
 	return jwtToken.SignedString([]byte(maker.secretKey))
 }
 
 func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 	keyFunc := func(token *jwt.Token) (interface{}, error) {
-		_, ok := token.Method.(*jwt.SigningMethodHMAC)
-		if !ok {
-			return nil, ErrInvalidToken
-		}
-
 		return []byte(maker.secretKey), nil
 	}
 
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
-	if err != nil {
-		verr, ok := err.(*jwt.ValidationError)
-		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
-			return nil, ErrExpiredToken
-		}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+	}
+	if maker.claims.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(maker.claims.Issuer))
+	}
+	if maker.claims.Audience != "" {
+		opts = append(opts, jwt.WithAudience(maker.claims.Audience))
+	}
 
+	claims := &jwtClaims{&Payload{}}
+	_, err := jwt.ParseWithClaims(token, claims, keyFunc, opts...)
+	switch {
+	case errors.Is(err, jwt.ErrTokenExpired):
+		return nil, ErrExpiredToken
+	case errors.Is(err, jwt.ErrTokenNotValidYet):
+		return nil, ErrTokenNotYetValid
+	case errors.Is(err, jwt.ErrTokenInvalidIssuer):
+		return nil, ErrInvalidIssuer
+	case errors.Is(err, jwt.ErrTokenInvalidAudience):
+		return nil, ErrInvalidAudience
+	case err != nil:
 		return nil, ErrInvalidToken
 	}
 
-	payload, ok := jwtToken.Claims.(*Payload)
-	if !ok {
-		return nil, ErrInvalidToken
+	// the jwt package allows clockSkew either way; Payload.Valid applies the
+	// same rules as the other makers
+	err = claims.Valid(maker.claims)
+	if err != nil {
+		return nil, err
 	}
 
-	return payload, nil
+	return claims.Payload, nil
 }
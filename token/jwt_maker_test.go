@@ -5,7 +5,7 @@ import (
 	"time"
 
 	"github.com/aulas/demo-bank/util"
-	"github.com/golang-jwt/jwt"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/require"
 )
 
@@ -54,7 +54,7 @@ func TestInvaidJWT(t *testing.T) {
 	payload, err := NewPayload(util.RandomOnwer(), time.Minute)
 	require.NoError(t, err)
 
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, payload)
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodNone, jwtClaims{payload})
 	token, err := jwtToken.SignedString(jwt.UnsafeAllowNoneSignatureType)
 	require.NoError(t, err)
 
@@ -66,3 +66,45 @@ func TestInvaidJWT(t *testing.T) {
 	require.EqualError(t, err, ErrInvalidToken.Error())
 	require.Nil(t, payload)
 }
+
+func TestJWTRegisteredClaims(t *testing.T) {
+	maker, err := NewJWTMaker(util.RandomString(32), WithIssuer("demo-bank"), WithAudience("demo-bank-api"))
+	require.NoError(t, err)
+
+	username := util.RandomOnwer()
+	token, err := maker.CreateToken(username, time.Minute)
+	require.NoError(t, err)
+
+	claims := jwt.MapClaims{}
+	_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+	require.NoError(t, err)
+	require.Equal(t, username, claims["sub"])
+	require.Equal(t, "demo-bank", claims["iss"])
+	require.Equal(t, []any{"demo-bank-api"}, claims["aud"])
+	for _, name := range []string{"exp", "iat", "nbf"} {
+		require.Contains(t, claims, name)
+	}
+
+	other, err := NewJWTMaker(maker.(*JWTMaker).secretKey, WithIssuer("someone-else"))
+	require.NoError(t, err)
+	_, err = other.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidIssuer)
+
+	other, err = NewJWTMaker(maker.(*JWTMaker).secretKey, WithAudience("reporting"))
+	require.NoError(t, err)
+	_, err = other.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidAudience)
+}
+
+func TestJWTWithoutExpiration(t *testing.T) {
+	secretKey := util.RandomString(32)
+	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": util.RandomOnwer()})
+	token, err := jwtToken.SignedString([]byte(secretKey))
+	require.NoError(t, err)
+
+	maker, err := NewJWTMaker(secretKey)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
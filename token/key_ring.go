@@ -0,0 +1,132 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+var (
+	ErrRetiredKey   = errors.New("token was signed with a retired key")
+	ErrUnknownKey   = errors.New("token was signed with an unknown key")
+	ErrNoSigningKey = errors.New("no signing key configured")
+)
+
+// KeyRing holds the Ed25519 keys of a PasetoPublicMaker. Tokens are signed
+// with the signing key and name it by ID in their footer; any key in the ring
+// verifies them. To rotate, add the new public key to every verifier, switch
+// the signer to the new key, and once the old tokens have expired retire the
+// old ID.
+type KeyRing struct {
+	signingKeyID string
+	signingKey   paseto.V4AsymmetricSecretKey
+	keys         map[string]paseto.V4AsymmetricPublicKey
+	retired      map[string]bool
+}
+
+func NewKeyRing() *KeyRing {
+	return &KeyRing{
+		keys:    make(map[string]paseto.V4AsymmetricPublicKey),
+		retired: make(map[string]bool),
+	}
+}
+
+// SetSigningKey signs new tokens with the hex encoded Ed25519 secret key and
+// adds its public half to the ring.
+func (r *KeyRing) SetSigningKey(id string, secretKeyHex string) error {
+	key, err := paseto.NewV4AsymmetricSecretKeyFromHex(secretKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid signing key %q: %w", id, err)
+	}
+
+	if err := r.addKey(id, key.Public()); err != nil {
+		return err
+	}
+
+	r.signingKeyID = id
+	r.signingKey = key
+	return nil
+}
+
+// AddVerificationKey accepts tokens signed by the hex encoded Ed25519 public
+// key.
+func (r *KeyRing) AddVerificationKey(id string, publicKeyHex string) error {
+	key, err := paseto.NewV4AsymmetricPublicKeyFromHex(publicKeyHex)
+	if err != nil {
+		return fmt.Errorf("invalid verification key %q: %w", id, err)
+	}
+
+	return r.addKey(id, key)
+}
+
+func (r *KeyRing) addKey(id string, key paseto.V4AsymmetricPublicKey) error {
+	if id == "" {
+		return errors.New("key id must not be empty")
+	}
+
+	if r.retired[id] {
+		return fmt.Errorf("key %q is retired", id)
+	}
+
+	if existing, ok := r.keys[id]; ok && existing.ExportHex() != key.ExportHex() {
+		return fmt.Errorf("key id %q is used by two different keys", id)
+	}
+
+	r.keys[id] = key
+	return nil
+}
+
+// Retire stops accepting tokens signed by the key, which VerifyToken then
+// reports as ErrRetiredKey. Retired IDs can't be reused.
+func (r *KeyRing) Retire(id string) error {
+	if id == r.signingKeyID && id != "" {
+		return fmt.Errorf("key %q is still used for signing", id)
+	}
+
+	delete(r.keys, id)
+	r.retired[id] = true
+	return nil
+}
+
+// KeyIDs lists the IDs of the keys tokens are accepted from.
+func (r *KeyRing) KeyIDs() []string {
+	ids := make([]string, 0, len(r.keys))
+	for id := range r.keys {
+		ids = append(ids, id)
+	}
+
+	sort.Strings(ids)
+	return ids
+}
+
+func (r *KeyRing) verificationKey(id string) (paseto.V4AsymmetricPublicKey, error) {
+	if r.retired[id] {
+		return paseto.V4AsymmetricPublicKey{}, ErrRetiredKey
+	}
+
+	key, ok := r.keys[id]
+	if !ok {
+		return paseto.V4AsymmetricPublicKey{}, ErrUnknownKey
+	}
+
+	return key, nil
+}
+
+// ParseVerificationKey splits a key written as "<id>:<public key hex>".
+func ParseVerificationKey(s string) (id string, publicKeyHex string, err error) {
+	id, publicKeyHex, ok := strings.Cut(s, ":")
+	if !ok || id == "" || publicKeyHex == "" {
+		return "", "", fmt.Errorf("verification key %q is not <id>:<public key hex>", s)
+	}
+
+	return id, publicKeyHex, nil
+}
+
+// GenerateSigningKey returns a new Ed25519 key pair, hex encoded.
+func GenerateSigningKey() (secretKeyHex string, publicKeyHex string) {
+	key := paseto.NewV4AsymmetricSecretKey()
+	return key.ExportHex(), key.Public().ExportHex()
+}
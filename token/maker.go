@@ -1,8 +1,83 @@
 package token
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
+
+// Verifier checks tokens without being able to make them.
+type Verifier interface {
+	VerifyToken(token string) (*Payload, error)
+}
 
 type Maker interface {
 	CreateToken(username string, duration time.Duration) (string, error)
-	VerifyToken(token string) (*Payload, error)
+	// CreateTokenFromPayload signs a payload built with NewPayload and then
+	// adjusted, e.g. to set its purpose.
+	CreateTokenFromPayload(payload *Payload) (string, error)
+	Verifier
+}
+
+const (
+	KindPasetoLocal  = "paseto_local"
+	KindPasetoPublic = "paseto_public"
+	KindJWT          = "jwt"
+)
+
+// Config picks a Maker. SymmetricKey is used by the paseto_local and jwt
+// makers; the others are for paseto_public.
+type Config struct {
+	Kind         string
+	SymmetricKey string
+	// Issuer and Audience are stamped on new tokens and required of
+	// verified ones, unless empty.
+	Issuer   string
+	Audience string
+
+	SigningKeyID string
+	SigningKey   string
+	// VerificationKeys are "<id>:<public key hex>", accepted alongside the
+	// signing key, e.g. while rotating keys.
+	VerificationKeys []string
+	RetiredKeyIDs    []string
+}
+
+// NewMaker builds the Maker cfg asks for. An empty Kind is paseto_local.
+func NewMaker(cfg Config) (Maker, error) {
+	opts := []Option{WithIssuer(cfg.Issuer), WithAudience(cfg.Audience)}
+
+	switch cfg.Kind {
+	case "", KindPasetoLocal:
+		return NewPasetoMaker(cfg.SymmetricKey, opts...)
+	case KindJWT:
+		return NewJWTMaker(cfg.SymmetricKey, opts...)
+	case KindPasetoPublic:
+		keys := NewKeyRing()
+		for _, id := range cfg.RetiredKeyIDs {
+			if err := keys.Retire(id); err != nil {
+				return nil, err
+			}
+		}
+
+		if cfg.SigningKeyID != "" || cfg.SigningKey != "" {
+			if err := keys.SetSigningKey(cfg.SigningKeyID, cfg.SigningKey); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, key := range cfg.VerificationKeys {
+			id, publicKeyHex, err := ParseVerificationKey(key)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := keys.AddVerificationKey(id, publicKeyHex); err != nil {
+				return nil, err
+			}
+		}
+
+		return NewPasetoPublicMaker(keys, opts...)
+	default:
+		return nil, fmt.Errorf("unknown token maker %q", cfg.Kind)
+	}
 }
@@ -0,0 +1,52 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMaker(t *testing.T) {
+	secretKey, _ := GenerateSigningKey()
+	_, oldPublicKey := GenerateSigningKey()
+
+	testCases := []struct {
+		name string
+		cfg  Config
+		want Maker
+		err  bool
+	}{
+		{name: "Default", cfg: Config{SymmetricKey: util.RandomString(32)}, want: &PasetoMaker{}},
+		{name: "PasetoLocal", cfg: Config{Kind: KindPasetoLocal, SymmetricKey: util.RandomString(32)}, want: &PasetoMaker{}},
+		{name: "JWT", cfg: Config{Kind: KindJWT, SymmetricKey: util.RandomString(32)}, want: &JWTMaker{}},
+		{
+			name: "PasetoPublic",
+			cfg: Config{
+				Kind:             KindPasetoPublic,
+				SigningKeyID:     "new",
+				SigningKey:       secretKey,
+				VerificationKeys: []string{"old:" + oldPublicKey},
+				RetiredKeyIDs:    []string{"older"},
+			},
+			want: &PasetoPublicMaker{},
+		},
+		{name: "PasetoPublicWithoutKeys", cfg: Config{Kind: KindPasetoPublic}, err: true},
+		{name: "BadVerificationKey", cfg: Config{Kind: KindPasetoPublic, VerificationKeys: []string{oldPublicKey}}, err: true},
+		{name: "RetiredSigningKey", cfg: Config{Kind: KindPasetoPublic, SigningKeyID: "new", SigningKey: secretKey, RetiredKeyIDs: []string{"new"}}, err: true},
+		{name: "Unknown", cfg: Config{Kind: "paseto_v9"}, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			maker, err := NewMaker(tc.cfg)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.IsType(t, tc.want, maker)
+		})
+	}
+}
@@ -11,9 +11,10 @@ import (
 type PasetoMaker struct {
 	paseto       *paseto.V2
 	symmetricKey []byte
+	claims       Claims
 }
 
-func NewPasetoMaker(symmetricKey string) (Maker, error) {
+func NewPasetoMaker(symmetricKey string, opts ...Option) (Maker, error) {
 	if len(symmetricKey) != chacha20poly1305.KeySize {
 		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
 	}
@@ -21,6 +22,7 @@ func NewPasetoMaker(symmetricKey string) (Maker, error) {
 	maker := &PasetoMaker{
 		paseto:       paseto.NewV2(),
 		symmetricKey: []byte(symmetricKey),
+		claims:       newClaims(opts),
 	}
 
 	return maker, nil
@@ -32,8 +34,14 @@ func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (
 		return "", err
 	}
 
+	return maker.CreateTokenFromPayload(payload)
+}
+
+func (maker *PasetoMaker) CreateTokenFromPayload(payload *Payload) (string, error) {
+	maker.claims.stamp(payload)
 	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
 }
+
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 	payload := &Payload{}
 	err := maker.paseto.Decrypt(token, maker.symmetricKey, payload, nil)
@@ -41,7 +49,7 @@ func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
-	err = payload.Valid()
+	err = payload.Valid(maker.claims)
 	if err != nil {
 		return nil, err
 
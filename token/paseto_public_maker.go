@@ -0,0 +1,102 @@
+package token
+
+import (
+	"encoding/json"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+// PasetoPublicMaker signs v4.public tokens, so services that only verify
+// tokens need the public keys and never the secret one.
+type PasetoPublicMaker struct {
+	keys   *KeyRing
+	parser paseto.Parser
+	claims Claims
+}
+
+type pasetoFooter struct {
+	KeyID string `json:"kid"`
+}
+
+func NewPasetoPublicMaker(keys *KeyRing, opts ...Option) (Maker, error) {
+	if len(keys.keys) == 0 {
+		return nil, ErrNoSigningKey
+	}
+
+	maker := &PasetoPublicMaker{
+		keys: keys,
+		// Payload.Valid checks the expiry
+		parser: paseto.NewParserWithoutExpiryCheck(),
+		claims: newClaims(opts),
+	}
+
+	return maker, nil
+}
+
+func (maker *PasetoPublicMaker) CreateToken(username string, duration time.Duration) (string, error) {
+	payload, err := NewPayload(username, duration)
+	if err != nil {
+		return "", err
+	}
+
+	return maker.CreateTokenFromPayload(payload)
+}
+
+func (maker *PasetoPublicMaker) CreateTokenFromPayload(payload *Payload) (string, error) {
+	if maker.keys.signingKeyID == "" {
+		return "", ErrNoSigningKey
+	}
+
+	maker.claims.stamp(payload)
+	claims, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	footer, err := json.Marshal(pasetoFooter{KeyID: maker.keys.signingKeyID})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := paseto.NewTokenFromClaimsJSON(claims, footer)
+	if err != nil {
+		return "", err
+	}
+
+	return token.V4Sign(maker.keys.signingKey, nil), nil
+}
+
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	rawFooter, err := maker.parser.UnsafeParseFooter(paseto.V4Public, token)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var footer pasetoFooter
+	if err := json.Unmarshal(rawFooter, &footer); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := maker.keys.verificationKey(footer.KeyID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := maker.parser.ParseV4Public(key, token, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	payload := &Payload{}
+	if err := json.Unmarshal(parsed.ClaimsJSON(), payload); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = payload.Valid(maker.claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
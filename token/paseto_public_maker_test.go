@@ -0,0 +1,122 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestKeyRing(t *testing.T, id string) (*KeyRing, string) {
+	secretKey, publicKey := GenerateSigningKey()
+	keys := NewKeyRing()
+	require.NoError(t, keys.SetSigningKey(id, secretKey))
+	return keys, publicKey
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	keys, _ := newTestKeyRing(t, "k1")
+	maker, err := NewPasetoPublicMaker(keys)
+	require.NoError(t, err)
+
+	username := util.RandomOnwer()
+	issuedAt := time.Now()
+
+	token, err := maker.CreateToken(username, time.Minute)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "v4.public."))
+
+	payload, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, issuedAt.Add(time.Minute), payload.ExpiresAt, time.Second)
+
+	// tokens from another key pair with the same id don't verify
+	other, _ := newTestKeyRing(t, "k1")
+	otherMaker, err := NewPasetoPublicMaker(other)
+	require.NoError(t, err)
+
+	_, err = otherMaker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	keys, _ := newTestKeyRing(t, "k1")
+	maker, err := NewPasetoPublicMaker(keys)
+	require.NoError(t, err)
+
+	token, err := maker.CreateToken(util.RandomOnwer(), -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.ErrorIs(t, err, ErrExpiredToken)
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicKeyRotation(t *testing.T) {
+	oldKeys, oldPublicKey := newTestKeyRing(t, "old")
+	oldMaker, err := NewPasetoPublicMaker(oldKeys)
+	require.NoError(t, err)
+
+	oldToken, err := oldMaker.CreateToken(util.RandomOnwer(), time.Minute)
+	require.NoError(t, err)
+
+	// the new signer still accepts tokens signed with the old key
+	newKeys, newPublicKey := newTestKeyRing(t, "new")
+	require.NoError(t, newKeys.AddVerificationKey("old", oldPublicKey))
+	require.Equal(t, []string{"new", "old"}, newKeys.KeyIDs())
+
+	newMaker, err := NewPasetoPublicMaker(newKeys)
+	require.NoError(t, err)
+
+	_, err = newMaker.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	newToken, err := newMaker.CreateToken(util.RandomOnwer(), time.Minute)
+	require.NoError(t, err)
+
+	// a verifier holding only public keys
+	verifierKeys := NewKeyRing()
+	require.NoError(t, verifierKeys.AddVerificationKey("new", newPublicKey))
+	verifier, err := NewPasetoPublicMaker(verifierKeys)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(newToken)
+	require.NoError(t, err)
+
+	_, err = verifier.VerifyToken(oldToken)
+	require.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = verifier.CreateToken(util.RandomOnwer(), time.Minute)
+	require.ErrorIs(t, err, ErrNoSigningKey)
+
+	// once retired, the old key's tokens fail distinctly
+	require.NoError(t, newKeys.Retire("old"))
+	_, err = newMaker.VerifyToken(oldToken)
+	require.ErrorIs(t, err, ErrRetiredKey)
+
+	require.Error(t, newKeys.Retire("new"))
+	require.Error(t, newKeys.AddVerificationKey("old", oldPublicKey))
+}
+
+func TestPasetoPublicMakerRejectsOtherTokens(t *testing.T) {
+	keys, _ := newTestKeyRing(t, "k1")
+	maker, err := NewPasetoPublicMaker(keys)
+	require.NoError(t, err)
+
+	localMaker, err := NewPasetoMaker(util.RandomString(32))
+	require.NoError(t, err)
+
+	localToken, err := localMaker.CreateToken(util.RandomOnwer(), time.Minute)
+	require.NoError(t, err)
+
+	_, err = maker.VerifyToken(localToken)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = NewPasetoPublicMaker(NewKeyRing())
+	require.ErrorIs(t, err, ErrNoSigningKey)
+}
@@ -8,15 +8,48 @@ import (
 )
 
 var (
-	ErrExpiredToken = errors.New("token has expired")
-	ErrInvalidToken    = errors.New("token is invalid")
+	ErrExpiredToken     = errors.New("token has expired")
+	ErrInvalidToken     = errors.New("token is invalid")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+	ErrInvalidIssuer    = errors.New("token was issued by someone else")
+	ErrInvalidAudience  = errors.New("token is meant for another audience")
 )
 
+// clockSkew is how far ahead of the verifier's clock the issuer's may be
+// before tokens are rejected as not valid yet.
+const clockSkew = 30 * time.Second
+
+// PurposeMFAChallenge marks the token login hands out to users with two-factor
+// authentication until they enter a code.
+const PurposeMFAChallenge = "mfa_challenge"
+
 type Payload struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiresAt time.Time `json:"expires_at"`
+	NotBefore time.Time `json:"not_before"`
+	Issuer    string    `json:"issuer,omitempty"`
+	Audience  []string  `json:"audience,omitempty"`
+	// SessionID is shared by the tokens issued from one login.
+	SessionID uuid.UUID `json:"session_id"`
+	Role      string    `json:"role,omitempty"`
+	// Scopes are what the token may be used for; see HasScopes.
+	Scopes []string `json:"scopes,omitempty"`
+	// AccountIDs, when not empty, are the only accounts the token may act on.
+	AccountIDs []int64 `json:"account_ids,omitempty"`
+	// MaxTransferAmount, when positive, caps a single transfer made with the
+	// token.
+	MaxTransferAmount int64 `json:"max_transfer_amount,omitempty"`
+	// ClientID is the OAuth client the token was issued to, empty for the
+	// user's own sessions.
+	ClientID string `json:"client_id,omitempty"`
+	// Purpose is empty for access tokens. Tokens with a purpose are only
+	// accepted by the endpoint they were issued for.
+	Purpose string `json:"purpose,omitempty"`
+	// MFAVerifiedAt is when the user last entered a second factor in this
+	// session, zero if they have not.
+	MFAVerifiedAt time.Time `json:"mfa_verified_at"`
 }
 
 func NewPayload(username string, duration time.Duration) (*Payload, error) {
@@ -25,17 +58,132 @@ func NewPayload(username string, duration time.Duration) (*Payload, error) {
 		return nil, err
 	}
 
+	sessionID, err := uuid.NewRandom()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	return &Payload{
 		ID:        tokenId,
 		Username:  username,
-		IssuedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(duration),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(duration),
+		NotBefore: now,
+		SessionID: sessionID,
 	}, nil
 }
 
-func (p *Payload) Valid() error {
-	if time.Now().After(p.ExpiresAt) {
+// Claims are the issuer and audience a maker stamps on the tokens it creates
+// and requires of the tokens it verifies. Empty fields are not checked.
+type Claims struct {
+	Issuer   string
+	Audience string
+}
+
+// Option configures the claims of a maker.
+type Option func(*Claims)
+
+func WithIssuer(issuer string) Option {
+	return func(c *Claims) {
+		c.Issuer = issuer
+	}
+}
+
+func WithAudience(audience string) Option {
+	return func(c *Claims) {
+		c.Audience = audience
+	}
+}
+
+func newClaims(opts []Option) Claims {
+	var c Claims
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+// stamp fills in the issuer and audience the payload doesn't set itself.
+func (c Claims) stamp(p *Payload) {
+	if p.Issuer == "" {
+		p.Issuer = c.Issuer
+	}
+
+	if len(p.Audience) == 0 && c.Audience != "" {
+		p.Audience = []string{c.Audience}
+	}
+}
+
+// Valid checks the payload's times and that it carries the expected claims.
+func (p *Payload) Valid(expected Claims) error {
+	now := time.Now()
+	if now.After(p.ExpiresAt) {
 		return ErrExpiredToken
 	}
+
+	if now.Add(clockSkew).Before(p.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+
+	if expected.Issuer != "" && p.Issuer != expected.Issuer {
+		return ErrInvalidIssuer
+	}
+
+	if expected.Audience != "" && !p.HasAudience(expected.Audience) {
+		return ErrInvalidAudience
+	}
+
 	return nil
 }
+
+func (p *Payload) HasAudience(audience string) bool {
+	for _, a := range p.Audience {
+		if a == audience {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasScopes reports whether the token was granted every one of scopes.
+func (p *Payload) HasScopes(scopes ...string) bool {
+	for _, scope := range scopes {
+		granted := false
+		for _, s := range p.Scopes {
+			if s == scope {
+				granted = true
+				break
+			}
+		}
+
+		if !granted {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CanUseAccount reports whether the token may act on the account. It does not
+// check who owns the account.
+func (p *Payload) CanUseAccount(accountID int64) bool {
+	if len(p.AccountIDs) == 0 {
+		return true
+	}
+
+	for _, id := range p.AccountIDs {
+		if id == accountID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CanTransfer reports whether the token may make a transfer of amount.
+func (p *Payload) CanTransfer(amount int64) bool {
+	return p.MaxTransferAmount <= 0 || amount <= p.MaxTransferAmount
+}
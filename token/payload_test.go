@@ -0,0 +1,112 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestMakers(t *testing.T, opts ...Option) map[string]Maker {
+	local, err := NewPasetoMaker(util.RandomString(32), opts...)
+	require.NoError(t, err)
+
+	jwtMaker, err := NewJWTMaker(util.RandomString(32), opts...)
+	require.NoError(t, err)
+
+	keys, _ := newTestKeyRing(t, "k1")
+	public, err := NewPasetoPublicMaker(keys, opts...)
+	require.NoError(t, err)
+
+	return map[string]Maker{"PasetoLocal": local, "JWT": jwtMaker, "PasetoPublic": public}
+}
+
+func TestPayloadClaims(t *testing.T) {
+	makers := newTestMakers(t, WithIssuer("demo-bank"), WithAudience("demo-bank-api"))
+
+	for name, maker := range makers {
+		t.Run(name, func(t *testing.T) {
+			payload, err := NewPayload(util.RandomOnwer(), time.Minute)
+			require.NoError(t, err)
+			payload.Role = "customer"
+			payload.Scopes = []string{"accounts:read", "webhooks"}
+
+			accessToken, err := maker.CreateTokenFromPayload(payload)
+			require.NoError(t, err)
+
+			got, err := maker.VerifyToken(accessToken)
+			require.NoError(t, err)
+			require.Equal(t, "demo-bank", got.Issuer)
+			require.Equal(t, []string{"demo-bank-api"}, got.Audience)
+			require.Equal(t, payload.SessionID, got.SessionID)
+			require.Equal(t, "customer", got.Role)
+			require.Equal(t, payload.Scopes, got.Scopes)
+			require.WithinDuration(t, payload.NotBefore, got.NotBefore, time.Second)
+
+			// tokens for another audience or from another issuer are rejected
+			payload.Audience = []string{"reporting"}
+			accessToken, err = maker.CreateTokenFromPayload(payload)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(accessToken)
+			require.ErrorIs(t, err, ErrInvalidAudience)
+
+			payload.Audience = nil
+			payload.Issuer = "someone-else"
+			accessToken, err = maker.CreateTokenFromPayload(payload)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(accessToken)
+			require.ErrorIs(t, err, ErrInvalidIssuer)
+
+			payload.Issuer = ""
+			payload.NotBefore = time.Now().Add(time.Hour)
+			accessToken, err = maker.CreateTokenFromPayload(payload)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(accessToken)
+			require.ErrorIs(t, err, ErrTokenNotYetValid)
+		})
+	}
+}
+
+func TestPayloadClaimsNotRequired(t *testing.T) {
+	for name, maker := range newTestMakers(t) {
+		t.Run(name, func(t *testing.T) {
+			payload, err := NewPayload(util.RandomOnwer(), time.Minute)
+			require.NoError(t, err)
+			payload.Audience = []string{"anyone"}
+			payload.NotBefore = time.Now().Add(clockSkew / 2)
+
+			accessToken, err := maker.CreateTokenFromPayload(payload)
+			require.NoError(t, err)
+
+			_, err = maker.VerifyToken(accessToken)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestHasScopes(t *testing.T) {
+	payload := &Payload{Scopes: []string{"accounts:read", "webhooks"}}
+
+	require.True(t, payload.HasScopes())
+	require.True(t, payload.HasScopes("webhooks"))
+	require.True(t, payload.HasScopes("accounts:read", "webhooks"))
+	require.False(t, payload.HasScopes("accounts:read", "admin"))
+	require.False(t, (&Payload{}).HasScopes("webhooks"))
+}
+
+func TestCanUseAccount(t *testing.T) {
+	require.True(t, (&Payload{}).CanUseAccount(7))
+
+	payload := &Payload{AccountIDs: []int64{3, 7}}
+	require.True(t, payload.CanUseAccount(7))
+	require.False(t, payload.CanUseAccount(4))
+}
+
+func TestCanTransfer(t *testing.T) {
+	require.True(t, (&Payload{}).CanTransfer(1_000_000))
+
+	payload := &Payload{MaxTransferAmount: 500}
+	require.True(t, payload.CanTransfer(500))
+	require.False(t, payload.CanTransfer(501))
+}
@@ -0,0 +1,173 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aidanwoods.dev/go-paseto"
+	"golang.org/x/crypto/blake2b"
+)
+
+const (
+	paserkPublicPrefix = "k4.public."
+	paserkIDPrefix     = "k4.pid."
+)
+
+var errInvalidPublicKey = errors.New("invalid public key")
+
+// PublicKey is one of the keys v4.public tokens are verified with, under the
+// ID tokens name it by in their footer.
+type PublicKey struct {
+	ID  string
+	Key ed25519.PublicKey
+}
+
+// KeySource is implemented by makers whose tokens can be verified with
+// public keys.
+type KeySource interface {
+	PublicKeys() []PublicKey
+}
+
+// PublicKeys lists the keys tokens are accepted from, ordered by ID.
+func (r *KeyRing) PublicKeys() []PublicKey {
+	keys := make([]PublicKey, 0, len(r.keys))
+	for _, id := range r.KeyIDs() {
+		keys = append(keys, PublicKey{ID: id, Key: r.keys[id].ExportBytes()})
+	}
+
+	return keys
+}
+
+// AddPublicKey accepts tokens signed by key.
+func (r *KeyRing) AddPublicKey(key PublicKey) error {
+	publicKey, err := paseto.NewV4AsymmetricPublicKeyFromEd25519(key.Key)
+	if err != nil {
+		return fmt.Errorf("invalid verification key %q: %w", key.ID, err)
+	}
+
+	return r.addKey(key.ID, publicKey)
+}
+
+func (maker *PasetoPublicMaker) PublicKeys() []PublicKey {
+	return maker.keys.PublicKeys()
+}
+
+// JWK is an Ed25519 public key as a JSON Web Key (RFC 8037).
+type JWK struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+}
+
+// JWKS is a JSON Web Key Set, served at /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewJWKS(keys []PublicKey) JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(keys))}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, JWK{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(key.Key),
+			KeyID:   key.ID,
+			Use:     "sig",
+		})
+	}
+
+	return set
+}
+
+// PublicKeys reads the Ed25519 signing keys of the set.
+func (s JWKS) PublicKeys() ([]PublicKey, error) {
+	keys := make([]PublicKey, 0, len(s.Keys))
+	for _, jwk := range s.Keys {
+		if jwk.KeyType != "OKP" || jwk.Curve != "Ed25519" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		key, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(key) != ed25519.PublicKeySize || jwk.KeyID == "" {
+			return nil, fmt.Errorf("key %q: %w", jwk.KeyID, errInvalidPublicKey)
+		}
+
+		keys = append(keys, PublicKey{ID: jwk.KeyID, Key: key})
+	}
+
+	return keys, nil
+}
+
+// PASERK serializes the key as a k4.public PASERK.
+func (k PublicKey) PASERK() string {
+	return paserkPublicPrefix + base64.RawURLEncoding.EncodeToString(k.Key)
+}
+
+// PASERKID is the k4.pid PASERK identifying the key, which unlike ID is
+// derived from the key itself.
+func (k PublicKey) PASERKID() string {
+	hash, _ := blake2b.New(33, nil)
+	hash.Write([]byte(paserkIDPrefix))
+	hash.Write([]byte(k.PASERK()))
+	return paserkIDPrefix + base64.RawURLEncoding.EncodeToString(hash.Sum(nil))
+}
+
+// ParsePASERK reads a k4.public PASERK.
+func ParsePASERK(s string) (ed25519.PublicKey, error) {
+	data, ok := strings.CutPrefix(s, paserkPublicPrefix)
+	if !ok {
+		return nil, errInvalidPublicKey
+	}
+
+	key, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, errInvalidPublicKey
+	}
+
+	return key, nil
+}
+
+// PASERKKey is an entry of the PASERK key set served at
+// /.well-known/paserk.json.
+type PASERKKey struct {
+	KeyID  string `json:"kid"`
+	PID    string `json:"pid"`
+	PASERK string `json:"paserk"`
+}
+
+type PASERKSet struct {
+	Keys []PASERKKey `json:"keys"`
+}
+
+func NewPASERKSet(keys []PublicKey) PASERKSet {
+	set := PASERKSet{Keys: make([]PASERKKey, 0, len(keys))}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, PASERKKey{
+			KeyID:  key.ID,
+			PID:    key.PASERKID(),
+			PASERK: key.PASERK(),
+		})
+	}
+
+	return set
+}
+
+// PublicKeys reads the keys of the set.
+func (s PASERKSet) PublicKeys() ([]PublicKey, error) {
+	keys := make([]PublicKey, 0, len(s.Keys))
+	for _, entry := range s.Keys {
+		key, err := ParsePASERK(entry.PASERK)
+		if err != nil || entry.KeyID == "" {
+			return nil, fmt.Errorf("key %q: %w", entry.KeyID, errInvalidPublicKey)
+		}
+
+		keys = append(keys, PublicKey{ID: entry.KeyID, Key: key})
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,56 @@
+package token
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeySets(t *testing.T) {
+	keys, _ := newTestKeyRing(t, "new")
+	_, oldPublicKey := GenerateSigningKey()
+	require.NoError(t, keys.AddVerificationKey("old", oldPublicKey))
+
+	maker, err := NewPasetoPublicMaker(keys)
+	require.NoError(t, err)
+
+	publicKeys := maker.(KeySource).PublicKeys()
+	require.Len(t, publicKeys, 2)
+	require.Equal(t, "new", publicKeys[0].ID)
+	require.Equal(t, "old", publicKeys[1].ID)
+
+	jwks := NewJWKS(publicKeys)
+	require.Equal(t, "OKP", jwks.Keys[0].KeyType)
+	require.Equal(t, "Ed25519", jwks.Keys[0].Curve)
+
+	got, err := jwks.PublicKeys()
+	require.NoError(t, err)
+	require.Equal(t, publicKeys, got)
+
+	paserks := NewPASERKSet(publicKeys)
+	require.True(t, strings.HasPrefix(paserks.Keys[0].PASERK, "k4.public."))
+	require.True(t, strings.HasPrefix(paserks.Keys[0].PID, "k4.pid."))
+	require.NotEqual(t, paserks.Keys[0].PID, paserks.Keys[1].PID)
+	require.Equal(t, publicKeys[0].PASERKID(), paserks.Keys[0].PID)
+
+	got, err = paserks.PublicKeys()
+	require.NoError(t, err)
+	require.Equal(t, publicKeys, got)
+}
+
+func TestParsePublicKeys(t *testing.T) {
+	_, err := ParsePASERK("k4.local.AAAA")
+	require.Error(t, err)
+
+	_, err = ParsePASERK("k4.public.AAAA")
+	require.Error(t, err)
+
+	_, err = JWKS{Keys: []JWK{{KeyType: "OKP", Curve: "Ed25519", X: "AAAA", KeyID: "k1"}}}.PublicKeys()
+	require.Error(t, err)
+
+	// keys that can't verify tokens are skipped
+	keys, err := JWKS{Keys: []JWK{{KeyType: "RSA", KeyID: "rsa"}}}.PublicKeys()
+	require.NoError(t, err)
+	require.Empty(t, keys)
+}
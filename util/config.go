@@ -12,6 +12,81 @@ type Config struct {
 	ServerAddress     string        `mapstructure:"SERVER_ADDRESS"`
 	TokenSymmetricKey string        `mapstructure:"TOKEN_SYMETRIC_KEY"`
 	TokenDuration     time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	TokenMaker        string        `mapstructure:"TOKEN_MAKER"`
+	TokenIssuer       string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience     string        `mapstructure:"TOKEN_AUDIENCE"`
+	MigrateOnStartup  bool          `mapstructure:"MIGRATE_ON_STARTUP"`
+	FeeScheduleFile   string        `mapstructure:"FEE_SCHEDULE_FILE"`
+	PublicBaseURL     string        `mapstructure:"PUBLIC_BASE_URL"`
+
+	// used by TOKEN_MAKER=paseto_public; verification keys are "<id>:<hex>"
+	TokenSigningKeyID     string   `mapstructure:"TOKEN_SIGNING_KEY_ID"`
+	TokenSigningKey       string   `mapstructure:"TOKEN_SIGNING_KEY"`
+	TokenVerificationKeys []string `mapstructure:"TOKEN_VERIFICATION_KEYS"`
+	TokenRetiredKeyIDs    []string `mapstructure:"TOKEN_RETIRED_KEY_IDS"`
+
+	ReconciliationInterval  time.Duration `mapstructure:"RECONCILIATION_INTERVAL"`
+	ReconciliationBatchSize int32         `mapstructure:"RECONCILIATION_BATCH_SIZE"`
+
+	InterestInterval  time.Duration `mapstructure:"INTEREST_INTERVAL"`
+	InterestBatchSize int32         `mapstructure:"INTEREST_BATCH_SIZE"`
+
+	OutboxSink      string        `mapstructure:"OUTBOX_SINK"`
+	OutboxTarget    string        `mapstructure:"OUTBOX_TARGET"`
+	OutboxInterval  time.Duration `mapstructure:"OUTBOX_INTERVAL"`
+	OutboxBatchSize int32         `mapstructure:"OUTBOX_BATCH_SIZE"`
+
+	WebhookInterval    time.Duration `mapstructure:"WEBHOOK_INTERVAL"`
+	WebhookBatchSize   int32         `mapstructure:"WEBHOOK_BATCH_SIZE"`
+	WebhookMaxAttempts int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+
+	DataExportInterval  time.Duration `mapstructure:"DATA_EXPORT_INTERVAL"`
+	DataExportBatchSize int32         `mapstructure:"DATA_EXPORT_BATCH_SIZE"`
+	DataExportRetention time.Duration `mapstructure:"DATA_EXPORT_RETENTION"`
+
+	Mailer       string `mapstructure:"MAILER"`
+	MailerTarget string `mapstructure:"MAILER_TARGET"`
+	MailFrom     string `mapstructure:"MAIL_FROM"`
+	SMTPHost     string `mapstructure:"SMTP_HOST"`
+	SMTPPort     int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword string `mapstructure:"SMTP_PASSWORD"`
+
+	EmailVerificationDuration time.Duration `mapstructure:"EMAIL_VERIFICATION_DURATION"`
+	PasswordResetDuration     time.Duration `mapstructure:"PASSWORD_RESET_DURATION"`
+
+	// PASSWORD_HASH_ALGORITHM is argon2id or bcrypt; logins rehash passwords
+	// stored with another algorithm or cost
+	PasswordHashAlgorithm     string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	PasswordArgon2Memory      uint32 `mapstructure:"PASSWORD_ARGON2_MEMORY"`
+	PasswordArgon2Iterations  uint32 `mapstructure:"PASSWORD_ARGON2_ITERATIONS"`
+	PasswordArgon2Parallelism uint8  `mapstructure:"PASSWORD_ARGON2_PARALLELISM"`
+	PasswordBcryptCost        int    `mapstructure:"PASSWORD_BCRYPT_COST"`
+	PasswordMinLength         int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength         int    `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordBreachedListFile  string `mapstructure:"PASSWORD_BREACHED_LIST_FILE"`
+
+	MFAStepUpAmount int64         `mapstructure:"MFA_STEP_UP_AMOUNT"`
+	MFAStepUpWindow time.Duration `mapstructure:"MFA_STEP_UP_WINDOW"`
+
+	LoginMaxFailures     int32         `mapstructure:"LOGIN_MAX_FAILURES"`
+	LoginMaxIPFailures   int32         `mapstructure:"LOGIN_MAX_IP_FAILURES"`
+	LoginFailureWindow   time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	LoginLockoutDuration time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+
+	// proxies whose X-Forwarded-For is believed for the client IP; none by
+	// default, so rate limits and lockouts key on the connecting address
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// rate limits are "<limit>/<period>", e.g. "10/1m"; empty disables one
+	RateLimitStore         string `mapstructure:"RATE_LIMIT_STORE"`
+	RateLimitPublic        string `mapstructure:"RATE_LIMIT_PUBLIC"`
+	RateLimitLogin         string `mapstructure:"RATE_LIMIT_LOGIN"`
+	RateLimitAuthenticated string `mapstructure:"RATE_LIMIT_AUTHENTICATED"`
+	RateLimitTransfers     string `mapstructure:"RATE_LIMIT_TRANSFERS"`
+
+	OAuthCodeDuration         time.Duration `mapstructure:"OAUTH_CODE_DURATION"`
+	OAuthRefreshTokenDuration time.Duration `mapstructure:"OAUTH_REFRESH_TOKEN_DURATION"`
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -1,20 +1,204 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+const (
+	PasswordAlgorithmArgon2id = "argon2id"
+	PasswordAlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrMismatchedPassword  = errors.New("password does not match")
+	ErrUnknownPasswordHash = errors.New("password hash is in an unknown format")
+)
+
+const (
+	argon2SaltLength       = 16
+	argon2KeyLength        = 32
+	bcryptMaxPasswordBytes = 72
+)
+
+// PasswordParams are the algorithm and cost new password hashes are made
+// with. Memory is in KiB; Memory, Iterations and Parallelism only apply to
+// argon2id, BcryptCost only to bcrypt.
+type PasswordParams struct {
+	Algorithm   string
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	BcryptCost  int
+}
+
+// DefaultPasswordParams follow the OWASP recommendation for argon2id.
+var DefaultPasswordParams = PasswordParams{
+	Algorithm:   PasswordAlgorithmArgon2id,
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	BcryptCost:  12,
+}
+
+// PasswordHasher hashes passwords into PHC strings, like
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>, and verifies passwords against
+// them. bcrypt hashes keep their own $2a$ format, so hashes made before
+// argon2id was introduced still verify.
+type PasswordHasher struct {
+	params PasswordParams
+}
+
+// NewPasswordHasher returns a hasher making hashes with params. Zero fields
+// take their value from DefaultPasswordParams.
+func NewPasswordHasher(params PasswordParams) (*PasswordHasher, error) {
+	if params.Algorithm == "" {
+		params.Algorithm = DefaultPasswordParams.Algorithm
+	}
+	if params.Memory == 0 {
+		params.Memory = DefaultPasswordParams.Memory
+	}
+	if params.Iterations == 0 {
+		params.Iterations = DefaultPasswordParams.Iterations
+	}
+	if params.Parallelism == 0 {
+		params.Parallelism = DefaultPasswordParams.Parallelism
+	}
+	if params.BcryptCost == 0 {
+		params.BcryptCost = DefaultPasswordParams.BcryptCost
+	}
+
+	switch params.Algorithm {
+	case PasswordAlgorithmArgon2id:
+	case PasswordAlgorithmBcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", params.Algorithm)
+	}
+
+	return &PasswordHasher{params: params}, nil
+}
+
+var defaultPasswordHasher = &PasswordHasher{params: DefaultPasswordParams}
+
+// Hash returns the PHC string of password. bcrypt only looks at the first 72
+// bytes of a password, so it refuses longer ones rather than truncate them.
+func (h *PasswordHasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == PasswordAlgorithmBcrypt {
+		if len(password) > bcryptMaxPasswordBytes {
+			return "", fmt.Errorf("%w: %w", ErrPasswordTooLong, bcrypt.ErrPasswordTooLong)
+		}
+
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("cannot hash password: %w", err)
+		}
+
+		return string(hashed), nil
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("cannot hash password: %w", err)
 	}
 
-	return string(hashedPassword), nil
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, argon2KeyLength)
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.params.Memory, h.params.Iterations, h.params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify checks password against hashed. rehash reports whether hashed was
+// made with another algorithm or cost than the hasher's, so the caller should
+// store a fresh hash while it has the password at hand.
+func (h *PasswordHasher) Verify(password string, hashed string) (rehash bool, err error) {
+	if strings.HasPrefix(hashed, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hashed)
+		if err != nil {
+			return false, err
+		}
+
+		got := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return false, ErrMismatchedPassword
+		}
+
+		rehash = h.params.Algorithm != PasswordAlgorithmArgon2id ||
+			params.Memory != h.params.Memory ||
+			params.Iterations != h.params.Iterations ||
+			params.Parallelism != h.params.Parallelism ||
+			len(salt) != argon2SaltLength ||
+			len(key) != argon2KeyLength
+		return rehash, nil
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashed))
+	if err != nil {
+		return false, ErrUnknownPasswordHash
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, ErrMismatchedPassword
+		}
+
+		return false, err
+	}
+
+	rehash = h.params.Algorithm != PasswordAlgorithmBcrypt || cost != h.params.BcryptCost
+	return rehash, nil
+}
+
+func decodeArgon2id(hashed string) (params PasswordParams, salt []byte, key []byte, err error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	params.Algorithm = PasswordAlgorithmArgon2id
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil || params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+
+	return params, salt, key, nil
+}
+
+// HashPassword hashes password with DefaultPasswordParams.
+func HashPassword(password string) (string, error) {
+	return defaultPasswordHasher.Hash(password)
 }
 
+// ComparePasswords checks password against a hash in any supported format.
 func ComparePasswords(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	_, err := defaultPasswordHasher.Verify(password, hashedPassword)
+	return err
 }
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrPasswordTooShort        = errors.New("password is too short")
+	ErrPasswordTooLong         = errors.New("password is too long")
+	ErrPasswordBreached        = errors.New("password appears in a list of breached passwords")
+	ErrPasswordMatchesIdentity = errors.New("password must not be the username or email address")
+)
+
+//go:embed common_passwords.txt
+var commonPasswords []byte
+
+// PasswordPolicy decides which passwords users may pick. Lengths are counted
+// in characters; MaxBytes, when set, also caps the encoded length.
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	MaxBytes  int
+	breached  map[string]struct{}
+}
+
+// NewPasswordPolicy returns a policy rejecting the most common passwords and,
+// unless breachedListFile is empty, the ones listed in that file. Zero lengths
+// default to 8 and 128. algorithm is the one new hashes are made with: bcrypt
+// caps passwords at 72 bytes, so the policy does too.
+func NewPasswordPolicy(minLength int, maxLength int, algorithm string, breachedListFile string) (*PasswordPolicy, error) {
+	if minLength == 0 {
+		minLength = 8
+	}
+	if maxLength == 0 {
+		maxLength = 128
+	}
+
+	maxBytes := 0
+	if algorithm == PasswordAlgorithmBcrypt {
+		maxBytes = bcryptMaxPasswordBytes
+		if maxLength > maxBytes {
+			maxLength = maxBytes
+		}
+	}
+
+	if minLength > maxLength {
+		return nil, fmt.Errorf("password min length %d exceeds max length %d", minLength, maxLength)
+	}
+
+	policy := &PasswordPolicy{
+		MinLength: minLength,
+		MaxLength: maxLength,
+		MaxBytes:  maxBytes,
+		breached:  make(map[string]struct{}),
+	}
+
+	err := policy.addBreached(bytes.NewReader(commonPasswords))
+	if err != nil {
+		return nil, err
+	}
+
+	if breachedListFile == "" {
+		return policy, nil
+	}
+
+	f, err := os.Open(breachedListFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read breached password list: %w", err)
+	}
+	defer f.Close()
+
+	err = policy.addBreached(f)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read breached password list: %w", err)
+	}
+
+	return policy, nil
+}
+
+func (p *PasswordPolicy) addBreached(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p.breached[strings.ToLower(line)] = struct{}{}
+	}
+
+	return scanner.Err()
+}
+
+// Check returns the first rule password breaks. identities are what the
+// password must not equal, like the username and email address; empty ones
+// are ignored.
+func (p *PasswordPolicy) Check(password string, identities ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("%w: use at least %d characters", ErrPasswordTooShort, p.MinLength)
+	}
+
+	if length > p.MaxLength {
+		return fmt.Errorf("%w: use at most %d characters", ErrPasswordTooLong, p.MaxLength)
+	}
+
+	if p.MaxBytes > 0 && len(password) > p.MaxBytes {
+		return fmt.Errorf("%w: use at most %d bytes", ErrPasswordTooLong, p.MaxBytes)
+	}
+
+	for _, identity := range identities {
+		if identity != "" && strings.EqualFold(password, identity) {
+			return ErrPasswordMatchesIdentity
+		}
+	}
+
+	if _, ok := p.breached[strings.ToLower(password)]; ok {
+		return ErrPasswordBreached
+	}
+
+	return nil
+}
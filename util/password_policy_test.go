@@ -0,0 +1,70 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	policy, err := NewPasswordPolicy(10, 20, PasswordAlgorithmArgon2id, "")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		password string
+		err      error
+	}{
+		{name: "TooLong", password: "correct horse battery", err: ErrPasswordTooLong},
+		{name: "OK", password: "horse battery staple"},
+		{name: "TooShort", password: "short", err: ErrPasswordTooShort},
+		{name: "MultibyteCountsOnce", password: "ééééééééé", err: ErrPasswordTooShort},
+		{name: "Breached", password: "Password123", err: ErrPasswordBreached},
+		{name: "Username", password: "AliceWonder", err: ErrPasswordMatchesIdentity},
+		{name: "Email", password: "alice@ex.com", err: ErrPasswordMatchesIdentity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.password, "alicewonder", "Alice@ex.com")
+			if tc.err == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestPasswordPolicyBreachedListFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# leaked\nCorrectHorse42\n\n"), 0o600))
+
+	policy, err := NewPasswordPolicy(0, 0, "", path)
+	require.NoError(t, err)
+	require.Equal(t, 8, policy.MinLength)
+
+	require.ErrorIs(t, policy.Check("correcthorse42"), ErrPasswordBreached)
+	require.ErrorIs(t, policy.Check("qwerty123"), ErrPasswordBreached)
+	require.NoError(t, policy.Check("CorrectHorse43"))
+
+	_, err = NewPasswordPolicy(0, 0, "", filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestPasswordPolicyBcrypt(t *testing.T) {
+	policy, err := NewPasswordPolicy(10, 128, PasswordAlgorithmBcrypt, "")
+	require.NoError(t, err)
+	require.Equal(t, 72, policy.MaxLength)
+
+	require.ErrorIs(t, policy.Check(strings.Repeat("x", 73)), ErrPasswordTooLong)
+	require.ErrorIs(t, policy.Check(strings.Repeat("é", 40)), ErrPasswordTooLong)
+	require.NoError(t, policy.Check(strings.Repeat("é", 36)))
+
+	_, err = NewPasswordPolicy(80, 128, PasswordAlgorithmBcrypt, "")
+	require.Error(t, err)
+}
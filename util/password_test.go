@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -34,3 +35,79 @@ func TestHashPasswordShouldBeDifferent(t *testing.T) {
 
 	require.NotEqual(t, hashedPassword1, hashedPassword2)
 }
+
+func TestPasswordHasherFormats(t *testing.T) {
+	argon, err := NewPasswordHasher(PasswordParams{Algorithm: PasswordAlgorithmArgon2id, Memory: 1024, Iterations: 1, Parallelism: 1})
+	require.NoError(t, err)
+
+	legacy, err := NewPasswordHasher(PasswordParams{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	require.NoError(t, err)
+
+	password := RandomString(12)
+
+	hashed, err := argon.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	rehash, err := argon.Verify(password, hashed)
+	require.NoError(t, err)
+	require.False(t, rehash)
+
+	_, err = argon.Verify(RandomString(12), hashed)
+	require.ErrorIs(t, err, ErrMismatchedPassword)
+
+	legacyHash, err := legacy.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(legacyHash, "$2a$"))
+
+	// hashes made with another algorithm or cost verify but ask for a rehash
+	rehash, err = argon.Verify(password, legacyHash)
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	rehash, err = legacy.Verify(password, hashed)
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	stronger, err := NewPasswordHasher(PasswordParams{Algorithm: PasswordAlgorithmArgon2id, Memory: 2048, Iterations: 1, Parallelism: 1})
+	require.NoError(t, err)
+
+	rehash, err = stronger.Verify(password, hashed)
+	require.NoError(t, err)
+	require.True(t, rehash)
+
+	_, err = argon.Verify(password, "$argon2id$v=19$m=1024$bad")
+	require.ErrorIs(t, err, ErrUnknownPasswordHash)
+
+	_, err = argon.Verify(password, "plaintext")
+	require.ErrorIs(t, err, ErrUnknownPasswordHash)
+}
+
+func TestPasswordHasherLongPasswords(t *testing.T) {
+	password := strings.Repeat("a", 72)
+
+	argon, err := NewPasswordHasher(PasswordParams{Memory: 1024, Iterations: 1})
+	require.NoError(t, err)
+
+	hashed, err := argon.Hash(password + "b")
+	require.NoError(t, err)
+
+	// unlike bcrypt, argon2id looks at every byte
+	_, err = argon.Verify(password+"c", hashed)
+	require.ErrorIs(t, err, ErrMismatchedPassword)
+
+	legacy, err := NewPasswordHasher(PasswordParams{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	require.NoError(t, err)
+
+	_, err = legacy.Hash(password + "b")
+	require.ErrorIs(t, err, bcrypt.ErrPasswordTooLong)
+	require.ErrorIs(t, err, ErrPasswordTooLong)
+}
+
+func TestNewPasswordHasherInvalid(t *testing.T) {
+	_, err := NewPasswordHasher(PasswordParams{Algorithm: "md5"})
+	require.Error(t, err)
+
+	_, err = NewPasswordHasher(PasswordParams{Algorithm: PasswordAlgorithmBcrypt, BcryptCost: 40})
+	require.Error(t, err)
+}
@@ -0,0 +1,215 @@
+// Package verifier lets other services check demo-bank access tokens. It
+// fetches the public keys the API publishes at /.well-known/jwks.json (or
+// /.well-known/paserk.json), caches them and verifies v4.public tokens with
+// them, so the services never hold a signing secret.
+package verifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aulas/demo-bank/token"
+)
+
+const (
+	// DefaultMaxAge is how long fetched keys are used before being fetched
+	// again.
+	DefaultMaxAge = time.Hour
+	// DefaultMinRefreshInterval is how often tokens signed with unknown keys
+	// can make the client fetch the keys again, so a flood of forged tokens
+	// can't turn into a flood of requests to the API.
+	DefaultMinRefreshInterval = time.Minute
+
+	maxKeySetSize = 1 << 20
+)
+
+// ErrNotAccessToken is returned for tokens issued for a single purpose, such
+// as the MFA challenge, which only the API itself accepts.
+var ErrNotAccessToken = errors.New("token is not an access token")
+
+type Config struct {
+	// URL is where the key set is served, e.g.
+	// https://bank.example/.well-known/jwks.json.
+	URL string
+	// Issuer and Audience, when set, must match the tokens' claims; they
+	// should match TOKEN_ISSUER and TOKEN_AUDIENCE of the API.
+	Issuer             string
+	Audience           string
+	HTTPClient         *http.Client
+	MaxAge             time.Duration
+	MinRefreshInterval time.Duration
+}
+
+// Client verifies tokens with keys fetched from URL. It implements
+// token.Verifier.
+type Client struct {
+	url                string
+	claims             []token.Option
+	httpClient         *http.Client
+	maxAge             time.Duration
+	minRefreshInterval time.Duration
+	now                func() time.Time
+
+	mu          sync.Mutex
+	verifier    token.Verifier
+	fetchedAt   time.Time
+	lastAttempt time.Time
+	lastErr     error
+}
+
+func New(cfg Config) *Client {
+	c := &Client{
+		url:                cfg.URL,
+		claims:             []token.Option{token.WithIssuer(cfg.Issuer), token.WithAudience(cfg.Audience)},
+		httpClient:         cfg.HTTPClient,
+		maxAge:             cfg.MaxAge,
+		minRefreshInterval: cfg.MinRefreshInterval,
+		now:                time.Now,
+	}
+
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if c.maxAge <= 0 {
+		c.maxAge = DefaultMaxAge
+	}
+	if c.minRefreshInterval <= 0 {
+		c.minRefreshInterval = DefaultMinRefreshInterval
+	}
+
+	return c
+}
+
+// VerifyToken accepts access tokens only.
+func (c *Client) VerifyToken(t string) (*token.Payload, error) {
+	payload, err := c.verify(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.Purpose != "" {
+		return nil, ErrNotAccessToken
+	}
+
+	return payload, nil
+}
+
+func (c *Client) verify(t string) (*token.Payload, error) {
+	verifier, err := c.keys(false)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := verifier.VerifyToken(t)
+	if !errors.Is(err, token.ErrUnknownKey) {
+		return payload, err
+	}
+
+	// the key may be new since the last fetch
+	refreshed, err := c.keys(true)
+	if err != nil || refreshed == verifier {
+		return nil, token.ErrUnknownKey
+	}
+
+	return refreshed.VerifyToken(t)
+}
+
+// keys returns a verifier for the cached keys, fetching them first if there
+// are none, they are older than maxAge, or force is set. Fetches are at least
+// minRefreshInterval apart; until the next one is allowed, the cached keys
+// are used.
+func (c *Client) keys(force bool) (token.Verifier, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if c.verifier != nil && !force && now.Sub(c.fetchedAt) < c.maxAge {
+		return c.verifier, nil
+	}
+
+	if !c.lastAttempt.IsZero() && now.Sub(c.lastAttempt) < c.minRefreshInterval {
+		if c.verifier != nil {
+			return c.verifier, nil
+		}
+
+		return nil, c.lastErr
+	}
+
+	c.lastAttempt = now
+	verifier, err := c.fetch()
+	if err != nil {
+		c.lastErr = fmt.Errorf("cannot fetch verification keys: %w", err)
+		if c.verifier != nil {
+			log.Printf("%v, using cached keys", c.lastErr)
+			return c.verifier, nil
+		}
+
+		return nil, c.lastErr
+	}
+
+	c.verifier = verifier
+	c.fetchedAt = now
+	return verifier, nil
+}
+
+func (c *Client) fetch() (token.Verifier, error) {
+	request, err := http.NewRequest(http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", c.url, response.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxKeySetSize))
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := parseKeySet(body)
+	if err != nil {
+		return nil, err
+	}
+
+	ring := token.NewKeyRing()
+	for _, key := range keys {
+		if err := ring.AddPublicKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return token.NewPasetoPublicMaker(ring, c.claims...)
+}
+
+// parseKeySet reads either a PASERK set or a JWKS.
+func parseKeySet(body []byte) ([]token.PublicKey, error) {
+	var paserks token.PASERKSet
+	if err := json.Unmarshal(body, &paserks); err != nil {
+		return nil, err
+	}
+
+	if len(paserks.Keys) > 0 && paserks.Keys[0].PASERK != "" {
+		return paserks.PublicKeys()
+	}
+
+	var jwks token.JWKS
+	if err := json.Unmarshal(body, &jwks); err != nil {
+		return nil, err
+	}
+
+	return jwks.PublicKeys()
+}
@@ -0,0 +1,203 @@
+package verifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aulas/demo-bank/token"
+	"github.com/aulas/demo-bank/util"
+	"github.com/stretchr/testify/require"
+)
+
+type keyServer struct {
+	*httptest.Server
+	keys   atomic.Value // []token.PublicKey
+	hits   atomic.Int32
+	fail   atomic.Bool
+	paserk bool
+}
+
+func newKeyServer(t *testing.T, paserk bool) *keyServer {
+	s := &keyServer{paserk: paserk}
+	s.keys.Store([]token.PublicKey{})
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		if s.fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		keys := s.keys.Load().([]token.PublicKey)
+		var body any = token.NewJWKS(keys)
+		if s.paserk {
+			body = token.NewPASERKSet(keys)
+		}
+
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newSigner(t *testing.T, id string) token.Maker {
+	secretKey, _ := token.GenerateSigningKey()
+	keys := token.NewKeyRing()
+	require.NoError(t, keys.SetSigningKey(id, secretKey))
+
+	maker, err := token.NewPasetoPublicMaker(keys)
+	require.NoError(t, err)
+	return maker
+}
+
+func publicKeys(maker token.Maker) []token.PublicKey {
+	return maker.(token.KeySource).PublicKeys()
+}
+
+func TestClientVerifiesTokens(t *testing.T) {
+	for _, paserk := range []bool{false, true} {
+		server := newKeyServer(t, paserk)
+		signer := newSigner(t, "k1")
+		server.keys.Store(publicKeys(signer))
+
+		client := New(Config{URL: server.URL})
+		username := util.RandomOnwer()
+		accessToken, err := signer.CreateToken(username, time.Minute)
+		require.NoError(t, err)
+
+		for i := 0; i < 3; i++ {
+			payload, err := client.VerifyToken(accessToken)
+			require.NoError(t, err)
+			require.Equal(t, username, payload.Username)
+		}
+
+		// keys are cached
+		require.Equal(t, int32(1), server.hits.Load())
+
+		expired, err := signer.CreateToken(username, -time.Minute)
+		require.NoError(t, err)
+		_, err = client.VerifyToken(expired)
+		require.ErrorIs(t, err, token.ErrExpiredToken)
+	}
+}
+
+func TestClientRefreshesOnUnknownKey(t *testing.T) {
+	server := newKeyServer(t, false)
+	oldSigner := newSigner(t, "old")
+	server.keys.Store(publicKeys(oldSigner))
+
+	now := time.Now()
+	client := New(Config{URL: server.URL, MinRefreshInterval: time.Minute})
+	client.now = func() time.Time { return now }
+
+	oldToken, err := oldSigner.CreateToken(util.RandomOnwer(), time.Minute)
+	require.NoError(t, err)
+	_, err = client.VerifyToken(oldToken)
+	require.NoError(t, err)
+
+	// the API rotates to a new key
+	rotated := newSigner(t, "new")
+	server.keys.Store(append(publicKeys(rotated), publicKeys(oldSigner)...))
+	now = now.Add(time.Minute)
+
+	newToken, err := rotated.CreateToken(util.RandomOnwer(), time.Minute)
+	require.NoError(t, err)
+	_, err = client.VerifyToken(newToken)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), server.hits.Load())
+
+	// unknown keys don't refetch more than once per interval
+	forged, err := newSigner(t, "forged").CreateToken(util.RandomOnwer(), time.Minute)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = client.VerifyToken(forged)
+		require.ErrorIs(t, err, token.ErrUnknownKey)
+	}
+	require.Equal(t, int32(2), server.hits.Load())
+
+	now = now.Add(time.Minute)
+	_, err = client.VerifyToken(forged)
+	require.ErrorIs(t, err, token.ErrUnknownKey)
+	require.Equal(t, int32(3), server.hits.Load())
+}
+
+func TestClientKeepsKeysWhenRefreshFails(t *testing.T) {
+	server := newKeyServer(t, false)
+	signer := newSigner(t, "k1")
+	server.keys.Store(publicKeys(signer))
+
+	now := time.Now()
+	client := New(Config{URL: server.URL, MaxAge: time.Hour})
+	client.now = func() time.Time { return now }
+
+	accessToken, err := signer.CreateToken(util.RandomOnwer(), 2*time.Hour)
+	require.NoError(t, err)
+	_, err = client.VerifyToken(accessToken)
+	require.NoError(t, err)
+
+	server.fail.Store(true)
+	now = now.Add(time.Hour)
+	_, err = client.VerifyToken(accessToken)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), server.hits.Load())
+}
+
+func TestClientWithoutKeys(t *testing.T) {
+	server := newKeyServer(t, false)
+	server.fail.Store(true)
+
+	client := New(Config{URL: server.URL})
+	_, err := client.VerifyToken("v4.public.whatever")
+	require.ErrorContains(t, err, "cannot fetch verification keys")
+
+	// failures are not retried on every request either
+	_, err = client.VerifyToken("v4.public.whatever")
+	require.Error(t, err)
+	require.Equal(t, int32(1), server.hits.Load())
+}
+
+func TestClientRejectsPurposeTokens(t *testing.T) {
+	server := newKeyServer(t, false)
+	signer := newSigner(t, "k1")
+	server.keys.Store(publicKeys(signer))
+	client := New(Config{URL: server.URL})
+
+	payload, err := token.NewPayload(util.RandomOnwer(), time.Minute)
+	require.NoError(t, err)
+	payload.Purpose = token.PurposeMFAChallenge
+
+	challenge, err := signer.CreateTokenFromPayload(payload)
+	require.NoError(t, err)
+
+	_, err = client.VerifyToken(challenge)
+	require.ErrorIs(t, err, ErrNotAccessToken)
+}
+
+func TestClientChecksIssuerAndAudience(t *testing.T) {
+	server := newKeyServer(t, false)
+	secretKey, _ := token.GenerateSigningKey()
+	keys := token.NewKeyRing()
+	require.NoError(t, keys.SetSigningKey("k1", secretKey))
+
+	signer, err := token.NewPasetoPublicMaker(keys, token.WithIssuer("demo-bank"), token.WithAudience("demo-bank-api"))
+	require.NoError(t, err)
+	server.keys.Store(publicKeys(signer))
+
+	accessToken, err := signer.CreateToken(util.RandomOnwer(), time.Minute)
+	require.NoError(t, err)
+
+	client := New(Config{URL: server.URL, Issuer: "demo-bank", Audience: "demo-bank-api"})
+	_, err = client.VerifyToken(accessToken)
+	require.NoError(t, err)
+
+	client = New(Config{URL: server.URL, Issuer: "someone-else"})
+	_, err = client.VerifyToken(accessToken)
+	require.ErrorIs(t, err, token.ErrInvalidIssuer)
+
+	client = New(Config{URL: server.URL, Audience: "reporting"})
+	_, err = client.VerifyToken(accessToken)
+	require.ErrorIs(t, err, token.ErrInvalidAudience)
+}
@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// newClient returns the client deliveries are sent with. Receivers are
+// customer-supplied URLs, so the client only connects to addresses allowed
+// returns true for and does not follow redirects, which could lead anywhere.
+func newClient(allowed func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialContext(dialer, allowed),
+			TLSHandshakeTimeout: requestTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// dialContext resolves the host itself and dials the resolved address, so the
+// address that was checked is the one connected to.
+func dialContext(dialer *net.Dialer, allowed func(net.IP) bool) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ip := range ips {
+			if !allowed(ip.IP) {
+				return nil, fmt.Errorf("receiver %s resolves to forbidden address %s", host, ip.IP)
+			}
+		}
+
+		return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+	}
+}
+
+// publicAddress reports whether ip is a public unicast address, as opposed to
+// a loopback, private, link-local or otherwise internal one.
+func publicAddress(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !ip.IsLinkLocalUnicast()
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/events"
+)
+
+// Event types customers can subscribe to.
+const (
+	EventTransferReceived = "transfer.received"
+	EventTransferSent     = "transfer.sent"
+	EventAccountCreated   = "account.created"
+)
+
+// Notification is the body POSTed to subscribers. EventID and Type together
+// identify it; receivers should deduplicate on them since a notification can
+// be delivered more than once.
+type Notification struct {
+	EventID    int64           `json:"event_id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Fanout is an outbox sink that turns domain events into webhook deliveries
+// for the subscriptions of the users they concern. Enqueueing is idempotent,
+// so events the relay publishes twice are only delivered once.
+type Fanout struct {
+	store db.Store
+}
+
+func NewFanout(store db.Store) *Fanout {
+	return &Fanout{store: store}
+}
+
+func (f *Fanout) Publish(ctx context.Context, event events.Event) error {
+	switch event.Type {
+	case events.TypeTransferPosted:
+		var data events.TransferPosted
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return err
+		}
+
+		from, err := f.store.GetAccount(ctx, data.FromAccountID)
+		if err != nil {
+			return err
+		}
+
+		if err := f.enqueue(ctx, from.Owner, EventTransferSent, event); err != nil {
+			return err
+		}
+
+		to, err := f.store.GetAccount(ctx, data.ToAccountID)
+		if err != nil {
+			return err
+		}
+
+		return f.enqueue(ctx, to.Owner, EventTransferReceived, event)
+	case events.TypeAccountCreated:
+		var data events.AccountCreated
+		if err := json.Unmarshal(event.Data, &data); err != nil {
+			return err
+		}
+
+		return f.enqueue(ctx, data.Owner, EventAccountCreated, event)
+	}
+
+	return nil
+}
+
+func (f *Fanout) enqueue(ctx context.Context, owner string, eventType string, event events.Event) error {
+	payload, err := json.Marshal(Notification{
+		EventID:    event.ID,
+		Type:       eventType,
+		OccurredAt: event.OccurredAt,
+		Data:       event.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = f.store.EnqueueWebhookDeliveries(ctx, db.EnqueueWebhookDeliveriesParams{
+		EventID:   event.ID,
+		EventType: eventType,
+		Payload:   payload,
+		Owner:     owner,
+	})
+	return err
+}
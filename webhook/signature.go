@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	DeliveryHeader  = "X-Webhook-ID"
+	EventTypeHeader = "X-Webhook-Event"
+
+	secretPrefix = "whsec_"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrSignatureExpired = errors.New("webhook signature timestamp outside tolerance")
+)
+
+// NewSecret returns a random signing secret for a subscription.
+func NewSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return secretPrefix + hex.EncodeToString(key), nil
+}
+
+// Sign returns the signature header for body sent at t. The timestamp is part
+// of the signed content, so a captured request cannot be replayed once it
+// falls outside the receiver's tolerance.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := t.Unix()
+	return fmt.Sprintf("t=%d,v1=%s", ts, digest(secret, ts, body))
+}
+
+// Verify checks a signature header produced by Sign. Receivers should reject
+// requests older than tolerance.
+func Verify(header string, secret string, body []byte, now time.Time, tolerance time.Duration) error {
+	var ts int64
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrInvalidSignature
+		}
+
+		switch key {
+		case "t":
+			var err error
+			ts, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return ErrInvalidSignature
+			}
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if ts == 0 || len(signatures) == 0 {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(ts, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	expected := digest(secret, ts, body)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+func digest(secret string, ts int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(secret, secretPrefix))
+
+	body := []byte(`{"event_id":1}`)
+	sentAt := time.Unix(1_700_000_000, 0)
+	header := Sign(secret, sentAt, body)
+	require.True(t, strings.HasPrefix(header, "t=1700000000,v1="))
+
+	require.NoError(t, Verify(header, secret, body, sentAt.Add(time.Minute), 5*time.Minute))
+
+	err = Verify(header, secret, []byte(`{"event_id":2}`), sentAt, 5*time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	err = Verify(header, "whsec_other", body, sentAt, 5*time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	err = Verify(header, secret, body, sentAt.Add(10*time.Minute), 5*time.Minute)
+	require.ErrorIs(t, err, ErrSignatureExpired)
+
+	// moving the timestamp forward to get past the tolerance breaks the MAC
+	replayed := strings.Replace(header, "t=1700000000", "t=1700000600", 1)
+	err = Verify(replayed, secret, body, sentAt.Add(10*time.Minute), 5*time.Minute)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	for _, malformed := range []string{"", "v1=abc", "t=1700000000", "t=x,v1=abc", "garbage"} {
+		err = Verify(malformed, secret, body, sentAt, 5*time.Minute)
+		require.ErrorIs(t, err, ErrInvalidSignature, malformed)
+	}
+}
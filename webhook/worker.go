@@ -0,0 +1,210 @@
+// Package webhook notifies customers of events on their accounts by POSTing
+// signed notifications to the URLs they subscribe. Deliveries are fanned out
+// from the outbox and retried with exponential backoff until they succeed or
+// run out of attempts, at which point they are dead-lettered.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	db "github.com/aulas/demo-bank/db/sqlc"
+)
+
+const (
+	defaultBatchSize   = 20
+	defaultMaxAttempts = 8
+
+	requestTimeout = 10 * time.Second
+	baseBackoff    = 30 * time.Second
+	maxBackoff     = 6 * time.Hour
+	maxErrorLength = 500
+)
+
+var (
+	deliveredTotal = expvar.NewInt("webhook_delivered_total")
+	failedTotal    = expvar.NewInt("webhook_failed_attempts_total")
+	deadTotal      = expvar.NewInt("webhook_dead_lettered_total")
+)
+
+type Worker struct {
+	store       db.Store
+	client      *http.Client
+	batchSize   int32
+	maxAttempts int32
+	now         func() time.Time
+}
+
+func NewWorker(store db.Store, batchSize int32, maxAttempts int32) *Worker {
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	return &Worker{
+		store:       store,
+		client:      newClient(publicAddress),
+		batchSize:   batchSize,
+		maxAttempts: maxAttempts,
+		now:         time.Now,
+	}
+}
+
+// Backoff is how long to wait before retrying a delivery that has failed
+// attempts times: 30s, 1m, 2m, ... up to 6h.
+func Backoff(attempts int32) time.Duration {
+	d := baseBackoff
+	for i := int32(1); i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return d
+}
+
+// RunOnce attempts one batch of due deliveries and returns how many were
+// delivered. Claimed deliveries are leased for long enough to send the whole
+// batch, so concurrent workers do not send the same delivery twice.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	lease := time.Duration(w.batchSize)*requestTimeout + time.Minute
+	deliveries, err := w.store.ClaimWebhookDeliveries(ctx, db.ClaimWebhookDeliveriesParams{
+		LeaseUntil: w.now().Add(lease),
+		Size:       w.batchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	subscriptions := make(map[int64]db.WebhookSubscription)
+	var delivered int
+	for _, d := range deliveries {
+		sub, ok := subscriptions[d.SubscriptionID]
+		if !ok {
+			sub, err = w.store.GetWebhookSubscription(ctx, d.SubscriptionID)
+			if errors.Is(err, sql.ErrNoRows) {
+				// Unsubscribed since; its deliveries are deleted with it.
+				continue
+			}
+			if err != nil {
+				return delivered, err
+			}
+
+			subscriptions[d.SubscriptionID] = sub
+		}
+
+		ok, err = w.attempt(ctx, sub, d)
+		if err != nil {
+			return delivered, err
+		}
+		if ok {
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+func (w *Worker) attempt(ctx context.Context, sub db.WebhookSubscription, d db.WebhookDelivery) (bool, error) {
+	code, sendErr := w.send(ctx, sub, d)
+	if sendErr == nil {
+		_, err := w.store.MarkWebhookDelivered(ctx, db.MarkWebhookDeliveredParams{
+			ID:             d.ID,
+			LastStatusCode: int32(code),
+		})
+		if err != nil {
+			return false, err
+		}
+
+		deliveredTotal.Add(1)
+		return true, nil
+	}
+
+	failedTotal.Add(1)
+	attempts := d.Attempts + 1
+	status := db.WebhookDeliveryStatusPending
+	next := w.now().Add(Backoff(attempts))
+	if attempts >= w.maxAttempts {
+		status = db.WebhookDeliveryStatusDead
+		next = w.now()
+		deadTotal.Add(1)
+	}
+
+	message := sendErr.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+
+	_, err := w.store.RecordWebhookFailure(ctx, db.RecordWebhookFailureParams{
+		ID:             d.ID,
+		Status:         status,
+		NextAttemptAt:  next,
+		LastStatusCode: int32(code),
+		LastError:      message,
+	})
+	return false, err
+}
+
+// send POSTs the delivery and returns the response status, or 0 when the
+// receiver could not be reached. Only 2xx responses count as delivered.
+func (w *Worker) send(ctx context.Context, sub db.WebhookSubscription, d db.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Url, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(EventTypeHeader, d.EventType)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, w.now(), d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Schedule sends due deliveries every interval until ctx is cancelled.
+func (w *Worker) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := w.RunOnce(ctx)
+				if err != nil {
+					log.Printf("webhook delivery failed: %v", err)
+					break
+				}
+				if n < int(w.batchSize) {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/aulas/demo-bank/db/mock"
+	db "github.com/aulas/demo-bank/db/sqlc"
+	"github.com/aulas/demo-bank/events"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+const testSecret = "whsec_test"
+
+func newTestWorker(t *testing.T, maxAttempts int32) (*Worker, *mockdb.MockStore, time.Time) {
+	store := mockdb.NewMockStore(gomock.NewController(t))
+	now := time.Now().Truncate(time.Second)
+
+	worker := NewWorker(store, 10, maxAttempts)
+	worker.now = func() time.Time { return now }
+	// the receivers are local test servers
+	worker.client = newClient(func(net.IP) bool { return true })
+
+	return worker, store, now
+}
+
+func pendingDelivery(sub db.WebhookSubscription, attempts int32) db.WebhookDelivery {
+	return db.WebhookDelivery{
+		ID:             11,
+		SubscriptionID: sub.ID,
+		EventID:        3,
+		EventType:      EventTransferReceived,
+		Payload:        json.RawMessage(`{"event_id":3,"type":"transfer.received"}`),
+		Status:         db.WebhookDeliveryStatusPending,
+		Attempts:       attempts,
+	}
+}
+
+func TestWorkerDelivers(t *testing.T) {
+	var gotBody []byte
+	var gotHeader http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	worker, store, now := newTestWorker(t, 3)
+	sub := db.WebhookSubscription{ID: 5, Url: receiver.URL, Secret: testSecret}
+	delivery := pendingDelivery(sub, 0)
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.WebhookDelivery{delivery}, nil)
+
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+		Times(1).
+		Return(sub, nil)
+
+	store.EXPECT().
+		MarkWebhookDelivered(gomock.Any(), gomock.Eq(db.MarkWebhookDeliveredParams{
+			ID:             delivery.ID,
+			LastStatusCode: http.StatusNoContent,
+		})).
+		Times(1)
+
+	n, err := worker.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	require.JSONEq(t, string(delivery.Payload), string(gotBody))
+	require.Equal(t, "11", gotHeader.Get(DeliveryHeader))
+	require.Equal(t, EventTransferReceived, gotHeader.Get(EventTypeHeader))
+	require.NoError(t, Verify(gotHeader.Get(SignatureHeader), testSecret, gotBody, now, time.Minute))
+}
+
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	worker, store, now := newTestWorker(t, 3)
+	sub := db.WebhookSubscription{ID: 5, Url: receiver.URL, Secret: testSecret}
+	delivery := pendingDelivery(sub, 1)
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.WebhookDelivery{delivery}, nil)
+
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+		Times(1).
+		Return(sub, nil)
+
+	store.EXPECT().
+		RecordWebhookFailure(gomock.Any(), gomock.Eq(db.RecordWebhookFailureParams{
+			ID:             delivery.ID,
+			Status:         db.WebhookDeliveryStatusPending,
+			NextAttemptAt:  now.Add(time.Minute),
+			LastStatusCode: http.StatusInternalServerError,
+			LastError:      "receiver responded 500 Internal Server Error",
+		})).
+		Times(1)
+
+	n, err := worker.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+}
+
+func TestWorkerDeadLetters(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	worker, store, now := newTestWorker(t, 3)
+	sub := db.WebhookSubscription{ID: 5, Url: receiver.URL, Secret: testSecret}
+	delivery := pendingDelivery(sub, 2)
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.WebhookDelivery{delivery}, nil)
+
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+		Times(1).
+		Return(sub, nil)
+
+	store.EXPECT().
+		RecordWebhookFailure(gomock.Any(), gomock.Eq(db.RecordWebhookFailureParams{
+			ID:             delivery.ID,
+			Status:         db.WebhookDeliveryStatusDead,
+			NextAttemptAt:  now,
+			LastStatusCode: http.StatusBadGateway,
+			LastError:      "receiver responded 502 Bad Gateway",
+		})).
+		Times(1)
+
+	_, err := worker.RunOnce(context.Background())
+	require.NoError(t, err)
+}
+
+func TestWorkerUnreachableReceiver(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close()
+
+	worker, store, _ := newTestWorker(t, 3)
+	sub := db.WebhookSubscription{ID: 5, Url: url, Secret: testSecret}
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.WebhookDelivery{pendingDelivery(sub, 0)}, nil)
+
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+		Times(1).
+		Return(sub, nil)
+
+	store.EXPECT().
+		RecordWebhookFailure(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordWebhookFailureParams) (db.WebhookDelivery, error) {
+			require.Equal(t, db.WebhookDeliveryStatusPending, arg.Status)
+			require.Zero(t, arg.LastStatusCode)
+			require.NotEmpty(t, arg.LastError)
+			return db.WebhookDelivery{}, nil
+		})
+
+	_, err := worker.RunOnce(context.Background())
+	require.NoError(t, err)
+}
+
+func TestWorkerRefusesInternalReceiver(t *testing.T) {
+	var called bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	worker, store, _ := newTestWorker(t, 3)
+	worker.client = newClient(publicAddress)
+	sub := db.WebhookSubscription{ID: 5, Url: receiver.URL, Secret: testSecret}
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.WebhookDelivery{pendingDelivery(sub, 0)}, nil)
+
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+		Times(1).
+		Return(sub, nil)
+
+	store.EXPECT().
+		RecordWebhookFailure(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordWebhookFailureParams) (db.WebhookDelivery, error) {
+			require.Zero(t, arg.LastStatusCode)
+			require.Contains(t, arg.LastError, "forbidden address")
+			return db.WebhookDelivery{}, nil
+		})
+
+	_, err := worker.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.False(t, called)
+}
+
+func TestWorkerDoesNotFollowRedirects(t *testing.T) {
+	var redirected bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+
+	receiver := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer receiver.Close()
+
+	worker, store, _ := newTestWorker(t, 3)
+	sub := db.WebhookSubscription{ID: 5, Url: receiver.URL, Secret: testSecret}
+
+	store.EXPECT().
+		ClaimWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return([]db.WebhookDelivery{pendingDelivery(sub, 0)}, nil)
+
+	store.EXPECT().
+		GetWebhookSubscription(gomock.Any(), gomock.Eq(sub.ID)).
+		Times(1).
+		Return(sub, nil)
+
+	store.EXPECT().
+		RecordWebhookFailure(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ context.Context, arg db.RecordWebhookFailureParams) (db.WebhookDelivery, error) {
+			require.Equal(t, int32(http.StatusTemporaryRedirect), arg.LastStatusCode)
+			return db.WebhookDelivery{}, nil
+		})
+
+	_, err := worker.RunOnce(context.Background())
+	require.NoError(t, err)
+	require.False(t, redirected)
+}
+
+func TestPublicAddress(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+	} {
+		require.Equal(t, public, publicAddress(net.ParseIP(addr)), addr)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(1))
+	require.Equal(t, time.Minute, Backoff(2))
+	require.Equal(t, 4*time.Minute, Backoff(4))
+	require.Equal(t, maxBackoff, Backoff(20))
+}
+
+func TestFanoutTransferPosted(t *testing.T) {
+	store := mockdb.NewMockStore(gomock.NewController(t))
+	fanout := NewFanout(store)
+
+	data, err := json.Marshal(events.TransferPosted{TransferID: 9, FromAccountID: 1, ToAccountID: 2, Amount: 50})
+	require.NoError(t, err)
+
+	event := events.Event{ID: 4, Type: events.TypeTransferPosted, OccurredAt: time.Now(), Data: data}
+
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(1))).Times(1).Return(db.Account{ID: 1, Owner: "alice"}, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(int64(2))).Times(1).Return(db.Account{ID: 2, Owner: "bob"}, nil)
+
+	enqueued := map[string]string{}
+	store.EXPECT().
+		EnqueueWebhookDeliveries(gomock.Any(), gomock.Any()).
+		Times(2).
+		DoAndReturn(func(_ context.Context, arg db.EnqueueWebhookDeliveriesParams) (int64, error) {
+			require.Equal(t, event.ID, arg.EventID)
+
+			var n Notification
+			require.NoError(t, json.Unmarshal(arg.Payload, &n))
+			require.Equal(t, arg.EventType, n.Type)
+			require.JSONEq(t, string(data), string(n.Data))
+
+			enqueued[arg.Owner] = arg.EventType
+			return 1, nil
+		})
+
+	require.NoError(t, fanout.Publish(context.Background(), event))
+	require.Equal(t, map[string]string{"alice": EventTransferSent, "bob": EventTransferReceived}, enqueued)
+}